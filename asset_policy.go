@@ -9,3 +9,13 @@ type RuntimeStats struct {
 	LayerCount    int
 	UploadCount   int
 }
+
+// StreamPolicy controls how many chunks of a streamed map stay in RAM.
+// Radius is how many chunks beyond the view are loaded ahead of the
+// camera. Capacity caps the resident chunks, evicting the least recently
+// used; zero allows twice the chunks the view needs. Chunks in view are
+// never evicted, so a small Capacity is exceeded rather than drop them.
+type StreamPolicy struct {
+	Radius   int
+	Capacity int
+}
@@ -79,6 +79,18 @@ func (b *Bundle) loadSheetEntry(entry format.BundleEntry) (*Sheet, error) {
 }
 
 func (b *Bundle) loadMapEntry(entry format.BundleEntry) (*Map, error) {
+	// A chunked map read through a loader that supports ranged reads
+	// streams its chunks instead of loading every cell.
+	if rl, ok := b.loader.(tileloader.RangeLoader); ok {
+		parsed, index, err := tileloader.OpenMap(entry.Path, rl)
+		if err != nil {
+			return nil, err
+		}
+		if index != nil {
+			return newStreamedMap(parsed, index, entry.Path, rl), nil
+		}
+		return newMap(parsed), nil
+	}
 	parsed, err := tileloader.LoadMap(entry.Path, b.loader)
 	if err != nil {
 		return nil, err
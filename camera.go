@@ -1,6 +1,8 @@
 package gosprite64
 
 import (
+	"math"
+
 	"github.com/drpaneas/gosprite64/internal/rendergeom"
 	"github.com/drpaneas/gosprite64/math2d"
 )
@@ -12,14 +14,68 @@ type Camera struct {
 
 	Zoom float32
 
+	// Rotation turns the view around the middle of the screen, in radians.
+	// Positive values turn the camera clockwise, so the world on screen
+	// turns counter-clockwise.
+	Rotation float32
+
 	FollowTarget *math2d.Vec2
 	FollowSpeed  float32
 
+	// DeadZone is a window in screen pixels that FollowTarget can move
+	// inside without moving the camera. A zero DeadZone keeps the target
+	// centered.
+	DeadZone math2d.Rect
+
+	// LookAhead leads the camera by this many frames of target motion, so
+	// more of the level is visible in the direction the target is moving.
+	// LookAheadMax caps the lead per axis; zero leaves that axis uncapped.
+	LookAhead    float32
+	LookAheadMax math2d.Vec2
+
+	// LockY holds the camera's height while the target stays inside the
+	// dead zone. SnapToLedge moves it to a new height when the target lands.
+	LockY bool
+
+	// FollowTargets frames every target at once and takes precedence over
+	// FollowTarget. With AutoZoom the camera zooms out so all targets plus
+	// FrameMargin pixels fit, between MinZoom and MaxZoom (zero MaxZoom
+	// means 1).
+	FollowTargets []*math2d.Vec2
+	AutoZoom      bool
+	FrameMargin   float32
+	MinZoom       float32
+	MaxZoom       float32
+
+	// Rail constrains the followed point to the nearest point on a path.
+	Rail *CameraRail
+
 	Bounds *math2d.Rect
 
+	// ShakeDecay is the trauma lost per UpdateShake; zero means 1/60.
+	// ShakeFrequency switches shake from per-frame noise to smooth noise
+	// that oscillates this many times per second. ShakeMax is the largest
+	// offset in pixels at full trauma; zero means 8.
+	ShakeDecay     float32
+	ShakeFrequency float32
+	ShakeMax       float32
+
+	// KickDecay is the fraction of a Kick removed per UpdateShake; zero
+	// means 0.2.
+	KickDecay float32
+
 	trauma    float32
 	shakeRng  *math2d.Rand
 	shakeTick uint64
+	shakeTime uint64
+	kick      math2d.Vec2
+
+	lastTarget math2d.Vec2
+	hasTarget  bool
+	lead       math2d.Vec2
+	ledgeY     float32
+	hasLedge   bool
+	moves      []cameraMove
 }
 
 func newDefaultCamera() *Camera {
@@ -39,55 +95,79 @@ func (c *Camera) EffectiveZoom() float32 {
 }
 
 // WorldToScreen converts world coordinates to screen coordinates,
-// accounting for camera position and zoom.
+// accounting for camera position, zoom and rotation. Zoom scales around
+// the camera's top-left corner; rotation turns around the screen center.
 func (c *Camera) WorldToScreen(worldX, worldY float32) (float32, float32) {
 	if c == nil {
 		return worldX, worldY
 	}
-	z := c.EffectiveZoom()
-	return (worldX - float32(c.X)) * z, (worldY - float32(c.Y)) * z
+	return c.view().WorldToScreen(worldX, worldY)
+}
+
+// ScreenToWorld converts screen coordinates back to world coordinates. It
+// is the inverse of WorldToScreen.
+func (c *Camera) ScreenToWorld(screenX, screenY float32) (float32, float32) {
+	if c == nil {
+		return screenX, screenY
+	}
+	return c.view().ScreenToWorld(screenX, screenY)
+}
+
+// transformed reports whether world drawing must scale or rotate.
+func (c *Camera) transformed() bool {
+	return c != nil && c.view().Transformed()
 }
 
 // UpdateFollow moves the camera toward FollowTarget by FollowSpeed (0..1).
 // Speed of 1.0 snaps instantly. Speed of 0.1 gives smooth lerp.
-// Centers the target in the viewport.
+// Centers the target in the viewport unless DeadZone is set. While a pan
+// queued by PanTo is playing, UpdateFollow plays it instead of following.
 func (c *Camera) UpdateFollow() {
-	if c == nil || c.FollowTarget == nil {
+	if c == nil {
 		return
 	}
-	targetX := c.FollowTarget.X - float32(c.Width)/2
-	targetY := c.FollowTarget.Y - float32(c.Height)/2
-
-	speed := c.FollowSpeed
-	if speed <= 0 {
-		speed = 1
+	if c.stepMoves() {
+		return
 	}
-	if speed > 1 {
-		speed = 1
+	goalX, goalY, ok := c.followGoal()
+	if !ok {
+		return
 	}
+	speed := c.followSpeed()
 
-	newX := math2d.Lerp(float32(c.X), targetX, speed)
-	newY := math2d.Lerp(float32(c.Y), targetY, speed)
+	newX := math2d.Lerp(float32(c.X), goalX, speed)
+	newY := math2d.Lerp(float32(c.Y), goalY, speed)
 
-	dx := targetX - newX
+	dx := goalX - newX
 	if dx < 0 {
 		dx = -dx
 	}
-	dy := targetY - newY
+	dy := goalY - newY
 	if dy < 0 {
 		dy = -dy
 	}
 	if dx < 1 {
-		newX = targetX
+		newX = goalX
 	}
 	if dy < 1 {
-		newY = targetY
+		newY = goalY
 	}
 
 	c.X = int(newX)
 	c.Y = int(newY)
 }
 
+func (c *Camera) followSpeed() float32 {
+	speed := c.FollowSpeed
+	if speed <= 0 {
+		speed = 1
+	}
+	if speed > 1 {
+		speed = 1
+	}
+	return speed
+}
+
 // ClampToBounds restricts the camera position to stay within Bounds.
 // No-op if Bounds is nil.
 func (c *Camera) ClampToBounds() {
@@ -134,29 +214,92 @@ func (c *Camera) AddTrauma(amount float32) {
 	c.shakeRng = math2d.NewRand(c.shakeTick * 7919)
 }
 
-// UpdateShake decays trauma each frame. Call once per Update().
+// Kick pushes the view by (dx, dy) pixels, for example against the
+// direction of a hit or recoil. The push eases back to zero by KickDecay
+// each UpdateShake and is added to ShakeOffset.
+func (c *Camera) Kick(dx, dy float32) {
+	if c == nil {
+		return
+	}
+	c.kick = c.kick.Add(math2d.Vec2{X: dx, Y: dy})
+}
+
+// UpdateShake decays trauma and kicks each frame. Call once per Update().
 func (c *Camera) UpdateShake() {
 	if c == nil {
 		return
 	}
-	c.trauma -= 1.0 / 60.0
+	decay := c.ShakeDecay
+	if decay <= 0 {
+		decay = 1.0 / 60.0
+	}
+	c.trauma -= decay
 	if c.trauma < 0 {
 		c.trauma = 0
 	}
+	c.shakeTime++
+
+	kickDecay := c.KickDecay
+	if kickDecay <= 0 {
+		kickDecay = 0.2
+	}
+	c.kick = c.kick.Scale(1 - math2d.Clamp(kickDecay, 0, 1))
+	if c.kick.LengthSq() < 0.25 {
+		c.kick = math2d.Vec2{}
+	}
 }
 
 // ShakeOffset returns the current frame's shake displacement.
 // Apply this to your draw offset: drawX = cam.X + shakeX.
 func (c *Camera) ShakeOffset() (int, int) {
-	if c == nil || c.trauma <= 0 {
+	if c == nil {
 		return 0, 0
 	}
-	if c.shakeRng == nil {
-		c.shakeRng = math2d.NewRand(12345)
+	ox, oy := c.kick.X, c.kick.Y
+	if c.trauma > 0 {
+		magnitude := c.trauma * c.trauma
+		maxOffset := c.ShakeMax
+		if maxOffset <= 0 {
+			maxOffset = 8
+		}
+		nx, ny := c.shakeNoise()
+		ox += nx * maxOffset * magnitude
+		oy += ny * maxOffset * magnitude
 	}
-	magnitude := c.trauma * c.trauma
-	maxOffset := float32(8)
-	ox := (c.shakeRng.Float32()*2 - 1) * maxOffset * magnitude
-	oy := (c.shakeRng.Float32()*2 - 1) * maxOffset * magnitude
 	return int(ox), int(oy)
 }
+
+// shakeNoise returns a displacement in -1..1 on each axis. Without a
+// ShakeFrequency every call draws fresh random values; with one, the
+// result is smooth noise sampled at the current UpdateShake frame.
+func (c *Camera) shakeNoise() (float32, float32) {
+	if c.ShakeFrequency <= 0 {
+		if c.shakeRng == nil {
+			c.shakeRng = math2d.NewRand(12345)
+		}
+		return c.shakeRng.Float32()*2 - 1, c.shakeRng.Float32()*2 - 1
+	}
+	t := float32(c.shakeTime) * c.ShakeFrequency / 60
+	return valueNoise(1, t), valueNoise(2, t)
+}
+
+// valueNoise interpolates hashed lattice values in -1..1 with smoothstep,
+// so the result is continuous in t and identical on every run.
+func valueNoise(channel uint64, t float32) float32 {
+	i := math.Floor(float64(t))
+	f := t - float32(i)
+	f = f * f * (3 - 2*f)
+	a := latticeValue(channel, int64(i))
+	b := latticeValue(channel, int64(i)+1)
+	return math2d.Lerp(a, b, f)
+}
+
+func latticeValue(channel uint64, i int64) float32 {
+	x := uint64(i)*0x9E3779B97F4A7C15 ^ channel*0xBF58476D1CE4E5B9
+	x ^= x >> 30
+	x *= 0xBF58476D1CE4E5B9
+	x ^= x >> 27
+	x *= 0x94D049BB133111EB
+	x ^= x >> 31
+	return float32(x>>40)/float32(1<<23) - 1
+}
@@ -0,0 +1,145 @@
+package gosprite64
+
+import "github.com/drpaneas/gosprite64/math2d"
+
+// SnapToLedge tells a LockY camera that the target has landed at worldY.
+// The camera eases vertically until worldY sits in the middle of the dead
+// zone, or of the screen when DeadZone is zero, and then holds that height.
+func (c *Camera) SnapToLedge(worldY float32) {
+	if c == nil {
+		return
+	}
+	c.ledgeY = worldY
+	c.hasLedge = true
+}
+
+// followGoal returns the top-left corner UpdateFollow is heading for. It
+// also advances the look-ahead lead and, with AutoZoom, the zoom level, so
+// it must be called at most once per frame.
+func (c *Camera) followGoal() (float32, float32, bool) {
+	focus, ok := c.followFocus()
+	if !ok {
+		return 0, 0, false
+	}
+	if c.Rail != nil && c.Rail.Len() > 0 {
+		focus = c.Rail.Point(c.Rail.Nearest(focus))
+	}
+
+	z := c.EffectiveZoom()
+	zone := c.DeadZone
+	if zone.W <= 0 && zone.H <= 0 {
+		zone = math2d.Rect{X: float32(c.Width) / 2, Y: float32(c.Height) / 2}
+	}
+	x, y := float32(c.X), float32(c.Y)
+	goalX := windowAxis(x, focus.X, zone.X/z, zone.Right()/z)
+	goalY := windowAxis(y, focus.Y, zone.Y/z, zone.Bottom()/z)
+
+	if c.LockY {
+		top, bottom := zone.Y, zone.Bottom()
+		if zone.H <= 0 {
+			top, bottom = 0, float32(c.Height)
+		}
+		switch {
+		case focus.Y < y+top/z || focus.Y > y+bottom/z:
+			// Leaving the window breaks the lock until the next landing.
+			goalY = windowAxis(y, focus.Y, top/z, bottom/z)
+			c.hasLedge = false
+		case c.hasLedge:
+			goalY = c.ledgeY - (top+bottom)/(2*z)
+		default:
+			goalY = y
+		}
+	}
+	return goalX, goalY, true
+}
+
+// windowAxis returns the camera position on one axis that keeps focus
+// between lo and hi world units from the camera edge.
+func windowAxis(pos, focus, lo, hi float32) float32 {
+	switch {
+	case focus < pos+lo:
+		return focus - lo
+	case focus > pos+hi:
+		return focus - hi
+	}
+	return pos
+}
+
+// followFocus returns the world point the camera should frame, including
+// the look-ahead lead.
+func (c *Camera) followFocus() (math2d.Vec2, bool) {
+	var focus math2d.Vec2
+	switch {
+	case len(c.FollowTargets) > 0:
+		center, ok := c.frameTargets()
+		if !ok {
+			return math2d.Vec2{}, false
+		}
+		focus = center
+	case c.FollowTarget != nil:
+		focus = *c.FollowTarget
+	default:
+		return math2d.Vec2{}, false
+	}
+	return focus.Add(c.updateLead(focus)), true
+}
+
+// updateLead measures how far focus moved since the last frame and eases
+// the look-ahead lead toward that motion scaled by LookAhead.
+func (c *Camera) updateLead(focus math2d.Vec2) math2d.Vec2 {
+	var want math2d.Vec2
+	if c.hasTarget && c.LookAhead != 0 {
+		want = focus.Sub(c.lastTarget).Scale(c.LookAhead)
+		if c.LookAheadMax.X > 0 {
+			want.X = math2d.Clamp(want.X, -c.LookAheadMax.X, c.LookAheadMax.X)
+		}
+		if c.LookAheadMax.Y > 0 {
+			want.Y = math2d.Clamp(want.Y, -c.LookAheadMax.Y, c.LookAheadMax.Y)
+		}
+	}
+	c.lastTarget = focus
+	c.hasTarget = true
+	c.lead = c.lead.Lerp(want, c.followSpeed())
+	return c.lead
+}
+
+// frameTargets returns the center of the box around FollowTargets and,
+// with AutoZoom, eases Zoom toward the level that fits the whole box.
+func (c *Camera) frameTargets() (math2d.Vec2, bool) {
+	var lo, hi math2d.Vec2
+	n := 0
+	for _, t := range c.FollowTargets {
+		if t == nil {
+			continue
+		}
+		if n == 0 {
+			lo, hi = *t, *t
+		} else {
+			lo, hi = lo.Min(*t), hi.Max(*t)
+		}
+		n++
+	}
+	if n == 0 {
+		return math2d.Vec2{}, false
+	}
+
+	if c.AutoZoom && c.Width > 0 && c.Height > 0 {
+		w := max(hi.X-lo.X+2*c.FrameMargin, 1)
+		h := max(hi.Y-lo.Y+2*c.FrameMargin, 1)
+		want := min(float32(c.Width)/w, float32(c.Height)/h)
+		maxZoom := c.MaxZoom
+		if maxZoom <= 0 {
+			maxZoom = 1
+		}
+		want = min(want, maxZoom)
+		if c.MinZoom > 0 {
+			want = max(want, c.MinZoom)
+		}
+		z := math2d.Lerp(c.EffectiveZoom(), want, c.followSpeed())
+		if d := z - want; d > -0.001 && d < 0.001 {
+			z = want
+		}
+		c.Zoom = z
+	}
+	return lo.Add(hi).Scale(0.5), true
+}
@@ -0,0 +1,228 @@
+package gosprite64
+
+import "github.com/drpaneas/gosprite64/math2d"
+
+// CameraRail is a smooth path through world points, used to constrain
+// Camera.Rail or to drive a cinematic PanAlongRail. The path is a
+// Catmull-Rom spline, so it passes through every point.
+type CameraRail struct {
+	points []math2d.Vec2
+}
+
+// railSamples is the number of samples per segment used by Nearest.
+const railSamples = 16
+
+// NewCameraRail creates a rail through points, in order.
+func NewCameraRail(points ...math2d.Vec2) *CameraRail {
+	return &CameraRail{points: append([]math2d.Vec2(nil), points...)}
+}
+
+// Len returns the number of points on the rail.
+func (r *CameraRail) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.points)
+}
+
+// Point returns the position at t, where 0 is the first point and 1 the
+// last. Each segment between two points covers an equal share of t.
+func (r *CameraRail) Point(t float32) math2d.Vec2 {
+	if r.Len() == 0 {
+		return math2d.Vec2{}
+	}
+	segments := len(r.points) - 1
+	if segments == 0 {
+		return r.points[0]
+	}
+	t = math2d.Clamp(t, 0, 1) * float32(segments)
+	i := int(t)
+	if i >= segments {
+		i = segments - 1
+	}
+	return r.segmentPoint(i, t-float32(i))
+}
+
+// Nearest returns the t of the rail point closest to p.
+func (r *CameraRail) Nearest(p math2d.Vec2) float32 {
+	segments := r.Len() - 1
+	if segments <= 0 {
+		return 0
+	}
+	steps := segments * railSamples
+	best, bestDist := 0, float32(-1)
+	for i := 0; i <= steps; i++ {
+		d := r.Point(float32(i) / float32(steps)).DistanceSq(p)
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+
+	// Refine around the best sample, narrowing the search each round.
+	t := float32(best) / float32(steps)
+	span := 1 / float32(steps)
+	for round := 0; round < 4; round++ {
+		center := t
+		for i := -4; i <= 4; i++ {
+			ti := math2d.Clamp(center+span*float32(i)/4, 0, 1)
+			if d := r.Point(ti).DistanceSq(p); d < bestDist {
+				t, bestDist = ti, d
+			}
+		}
+		span /= 4
+	}
+	return t
+}
+
+func (r *CameraRail) segmentPoint(i int, u float32) math2d.Vec2 {
+	p1, p2 := r.points[i], r.points[i+1]
+	p0, p3 := p1, p2
+	if i > 0 {
+		p0 = r.points[i-1]
+	}
+	if i+2 < len(r.points) {
+		p3 = r.points[i+2]
+	}
+	u2 := u * u
+	u3 := u2 * u
+	return math2d.Vec2{
+		X: catmullRom(p0.X, p1.X, p2.X, p3.X, u, u2, u3),
+		Y: catmullRom(p0.Y, p1.Y, p2.Y, p3.Y, u, u2, u3),
+	}
+}
+
+func catmullRom(p0, p1, p2, p3, u, u2, u3 float32) float32 {
+	return 0.5 * (2*p1 + (p2-p0)*u + (2*p0-5*p1+4*p2-p3)*u2 + (3*p1-p0-3*p2+p3)*u3)
+}
+
+type cameraMoveKind uint8
+
+const (
+	cameraMovePan cameraMoveKind = iota
+	cameraMoveHold
+	cameraMoveRail
+	cameraMoveReturn
+)
+
+// cameraMove is one queued cinematic step. from is the camera's top-left
+// corner when the step starts.
+type cameraMove struct {
+	kind    cameraMoveKind
+	to      math2d.Vec2
+	rail    *CameraRail
+	frames  int
+	elapsed int
+	ease    math2d.EaseFunc
+	from    math2d.Vec2
+	started bool
+}
+
+// PanTo queues a pan that centers the view on (x, y) over frames, shaped
+// by ease (linear when nil). Queued moves play one after another through
+// UpdateFollow, which stops following until the queue is empty:
+//
+//	cam.PanTo(door.X, door.Y, 45, math2d.EaseInOutQuad)
+//	cam.Hold(90)
+//	cam.ReturnToFollow(45, math2d.EaseInOutQuad)
+func (c *Camera) PanTo(x, y float32, frames int, ease math2d.EaseFunc) {
+	c.queueMove(cameraMove{kind: cameraMovePan, to: math2d.Vec2{X: x, Y: y}, frames: frames, ease: ease})
+}
+
+// Hold queues a pause that keeps the camera still for frames.
+func (c *Camera) Hold(frames int) {
+	c.queueMove(cameraMove{kind: cameraMoveHold, frames: frames})
+}
+
+// PanAlongRail queues a move that centers the view on rail, from its first
+// point to its last, over frames.
+func (c *Camera) PanAlongRail(rail *CameraRail, frames int, ease math2d.EaseFunc) {
+	if rail.Len() == 0 {
+		return
+	}
+	c.queueMove(cameraMove{kind: cameraMoveRail, rail: rail, frames: frames, ease: ease})
+}
+
+// ReturnToFollow queues a pan back to where UpdateFollow would place the
+// camera. The destination is tracked every frame, so a moving target is
+// met where it is when the pan ends.
+func (c *Camera) ReturnToFollow(frames int, ease math2d.EaseFunc) {
+	c.queueMove(cameraMove{kind: cameraMoveReturn, frames: frames, ease: ease})
+}
+
+// StopPan drops every queued move and leaves the camera where it is.
+func (c *Camera) StopPan() {
+	if c == nil {
+		return
+	}
+	clear(c.moves)
+	c.moves = c.moves[:0]
+}
+
+// Panning reports whether queued moves are still playing.
+func (c *Camera) Panning() bool {
+	return c != nil && len(c.moves) > 0
+}
+
+func (c *Camera) queueMove(m cameraMove) {
+	if c == nil {
+		return
+	}
+	if m.frames < 0 {
+		m.frames = 0
+	}
+	if m.ease == nil {
+		m.ease = math2d.Linear
+	}
+	c.moves = append(c.moves, m)
+}
+
+// stepMoves plays one frame of the front queued move and reports whether
+// one was playing.
+func (c *Camera) stepMoves() bool {
+	if len(c.moves) == 0 {
+		return false
+	}
+	m := &c.moves[0]
+	if !m.started {
+		m.started = true
+		m.from = math2d.Vec2{X: float32(c.X), Y: float32(c.Y)}
+	}
+	if m.elapsed < m.frames {
+		m.elapsed++
+	}
+	p := float32(1)
+	if m.frames > 0 {
+		p = float32(m.elapsed) / float32(m.frames)
+	}
+	p = m.ease(p)
+
+	pos := m.from
+	switch m.kind {
+	case cameraMovePan:
+		pos = m.from.Lerp(c.cornerFor(m.to), p)
+	case cameraMoveRail:
+		pos = c.cornerFor(m.rail.Point(p))
+	case cameraMoveReturn:
+		if x, y, ok := c.followGoal(); ok {
+			pos = m.from.Lerp(math2d.Vec2{X: x, Y: y}, p)
+		}
+	}
+	if m.kind != cameraMoveReturn {
+		// The target's motion during the pan is not velocity.
+		c.hasTarget = false
+	}
+	c.X, c.Y = int(pos.X), int(pos.Y)
+
+	if m.elapsed >= m.frames {
+		n := copy(c.moves, c.moves[1:])
+		c.moves[n] = cameraMove{}
+		c.moves = c.moves[:n]
+	}
+	return true
+}
+
+// cornerFor returns the top-left corner that centers the view on p.
+func (c *Camera) cornerFor(p math2d.Vec2) math2d.Vec2 {
+	z := c.EffectiveZoom()
+	return math2d.Vec2{X: p.X - float32(c.Width)/(2*z), Y: p.Y - float32(c.Height)/(2*z)}
+}
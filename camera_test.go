@@ -1,6 +1,7 @@
 package gosprite64
 
 import (
+	"math"
 	"testing"
 
 	"github.com/drpaneas/gosprite64/math2d"
@@ -149,3 +150,186 @@ func TestCameraShakeZeroTrauma(t *testing.T) {
 		t.Fatal("zero trauma should produce zero offset")
 	}
 }
+
+func TestCameraWorldToScreenWithRotation(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100, Rotation: math.Pi / 2}
+	sx, sy := c.WorldToScreen(110, 50)
+	if !almostEq(sx, 100, 0.01) || !almostEq(sy, 40, 0.01) {
+		t.Fatalf("expected (100, 40), got (%f, %f)", sx, sy)
+	}
+}
+
+func TestCameraScreenToWorldRoundTrip(t *testing.T) {
+	c := &Camera{X: 64, Y: -16, Width: 288, Height: 216, Zoom: 0.75, Rotation: -0.4}
+	sx, sy := c.WorldToScreen(300, 90)
+	wx, wy := c.ScreenToWorld(sx, sy)
+	if !almostEq(wx, 300, 0.01) || !almostEq(wy, 90, 0.01) {
+		t.Fatalf("expected (300, 90), got (%f, %f)", wx, wy)
+	}
+}
+
+func TestCameraSpriteOptionsFoldZoomAndRotation(t *testing.T) {
+	c := &Camera{Zoom: 2, Rotation: 0.5}
+	got := cameraSpriteOptions(c, DrawSpriteOptions{ScaleX: 1.5, Rotation: 0.25, FlipH: true})
+	if got.ScaleX != 3 || got.ScaleY != 2 || !almostEq(got.Rotation, -0.25, 0.0001) || !got.FlipH {
+		t.Fatalf("cameraSpriteOptions() = %+v", got)
+	}
+}
+
+func TestCameraDeadZoneHoldsInside(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100, DeadZone: math2d.Rect{X: 80, Y: 30, W: 40, H: 40}}
+	c.FollowTarget = &math2d.Vec2{X: 110, Y: 50}
+	c.UpdateFollow()
+	if c.X != 0 || c.Y != 0 {
+		t.Fatalf("target inside dead zone moved camera to (%d, %d)", c.X, c.Y)
+	}
+
+	c.FollowTarget.X = 150
+	c.UpdateFollow()
+	if c.X != 30 || c.Y != 0 {
+		t.Fatalf("expected camera at (30, 0) with target on zone edge, got (%d, %d)", c.X, c.Y)
+	}
+}
+
+func TestCameraLookAheadLeadsMotion(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100, LookAhead: 10, LookAheadMax: math2d.Vec2{X: 30}}
+	c.FollowTarget = &math2d.Vec2{X: 100, Y: 50}
+	c.UpdateFollow()
+	if c.X != 0 {
+		t.Fatalf("first frame has no velocity, expected X=0, got %d", c.X)
+	}
+	c.FollowTarget.X += 2
+	c.UpdateFollow()
+	if c.X != 22 {
+		t.Fatalf("expected 2px/frame * 10 frames lead, X=22, got %d", c.X)
+	}
+	c.FollowTarget.X += 5
+	c.UpdateFollow()
+	if c.X != 37 {
+		t.Fatalf("expected lead capped at 30, X=37, got %d", c.X)
+	}
+}
+
+func TestCameraLockYSnapsToLedge(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100, LockY: true}
+	c.FollowTarget = &math2d.Vec2{X: 100, Y: 50}
+	c.FollowTarget.Y = 20 // jump
+	c.UpdateFollow()
+	if c.Y != 0 {
+		t.Fatalf("locked camera followed a jump to Y=%d", c.Y)
+	}
+
+	c.SnapToLedge(80)
+	c.UpdateFollow()
+	if c.Y != 30 {
+		t.Fatalf("expected ledge centered at Y=30, got %d", c.Y)
+	}
+
+	c.FollowTarget.Y = 200 // fell off the screen
+	c.UpdateFollow()
+	if c.Y != 100 {
+		t.Fatalf("expected camera to follow the fall to Y=100, got %d", c.Y)
+	}
+}
+
+func TestCameraFrameTargetsAutoZoom(t *testing.T) {
+	a := &math2d.Vec2{X: 0, Y: 0}
+	b := &math2d.Vec2{X: 400, Y: 100}
+	c := &Camera{Width: 200, Height: 100, AutoZoom: true, MinZoom: 0.25}
+	c.FollowTargets = []*math2d.Vec2{a, nil, b}
+	c.UpdateFollow()
+	if !almostEq(c.Zoom, 0.5, 0.001) {
+		t.Fatalf("expected zoom 0.5 to fit both targets, got %f", c.Zoom)
+	}
+	if c.X != 0 || c.Y != -50 {
+		t.Fatalf("expected camera at (0, -50), got (%d, %d)", c.X, c.Y)
+	}
+
+	b.X, b.Y = 10, 10
+	c.UpdateFollow()
+	if c.Zoom != 1 {
+		t.Fatalf("close targets should zoom to MaxZoom default 1, got %f", c.Zoom)
+	}
+}
+
+func TestCameraRailPassesThroughPoints(t *testing.T) {
+	r := NewCameraRail(math2d.Vec2{X: 0, Y: 0}, math2d.Vec2{X: 100, Y: 0}, math2d.Vec2{X: 100, Y: 100})
+	if p := r.Point(0.5); !almostEq(p.X, 100, 0.01) || !almostEq(p.Y, 0, 0.01) {
+		t.Fatalf("expected middle point (100, 0), got %v", p)
+	}
+	if p := r.Point(1); !almostEq(p.X, 100, 0.01) || !almostEq(p.Y, 100, 0.01) {
+		t.Fatalf("expected end point (100, 100), got %v", p)
+	}
+	if got := r.Nearest(math2d.Vec2{X: 100, Y: 140}); !almostEq(got, 1, 0.01) {
+		t.Fatalf("expected nearest t=1, got %f", got)
+	}
+}
+
+func TestCameraFollowsRail(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100}
+	c.Rail = NewCameraRail(math2d.Vec2{X: 0, Y: 50}, math2d.Vec2{X: 1000, Y: 50})
+	c.FollowTarget = &math2d.Vec2{X: 300.5, Y: 90}
+	c.UpdateFollow()
+	if c.X != 200 || c.Y != 0 {
+		t.Fatalf("expected camera held on rail at (200, 0), got (%d, %d)", c.X, c.Y)
+	}
+}
+
+func TestCameraPanHoldReturn(t *testing.T) {
+	c := &Camera{Width: 200, Height: 100}
+	c.FollowTarget = &math2d.Vec2{X: 100, Y: 50}
+	c.PanTo(500, 50, 4, nil)
+	c.Hold(2)
+	c.ReturnToFollow(4, nil)
+
+	c.UpdateFollow()
+	if c.X != 100 {
+		t.Fatalf("expected a quarter of the pan, X=100, got %d", c.X)
+	}
+	for i := 0; i < 3; i++ {
+		c.UpdateFollow()
+	}
+	if c.X != 400 || !c.Panning() {
+		t.Fatalf("expected pan to end at X=400 and keep holding, got X=%d panning=%v", c.X, c.Panning())
+	}
+	for i := 0; i < 6; i++ {
+		c.UpdateFollow()
+	}
+	if c.X != 0 || c.Panning() {
+		t.Fatalf("expected return to X=0 with queue empty, got X=%d panning=%v", c.X, c.Panning())
+	}
+}
+
+func TestCameraShakeDecayAndFrequency(t *testing.T) {
+	c := &Camera{ShakeDecay: 0.25, ShakeFrequency: 8}
+	c.AddTrauma(1)
+	ax, ay := c.ShakeOffset()
+	bx, by := c.ShakeOffset()
+	if ax != bx || ay != by {
+		t.Fatal("smooth shake should be stable within a frame")
+	}
+	for i := 0; i < 4; i++ {
+		c.UpdateShake()
+	}
+	if c.trauma != 0 {
+		t.Fatalf("expected trauma gone after 4 frames at decay 0.25, got %f", c.trauma)
+	}
+}
+
+func TestCameraKickDecays(t *testing.T) {
+	c := &Camera{KickDecay: 0.5}
+	c.Kick(8, -4)
+	if ox, oy := c.ShakeOffset(); ox != 8 || oy != -4 {
+		t.Fatalf("expected kick offset (8, -4), got (%d, %d)", ox, oy)
+	}
+	c.UpdateShake()
+	if ox, oy := c.ShakeOffset(); ox != 4 || oy != -2 {
+		t.Fatalf("expected halved kick (4, -2), got (%d, %d)", ox, oy)
+	}
+	for i := 0; i < 8; i++ {
+		c.UpdateShake()
+	}
+	if ox, oy := c.ShakeOffset(); ox != 0 || oy != 0 {
+		t.Fatalf("expected kick to settle, got (%d, %d)", ox, oy)
+	}
+}
@@ -1,6 +1,7 @@
 package gosprite64
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/clktmr/n64/drivers/cartfs"
@@ -29,10 +30,47 @@ func LoadFromCartridge(filename string) ([]byte, error) {
 	return io.ReadAll(f)
 }
 
-var _ tileloader.Loader = cartLoader{}
+var _ tileloader.RangeLoader = cartLoader{}
 
 type cartLoader struct{}
 
 func (cartLoader) ReadAsset(path string) ([]byte, error) {
 	return LoadFromCartridge(path)
 }
+
+// AssetSize returns the size of a cartridge file, so streamed maps can
+// check the sizes they declare before reading.
+func (cartLoader) AssetSize(path string) (int64, error) {
+	f, err := _cartFS.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// ReadAssetAt reads part of a cartridge file, so streamed maps can load
+// one chunk at a time.
+func (cartLoader) ReadAssetAt(path string, p []byte, off int64) (n int, err error) {
+	f, err := _cartFS.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		closeErr := f.Close()
+		if closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}()
+
+	at, ok := f.(io.ReaderAt)
+	if !ok {
+		return 0, fmt.Errorf("cartridge file %q does not support ranged reads", path)
+	}
+	return at.ReadAt(p, off)
+}
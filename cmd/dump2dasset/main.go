@@ -35,7 +35,7 @@ func run(args []string) error {
 		if err != nil {
 			return err
 		}
-		fmt.Printf("sheet %dx%d tiles=%d palette=%d\n", sheet.TileWidth, sheet.TileHeight, sheet.TileCount, sheet.PaletteEntries)
+		fmt.Printf("sheet %dx%d tiles=%d palette=%d encoding=%s\n", sheet.TileWidth, sheet.TileHeight, sheet.TileCount, sheet.PaletteEntries, sheet.Encoding)
 	case "MAP2":
 		m, err := format.ParseMap(raw)
 		if err != nil {
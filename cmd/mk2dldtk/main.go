@@ -0,0 +1,111 @@
+//go:build !noos
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/internal/tile2d/ldtk"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "mk2dldtk: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run converts an LDtk project into one .sheet per tileset and IntGrid
+// layer, and one .map plus one .bundle per level, all in the output
+// directory. Each bundle lists the level's sheets in sheet ID order and
+// then its map, as mk2dbundle would.
+func run(args []string) error {
+	fs := flag.NewFlagSet("mk2dldtk", flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+
+	var in, out, encoding, dither string
+	fs.StringVar(&in, "in", "", "Input .ldtk project path")
+	fs.StringVar(&out, "out", "", "Output directory")
+	fs.StringVar(&encoding, "format", "auto", "Pixel encoding of the sheets, as for mk2dsheet")
+	fs.StringVar(&dither, "dither", "none", "Dithering of the sheets, as for mk2dsheet")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if in == "" || out == "" {
+		return fmt.Errorf("both -in and -out are required")
+	}
+	opts, err := format.ParseSheetOptions(encoding, dither)
+	if err != nil {
+		return err
+	}
+
+	imp, err := ldtk.ImportProject(in)
+	if err != nil {
+		return err
+	}
+	for _, w := range imp.Warnings {
+		fmt.Fprintf(os.Stderr, "mk2dldtk: warning: %s\n", w)
+	}
+	sheetNames := make([]string, len(imp.Sheets))
+	for i, s := range imp.Sheets {
+		sheetNames[i] = s.Name
+	}
+	if err := format.CheckFileNames("sheet", sheetNames); err != nil {
+		return err
+	}
+	levelNames := make([]string, len(imp.Levels))
+	for i, lv := range imp.Levels {
+		levelNames[i] = lv.Name
+	}
+	if err := format.CheckFileNames("level", levelNames); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+
+	sheetPaths := make(map[string]string, len(imp.Sheets))
+	for _, s := range imp.Sheets {
+		raw, warning, err := format.BuildSheetWithOptions(s.Image, s.TileWidth, s.TileHeight, s.Props, opts)
+		if err != nil {
+			return fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "mk2dldtk: warning: sheet %q: %s\n", s.Name, warning)
+		}
+		path := filepath.Join(out, s.Name+".sheet")
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return err
+		}
+		sheetPaths[s.Name] = path
+	}
+
+	for _, lv := range imp.Levels {
+		built, err := format.BuildMap(lv.Map)
+		if err != nil {
+			return fmt.Errorf("level %q: %w", lv.Name, err)
+		}
+		mapPath := filepath.Join(out, lv.Name+".map")
+		if err := os.WriteFile(mapPath, built, 0o644); err != nil {
+			return err
+		}
+
+		entries := make([]format.BundleEntry, 0, len(lv.Sheets)+1)
+		for _, name := range lv.Sheets {
+			entries = append(entries, format.BundleEntry{Kind: format.BundleKindSheet, Name: name, Path: sheetPaths[name]})
+		}
+		entries = append(entries, format.BundleEntry{Kind: format.BundleKindMap, Name: lv.Name, Path: mapPath})
+		raw, err := format.BuildBundle(entries)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(out, lv.Name+".bundle"), raw, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func TestMk2DLDtkWritesSheetsMapsAndBundles(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "tiles.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	project := `{"defs": {
+  "layers": [{"uid": 1, "identifier": "Walls", "type": "IntGrid", "intGridValues": [{"value": 1, "identifier": "solid", "color": "#808080"}]}],
+  "tilesets": [{"uid": 7, "identifier": "Tiles", "relPath": "tiles.png", "tileGridSize": 8, "__cWid": 2, "__cHei": 1}]},
+ "levels": [
+  {"identifier": "Start", "iid": "a", "worldX": 0, "worldY": 0, "pxWid": 16, "pxHei": 8,
+   "__neighbours": [{"levelIid": "b", "dir": "e"}],
+   "layerInstances": [
+    {"__identifier": "Ground", "__type": "Tiles", "__cWid": 2, "__cHei": 1, "__gridSize": 8, "__tilesetDefUid": 7, "visible": true,
+     "gridTiles": [{"px": [0, 0], "t": 1}, {"px": [8, 0], "f": 1, "t": 0}]},
+    {"__identifier": "Walls", "__type": "IntGrid", "__cWid": 2, "__cHei": 1, "__gridSize": 8, "layerDefUid": 1, "intGridCsv": [0, 1]}]},
+  {"identifier": "Next", "iid": "b", "worldX": 16, "worldY": 0, "pxWid": 16, "pxHei": 8,
+   "layerInstances": [
+    {"__identifier": "Walls", "__type": "IntGrid", "__cWid": 2, "__cHei": 1, "__gridSize": 8, "layerDefUid": 1, "intGridCsv": [1, 1]}]}
+ ]}`
+	in := filepath.Join(dir, "world.ldtk")
+	if err := os.WriteFile(in, []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "assets")
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(out, "Start.map"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if len(parsed.Layers) != 2 || parsed.Layers[0].Name != "Walls" || !parsed.Layers[0].Hidden || parsed.Layers[1].Name != "Ground" {
+		t.Fatalf("layers = %+v, want a hidden Walls under Ground", parsed.Layers)
+	}
+	if parsed.Layers[1].Flips[1] != format.CellFlipH {
+		t.Fatalf("flips = %v, want the second tile mirrored", parsed.Layers[1].Flips)
+	}
+	if len(parsed.Links) != 1 || parsed.Links[0].Map != "Next" || parsed.Links[0].OffsetX != 16 {
+		t.Fatalf("links = %+v, want Next 16px to the east", parsed.Links)
+	}
+
+	raw, err = os.ReadFile(filepath.Join(out, "Next.bundle"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	manifest, err := format.ParseBundle(raw)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	want := []format.BundleEntry{
+		{Kind: format.BundleKindSheet, Name: "Walls", Path: filepath.Join(out, "Walls.sheet")},
+		{Kind: format.BundleKindMap, Name: "Next", Path: filepath.Join(out, "Next.map")},
+	}
+	if len(manifest.Entries) != 2 || manifest.Entries[0] != want[0] || manifest.Entries[1] != want[1] {
+		t.Fatalf("bundle entries = %+v, want %+v", manifest.Entries, want)
+	}
+	for _, name := range []string{"Tiles.sheet", "Walls.sheet", "Start.bundle"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Fatalf("%s not written: %v", name, err)
+		}
+	}
+	raw, err = os.ReadFile(filepath.Join(out, "Tiles.sheet"))
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	if sheet, err := format.ParseSheet(raw); err != nil || sheet.Encoding != format.SheetCI4 {
+		t.Fatalf("Tiles.sheet encoding = %v, %v, want ci4 picked as mk2dsheet would", sheet.Encoding, err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out, "-format", "i4"}); err != nil {
+		t.Fatalf("run(-format i4) error = %v", err)
+	}
+	if err := run([]string{"-in", in, "-out", out, "-dither", "random"}); err == nil {
+		t.Fatal("expected error for an unknown -dither")
+	}
+}
+
+func TestMk2DLDtkRequiresInAndOut(t *testing.T) {
+	err := run([]string{"-in", "world.ldtk"})
+	if err == nil || !strings.Contains(err.Error(), "both -in and -out are required") {
+		t.Fatalf("run() error = %v, want a missing flag error", err)
+	}
+}
+
+func TestMk2DLDtkRejectsUnsafeNames(t *testing.T) {
+	dir := t.TempDir()
+	level := func(identifier, iid string) string {
+		return `{"identifier": "` + identifier + `", "iid": "` + iid + `", "worldX": 0, "worldY": 0, "pxWid": 8, "pxHei": 8,
+   "layerInstances": [{"__identifier": "Walls", "__type": "IntGrid", "__cWid": 1, "__cHei": 1, "__gridSize": 8, "layerDefUid": 1, "intGridCsv": [1]}]}`
+	}
+	for _, tt := range []struct {
+		layer, levels, want string
+	}{
+		{"Walls", level("../x", "a"), `level "../x": name is not a valid file name`},
+		{"Walls", level("Start", "a") + "," + level("start", "b"), "would both be written to the same file"},
+		{`a\\b`, level("Start", "a"), "name is not a valid file name"},
+	} {
+		project := `{"defs": {
+  "layers": [{"uid": 1, "identifier": "` + tt.layer + `", "type": "IntGrid", "intGridValues": [{"value": 1, "identifier": "solid", "color": "#808080"}]}],
+  "tilesets": []},
+ "levels": [` + tt.levels + `]}`
+		in := filepath.Join(dir, "world.ldtk")
+		if err := os.WriteFile(in, []byte(project), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := run([]string{"-in", in, "-out", filepath.Join(dir, "assets")})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("run() error = %v, want %q", err, tt.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.map")); err == nil {
+		t.Fatal("map written outside the output directory")
+	}
+}
@@ -7,8 +7,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/drpaneas/gosprite64/internal/tile2d/autotile"
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/internal/tile2d/tiled"
 )
 
 func main() {
@@ -22,9 +26,15 @@ func run(args []string) error {
 	fs := flag.NewFlagSet("mk2dmap", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
-	var in, out string
-	fs.StringVar(&in, "in", "", "Input JSON path")
+	var in, out, bundle, rules, encoding, dither string
+	var stream bool
+	fs.StringVar(&in, "in", "", "Input JSON, .tmx or .tmj path")
 	fs.StringVar(&out, "out", "", "Output .map path")
+	fs.StringVar(&bundle, "bundle", "", "Output .bundle path listing the Tiled map's sheets and map")
+	fs.BoolVar(&stream, "stream", false, "Store cells by chunk so the map streams at runtime")
+	fs.StringVar(&rules, "rules", "", "Sheet properties JSON whose autotile rules turn layer terrain into tiles")
+	fs.StringVar(&encoding, "format", "auto", "Pixel encoding of the Tiled map's sheets, as for mk2dsheet")
+	fs.StringVar(&dither, "dither", "none", "Dithering of the Tiled map's sheets, as for mk2dsheet")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -32,6 +42,24 @@ func run(args []string) error {
 		return fmt.Errorf("both -in and -out are required")
 	}
 
+	switch strings.ToLower(filepath.Ext(in)) {
+	case ".tmx", ".tmj":
+		if rules != "" {
+			return fmt.Errorf("-rules needs a JSON input")
+		}
+		opts, err := format.ParseSheetOptions(encoding, dither)
+		if err != nil {
+			return err
+		}
+		return runTiled(in, out, bundle, stream, opts)
+	}
+	if bundle != "" {
+		return fmt.Errorf("-bundle needs a .tmx or .tmj input")
+	}
+	if encoding != "auto" || dither != "none" {
+		return fmt.Errorf("-format and -dither need a .tmx or .tmj input")
+	}
+
 	raw, err := os.ReadFile(in)
 	if err != nil {
 		return err
@@ -41,6 +69,10 @@ func run(args []string) error {
 	if err := json.Unmarshal(raw, &cfg); err != nil {
 		return err
 	}
+	cfg.Streamed = cfg.Streamed || stream
+	if err := applyAutotile(&cfg, rules); err != nil {
+		return err
+	}
 
 	built, err := format.BuildMap(cfg)
 	if err != nil {
@@ -49,3 +81,116 @@ func run(args []string) error {
 
 	return os.WriteFile(out, built, 0o644)
 }
+
+// runTiled converts a Tiled map. Each tileset becomes a .sheet named after
+// it beside the .map, in sheet ID order, and the optional bundle lists the
+// sheets and then the map as mk2dbundle would. opts store the sheets'
+// pixels as mk2dsheet would.
+func runTiled(in, out, bundle string, stream bool, opts format.SheetOptions) error {
+	imp, err := tiled.ImportMap(in)
+	if err != nil {
+		return err
+	}
+	for _, w := range imp.Warnings {
+		fmt.Fprintf(os.Stderr, "mk2dmap: warning: %s\n", w)
+	}
+
+	imp.Map.Streamed = stream
+	built, err := format.BuildMap(imp.Map)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(imp.Tilesets))
+	for i, ts := range imp.Tilesets {
+		names[i] = ts.Name
+	}
+	if err := format.CheckFileNames("tileset", names); err != nil {
+		return err
+	}
+	entries := make([]format.BundleEntry, 0, len(imp.Tilesets)+1)
+	for _, ts := range imp.Tilesets {
+		raw, warning, err := format.BuildSheetWithOptions(ts.Image, ts.TileWidth, ts.TileHeight, ts.Props, opts)
+		if err != nil {
+			return fmt.Errorf("tileset %q: %w", ts.Name, err)
+		}
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "mk2dmap: warning: tileset %q: %s\n", ts.Name, warning)
+		}
+		path := filepath.Join(filepath.Dir(out), ts.Name+".sheet")
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			return err
+		}
+		entries = append(entries, format.BundleEntry{Kind: format.BundleKindSheet, Name: ts.Name, Path: path})
+	}
+	if err := os.WriteFile(out, built, 0o644); err != nil {
+		return err
+	}
+	if bundle == "" {
+		return nil
+	}
+
+	entries = append(entries, format.BundleEntry{
+		Kind: format.BundleKindMap,
+		Name: strings.TrimSuffix(filepath.Base(out), filepath.Ext(out)),
+		Path: out,
+	})
+	raw, err := format.BuildBundle(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(bundle, raw, 0o644)
+}
+
+// applyAutotile fills the cells of layers with terrain from the autotile
+// rules in the sheet properties file at path. Cells without terrain keep
+// their tile.
+func applyAutotile(cfg *format.MapConfig, path string) error {
+	var set *autotile.Set
+	for i := range cfg.Layers {
+		layer := &cfg.Layers[i]
+		if len(layer.Terrain) == 0 {
+			continue
+		}
+		if path == "" {
+			return fmt.Errorf("layer %d has terrain but no -rules file", i)
+		}
+		size := int(cfg.Width) * int(cfg.Height)
+		if len(layer.Terrain) != size {
+			return fmt.Errorf("layer %d terrain has %d cells, want %d", i, len(layer.Terrain), size)
+		}
+		if set == nil {
+			var err error
+			if set, err = readAutotile(path); err != nil {
+				return err
+			}
+		}
+		if layer.Cells == nil {
+			layer.Cells = make([]uint16, size)
+		}
+		if len(layer.Cells) != size {
+			return fmt.Errorf("layer %d has %d cells, want %d", i, len(layer.Cells), size)
+		}
+		set.Fill(layer.Terrain, layer.Cells, int(cfg.Width))
+	}
+	return nil
+}
+
+func readAutotile(path string) (*autotile.Set, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var props format.TilePropsConfig
+	if err := json.Unmarshal(raw, &props); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(props.Autotile) == 0 {
+		return nil, fmt.Errorf("%s: no autotile rules", path)
+	}
+	rules, err := format.AutotileRules(props.Autotile, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return autotile.New(rules), nil
+}
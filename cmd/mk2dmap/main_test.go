@@ -1,8 +1,11 @@
 package main
 
 import (
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
@@ -35,3 +38,335 @@ func TestMk2DMapProduces16BitCellMap(t *testing.T) {
 		t.Fatalf("CellBits = %d, want 16", parsed.CellBits)
 	}
 }
+
+func TestMk2DMapStreamFlagChunksCells(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "level.json")
+	out := filepath.Join(dir, "level.map")
+
+	input := []byte(`{"width":4,"height":2,"layer_count":1,"cell_bits":8,"chunk_width":2,"chunk_height":2,"layers":[{"cells":[1,2,3,4,5,6,7,8]}]}`)
+	if err := os.WriteFile(in, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out, "-stream"}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if !parsed.Streamed || parsed.Layers[0].Cells[6] != 7 {
+		t.Fatalf("parsed = %+v, want streamed cells", parsed)
+	}
+}
+
+func TestMk2DMapRulesTurnTerrainIntoTiles(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "level.json")
+	rules := filepath.Join(dir, "tiles.json")
+	out := filepath.Join(dir, "level.map")
+
+	// Edge16 tiles 1..16 by mask, N=1 E=2 S=4 W=8.
+	props := `{"tiles":[],"autotile":[{"terrain":1,"mode":"edge16","tiles":[1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16]}]}`
+	input := `{"width":3,"height":2,"layer_count":1,"cell_bits":8,"chunk_width":3,"chunk_height":2,
+		"layers":[{"cells":[0,0,0,0,0,20],"terrain":[1,1,1,0,1,0]}]}`
+	if err := os.WriteFile(rules, []byte(props), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(in, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err == nil {
+		t.Fatal("expected error for terrain without -rules")
+	}
+	if err := run([]string{"-in", in, "-out", out, "-rules", rules}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	// Row 0: E end, E+W+S middle, W end; row 1: the N end below the middle
+	// and the untouched tile 20.
+	want := []uint16{1 + 2, 1 + 2 + 4 + 8, 1 + 8, 0, 1 + 1, 20}
+	for i, cell := range parsed.Layers[0].Cells {
+		if cell != want[i] {
+			t.Fatalf("cells = %v, want %v", parsed.Layers[0].Cells, want)
+		}
+	}
+}
+
+func TestMk2DMapWritesLayerParallax(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "level.json")
+	out := filepath.Join(dir, "level.map")
+
+	input := []byte(`{"width":2,"height":1,"layer_count":2,"cell_bits":8,"chunk_width":2,"chunk_height":1,
+		"layers":[{"sheet_id":1,"cells":[1,2],"scroll_x":0.5,"scroll_y":0,"repeat_x":true},{"sheet_id":1,"cells":[0,1]}]}`)
+	if err := os.WriteFile(in, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	sky := parsed.Layers[0]
+	if sky.ScrollX != 0.5 || sky.ScrollY != 0 || !sky.RepeatX {
+		t.Fatalf("layer 0 = %+v, want scroll (0.5,0) repeating on x", sky)
+	}
+	if parsed.Layers[1].ScrollX != 1 {
+		t.Fatalf("layer 1 ScrollX = %v, want 1", parsed.Layers[1].ScrollX)
+	}
+}
+
+func TestMk2DMapWritesCellFlips(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "level.json")
+	out := filepath.Join(dir, "level.map")
+
+	input := []byte(`{"width":3,"height":1,"layer_count":1,"cell_bits":16,"chunk_width":3,"chunk_height":1,
+		"layers":[{"sheet_id":1,"cells":[4,4,4],"flips":[0,1,5]}]}`)
+	if err := os.WriteFile(in, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	layer := parsed.Layers[0]
+	if layer.Cells[2] != 4 || layer.Flips[1] != format.CellFlipH || layer.Flips[2] != format.CellFlipH|format.CellFlipD {
+		t.Fatalf("layer = %+v, want tile 4 with flips [0 H D|H]", layer)
+	}
+}
+
+func TestMk2DMapWritesObjectLayers(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "level.json")
+	out := filepath.Join(dir, "level.map")
+
+	input := []byte(`{"width":1,"height":1,"layer_count":1,"cell_bits":8,"chunk_width":1,"chunk_height":1,
+		"object_layers":[{"name":"spawns","objects":[{"name":"slime","type":"enemy","kind":"point","x":8,"y":16,"props":{"hp":3,"speed":0.5}}]}]}`)
+	if err := os.WriteFile(in, input, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	obj := parsed.ObjectLayers[0].Objects[0]
+	if obj.Name != "slime" || obj.Y != 16 || obj.Props[0].Kind != format.PropInt || obj.Props[1].Kind != format.PropFloat {
+		t.Fatalf("object = %+v, want slime with an int hp and a float speed", obj)
+	}
+}
+
+func TestMk2DMapConvertsTiledMapWithSheetsAndBundle(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "tiles.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tmx := `<map orientation="orthogonal" width="2" height="1" tilewidth="8" tileheight="8">
+ <tileset firstgid="1" name="tiles" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>
+ <layer name="ground"><data encoding="csv">2,2147483649</data></layer>
+ <objectgroup name="spawns"><object id="1" name="player" x="4" y="4"><point/></object></objectgroup>
+</map>`
+	in := filepath.Join(dir, "level.tmx")
+	if err := os.WriteFile(in, []byte(tmx), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "level.map")
+	bundle := filepath.Join(dir, "level.bundle")
+
+	if err := run([]string{"-in", in, "-out", out, "-bundle", bundle}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	parsed, err := format.ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if got := parsed.Layers[0].Cells; got[0] != 2 || got[1] != 1 || parsed.Layers[0].Flips[1] != format.CellFlipH {
+		t.Fatalf("cells = %v flips = %v, want 2 then 1 flipped horizontally", got, parsed.Layers[0].Flips)
+	}
+	if parsed.ObjectLayers[0].Objects[0].Name != "player" {
+		t.Fatalf("ObjectLayers = %+v, want the player spawn", parsed.ObjectLayers)
+	}
+
+	sheetPath := filepath.Join(dir, "tiles.sheet")
+	if got := sheetEncoding(t, sheetPath); got != format.SheetCI4 {
+		t.Fatalf("sheet encoding = %v, want ci4 picked as mk2dsheet would", got)
+	}
+	raw, err = os.ReadFile(bundle)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	manifest, err := format.ParseBundle(raw)
+	if err != nil {
+		t.Fatalf("ParseBundle() error = %v", err)
+	}
+	want := []format.BundleEntry{
+		{Kind: format.BundleKindSheet, Name: "tiles", Path: sheetPath},
+		{Kind: format.BundleKindMap, Name: "level", Path: out},
+	}
+	if len(manifest.Entries) != 2 || manifest.Entries[0] != want[0] || manifest.Entries[1] != want[1] {
+		t.Fatalf("bundle entries = %+v, want %+v", manifest.Entries, want)
+	}
+}
+
+func TestMk2DMapRejectsUnsupportedTiledFeatures(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "iso.tmx")
+	if err := os.WriteFile(in, []byte(`<map orientation="isometric" width="1" height="1" tilewidth="8" tileheight="8"/>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := run([]string{"-in", in, "-out", filepath.Join(dir, "iso.map")})
+	if err == nil || !strings.Contains(err.Error(), "isometric maps are not supported") {
+		t.Fatalf("run() error = %v, want an isometric error", err)
+	}
+}
+
+func TestMk2DMapRejectsUnsafeTilesetNames(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "tiles.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		tilesets string
+		want     string
+	}{
+		{`<tileset firstgid="1" name="../x" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>`, "not a valid file name"},
+		{`<tileset firstgid="1" name="a/b" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>`, "not a valid file name"},
+		{`<tileset firstgid="1" name="tiles" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>
+ <tileset firstgid="2" name="Tiles" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>`, "would both be written to the same file"},
+	} {
+		tmx := `<map orientation="orthogonal" width="1" height="1" tilewidth="8" tileheight="8">
+ ` + tt.tilesets + `
+ <layer name="ground"><data encoding="csv">1</data></layer>
+</map>`
+		in := filepath.Join(dir, "level.tmx")
+		if err := os.WriteFile(in, []byte(tmx), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := run([]string{"-in", in, "-out", filepath.Join(dir, "out", "level.map")})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Fatalf("run() error = %v, want %q", err, tt.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.sheet")); err == nil {
+		t.Fatal("sheet written outside the output directory")
+	}
+}
+
+func TestMk2DMapStoresTiledSheetsWithFormat(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "tiles.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tmx := `<map orientation="orthogonal" width="1" height="1" tilewidth="8" tileheight="8">
+ <tileset firstgid="1" name="tiles" tilewidth="8" tileheight="8"><image source="tiles.png"/></tileset>
+ <layer name="ground"><data encoding="csv">1</data></layer>
+</map>`
+	in := filepath.Join(dir, "level.tmx")
+	if err := os.WriteFile(in, []byte(tmx), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "level.map")
+
+	if err := run([]string{"-in", in, "-out", out, "-format", "rgba16", "-dither", "ordered"}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if got := sheetEncoding(t, filepath.Join(dir, "tiles.sheet")); got != format.SheetRGBA16 {
+		t.Fatalf("sheet encoding = %v, want rgba16", got)
+	}
+	if err := run([]string{"-in", in, "-out", out, "-format", "ci2"}); err == nil {
+		t.Fatal("expected error for an unknown -format")
+	}
+	jsonPath := filepath.Join(dir, "level.json")
+	if err := os.WriteFile(jsonPath, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"-in", jsonPath, "-out", out, "-format", "ci4"}); err == nil {
+		t.Fatal("expected error for -format with a JSON input")
+	}
+}
+
+func sheetEncoding(t *testing.T, path string) format.SheetEncoding {
+	t.Helper()
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	sheet, err := format.ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	return sheet.Encoding
+}
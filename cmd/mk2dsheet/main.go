@@ -3,12 +3,20 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"image"
 	"image/png"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/internal/tile2d/tiled"
 )
 
 func main() {
@@ -19,22 +27,37 @@ func main() {
 }
 
 func run(args []string) error {
-	fs := flag.NewFlagSet("mk2dsheet", flag.ContinueOnError)
-	fs.SetOutput(os.Stderr)
+	flags := flag.NewFlagSet("mk2dsheet", flag.ContinueOnError)
+	flags.SetOutput(os.Stderr)
 
-	var in, out string
+	var in, out, propsPath, encoding, dither string
 	var tileWidth, tileHeight int
-	fs.StringVar(&in, "in", "", "Input PNG path")
-	fs.StringVar(&out, "out", "", "Output .sheet path")
-	fs.IntVar(&tileWidth, "tile-width", 8, "Tile width in pixels")
-	fs.IntVar(&tileHeight, "tile-height", 8, "Tile height in pixels")
-	if err := fs.Parse(args); err != nil {
+	flags.StringVar(&in, "in", "", "Input PNG, .tsx or .tsj path")
+	flags.StringVar(&out, "out", "", "Output .sheet path")
+	flags.IntVar(&tileWidth, "tile-width", 8, "Tile width in pixels")
+	flags.IntVar(&tileHeight, "tile-height", 8, "Tile height in pixels")
+	flags.StringVar(&propsPath, "props", "", "Tile properties JSON path (default: the input path with a .json extension, if present)")
+	flags.StringVar(&encoding, "format", "auto", "Pixel encoding: rgba32, rgba16, ci8, ci4, ia8, ia4, i8, i4, or auto for the smallest that keeps every pixel")
+	flags.StringVar(&dither, "dither", "none", "Dithering for lossy encodings: none, ordered or floyd-steinberg")
+	if err := flags.Parse(args); err != nil {
 		return err
 	}
 	if in == "" || out == "" {
 		return fmt.Errorf("both -in and -out are required")
 	}
 
+	switch strings.ToLower(filepath.Ext(in)) {
+	case ".tsx", ".tsj":
+		if propsPath != "" {
+			return fmt.Errorf("-props cannot be used with a Tiled tileset")
+		}
+		ts, err := tiled.ImportTileset(in)
+		if err != nil {
+			return err
+		}
+		return writeSheet(out, ts.Image, ts.TileWidth, ts.TileHeight, ts.Props, encoding, dither)
+	}
+
 	f, err := os.Open(in)
 	if err != nil {
 		return err
@@ -46,10 +69,52 @@ func run(args []string) error {
 		return err
 	}
 
-	raw, err := format.BuildSheet(img, tileWidth, tileHeight)
+	props, err := readTileProps(in, propsPath)
 	if err != nil {
 		return err
 	}
 
+	return writeSheet(out, img, tileWidth, tileHeight, props, encoding, dither)
+}
+
+// stderr receives warnings; tests replace it.
+var stderr io.Writer = os.Stderr
+
+// writeSheet builds the sheet with the encoding named by the -format flag,
+// dithered as the -dither flag says.
+func writeSheet(out string, img image.Image, tileWidth, tileHeight int, props format.TilePropsConfig, encoding, dither string) error {
+	opts, err := format.ParseSheetOptions(encoding, dither)
+	if err != nil {
+		return err
+	}
+	raw, warning, err := format.BuildSheetWithOptions(img, tileWidth, tileHeight, props, opts)
+	if err != nil {
+		return err
+	}
+	if warning != "" {
+		fmt.Fprintf(stderr, "mk2dsheet: warning: %s\n", warning)
+	}
 	return os.WriteFile(out, raw, 0o644)
 }
+
+// readTileProps loads the tile properties file. Without an explicit path it
+// looks for a JSON file beside the PNG and treats a missing one as empty.
+func readTileProps(in, path string) (format.TilePropsConfig, error) {
+	var cfg format.TilePropsConfig
+	explicit := path != ""
+	if !explicit {
+		path = strings.TrimSuffix(in, filepath.Ext(in)) + ".json"
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"image/png"
@@ -51,3 +52,187 @@ func TestMk2DSheetProducesStableHeader(t *testing.T) {
 		t.Fatalf("TileCount = %d, want 2", sheet.TileCount)
 	}
 }
+
+func TestMk2DSheetReadsTilePropsBesidePNG(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "tiles.png")
+	out := filepath.Join(dir, "tiles.sheet")
+
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 16, 8))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	props := `{"flags": ["water"], "tiles": [{"id": 2, "shape": "ladder", "flags": ["water"], "props": {"speed": 1.5}}],
+		"anims": [{"tile": 1, "fps": 8, "frames": [1, 2]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "tiles.json"), []byte(props), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	sheet, err := format.ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	tile, ok := sheet.TileProps.Find(2)
+	if !ok || tile.Flags != 1 || len(tile.Props) != 1 || tile.Props[0].Value != "1.5" {
+		t.Fatalf("tile 2 props = %+v, %v", tile, ok)
+	}
+	if anims := sheet.TileAnims; len(anims) != 1 || anims[0].Tile != 1 || anims[0].FPS != 8 || len(anims[0].Frames) != 2 {
+		t.Fatalf("TileAnims = %+v, want tile 1 at 8 fps over 2 frames", anims)
+	}
+
+	if err := run([]string{"-in", in, "-out", out, "-props", filepath.Join(dir, "missing.json")}); err == nil {
+		t.Fatal("expected error for a missing -props file")
+	}
+}
+
+func TestMk2DSheetReadsTiledTileset(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "tiles.sheet")
+
+	f, err := os.Create(filepath.Join(dir, "tiles.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, 32, 16))); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	tsx := `<tileset name="tiles" tilewidth="16" tileheight="16" tilecount="2" columns="2">
+ <image source="tiles.png" width="32" height="16"/>
+ <tile id="1"><properties><property name="shape" value="solid"/></properties></tile>
+</tileset>`
+	in := filepath.Join(dir, "tiles.tsx")
+	if err := os.WriteFile(in, []byte(tsx), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"-in", in, "-out", out}); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	raw, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("os.ReadFile() error = %v", err)
+	}
+	sheet, err := format.ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	if sheet.TileWidth != 16 || sheet.TileCount != 2 {
+		t.Fatalf("sheet = %dpx tiles x %d, want 16px x 2", sheet.TileWidth, sheet.TileCount)
+	}
+	if len(sheet.TileProps.Tiles) != 1 || sheet.TileProps.Tiles[0].ID != 2 {
+		t.Fatalf("TileProps = %+v, want tile 2", sheet.TileProps)
+	}
+}
+
+func TestMk2DSheetPicksEncoding(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "tiles.png")
+	out := filepath.Join(dir, "tiles.sheet")
+
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	f, err := os.Create(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		flag   string
+		dither string
+		want   format.SheetEncoding
+	}{
+		{"", "", format.SheetCI4},
+		{"ci8", "", format.SheetCI8},
+		{"rgba32", "", format.SheetRGBA32},
+		{"rgba16", "", format.SheetRGBA16},
+		{"i4", "ordered", format.SheetI4},
+		{"ia8", "floyd-steinberg", format.SheetIA8},
+	} {
+		args := []string{"-in", in, "-out", out}
+		if tt.flag != "" {
+			args = append(args, "-format", tt.flag)
+		}
+		if tt.dither != "" {
+			args = append(args, "-dither", tt.dither)
+		}
+		if err := run(args); err != nil {
+			t.Fatalf("run(-format %q) error = %v", tt.flag, err)
+		}
+		raw, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("os.ReadFile() error = %v", err)
+		}
+		sheet, err := format.ParseSheet(raw)
+		if err != nil {
+			t.Fatalf("ParseSheet() error = %v", err)
+		}
+		if sheet.Encoding != tt.want {
+			t.Fatalf("-format %q encoding = %v, want %v", tt.flag, sheet.Encoding, tt.want)
+		}
+	}
+
+	if err := run([]string{"-in", in, "-out", out, "-format", "ci2"}); err == nil {
+		t.Fatal("expected error for an unknown -format")
+	}
+	if err := run([]string{"-in", in, "-out", out, "-dither", "random"}); err == nil {
+		t.Fatal("expected error for an unknown -dither")
+	}
+}
+
+func TestMk2DSheetWarnsAboutLostPrecision(t *testing.T) {
+	var warnings bytes.Buffer
+	saved := stderr
+	stderr = &warnings
+	defer func() { stderr = saved }()
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "tiles.sheet")
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	if err := writeSheet(out, img, 8, 8, format.TilePropsConfig{}, "ci8", "none"); err != nil {
+		t.Fatalf("writeSheet() error = %v", err)
+	}
+	if warnings.Len() != 0 {
+		t.Fatalf("unexpected warning for colors the TLUT stores exactly: %s", warnings.String())
+	}
+
+	// These channels sit halfway between two RGBA16 levels.
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			img.Set(x, y, color.NRGBA{R: 4, G: 12, B: 20, A: 255})
+		}
+	}
+	if err := writeSheet(out, img, 8, 8, format.TilePropsConfig{}, "ci8", "none"); err != nil {
+		t.Fatalf("writeSheet() error = %v", err)
+	}
+	if !bytes.Contains(warnings.Bytes(), []byte("ci8 loses detail")) {
+		t.Fatalf("warnings = %q, want one for ci8", warnings.String())
+	}
+}
@@ -0,0 +1,160 @@
+package gosprite64
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/drpaneas/gosprite64/internal/sprite"
+)
+
+// DrawList collects draws during Draw and runs them at Flush sorted by
+// layer, lowest first, then by sort key, lowest first. Draws with the same
+// layer and key keep their call order. A key is usually a z-index or, for
+// top-down games, a sprite's foot Y, so SpriteY and WorldSpriteY key by it.
+//
+// Scene tile layers can be queued with SceneLayers, so sprites can sit
+// between them. The zero value is ready to use.
+type DrawList struct {
+	items   []drawListItem
+	uploads int
+}
+
+type drawListItem struct {
+	layer int
+	key   float32
+	// run draws anything but a sprite, which is drawn from sprite.
+	run    func()
+	sprite sprite.Draw
+}
+
+func (l *DrawList) add(layer int, key float32, run func(), d sprite.Draw) {
+	if l == nil {
+		return
+	}
+	l.items = append(l.items, drawListItem{layer: layer, key: key, run: run, sprite: d})
+}
+
+// Sprite queues a DrawSpriteWithOptions call on layer with sort key z.
+func (l *DrawList) Sprite(layer int, z float32, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions) {
+	d, ok := spriteDraw(sheet, frame, x, y, opts)
+	if !ok {
+		return
+	}
+	l.add(layer, z, nil, d)
+}
+
+// SpriteY queues a DrawSpriteWithOptions call on layer, keyed by the
+// sprite's foot: the screen Y of its unrotated bottom edge.
+func (l *DrawList) SpriteY(layer int, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions) {
+	l.Sprite(layer, footY(sheet, y, opts), sheet, frame, x, y, opts)
+}
+
+// WorldSprite queues a DrawWorldSpriteWithOptions call on layer with sort
+// key z.
+func (l *DrawList) WorldSprite(layer int, z float32, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions) {
+	x, y, opts := worldSpriteOptions(worldX, worldY, cam, opts)
+	l.Sprite(layer, z, sheet, frame, x, y, opts)
+}
+
+// WorldSpriteY queues a DrawWorldSpriteWithOptions call on layer, keyed by
+// the sprite's foot in world space, so the order holds under a rotated
+// camera.
+func (l *DrawList) WorldSpriteY(layer int, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions) {
+	l.WorldSprite(layer, footY(sheet, worldY, opts), sheet, frame, worldX, worldY, cam, opts)
+}
+
+// footY returns the Y of the bottom edge of a sprite drawn at y.
+func footY(sheet *SpriteSheet, y float32, opts DrawSpriteOptions) float32 {
+	return y + (float32(sheet.FrameHeight())-opts.OriginY)*opts.effectiveScaleY()
+}
+
+// Image queues a DrawImage call on layer with sort key z.
+func (l *DrawList) Image(layer int, z float32, src image.Image, x, y int) {
+	l.Func(layer, z, func() { DrawImage(src, x, y) })
+}
+
+// Rect queues a DrawRect call on layer with sort key z.
+func (l *DrawList) Rect(layer int, z float32, x1, y1, x2, y2 int, c color.Color) {
+	l.Func(layer, z, func() { DrawRect(x1, y1, x2, y2, c) })
+}
+
+// FillRect queues a FillRect call on layer with sort key z.
+func (l *DrawList) FillRect(layer int, z float32, x1, y1, x2, y2 int, c color.Color) {
+	l.Func(layer, z, func() { FillRect(x1, y1, x2, y2, c) })
+}
+
+// Line queues a DrawLine call on layer with sort key z.
+func (l *DrawList) Line(layer int, z float32, x1, y1, x2, y2 int, c color.Color) {
+	l.Func(layer, z, func() { DrawLine(x1, y1, x2, y2, c) })
+}
+
+// Func queues any drawing code, such as DrawText, on layer with sort key z.
+func (l *DrawList) Func(layer int, z float32, fn func()) {
+	if fn == nil {
+		return
+	}
+	l.add(layer, z, fn, sprite.Draw{})
+}
+
+// SceneLayers queues scene.DrawLayers(cam, from, to) on layer, before the
+// list's other draws on that layer.
+func (l *DrawList) SceneLayers(layer int, scene *Scene, cam *Camera, from, to int) {
+	l.Func(layer, float32(math.Inf(-1)), func() { scene.DrawLayers(cam, from, to) })
+}
+
+// Len returns the number of queued draws.
+func (l *DrawList) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.items)
+}
+
+// Flush runs the queued draws in sorted order and empties the list.
+// Consecutive sprites share their RDP setup, and a run of one frame loads
+// its texture once.
+func (l *DrawList) Flush() {
+	if l == nil {
+		return
+	}
+	sort.SliceStable(l.items, func(i, j int) bool {
+		a, b := l.items[i], l.items[j]
+		if a.layer != b.layer {
+			return a.layer < b.layer
+		}
+		return a.key < b.key
+	})
+
+	l.uploads = 0
+	var run []sprite.Draw
+	flushSprites := func() {
+		if video := currentVideo(); len(run) > 0 && video != nil && video.Framebuffer != nil {
+			l.uploads += sprite.Render(video.Framebuffer, run)
+		}
+		run = run[:0]
+	}
+	for _, item := range l.items {
+		if item.run == nil {
+			run = append(run, item.sprite)
+			continue
+		}
+		// Other draws change RDP state the sprite run tracked.
+		flushSprites()
+		item.run()
+	}
+	flushSprites()
+
+	clear(l.items)
+	l.items = l.items[:0]
+}
+
+// Stats reports the sprite texture loads of the last Flush in
+// UploadCount. Scene layers report their own uploads in Scene.Stats.
+func (l *DrawList) Stats() RuntimeStats {
+	if l == nil {
+		return RuntimeStats{}
+	}
+	return RuntimeStats{UploadCount: l.uploads}
+}
@@ -0,0 +1,83 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rendergeom"
+)
+
+func TestDrawListSortsByLayerThenKey(t *testing.T) {
+	var got []string
+	record := func(name string) func() {
+		return func() { got = append(got, name) }
+	}
+
+	var list DrawList
+	list.Func(2, 0, record("hud"))
+	list.Func(1, 50, record("tree"))
+	list.Func(1, 20, record("player"))
+	list.Func(0, 99, record("shadow"))
+	list.Func(1, 20, record("sword"))
+	list.SceneLayers(1, nil, nil, 1, 1)
+	list.Func(1, float32(-1e9), record("first"))
+	if list.Len() != 7 {
+		t.Fatalf("Len() = %d, want 7", list.Len())
+	}
+	list.Flush()
+
+	want := []string{"shadow", "first", "player", "sword", "tree", "hud"}
+	if len(got) != len(want) {
+		t.Fatalf("draw order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("draw order = %v, want %v", got, want)
+		}
+	}
+	if list.Len() != 0 {
+		t.Fatalf("Len() = %d after Flush, want 0", list.Len())
+	}
+}
+
+func TestDrawListYSortsSpritesByFoot(t *testing.T) {
+	saved := activeRuntime
+	defer activateRuntime(saved)
+	activateRuntime(&runtimeState{video: &videoState{
+		Framebuffer: texture.NewRGBA16(rendergeom.FramebufferBounds()),
+	}})
+
+	sheet := loadTestSpriteSheet(t, 2)
+	if got := footY(sheet, 10, DrawSpriteOptions{OriginY: 4, ScaleY: 2}); got != 18 {
+		t.Fatalf("footY() = %v, want 18", got)
+	}
+
+	var list DrawList
+	// Frame 0 at y 40 sorts after frame 1 at y 30, which sorts after the
+	// frame 0 at y 20: three runs, three loads.
+	list.SpriteY(0, sheet, 0, 0, 40, DrawSpriteOptions{})
+	list.SpriteY(0, sheet, 1, 0, 30, DrawSpriteOptions{})
+	list.SpriteY(0, sheet, 0, 0, 20, DrawSpriteOptions{})
+	list.WorldSpriteY(0, sheet, 0, 0, 60, &Camera{Y: 10}, DrawSpriteOptions{})
+	list.Flush()
+	if got := list.Stats().UploadCount; got != 3 {
+		t.Fatalf("UploadCount = %d, want 3", got)
+	}
+
+	// A non-sprite draw between two sprites of one frame forces a reload.
+	list.Sprite(0, 0, sheet, 0, 0, 0, DrawSpriteOptions{})
+	list.Func(0, 1, func() {})
+	list.Sprite(0, 2, sheet, 0, 0, 0, DrawSpriteOptions{})
+	list.Flush()
+	if got := list.Stats().UploadCount; got != 2 {
+		t.Fatalf("UploadCount = %d, want 2", got)
+	}
+
+	var nilList *DrawList
+	nilList.SpriteY(0, sheet, 0, 0, 0, DrawSpriteOptions{})
+	nilList.Func(0, 0, func() {})
+	nilList.Flush()
+	if nilList.Len() != 0 || nilList.Stats() != (RuntimeStats{}) {
+		t.Fatal("nil list is not a no-op")
+	}
+}
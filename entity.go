@@ -0,0 +1,402 @@
+package gosprite64
+
+import (
+	"slices"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// Entity is a handle to an entity owned by an EntityManager. Handles carry a
+// generation, so a handle to a destroyed entity never aliases a newer one
+// that reuses the same slot. The zero Entity is never alive.
+type Entity struct {
+	index      uint32
+	generation uint32
+}
+
+// Component is a bitmask of the built-in components an entity can carry.
+type Component uint8
+
+const (
+	ComponentTransform Component = 1 << iota
+	ComponentSprite
+	ComponentAnimation
+	ComponentCollider
+	ComponentVelocity
+)
+
+// Transform places an entity in world space.
+type Transform struct {
+	Position math2d.Vec2
+}
+
+// Sprite draws a frame of a SpriteSheet at the entity's Transform.
+// Sprites with a lower Layer draw first.
+type Sprite struct {
+	Sheet   *SpriteSheet
+	Frame   int
+	Options DrawSpriteOptions
+	Layer   int
+	Hidden  bool
+}
+
+// CollisionFunc receives the two entities of an overlapping pair. The first
+// entity matches the handler's layer, the second its other layer.
+type CollisionFunc func(a, b Entity)
+
+type collisionHandler struct {
+	layer math2d.Layer
+	other math2d.Layer
+	fn    CollisionFunc
+}
+
+// EntityManager stores entities and their built-in components in
+// fixed-capacity arrays. Every system walks entities in slot order, so a
+// run is fully deterministic and input replays stay valid.
+type EntityManager struct {
+	generations []uint32
+	masks       []Component
+	transforms  []Transform
+	sprites     []Sprite
+	animations  []*AnimationPlayer
+	colliders   []math2d.Collider
+	velocities  []math2d.Vec2
+
+	free     []uint32
+	next     uint32
+	count    int
+	handlers []collisionHandler
+	order    []uint32
+}
+
+// NewEntityManager creates a manager that holds up to capacity entities.
+// Storage is allocated once, so component pointers stay valid for the
+// lifetime of the entity.
+func NewEntityManager(capacity int) *EntityManager {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &EntityManager{
+		generations: make([]uint32, capacity),
+		masks:       make([]Component, capacity),
+		transforms:  make([]Transform, capacity),
+		sprites:     make([]Sprite, capacity),
+		animations:  make([]*AnimationPlayer, capacity),
+		colliders:   make([]math2d.Collider, capacity),
+		velocities:  make([]math2d.Vec2, capacity),
+		free:        make([]uint32, 0, capacity),
+		order:       make([]uint32, 0, capacity),
+	}
+}
+
+// Create allocates a new entity with no components. It returns false when
+// the manager is full.
+func (m *EntityManager) Create() (Entity, bool) {
+	if m == nil {
+		return Entity{}, false
+	}
+	var idx uint32
+	if n := len(m.free); n > 0 {
+		idx = m.free[n-1]
+		m.free = m.free[:n-1]
+	} else if int(m.next) < len(m.generations) {
+		idx = m.next
+		m.next++
+	} else {
+		return Entity{}, false
+	}
+	m.generations[idx]++
+	m.count++
+	return Entity{index: idx, generation: m.generations[idx]}, true
+}
+
+// Destroy removes an entity and all of its components. Destroying an entity
+// from inside a system or collision callback is safe; it is skipped for the
+// rest of that pass.
+func (m *EntityManager) Destroy(e Entity) {
+	if !m.Alive(e) {
+		return
+	}
+	idx := e.index
+	m.generations[idx]++
+	m.masks[idx] = 0
+	m.transforms[idx] = Transform{}
+	m.sprites[idx] = Sprite{}
+	m.animations[idx] = nil
+	m.colliders[idx] = math2d.Collider{}
+	m.velocities[idx] = math2d.Vec2{}
+	m.free = append(m.free, idx)
+	m.count--
+}
+
+// Alive reports whether e refers to a live entity.
+func (m *EntityManager) Alive(e Entity) bool {
+	if m == nil || e.generation == 0 || int(e.index) >= len(m.generations) {
+		return false
+	}
+	// Live slots have an odd generation; Destroy bumps it to even.
+	return m.generations[e.index] == e.generation && e.generation%2 == 1
+}
+
+// Len returns the number of live entities.
+func (m *EntityManager) Len() int {
+	if m == nil {
+		return 0
+	}
+	return m.count
+}
+
+// Cap returns the maximum number of live entities.
+func (m *EntityManager) Cap() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.generations)
+}
+
+// Has reports whether e carries every component in c.
+func (m *EntityManager) Has(e Entity, c Component) bool {
+	return m.Alive(e) && m.masks[e.index]&c == c
+}
+
+// Remove strips the components in c from e.
+func (m *EntityManager) Remove(e Entity, c Component) {
+	if !m.Alive(e) {
+		return
+	}
+	m.masks[e.index] &^= c
+	if c&ComponentAnimation != 0 {
+		m.animations[e.index] = nil
+	}
+}
+
+// Each calls fn for every live entity carrying all components in c, in slot
+// order.
+func (m *EntityManager) Each(c Component, fn func(Entity)) {
+	if m == nil || fn == nil {
+		return
+	}
+	for i := uint32(0); i < m.next; i++ {
+		if m.masks[i]&c != c || m.generations[i]%2 == 0 {
+			continue
+		}
+		fn(Entity{index: i, generation: m.generations[i]})
+	}
+}
+
+// SetTransform attaches or replaces e's Transform.
+func (m *EntityManager) SetTransform(e Entity, t Transform) {
+	if !m.Alive(e) {
+		return
+	}
+	m.transforms[e.index] = t
+	m.masks[e.index] |= ComponentTransform
+}
+
+// Transform returns e's Transform, or nil if it has none.
+func (m *EntityManager) Transform(e Entity) *Transform {
+	if !m.Has(e, ComponentTransform) {
+		return nil
+	}
+	return &m.transforms[e.index]
+}
+
+// SetSprite attaches or replaces e's Sprite.
+func (m *EntityManager) SetSprite(e Entity, s Sprite) {
+	if !m.Alive(e) {
+		return
+	}
+	m.sprites[e.index] = s
+	m.masks[e.index] |= ComponentSprite
+}
+
+// Sprite returns e's Sprite, or nil if it has none.
+func (m *EntityManager) Sprite(e Entity) *Sprite {
+	if !m.Has(e, ComponentSprite) {
+		return nil
+	}
+	return &m.sprites[e.index]
+}
+
+// SetAnimation attaches an AnimationPlayer that drives e's Sprite frame.
+func (m *EntityManager) SetAnimation(e Entity, p *AnimationPlayer) {
+	if !m.Alive(e) || p == nil {
+		return
+	}
+	m.animations[e.index] = p
+	m.masks[e.index] |= ComponentAnimation
+}
+
+// Animation returns e's AnimationPlayer, or nil if it has none.
+func (m *EntityManager) Animation(e Entity) *AnimationPlayer {
+	if !m.Has(e, ComponentAnimation) {
+		return nil
+	}
+	return m.animations[e.index]
+}
+
+// SetCollider attaches or replaces e's Collider. Bounds are relative to the
+// entity's Transform position.
+func (m *EntityManager) SetCollider(e Entity, c math2d.Collider) {
+	if !m.Alive(e) {
+		return
+	}
+	m.colliders[e.index] = c
+	m.masks[e.index] |= ComponentCollider
+}
+
+// Collider returns e's Collider, or nil if it has none.
+func (m *EntityManager) Collider(e Entity) *math2d.Collider {
+	if !m.Has(e, ComponentCollider) {
+		return nil
+	}
+	return &m.colliders[e.index]
+}
+
+// WorldCollider returns e's Collider with Bounds moved into world space.
+func (m *EntityManager) WorldCollider(e Entity) (math2d.Collider, bool) {
+	if !m.Has(e, ComponentCollider) {
+		return math2d.Collider{}, false
+	}
+	return m.worldCollider(e.index), true
+}
+
+func (m *EntityManager) worldCollider(idx uint32) math2d.Collider {
+	c := m.colliders[idx]
+	if m.masks[idx]&ComponentTransform != 0 {
+		c.Bounds.X += m.transforms[idx].Position.X
+		c.Bounds.Y += m.transforms[idx].Position.Y
+	}
+	return c
+}
+
+// SetVelocity attaches or replaces e's velocity in pixels per frame.
+func (m *EntityManager) SetVelocity(e Entity, v math2d.Vec2) {
+	if !m.Alive(e) {
+		return
+	}
+	m.velocities[e.index] = v
+	m.masks[e.index] |= ComponentVelocity
+}
+
+// Velocity returns e's velocity, or nil if it has none.
+func (m *EntityManager) Velocity(e Entity) *math2d.Vec2 {
+	if !m.Has(e, ComponentVelocity) {
+		return nil
+	}
+	return &m.velocities[e.index]
+}
+
+// OnCollision registers fn for overlapping pairs where one collider is on
+// layer and the other on other. Handlers run in registration order.
+func (m *EntityManager) OnCollision(layer, other math2d.Layer, fn CollisionFunc) {
+	if m == nil || fn == nil {
+		return
+	}
+	m.handlers = append(m.handlers, collisionHandler{layer: layer, other: other, fn: fn})
+}
+
+// Update runs the animation, movement and collision systems for one frame.
+func (m *EntityManager) Update() {
+	m.UpdateAnimations(1)
+	m.UpdateMovement()
+	m.UpdateCollisions()
+}
+
+// UpdateAnimations advances every AnimationPlayer by ticks and copies the
+// current frame into the entity's Sprite.
+func (m *EntityManager) UpdateAnimations(ticks int) {
+	if m == nil {
+		return
+	}
+	for i := uint32(0); i < m.next; i++ {
+		if m.masks[i]&ComponentAnimation == 0 {
+			continue
+		}
+		player := m.animations[i]
+		player.Advance(ticks)
+		if m.masks[i]&ComponentSprite != 0 {
+			m.sprites[i].Frame = player.Frame()
+		}
+	}
+}
+
+// UpdateMovement adds each entity's velocity to its Transform position.
+func (m *EntityManager) UpdateMovement() {
+	if m == nil {
+		return
+	}
+	const moving = ComponentTransform | ComponentVelocity
+	for i := uint32(0); i < m.next; i++ {
+		if m.masks[i]&moving != moving {
+			continue
+		}
+		m.transforms[i].Position = m.transforms[i].Position.Add(m.velocities[i])
+	}
+}
+
+// UpdateCollisions tests every pair of colliders once, lower slot first,
+// and calls the matching OnCollision handlers. A pair must pass
+// math2d.ColliderOverlap, so each collider's Mask still filters contacts.
+func (m *EntityManager) UpdateCollisions() {
+	if m == nil || len(m.handlers) == 0 {
+		return
+	}
+	for i := uint32(0); i < m.next; i++ {
+		for j := i + 1; j < m.next; j++ {
+			// Re-checked per pair: a handler may have destroyed i.
+			if m.masks[i]&ComponentCollider == 0 {
+				break
+			}
+			if m.masks[j]&ComponentCollider == 0 {
+				continue
+			}
+			a := m.worldCollider(i)
+			b := m.worldCollider(j)
+			if !math2d.ColliderOverlap(a, b) {
+				continue
+			}
+			ea := Entity{index: i, generation: m.generations[i]}
+			eb := Entity{index: j, generation: m.generations[j]}
+			for _, h := range m.handlers {
+				if !m.Alive(ea) || !m.Alive(eb) {
+					break
+				}
+				switch {
+				case a.Layer.Matches(h.layer) && b.Layer.Matches(h.other):
+					h.fn(ea, eb)
+				case b.Layer.Matches(h.layer) && a.Layer.Matches(h.other):
+					h.fn(eb, ea)
+				}
+			}
+		}
+	}
+}
+
+// Draw draws every visible Sprite relative to cam through
+// DrawWorldSpriteWithOptions, ordered by Layer and then by slot.
+func (m *EntityManager) Draw(cam *Camera) {
+	if m == nil {
+		return
+	}
+	for _, idx := range m.drawOrder() {
+		s := &m.sprites[idx]
+		pos := m.transforms[idx].Position
+		DrawWorldSpriteWithOptions(s.Sheet, s.Frame, pos.X, pos.Y, cam, s.Options)
+	}
+}
+
+func (m *EntityManager) drawOrder() []uint32 {
+	const drawable = ComponentTransform | ComponentSprite
+	m.order = m.order[:0]
+	for i := uint32(0); i < m.next; i++ {
+		if m.masks[i]&drawable != drawable || m.sprites[i].Hidden {
+			continue
+		}
+		m.order = append(m.order, i)
+	}
+	slices.SortStableFunc(m.order, func(a, b uint32) int {
+		return m.sprites[a].Layer - m.sprites[b].Layer
+	})
+	return m.order
+}
@@ -0,0 +1,188 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+func TestEntityManagerCreateDestroy(t *testing.T) {
+	m := NewEntityManager(2)
+	a, ok := m.Create()
+	if !ok || !m.Alive(a) {
+		t.Fatal("Create should return a live entity")
+	}
+	b, _ := m.Create()
+	if _, ok := m.Create(); ok {
+		t.Fatal("Create should fail when the manager is full")
+	}
+	if m.Len() != 2 || m.Cap() != 2 {
+		t.Fatalf("expected len 2 cap 2, got %d %d", m.Len(), m.Cap())
+	}
+
+	m.Destroy(a)
+	if m.Alive(a) || m.Len() != 1 {
+		t.Fatal("destroyed entity should not be alive")
+	}
+	c, ok := m.Create()
+	if !ok || c.index != a.index {
+		t.Fatal("Create should reuse the freed slot")
+	}
+	if m.Alive(a) {
+		t.Fatal("stale handle must not alias the reused slot")
+	}
+	if !m.Alive(b) || !m.Alive(c) {
+		t.Fatal("other entities should stay alive")
+	}
+	if m.Alive(Entity{}) {
+		t.Fatal("zero Entity should never be alive")
+	}
+}
+
+func TestEntityManagerComponents(t *testing.T) {
+	m := NewEntityManager(4)
+	e, _ := m.Create()
+	if m.Transform(e) != nil || m.Has(e, ComponentTransform) {
+		t.Fatal("new entity should have no components")
+	}
+
+	m.SetTransform(e, Transform{Position: math2d.Vec2{X: 5, Y: 6}})
+	m.SetVelocity(e, math2d.Vec2{X: 1})
+	if !m.Has(e, ComponentTransform|ComponentVelocity) {
+		t.Fatal("entity should have transform and velocity")
+	}
+	m.Transform(e).Position.Y = 10
+	if m.Transform(e).Position.Y != 10 {
+		t.Fatal("Transform should return a mutable pointer")
+	}
+
+	m.Remove(e, ComponentVelocity)
+	if m.Velocity(e) != nil || !m.Has(e, ComponentTransform) {
+		t.Fatal("Remove should only strip the given components")
+	}
+
+	m.Destroy(e)
+	if m.Transform(e) != nil {
+		t.Fatal("destroyed entity should have no components")
+	}
+}
+
+func TestEntityManagerMovementAndAnimation(t *testing.T) {
+	m := NewEntityManager(4)
+	e, _ := m.Create()
+	m.SetTransform(e, Transform{Position: math2d.Vec2{X: 10, Y: 20}})
+	m.SetVelocity(e, math2d.Vec2{X: 2, Y: -1})
+	m.SetSprite(e, Sprite{})
+	player := NewAnimationPlayer()
+	player.SetLoop(true)
+	player.Play(AnimationClip{Frames: []uint16{4, 7}, FPS: 60})
+	m.SetAnimation(e, player)
+
+	m.Update()
+	pos := m.Transform(e).Position
+	if pos.X != 12 || pos.Y != 19 {
+		t.Fatalf("expected position (12,19), got (%v,%v)", pos.X, pos.Y)
+	}
+	if m.Sprite(e).Frame != 7 {
+		t.Fatalf("animation should drive the sprite frame, got %d", m.Sprite(e).Frame)
+	}
+}
+
+func TestEntityManagerCollisionCallbacks(t *testing.T) {
+	const (
+		layerPlayer math2d.Layer = 1 << iota
+		layerEnemy
+		layerBullet
+	)
+	m := NewEntityManager(8)
+	spawn := func(x float32, layer, mask math2d.Layer) Entity {
+		e, _ := m.Create()
+		m.SetTransform(e, Transform{Position: math2d.Vec2{X: x}})
+		m.SetCollider(e, math2d.Collider{Bounds: math2d.Rect{W: 8, H: 8}, Layer: layer, Mask: mask})
+		return e
+	}
+	enemy := spawn(0, layerEnemy, layerPlayer|layerBullet)
+	player := spawn(4, layerPlayer, layerEnemy)
+	bullet := spawn(2, layerBullet, layerEnemy)
+	far := spawn(100, layerEnemy, layerPlayer)
+
+	var hits []string
+	m.OnCollision(layerPlayer, layerEnemy, func(a, b Entity) {
+		if a != player || b != enemy {
+			t.Fatalf("player handler got wrong order")
+		}
+		hits = append(hits, "player")
+	})
+	m.OnCollision(layerBullet, layerEnemy, func(a, b Entity) {
+		if a != bullet || b != enemy {
+			t.Fatalf("bullet handler got wrong order")
+		}
+		hits = append(hits, "bullet")
+		m.Destroy(a)
+		m.Destroy(b)
+	})
+
+	m.UpdateCollisions()
+	if len(hits) != 2 || hits[0] != "player" || hits[1] != "bullet" {
+		t.Fatalf("expected [player bullet], got %v", hits)
+	}
+	if m.Alive(enemy) || m.Alive(bullet) || !m.Alive(far) {
+		t.Fatal("bullet handler should have destroyed the pair")
+	}
+
+	hits = hits[:0]
+	m.UpdateCollisions()
+	if len(hits) != 0 {
+		t.Fatalf("destroyed entities should not collide again, got %v", hits)
+	}
+}
+
+func TestEntityManagerWorldCollider(t *testing.T) {
+	m := NewEntityManager(1)
+	e, _ := m.Create()
+	m.SetTransform(e, Transform{Position: math2d.Vec2{X: 10, Y: 20}})
+	m.SetCollider(e, math2d.Collider{Bounds: math2d.Rect{X: 1, Y: 2, W: 4, H: 4}})
+	c, ok := m.WorldCollider(e)
+	if !ok || c.Bounds.X != 11 || c.Bounds.Y != 22 {
+		t.Fatalf("expected world bounds at (11,22), got %+v", c.Bounds)
+	}
+}
+
+func TestEntityManagerDrawOrderIsLayerThenSlot(t *testing.T) {
+	m := NewEntityManager(8)
+	layers := []int{2, 0, 1, 0, 2}
+	for _, layer := range layers {
+		e, _ := m.Create()
+		m.SetTransform(e, Transform{})
+		m.SetSprite(e, Sprite{Layer: layer})
+	}
+	hidden, _ := m.Create()
+	m.SetTransform(hidden, Transform{})
+	m.SetSprite(hidden, Sprite{Hidden: true})
+
+	order := m.drawOrder()
+	expected := []uint32{1, 3, 2, 0, 4}
+	if len(order) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, order)
+	}
+	for i, idx := range expected {
+		if order[i] != idx {
+			t.Fatalf("order[%d] = %d, want %d", i, order[i], idx)
+		}
+	}
+	m.Draw(nil)
+}
+
+func TestEntityManagerNilIsNoop(t *testing.T) {
+	var m *EntityManager
+	if _, ok := m.Create(); ok {
+		t.Fatal("nil manager should not create entities")
+	}
+	m.Destroy(Entity{})
+	m.Update()
+	m.Draw(nil)
+	m.Each(0, func(Entity) {})
+	if m.Len() != 0 || m.Transform(Entity{}) != nil {
+		t.Fatal("nil manager should be empty")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"image/color"
 
 	"github.com/drpaneas/gosprite64"
 	"github.com/drpaneas/gosprite64/math2d"
@@ -149,6 +150,14 @@ func (s *PauseState) Update() {
 	}
 }
 
+// Policy keeps the gameplay screen visible, dimmed, behind the menu.
+func (s *PauseState) Policy() gosprite64.StatePolicy {
+	return gosprite64.StatePolicy{
+		DrawBelow: true,
+		Dim:       color.RGBA{A: 128},
+	}
+}
+
 func (s *PauseState) Draw() {
 	gosprite64.FillRect(60, 80, 228, 136, gosprite64.DarkPurple)
 	gosprite64.DrawRect(60, 80, 228, 136, gosprite64.White)
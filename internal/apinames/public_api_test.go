@@ -183,7 +183,7 @@ func TestTileEngineNaming(t *testing.T) {
 		"func (m *Map) LayerCount() int",
 		"func (m *Map) LayerInfo(layer int) (MapLayerInfo, bool)",
 		"func (m *Map) LayerSheetID(layer int) (uint16, bool)",
-		"func (m *Map) TileAt(layer, x, y int) (uint16, bool)",
+		"func (m *Map) TileAt(layer, x, y int) (uint16, TileFlip, bool)",
 		"func (m *Map) PixelWidth() int",
 		"func (m *Map) PixelHeight() int",
 	} {
@@ -380,6 +380,10 @@ func TestDrawSpriteAPI(t *testing.T) {
 	requireContains(t, sd, "BlendNone")
 	requireContains(t, sd, "BlendMasked")
 	requireContains(t, sd, "BlendAlpha")
+	requireContains(t, sd, "BlendAdd")
+	requireContains(t, sd, "BlendMultiply")
+	requireContains(t, sd, "Tint color.RGBA")
+	requireContains(t, sd, "Flash color.RGBA")
 	requireContains(t, sd, "func DrawSprite(sheet *SpriteSheet, frame int, x, y float32)")
 	requireContains(t, sd, "func DrawSpriteWithOptions(sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions)")
 	requireContains(t, sd, "func DrawWorldSprite(sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera)")
@@ -463,6 +467,232 @@ func TestStateMachineAPI(t *testing.T) {
 	requireContains(t, src, "func (sm *StateMachine) Pop()")
 	requireContains(t, src, "func (sm *StateMachine) Current() GameState")
 	requireContains(t, src, "func (sm *StateMachine) Depth() int")
+	requireContains(t, src, "type StatePolicy struct {")
+	requireContains(t, src, "type OverlayState interface {")
+	requireContains(t, src, "func (sm *StateMachine) PopTo(state GameState) bool")
+	requireContains(t, src, "func (sm *StateMachine) ReplaceAll(state GameState)")
+	requireContains(t, src, "func FindState[T GameState](sm *StateMachine) (T, bool)")
+}
+
+func TestEntityManagerAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "entity.go")
+	requireContains(t, src, "type Entity struct {")
+	requireContains(t, src, "type EntityManager struct {")
+	requireContains(t, src, "func NewEntityManager(capacity int) *EntityManager")
+	requireContains(t, src, "func (m *EntityManager) Create() (Entity, bool)")
+	requireContains(t, src, "func (m *EntityManager) Destroy(e Entity)")
+	requireContains(t, src, "type Transform struct {")
+	requireContains(t, src, "type Sprite struct {")
+	requireContains(t, src, "func (m *EntityManager) SetAnimation(e Entity, p *AnimationPlayer)")
+	requireContains(t, src, "func (m *EntityManager) SetCollider(e Entity, c math2d.Collider)")
+	requireContains(t, src, "func (m *EntityManager) SetVelocity(e Entity, v math2d.Vec2)")
+	requireContains(t, src, "func (m *EntityManager) OnCollision(layer, other math2d.Layer, fn CollisionFunc)")
+	requireContains(t, src, "func (m *EntityManager) Draw(cam *Camera)")
+	requireContains(t, src, "DrawWorldSpriteWithOptions(")
+}
+
+func TestScriptRunnerAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "script.go")
+	requireContains(t, src, "type Script func(sc *ScriptContext)")
+	requireContains(t, src, "type ScriptRunner struct {")
+	requireContains(t, src, "func NewScriptRunner() *ScriptRunner")
+	requireContains(t, src, "func (r *ScriptRunner) Start(script Script)")
+	requireContains(t, src, "func (r *ScriptRunner) Update()")
+	requireContains(t, src, "func (sc *ScriptContext) Wait(frames int)")
+	requireContains(t, src, "func (sc *ScriptContext) WaitUntil(cond func() bool)")
+	requireContains(t, src, "func (sc *ScriptContext) Tween(value *float32, to float32, frames int, ease func(t float32) float32)")
+	requireContains(t, src, "func (sc *ScriptContext) Say(text string)")
+	requireContains(t, src, "func (sc *ScriptContext) Parallel(scripts ...Script)")
+	requireNotContains(t, src, "go func(")
+}
+
+func TestTweenAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "tween.go")
+	requireContains(t, src, "type Tween struct {")
+	requireContains(t, src, "type TweenManager struct {")
+	requireContains(t, src, "func NewTweenManager() *TweenManager")
+	requireContains(t, src, "func (m *TweenManager) Float(value *float32, to float32, frames int, ease math2d.EaseFunc) *Tween")
+	requireContains(t, src, "func (m *TweenManager) Vec2(value *math2d.Vec2, to math2d.Vec2, frames int, ease math2d.EaseFunc) *Tween")
+	requireContains(t, src, "func (m *TweenManager) Color(value *color.RGBA, to color.RGBA, frames int, ease math2d.EaseFunc) *Tween")
+	requireContains(t, src, "func (tw *Tween) Then(next *Tween) *Tween")
+
+	easing := mustReadRepoFile(t, "math2d/easing.go")
+	requireContains(t, easing, "type EaseFunc func(t float32) float32")
+	for _, curve := range []string{"Sine", "Expo", "Circ", "Back", "Elastic", "Bounce"} {
+		requireContains(t, easing, "func EaseIn"+curve+"(t float32) float32")
+		requireContains(t, easing, "func EaseOut"+curve+"(t float32) float32")
+		requireContains(t, easing, "func EaseInOut"+curve+"(t float32) float32")
+	}
+}
+
+func TestSceneLayerAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "scene.go")
+	requireContains(t, src, "func (s *Scene) DrawLayer(cam *Camera, layer int)")
+	requireContains(t, src, "func (s *Scene) DrawLayers(cam *Camera, from, to int)")
+	requireContains(t, src, "func (s *Scene) SetLayerVisible(layer int, visible bool)")
+	requireContains(t, src, "func (s *Scene) SetLayerOffset(layer int, x, y int)")
+	requireContains(t, src, "func (s *Scene) SetLayerTint(layer int, tint color.RGBA)")
+	requireContains(t, src, "func (s *Scene) SetLayerOpacity(layer int, opacity float32)")
+	requireContains(t, src, "currentTile().resetTexturedState()")
+	requireContains(t, src, "func (s *Scene) SetLayerParallax(layer int, p ParallaxLayer)")
+	requireContains(t, src, "func (s *Scene) SetParallax(cfg ParallaxConfig)")
+	requireContains(t, src, "func (s *Scene) SetLayerRepeat(layer int, repeatX, repeatY bool)")
+}
+
+func TestTileCollisionAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "tile_collision.go")
+	requireContains(t, src, "type TileShape uint8")
+	requireContains(t, src, "TileOneWay")
+	requireContains(t, src, "TileSlopeUp22Low")
+	requireContains(t, src, "TileLadder")
+	requireContains(t, src, "TileHazard")
+	requireContains(t, src, "type TileContacts struct {")
+	requireContains(t, src, "func (m *Map) SetTileShape(tile uint16, shape TileShape)")
+	requireContains(t, src, "func (m *Map) MoveAndSlide(rect math2d.Rect, velocity math2d.Vec2, layer int) (math2d.Vec2, TileContacts)")
+	requireContains(t, src, "func (m *Map) RaycastTiles(layer int, origin, dir math2d.Vec2, maxDist float32) (TileHit, bool)")
+	requireNotContains(t, src, "TMEM")
+	requireNotContains(t, src, "DMA")
+}
+
+func TestTilePropsAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "tile_props.go")
+	requireContains(t, src, "type TileFlags uint32")
+	requireContains(t, src, "type TileProps struct {")
+	requireContains(t, src, "func (s *Sheet) TileProps(tileID uint16) TileProps")
+	requireContains(t, src, "func (s *Sheet) TileFlag(name string) TileFlags")
+	requireContains(t, src, "func (m *Map) TilePropsAt(layer, x, y int) TileProps")
+	requireContains(t, src, "func (p TileProps) Str(key, def string) string")
+	requireContains(t, src, "func (p TileProps) Bool(key string, def bool) bool")
+	requireNotContains(t, src, "func (p TileProps) String(")
+
+	sheet := mustReadRepoFile(t, "cmd/mk2dsheet/main.go")
+	requireContains(t, sheet, `"props"`)
+}
+
+func TestTileFlipAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "tile_flip.go")
+	requireContains(t, src, "type TileFlip uint8")
+	requireContains(t, src, "TileFlipH TileFlip")
+	requireContains(t, src, "TileFlipV TileFlip")
+	requireContains(t, src, "TileFlipD TileFlip")
+	requireNotContains(t, src, "TMEM")
+
+	m := mustReadRepoFile(t, "internal/tile2d/format/map.go")
+	requireContains(t, m, "const CellIDMask uint16 = 1<<13 - 1")
+}
+
+func TestTileAnimAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "tile_anim.go")
+	requireContains(t, src, "type TileAnim struct {")
+	requireContains(t, src, "func (a TileAnim) FrameAt(tick int) uint16")
+	requireContains(t, src, "func (s *Sheet) TileAnim(tileID uint16) (TileAnim, bool)")
+	requireContains(t, src, "func (s *Scene) Update()")
+	requireContains(t, src, "func (s *Scene) TileTick() int")
+	requireContains(t, src, "func (s *Scene) SetTileTick(tick int)")
+	requireNotContains(t, src, "TMEM")
+}
+
+func TestMapEditAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "map_edit.go")
+	requireContains(t, src, "type TileChangeFunc func(layer, x, y int, old, tile uint16)")
+	requireContains(t, src, "func (m *Map) SetTileAt(layer, x, y int, id uint16) bool")
+	requireContains(t, src, "func (m *Map) OnTileChange(fn TileChangeFunc)")
+	requireNotContains(t, src, "TMEM")
+}
+
+func TestMapObjectsAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "map_objects.go")
+	requireContains(t, src, "type MapObject struct {")
+	requireContains(t, src, "type ObjectKind uint8")
+	requireContains(t, src, "func (m *Map) Objects(layer int) []MapObject")
+	requireContains(t, src, "func (m *Map) ObjectByName(name string) (MapObject, bool)")
+	requireContains(t, src, "func NewTriggers(objects []MapObject) *Triggers")
+	requireContains(t, src, "func (t *Triggers) Update(body math2d.Rect) []TriggerEvent")
+	requireContains(t, src, "func (o MapObject) Str(key, def string) string")
+	requireContains(t, src, "func (o MapObject) Bool(key string, def bool) bool")
+	requireNotContains(t, src, "func (o MapObject) String(")
+	requireNotContains(t, src, "TMEM")
+}
+
+func TestMapLinksAPI(t *testing.T) {
+	links := mustReadRepoFile(t, "map_links.go")
+	requireContains(t, links, "type MapLink struct {")
+	requireContains(t, links, "func (m *Map) Links() []MapLink")
+	requireNotContains(t, links, "TMEM")
+
+	m := mustReadRepoFile(t, "map.go")
+	requireContains(t, m, "func (m *Map) LayerByName(name string) (int, bool)")
+}
+
+func TestMapStreamAPI(t *testing.T) {
+	stream := mustReadRepoFile(t, "map_stream.go")
+	requireContains(t, stream, "func (m *Map) Streamed() bool")
+	requireContains(t, stream, "func (m *Map) StreamErr() error")
+	requireContains(t, stream, "func (m *Map) SetStreamPolicy(p StreamPolicy)")
+	requireContains(t, stream, "func (m *Map) StreamPolicy() StreamPolicy")
+	requireNotContains(t, stream, "TMEM")
+
+	policy := mustReadRepoFile(t, "asset_policy.go")
+	requireContains(t, policy, "type StreamPolicy struct {")
+}
+
+func TestWorldAPI(t *testing.T) {
+	world := mustReadRepoFile(t, "world.go")
+	requireContains(t, world, "type World struct {")
+	requireContains(t, world, "func LoadWorld(bundle *Bundle, start string) (*World, error)")
+	requireContains(t, world, "func (w *World) Go(link MapLink, tr RoomTransition) error")
+	requireContains(t, world, "func (w *World) LinkAt(p math2d.Vec2) (MapLink, bool)")
+	requireContains(t, world, "func (w *World) State(room string) *RoomState")
+	requireContains(t, world, "func (w *World) OnRoomChange(fn RoomChangeFunc)")
+	requireContains(t, world, "type RoomState struct {")
+	requireNotContains(t, world, "TMEM")
+}
+
+func TestAutotileAPI(t *testing.T) {
+	auto := mustReadRepoFile(t, "map_autotile.go")
+	requireContains(t, auto, "func (m *Map) ApplyAutotile(layer int, region image.Rectangle) int")
+	requireContains(t, auto, "func (m *Map) SetTerrainAt(layer, x, y int, terrain uint16) bool")
+	requireContains(t, auto, "func (m *Map) TerrainAt(layer, x, y int) uint16")
+	requireNotContains(t, auto, "TMEM")
+}
+
+func TestSpriteBatchAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "sprite_batch.go")
+	requireContains(t, src, "type SpriteBatch struct {")
+	requireContains(t, src, "func (b *SpriteBatch) Draw(layer int, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (b *SpriteBatch) DrawWorld(layer int, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (b *SpriteBatch) Len() int")
+	requireContains(t, src, "func (b *SpriteBatch) Flush()")
+	requireContains(t, src, "func (b *SpriteBatch) Stats() RuntimeStats")
+	requireNotContains(t, src, "TMEM")
+}
+
+func TestDrawListAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "draw_list.go")
+	requireContains(t, src, "type DrawList struct {")
+	requireContains(t, src, "func (l *DrawList) Sprite(layer int, z float32, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (l *DrawList) SpriteY(layer int, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (l *DrawList) WorldSprite(layer int, z float32, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (l *DrawList) WorldSpriteY(layer int, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions)")
+	requireContains(t, src, "func (l *DrawList) Func(layer int, z float32, fn func())")
+	requireContains(t, src, "func (l *DrawList) SceneLayers(layer int, scene *Scene, cam *Camera, from, to int)")
+	requireContains(t, src, "func (l *DrawList) Flush()")
+	requireContains(t, src, "func (l *DrawList) Stats() RuntimeStats")
+}
+
+func TestPaletteAPI(t *testing.T) {
+	src := mustReadRepoFile(t, "palette.go")
+	requireContains(t, src, "type Palette struct {")
+	requireContains(t, src, "func NewPalette(colors ...color.Color) *Palette")
+	requireContains(t, src, "func (p *Palette) Len() int")
+	requireContains(t, src, "func (p *Palette) Color(i int) color.Color")
+	requireContains(t, src, "func (p *Palette) SetColor(i int, c color.Color)")
+	requireContains(t, src, "func (p *Palette) Cycle(first, last, step int)")
+	requireContains(t, src, "func (p *Palette) Clone() *Palette")
+
+	requireContains(t, mustReadRepoFile(t, "sheet.go"), "func (s *Sheet) Palette() *Palette")
+	requireContains(t, mustReadRepoFile(t, "sprite_sheet.go"), "func (s *SpriteSheet) Palette() *Palette")
+	requireContains(t, mustReadRepoFile(t, "sprite_draw.go"), "Palette *Palette")
 }
 
 func TestCollisionAPI(t *testing.T) {
@@ -493,10 +723,25 @@ func TestCameraEnhancedAPI(t *testing.T) {
 	requireContains(t, src, "Bounds")
 	requireContains(t, src, "func (c *Camera) EffectiveZoom() float32")
 	requireContains(t, src, "func (c *Camera) WorldToScreen(")
+	requireContains(t, src, "Rotation float32")
+	requireContains(t, src, "func (c *Camera) ScreenToWorld(screenX, screenY float32) (float32, float32)")
 	requireContains(t, src, "func (c *Camera) UpdateFollow()")
 	requireContains(t, src, "func (c *Camera) ClampToBounds()")
 	requireContains(t, src, "func (c *Camera) AddTrauma(")
 	requireContains(t, src, "func (c *Camera) ShakeOffset()")
+	requireContains(t, src, "DeadZone math2d.Rect")
+	requireContains(t, src, "FollowTargets []*math2d.Vec2")
+	requireContains(t, src, "ShakeFrequency float32")
+	requireContains(t, src, "func (c *Camera) Kick(dx, dy float32)")
+
+	follow := mustReadRepoFile(t, "camera_follow.go")
+	requireContains(t, follow, "func (c *Camera) SnapToLedge(worldY float32)")
+
+	rail := mustReadRepoFile(t, "camera_rail.go")
+	requireContains(t, rail, "type CameraRail struct {")
+	requireContains(t, rail, "func NewCameraRail(points ...math2d.Vec2) *CameraRail")
+	requireContains(t, rail, "func (c *Camera) PanTo(x, y float32, frames int, ease math2d.EaseFunc)")
+	requireContains(t, rail, "func (c *Camera) ReturnToFollow(frames int, ease math2d.EaseFunc)")
 }
 
 func TestFontAPI(t *testing.T) {
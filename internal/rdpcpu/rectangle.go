@@ -0,0 +1,66 @@
+package rdpcpu
+
+// TexRect describes a textured rectangle in framebuffer pixels. X0/Y0 is
+// the top-left corner and X1/Y1 the exclusive bottom-right corner; both may
+// be fractional. S and T are the texel at the top-left corner and DsDx and
+// DtDy the texels stepped per pixel, so 0.5 doubles the texture's size and
+// a negative step mirrors it.
+//
+// Flip swaps the texture axes: S then steps down the rows by DsDx and T
+// across the columns by DtDy, which transposes the texture.
+type TexRect struct {
+	X0, Y0, X1, Y1 float32
+	S, T           float32
+	DsDx, DtDy     float32
+	Flip           bool
+}
+
+// Clip trims the rectangle to clip (framebuffer pixels) and advances S and T
+// by the texels that were cut away. It reports false when nothing is left.
+func (r TexRect) Clip(minX, minY, maxX, maxY float32) (TexRect, bool) {
+	if r.X0 < minX {
+		if r.Flip {
+			r.T += (minX - r.X0) * r.DtDy
+		} else {
+			r.S += (minX - r.X0) * r.DsDx
+		}
+		r.X0 = minX
+	}
+	if r.Y0 < minY {
+		if r.Flip {
+			r.S += (minY - r.Y0) * r.DsDx
+		} else {
+			r.T += (minY - r.Y0) * r.DtDy
+		}
+		r.Y0 = minY
+	}
+	r.X1 = min(r.X1, maxX)
+	r.Y1 = min(r.Y1, maxY)
+	return r, r.X0 < r.X1 && r.Y0 < r.Y1
+}
+
+// BuildTextureRectangle returns the raw command words for an RDP texture
+// rectangle (opcode 0x24, or 0x25 when Flip is set) in 1-cycle mode. Unlike the integer scale
+// accepted by rdp.TextureRectangle, any positive or negative step works.
+// The rectangle must already be clipped to non-negative coordinates.
+func BuildTextureRectangle(tileIdx uint8, r TexRect) []uint64 {
+	xl := fixed(r.X1, 2) & 0xFFF
+	yl := fixed(r.Y1, 2) & 0xFFF
+	xh := fixed(r.X0, 2) & 0xFFF
+	yh := fixed(r.Y0, 2) & 0xFFF
+
+	op := uint64(0xE4)
+	if r.Flip {
+		op = 0xE5
+	}
+	w0 := op<<56 | xl<<44 | yl<<32 | uint64(tileIdx&7)<<24 | xh<<12 | yh
+	w1 := (fixed(r.S, 5)&0xFFFF)<<48 | (fixed(r.T, 5)&0xFFFF)<<32 |
+		(fixed(r.DsDx, 10)&0xFFFF)<<16 | fixed(r.DtDy, 10)&0xFFFF
+	return []uint64{w0, w1}
+}
+
+// fixed converts v to a two's complement fixed-point value with frac
+// fractional bits, rounding toward negative infinity.
+func fixed(v float32, frac uint) uint64 {
+	return uint64(int64(floorF32(v * float32(int32(1)<<frac))))
+}
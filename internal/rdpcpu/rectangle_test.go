@@ -0,0 +1,71 @@
+package rdpcpu
+
+import "testing"
+
+func TestBuildTextureRectangleMatchesIntegerEncoding(t *testing.T) {
+	// Same fields rdp.TextureRectangle writes for a 1:1 8x8 tile at (16,12).
+	cmds := BuildTextureRectangle(3, TexRect{X0: 16, Y0: 12, X1: 24, Y1: 20, S: 0, T: 0, DsDx: 1, DtDy: 1})
+	if len(cmds) != 2 {
+		t.Fatalf("expected 2 words, got %d", len(cmds))
+	}
+	want0 := uint64(0xe4)<<56 | uint64(24)<<46 | uint64(20)<<34 | uint64(3)<<24 | uint64(16)<<14 | uint64(12)<<2
+	want1 := uint64((0x8000/1)>>5)<<16 | (0x8000/1)>>5
+	if cmds[0] != want0 {
+		t.Fatalf("word 0 = %016X, want %016X", cmds[0], want0)
+	}
+	if cmds[1] != want1 {
+		t.Fatalf("word 1 = %016X, want %016X", cmds[1], want1)
+	}
+}
+
+func TestBuildTextureRectangleFractionalStep(t *testing.T) {
+	cmds := BuildTextureRectangle(0, TexRect{X0: 0.5, Y0: 0, X1: 12.25, Y1: 12, S: 2, T: -1, DsDx: 1.0 / 1.5, DtDy: -1})
+	if got := (cmds[0] >> 12) & 0xFFF; got != 2 {
+		t.Fatalf("XH = %d, want 2 (0.5 in 10.2)", got)
+	}
+	if got := (cmds[0] >> 44) & 0xFFF; got != 49 {
+		t.Fatalf("XL = %d, want 49 (12.25 in 10.2)", got)
+	}
+	if got := (cmds[1] >> 48) & 0xFFFF; got != 64 {
+		t.Fatalf("S = %d, want 64 (2 in s10.5)", got)
+	}
+	if got := int16((cmds[1] >> 32) & 0xFFFF); got != -32 {
+		t.Fatalf("T = %d, want -32 (-1 in s10.5)", got)
+	}
+	if got := (cmds[1] >> 16) & 0xFFFF; got != 682 {
+		t.Fatalf("DsDx = %d, want 682 (1/1.5 in s5.10)", got)
+	}
+	if got := int16(cmds[1] & 0xFFFF); got != -1024 {
+		t.Fatalf("DtDy = %d, want -1024", got)
+	}
+}
+
+func TestTexRectClipAdvancesTexels(t *testing.T) {
+	r, ok := TexRect{X0: -4, Y0: 2, X1: 12, Y1: 18, DsDx: 0.5, DtDy: 0.5}.Clip(0, 0, 10, 10)
+	if !ok {
+		t.Fatal("Clip() = false, want visible")
+	}
+	if r.X0 != 0 || r.S != 2 || r.X1 != 10 || r.Y1 != 10 || r.T != 0 {
+		t.Fatalf("Clip() = %+v", r)
+	}
+	if _, ok := (TexRect{X0: 20, Y0: 0, X1: 30, Y1: 8, DsDx: 1, DtDy: 1}).Clip(0, 0, 10, 10); ok {
+		t.Fatal("Clip() = true for a rectangle outside the clip")
+	}
+}
+
+func TestBuildTextureRectangleFlipUsesFlipOpcode(t *testing.T) {
+	cmds := BuildTextureRectangle(0, TexRect{X0: 0, Y0: 0, X1: 8, Y1: 8, DsDx: 1, DtDy: 1, Flip: true})
+	if got := cmds[0] >> 56; got != 0xE5 {
+		t.Fatalf("opcode = %#x, want 0xe5", got)
+	}
+}
+
+func TestTexRectClipSwapsAxesWhenFlipped(t *testing.T) {
+	r, ok := TexRect{X0: -2, Y0: -3, X1: 8, Y1: 8, S: 7, DsDx: -1, DtDy: 1, Flip: true}.Clip(0, 0, 10, 10)
+	if !ok {
+		t.Fatal("Clip() = false, want visible")
+	}
+	if r.T != 2 || r.S != 4 {
+		t.Fatalf("Clip() = %+v, want T advanced by X and S by Y", r)
+	}
+}
@@ -0,0 +1,45 @@
+//go:build n64
+
+package rdpcpu
+
+import (
+	"image"
+
+	"github.com/clktmr/n64/rcp/rdp"
+	"github.com/clktmr/n64/rcp/texture"
+)
+
+// MaxTileSize returns the largest tex the renderers load into TMEM at once.
+// Palettized textures get half, since the TLUT takes the upper half of
+// TMEM.
+func MaxTileSize(tex *texture.Texture) image.Rectangle {
+	max := rdp.MaxTileSize(tex.Format())
+	if tex.Palette() != nil {
+		max.Max.Y /= 2
+	}
+	return max
+}
+
+// FitsTMEM reports whether tex can be loaded into TMEM at once.
+func FitsTMEM(tex *texture.Texture) bool {
+	max := MaxTileSize(tex)
+	return tex.Bounds().Dx() <= max.Dx() && tex.Bounds().Dy() <= max.Dy()
+}
+
+// LoadTLUT loads pal into the upper half of TMEM, where the RDP looks up
+// the indices of palettized textures.
+func LoadTLUT(pal *texture.Texture) {
+	rdp.RDP.SetTextureImage(pal)
+	// The TLUT is loaded through a 4bpp tile, whatever the texture's depth.
+	_, idx := rdp.RDP.SetTile(rdp.TileDescriptor{Format: texture.CI4, Addr: 0x100})
+	rdp.RDP.LoadTLUT(idx, pal.Bounds())
+}
+
+// TLUTMode returns the mode flag that makes the RDP look tex's texels up
+// in the loaded TLUT, or no flag for textures without a palette.
+func TLUTMode(tex *texture.Texture) rdp.ModeFlags {
+	if tex.Palette() == nil {
+		return 0
+	}
+	return rdp.TLUT
+}
@@ -0,0 +1,191 @@
+package sprite
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/clktmr/n64/rcp/texture"
+)
+
+const (
+	blendMasked   = 1
+	blendAlpha    = 2
+	blendAdd      = 3
+	blendMultiply = 4
+)
+
+// Draw is one sprite draw, with the arguments of RenderSprite and the
+// layer a batch sorts it by.
+type Draw struct {
+	Src      image.Image
+	X, Y     int
+	FlipH    bool
+	FlipV    bool
+	ScaleX   float32
+	ScaleY   float32
+	Blend    uint8
+	Alpha    float32
+	Rotation float32
+	OriginX  float32
+	OriginY  float32
+	// Tint multiplies the frame's RGB; the zero value leaves it alone.
+	Tint color.RGBA
+	// Flash mixes the frame's RGB toward its RGB by its alpha, keeping the
+	// frame's alpha. It replaces Tint when its alpha is non-zero.
+	Flash color.RGBA
+	// Palette replaces the TLUT of a palettized Src; nil keeps Src's own.
+	Palette *texture.Texture
+	Layer   int
+}
+
+// scales returns the draw's scale factors, treating zero as 1.
+func (d Draw) scales() (sx, sy float32) {
+	sx, sy = d.ScaleX, d.ScaleY
+	if sx == 0 {
+		sx = 1
+	}
+	if sy == 0 {
+		sy = 1
+	}
+	return sx, sy
+}
+
+// destSize returns the size of the unrotated sprite on screen, rounding
+// each side to the nearest pixel.
+func (d Draw) destSize() (w, h int) {
+	size := d.Src.Bounds().Size()
+	sx, sy := d.scales()
+	return int(float32(size.X)*sx + 0.5), int(float32(size.Y)*sy + 0.5)
+}
+
+// rectDrawable reports whether the RDP can draw d as a texture rectangle:
+// unrotated, scaled by whole factors, and flipped only along power-of-two
+// sides, which the tile descriptor mirrors. Other draws take the triangle
+// path.
+func (d Draw) rectDrawable() bool {
+	if d.Rotation != 0 || d.Src == nil {
+		return false
+	}
+	sx, sy := d.scales()
+	size := d.Src.Bounds().Size()
+	return wholeScale(sx) && wholeScale(sy) &&
+		(!d.FlipH || isPowerOf2(size.X)) && (!d.FlipV || isPowerOf2(size.Y))
+}
+
+// mirrors reports the flips the RDP applies through the tile descriptor.
+// Triangle draws flip through their texture coordinates instead.
+func (d Draw) mirrors() (h, v bool) {
+	if !d.rectDrawable() {
+		return false, false
+	}
+	return d.FlipH, d.FlipV
+}
+
+// stateKey identifies the texture load and blend setup of a draw.
+type stateKey struct {
+	src   image.Image
+	pal   *texture.Texture
+	h, v  bool
+	blend uint8
+	alpha float32
+	tint  color.RGBA
+	flash color.RGBA
+}
+
+func (d Draw) state() stateKey {
+	k := stateKey{src: d.Src, pal: d.palette(), blend: d.Blend, tint: d.Tint, flash: d.Flash}
+	k.h, k.v = d.mirrors()
+	if d.Blend >= blendAlpha {
+		k.alpha = d.Alpha
+	}
+	if !tinted(k.tint) {
+		k.tint = color.RGBA{}
+	}
+	if k.flash.A == 0 {
+		k.flash = color.RGBA{}
+	}
+	return k
+}
+
+// sameTexture reports whether d can draw from the TMEM load of prev.
+func (d Draw) sameTexture(prev Draw) bool {
+	a, b := d.state(), prev.state()
+	return a.src == b.src && a.pal == b.pal && a.h == b.h && a.v == b.v
+}
+
+// palette returns the TLUT a palettized Src draws with, or nil for other
+// sources.
+func (d Draw) palette() *texture.Texture {
+	tex, ok := d.Src.(*texture.Texture)
+	if !ok || tex == nil || tex.Palette() == nil {
+		return nil
+	}
+	if d.Palette != nil {
+		return d.Palette
+	}
+	return tex.Palette()
+}
+
+// Sort orders draws by layer and, within a layer, groups the draws that
+// share a texture and blend state, in the order each group first appears.
+// Draws keep their order within a group.
+func Sort(draws []Draw) {
+	type groupKey struct {
+		layer int
+		state stateKey
+	}
+	groups := make(map[groupKey]int)
+	ranks := make([]int, len(draws))
+	for i := range draws {
+		k := groupKey{draws[i].Layer, draws[i].state()}
+		rank, ok := groups[k]
+		if !ok {
+			rank = len(groups)
+			groups[k] = rank
+		}
+		ranks[i] = rank
+	}
+
+	order := make([]int, len(draws))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		i, j := order[a], order[b]
+		if draws[i].Layer != draws[j].Layer {
+			return draws[i].Layer < draws[j].Layer
+		}
+		return ranks[i] < ranks[j]
+	})
+	sorted := make([]Draw, len(draws))
+	for i, j := range order {
+		sorted[i] = draws[j]
+	}
+	copy(draws, sorted)
+}
+
+// Render draws draws in order into fb, setting up the RDP once and loading
+// a texture into TMEM once per run of draws that share it. It returns the
+// number of texture loads.
+func Render(fb *texture.Texture, draws []Draw) int {
+	r := renderer{fb: fb}
+	for _, d := range draws {
+		r.draw(d)
+	}
+	return r.uploads
+}
+
+// tinted reports whether tint changes a color: the zero value and white
+// do not.
+func tinted(tint color.RGBA) bool {
+	return tint != (color.RGBA{}) && (tint.R != 255 || tint.G != 255 || tint.B != 255)
+}
+
+func wholeScale(s float32) bool {
+	return s >= 1 && s == float32(int(s))
+}
+
+func isPowerOf2(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
@@ -0,0 +1,86 @@
+package sprite
+
+import (
+	"image"
+	"testing"
+
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rendergeom"
+)
+
+func TestSortGroupsByLayerThenState(t *testing.T) {
+	a := texture.NewRGBA16(image.Rect(0, 0, 8, 8))
+	b := texture.NewRGBA16(image.Rect(0, 0, 8, 8))
+	draws := []Draw{
+		{Src: a, X: 0, Layer: 1},
+		{Src: b, X: 1, Layer: 1},
+		{Src: a, X: 2, Layer: 0},
+		{Src: a, X: 3, Layer: 1},
+		{Src: a, X: 4, Layer: 1, Blend: blendAlpha, Alpha: 0.5},
+		{Src: b, X: 5, Layer: 1},
+		{Src: a, X: 6, Layer: 1, Blend: blendAlpha, Alpha: 0.5},
+	}
+	Sort(draws)
+
+	var got []int
+	for _, d := range draws {
+		got = append(got, d.X)
+	}
+	want := []int{2, 0, 3, 1, 5, 4, 6}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sorted order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRenderLoadsOncePerRun(t *testing.T) {
+	fb := texture.NewRGBA16(rendergeom.FramebufferBounds())
+	a := texture.NewRGBA16(image.Rect(0, 0, 8, 8))
+	b := texture.NewRGBA16(image.Rect(0, 0, 8, 8))
+
+	var draws []Draw
+	for i := 0; i < 200; i++ {
+		draws = append(draws, Draw{Src: a, X: i, Y: 10})
+	}
+	draws = append(draws,
+		Draw{Src: b, X: 0, Y: 20},
+		Draw{Src: b, X: 10, Y: 20, FlipH: true},
+		Draw{Src: b, X: -100, Y: 20},
+	)
+	// 200 draws of a, b, and b mirrored; the off-screen draw loads nothing.
+	if got := Render(fb, draws); got != 3 {
+		t.Fatalf("Render() uploads = %d, want 3", got)
+	}
+	if got := Render(nil, draws); got != 0 {
+		t.Fatalf("Render(nil) uploads = %d, want 0", got)
+	}
+}
+
+func TestRectDrawableNeedsWholeScalesAndMirrorableFlips(t *testing.T) {
+	pow2 := texture.NewRGBA16(image.Rect(0, 0, 16, 8))
+	odd := texture.NewRGBA16(image.Rect(0, 0, 12, 8))
+	tests := []struct {
+		d    Draw
+		rect bool
+	}{
+		{Draw{Src: odd}, true},
+		{Draw{Src: pow2, FlipH: true, FlipV: true, ScaleX: 2, ScaleY: 3}, true},
+		{Draw{Src: odd, FlipV: true}, true},
+		{Draw{Src: odd, FlipH: true}, false},
+		{Draw{Src: pow2, ScaleX: 0.5}, false},
+		{Draw{Src: pow2, ScaleY: 1.5}, false},
+		{Draw{Src: pow2, Rotation: 1}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.d.rectDrawable(); got != tt.rect {
+			t.Fatalf("case %d: rectDrawable() = %v, want %v", i, got, tt.rect)
+		}
+		if h, _ := tt.d.mirrors(); h && !tt.rect {
+			t.Fatalf("case %d: triangle draw mirrors through the tile descriptor", i)
+		}
+	}
+	if w, h := (Draw{Src: odd, ScaleX: 0.5, ScaleY: 1.5}).destSize(); w != 6 || h != 12 {
+		t.Fatalf("destSize() = %dx%d, want 6x12", w, h)
+	}
+}
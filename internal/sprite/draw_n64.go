@@ -14,11 +14,6 @@ import (
 	"github.com/drpaneas/gosprite64/internal/rdpcpu"
 )
 
-const (
-	blendMasked = 1
-	blendAlpha  = 2
-)
-
 var blendSrcSprites = rdp.BlendMode{
 	P1: rdp.BlenderPMColorCombiner,
 	A1: rdp.BlenderAColorCombinerAlpha,
@@ -34,78 +29,64 @@ var blendOverSprites = rdp.BlendMode{
 }
 
 // RenderSprite draws a sprite frame via the RDP with flip, scale, blend, and rotation.
-// For non-rotated sprites, the fast TextureRectangle path is used. For rotated
-// sprites, a CPU triangle setup path emits two textured RDP triangles.
+// Unrotated sprites with whole scales, flipped only along power-of-two sides,
+// use the fast TextureRectangle path. Other sprites use a CPU triangle setup
+// path that emits two textured RDP triangles.
 func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
 	flipH, flipV bool, scaleX, scaleY float32, blendMode uint8, alpha float32,
 	rotation, originX, originY float32) {
 
-	tex, ok := src.(*texture.Texture)
-	if !ok || tex == nil || fb == nil {
+	r := renderer{fb: fb}
+	r.draw(Draw{
+		Src: src, X: x, Y: y,
+		FlipH: flipH, FlipV: flipV,
+		ScaleX: scaleX, ScaleY: scaleY,
+		Blend: blendMode, Alpha: alpha,
+		Rotation: rotation, OriginX: originX, OriginY: originY,
+	})
+}
+
+// renderer tracks the RDP state left by the draws so far, so a run of
+// draws sharing a texture and blend state skips the repeated setup.
+type renderer struct {
+	fb      *texture.Texture
+	begun   bool
+	blended bool
+	mode    blendState
+	tex     *texture.Texture
+	pal     *texture.Texture
+	desc    rdp.TileDescriptor
+	drawIdx uint8
+	uploads int
+}
+
+func (r *renderer) draw(d Draw) {
+	tex, ok := d.Src.(*texture.Texture)
+	if !ok || tex == nil || r.fb == nil {
 		return
 	}
 
-	if rotation != 0 {
-		renderRotatedSprite(fb, tex, x, y, flipH, flipV, scaleX, scaleY,
-			blendMode, alpha, rotation, originX, originY)
+	if !rdpcpu.FitsTMEM(tex) {
 		return
 	}
-
 	srcBounds := tex.Bounds()
 	srcW := srcBounds.Dx()
 	srcH := srcBounds.Dy()
 
-	maxTile := rdp.MaxTileSize(tex.Format())
-	if srcW > maxTile.Dx() || srcH > maxTile.Dy() {
+	destW, destH := d.destSize()
+	logicalDst := image.Rect(d.X, d.Y, d.X+destW, d.Y+destH)
+	clipped := logicalDst.Intersect(rendergeom.LogicalBounds())
+	if d.Rotation == 0 && clipped.Empty() {
 		return
 	}
-
-	if flipH && !isPowerOf2(srcW) {
-		flipH = false
-	}
-	if flipV && !isPowerOf2(srcH) {
-		flipV = false
-	}
-
-	rdp.RDP.SetColorImage(fb)
-	rdp.RDP.SetScissor(image.Rectangle{Max: fb.Bounds().Size()}, rdp.InterlaceNone)
-
-	setupBlendMode(tex, blendMode, alpha)
-
-	tileDesc := rdp.TileDescriptor{
-		Format: tex.Format(),
-		Addr:   0x0,
-		Line:   uint16(tex.Format().TMEMWords(srcW)),
-	}
-	if flipH {
-		tileDesc.Flags |= rdp.MirrorS
-		tileDesc.MaskS = log2u(srcW)
-	}
-	if flipV {
-		tileDesc.Flags |= rdp.MirrorT
-		tileDesc.MaskT = log2u(srcH)
-	}
-
-	rdp.RDP.SetTextureImage(tex)
-	loadIdx, drawIdx := rdp.RDP.SetTile(tileDesc)
-	rdp.RDP.LoadTile(loadIdx, srcBounds)
-
-	if scaleX < 1 {
-		scaleX = 1
-	}
-	if scaleY < 1 {
-		scaleY = 1
-	}
-
-	destW := int(float32(srcW) * scaleX)
-	destH := int(float32(srcH) * scaleY)
-
-	logicalDst := image.Rect(x, y, x+destW, y+destH)
-	clipped := logicalDst.Intersect(rendergeom.LogicalBounds())
-	if clipped.Empty() {
+	if !d.rectDrawable() {
+		r.drawTriangles(d, tex)
 		return
 	}
 
+	flipH, flipV := d.mirrors()
+	scaleX, scaleY := d.scales()
+
 	framebufferRect, ok2 := rendergeom.MapRectInclusive(image.Rectangle{
 		Min: clipped.Min,
 		Max: clipped.Max.Sub(image.Pt(1, 1)),
@@ -114,6 +95,9 @@ func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
 		return
 	}
 
+	r.setup(tex, d)
+	drawIdx := r.load(tex, d.palette(), flipH, flipV)
+
 	clipOffsetX := clipped.Min.X - logicalDst.Min.X
 	clipOffsetY := clipped.Min.Y - logicalDst.Min.Y
 
@@ -130,8 +114,8 @@ func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
 	srcPtX += int(float32(clipOffsetX) / scaleX)
 	srcPtY += int(float32(clipOffsetY) / scaleY)
 
-	rdpScaleX := max(1, int(scaleX+0.5))
-	rdpScaleY := max(1, int(scaleY+0.5))
+	rdpScaleX := int(scaleX)
+	rdpScaleY := int(scaleY)
 
 	rdp.RDP.TextureRectangle(
 		image.Rect(
@@ -144,37 +128,86 @@ func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
 	)
 }
 
-// renderRotatedSprite draws a rotated sprite as two textured triangles.
-func renderRotatedSprite(fb *texture.Texture, tex *texture.Texture,
-	drawX, drawY int, flipH, flipV bool, scaleX, scaleY float32,
-	blendMode uint8, alpha float32, rotation, originX, originY float32) {
+// blendState is what setupBlendMode was last called with.
+type blendState struct {
+	blend  uint8
+	alpha  float32
+	opaque bool
+	tint   color.RGBA
+	flash  color.RGBA
+	tlut   bool
+}
 
-	srcBounds := tex.Bounds()
-	srcW := srcBounds.Dx()
-	srcH := srcBounds.Dy()
-	maxTile := rdp.MaxTileSize(tex.Format())
-	if srcW > maxTile.Dx() || srcH > maxTile.Dy() {
+// setup targets the framebuffer on the first draw and sets the blend mode
+// when it differs from the previous draw's.
+func (r *renderer) setup(tex *texture.Texture, d Draw) {
+	if !r.begun {
+		rdp.RDP.SetColorImage(r.fb)
+		rdp.RDP.SetScissor(image.Rectangle{Max: r.fb.Bounds().Size()}, rdp.InterlaceNone)
+		r.begun = true
+	}
+	mode := blendState{d.Blend, d.Alpha, !tex.HasAlpha(), d.Tint, d.Flash, tex.Palette() != nil}
+	if r.blended && r.mode == mode {
 		return
 	}
+	setupBlendMode(tex, d.Blend, d.Alpha, d.Tint, d.Flash)
+	r.blended, r.mode = true, mode
+}
 
-	rdp.RDP.SetColorImage(fb)
-	rdp.RDP.SetScissor(image.Rectangle{Max: fb.Bounds().Size()}, rdp.InterlaceNone)
-	setupBlendMode(tex, blendMode, alpha)
-
-	tileDesc := rdp.TileDescriptor{
+// load loads tex, and pal for palettized textures, into TMEM unless the
+// previous draw already did with the same tile descriptor, and returns the
+// tile to draw with.
+func (r *renderer) load(tex, pal *texture.Texture, flipH, flipV bool) uint8 {
+	srcW, srcH := tex.Bounds().Dx(), tex.Bounds().Dy()
+	desc := rdp.TileDescriptor{
 		Format: tex.Format(),
 		Addr:   0x0,
 		Line:   uint16(tex.Format().TMEMWords(srcW)),
 	}
+	if flipH {
+		desc.Flags |= rdp.MirrorS
+		desc.MaskS = log2u(srcW)
+	}
+	if flipV {
+		desc.Flags |= rdp.MirrorT
+		desc.MaskT = log2u(srcH)
+	}
+	if r.tex == tex && r.pal == pal && r.desc == desc {
+		return r.drawIdx
+	}
+
+	if pal != nil {
+		rdpcpu.LoadTLUT(pal)
+	}
 	rdp.RDP.SetTextureImage(tex)
-	loadIdx, drawIdx := rdp.RDP.SetTile(tileDesc)
-	rdp.RDP.LoadTile(loadIdx, srcBounds)
+	loadIdx, drawIdx := rdp.RDP.SetTile(desc)
+	rdp.RDP.LoadTile(loadIdx, tex.Bounds())
+	r.tex, r.pal, r.desc, r.drawIdx = tex, pal, desc, drawIdx
+	r.uploads++
+	return drawIdx
+}
+
+// drawTriangles draws a sprite as two textured triangles, which take any
+// rotation, scale and flip. Rotated sprites turn around (X, Y), which the
+// frame's origin is drawn at; unrotated sprites have (X, Y) at their
+// top-left corner.
+func (r *renderer) drawTriangles(d Draw, tex *texture.Texture) {
+	srcW := tex.Bounds().Dx()
+	srcH := tex.Bounds().Dy()
+	scaleX, scaleY := d.scales()
+	originX, originY := d.OriginX, d.OriginY
+	if d.Rotation == 0 {
+		originX, originY = 0, 0
+	}
+
+	r.setup(tex, d)
+	drawIdx := r.load(tex, d.palette(), false, false)
 
 	frameOrigin := rendergeom.Origin()
-	quad := rotatedQuad(float64(drawX+frameOrigin.X), float64(drawY+frameOrigin.Y),
+	quad := rotatedQuad(float64(d.X+frameOrigin.X), float64(d.Y+frameOrigin.Y),
 		float64(srcW), float64(srcH), float64(scaleX), float64(scaleY),
-		float64(rotation), float64(originX), float64(originY))
-	st := textureCoords(float32(srcW), float32(srcH), flipH, flipV)
+		float64(d.Rotation), float64(originX), float64(originY))
+	st := textureCoords(float32(srcW), float32(srcH), d.FlipH, d.FlipV)
 
 	packet1 := rdpcpu.BuildTexturedTriangle(drawIdx, 0,
 		rdpcpu.TexVertex{X: float32(quad[0][0]), Y: float32(quad[0][1]), S: st[0][0], T: st[0][1], InvW: 1},
@@ -186,8 +219,12 @@ func renderRotatedSprite(fb *texture.Texture, tex *texture.Texture,
 		rdpcpu.TexVertex{X: float32(quad[2][0]), Y: float32(quad[2][1]), S: st[2][0], T: st[2][1], InvW: 1},
 		rdpcpu.TexVertex{X: float32(quad[3][0]), Y: float32(quad[3][1]), S: st[3][0], T: st[3][1], InvW: 1},
 	)
+	// Triangles are not clipped on the CPU, so the scissor keeps them out
+	// of the border around the logical canvas.
+	rdp.RDP.SetScissor(rendergeom.LogicalBounds().Add(frameOrigin), rdp.InterlaceNone)
 	gfx.PushRaw(packet1...)
 	gfx.PushRaw(packet2...)
+	rdp.RDP.SetScissor(image.Rectangle{Max: r.fb.Bounds().Size()}, rdp.InterlaceNone)
 }
 
 func rotatedQuad(drawX, drawY, srcW, srcH, scaleX, scaleY, rotation, originX, originY float64) [4][2]float64 {
@@ -224,38 +261,87 @@ func textureCoords(srcW, srcH float32, flipH, flipV bool) [4][2]float32 {
 	}
 }
 
-func setupBlendMode(tex *texture.Texture, blendMode uint8, alpha float32) {
+var blendAddSprites = rdp.BlendMode{
+	P1: rdp.BlenderPMColorCombiner,
+	A1: rdp.BlenderAColorCombinerAlpha,
+	M1: rdp.BlenderPMFramebuffer,
+	B1: rdp.BlenderBOne,
+}
+
+// blendMultiplySprites scales the framebuffer by the combiner alpha: the
+// blender multiplies by one factor per pixel, never by a color.
+var blendMultiplySprites = rdp.BlendMode{
+	P1: rdp.BlenderPMFramebuffer,
+	A1: rdp.BlenderAColorCombinerAlpha,
+	M1: rdp.BlenderPMFramebuffer,
+	B1: rdp.BlenderBZero,
+}
+
+// setupBlendMode sets the color combiner and blender for a sprite. A flash
+// color with non-zero alpha mixes the texel color toward its RGB by its
+// alpha; otherwise a tint multiplies the texel color. Both go through the
+// primitive color, so a flash replaces the tint. Palettized textures look
+// their texels up in the loaded TLUT.
+func setupBlendMode(tex *texture.Texture, blendMode uint8, alpha float32, tint, flash color.RGBA) {
 	alphaSource := rdp.CombineTex0
 	if !tex.HasAlpha() {
 		alphaSource = rdp.CombineDAlphaOne
 	}
+	tlut := rdpcpu.TLUTMode(tex)
+
+	rgb := rdp.CombineParams{0, 0, 0, rdp.CombineTex0}
+	switch {
+	case flash.A != 0:
+		rdp.RDP.SetPrimitiveColor(color.NRGBA{R: flash.R, G: flash.G, B: flash.B, A: flash.A})
+		rgb = rdp.CombineParams{
+			A: rdp.CombinePrimitive,
+			B: rdp.CombineTex0,
+			C: rdp.CombineCColorPrimitiveAlpha,
+			D: rdp.CombineTex0,
+		}
+	case tinted(tint):
+		rdp.RDP.SetPrimitiveColor(color.NRGBA{R: tint.R, G: tint.G, B: tint.B, A: 255})
+		rgb = rdp.CombineParams{
+			A: rdp.CombineTex0,
+			B: rdp.CombineBColorZero,
+			C: rdp.CombinePrimitive,
+			D: rdp.CombineDColorZero,
+		}
+	}
 
 	switch blendMode {
 	case blendMasked:
 		rdp.RDP.SetOtherModes(
-			rdp.AlphaCompare|rdp.ForceBlend|rdp.ImageRead|rdp.BiLerp0,
+			rdp.AlphaCompare|rdp.ForceBlend|rdp.ImageRead|rdp.BiLerp0|tlut,
 			rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone,
 			rdp.ZmodeOpaque, rdp.CvgDestClamp, blendOverSprites,
 		)
 		rdp.RDP.SetBlendColor(color.NRGBA{A: 1})
 		rdp.RDP.SetCombineMode(rdp.CombineMode{
 			Two: rdp.CombinePass{
-				RGB:   rdp.CombineParams{0, 0, 0, rdp.CombineTex0},
+				RGB:   rgb,
 				Alpha: rdp.CombineParams{0, 0, 0, alphaSource},
 			},
 		})
 
-	case blendAlpha:
+	case blendAlpha, blendAdd, blendMultiply:
+		mode := blendOverSprites
+		switch blendMode {
+		case blendAdd:
+			mode = blendAddSprites
+		case blendMultiply:
+			mode = blendMultiplySprites
+		}
 		a := uint8(clampf(alpha, 0, 1) * 255)
 		rdp.RDP.SetEnvironmentColor(color.NRGBA{R: 255, G: 255, B: 255, A: a})
 		rdp.RDP.SetOtherModes(
-			rdp.ForceBlend|rdp.ImageRead|rdp.BiLerp0,
+			rdp.ForceBlend|rdp.ImageRead|rdp.BiLerp0|tlut,
 			rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone,
-			rdp.ZmodeOpaque, rdp.CvgDestClamp, blendOverSprites,
+			rdp.ZmodeOpaque, rdp.CvgDestClamp, mode,
 		)
 		rdp.RDP.SetCombineMode(rdp.CombineMode{
 			Two: rdp.CombinePass{
-				RGB: rdp.CombineParams{0, 0, 0, rdp.CombineTex0},
+				RGB: rgb,
 				Alpha: rdp.CombineParams{
 					A: alphaSource,
 					B: rdp.CombineAAlphaZero,
@@ -267,13 +353,13 @@ func setupBlendMode(tex *texture.Texture, blendMode uint8, alpha float32) {
 
 	default:
 		rdp.RDP.SetOtherModes(
-			rdp.ForceBlend|rdp.BiLerp0,
+			rdp.ForceBlend|rdp.BiLerp0|tlut,
 			rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone,
 			rdp.ZmodeOpaque, rdp.CvgDestClamp, blendSrcSprites,
 		)
 		rdp.RDP.SetCombineMode(rdp.CombineMode{
 			Two: rdp.CombinePass{
-				RGB:   rdp.CombineParams{0, 0, 0, rdp.CombineTex0},
+				RGB:   rgb,
 				Alpha: rdp.CombineParams{0, 0, 0, alphaSource},
 			},
 		})
@@ -290,10 +376,6 @@ func clampf(v, lo, hi float32) float32 {
 	return v
 }
 
-func isPowerOf2(n int) bool {
-	return n > 0 && n&(n-1) == 0
-}
-
 func log2u(n int) uint8 {
 	var r uint8
 	for n > 1 {
@@ -4,6 +4,7 @@ package sprite
 
 import (
 	"image"
+	"image/color"
 	"image/draw"
 
 	"github.com/clktmr/n64/rcp/texture"
@@ -11,18 +12,46 @@ import (
 )
 
 // RenderSprite draws a sprite using software rendering on the host.
-// Flip, scale, and rotation options are ignored; the image is drawn at the
-// given position. Rotation is not implemented on the host fallback path.
+// Flips and scales are applied with nearest-neighbour sampling, and blend
+// modes, tints and flashes compute what the RDP setup does. Rotation is not
+// implemented on the host fallback path; rotated sprites are drawn
+// unrotated with their origin at the given position.
 func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
-	_ bool, _ bool, _ float32, _ float32, blendMode uint8, _ float32,
-	_ float32, _ float32, _ float32) {
+	flipH, flipV bool, scaleX, scaleY float32, blendMode uint8, alpha float32,
+	rotation, originX, originY float32) {
 
-	if fb == nil || src == nil {
+	r := renderer{fb: fb}
+	r.draw(Draw{
+		Src: src, X: x, Y: y,
+		FlipH: flipH, FlipV: flipV,
+		ScaleX: scaleX, ScaleY: scaleY,
+		Blend: blendMode, Alpha: alpha,
+		Rotation: rotation, OriginX: originX, OriginY: originY,
+	})
+}
+
+// renderer counts the texture loads the RDP path would make, so batches
+// report the same uploads on the host.
+type renderer struct {
+	fb      *texture.Texture
+	last    Draw
+	loaded  bool
+	uploads int
+}
+
+func (r *renderer) draw(d Draw) {
+	if r.fb == nil || d.Src == nil {
 		return
 	}
 
-	srcBounds := src.Bounds()
-	logicalDst := image.Rect(x, y, x+srcBounds.Dx(), y+srcBounds.Dy())
+	x, y := d.X, d.Y
+	if d.Rotation != 0 {
+		sx, sy := d.scales()
+		x -= int(d.OriginX * sx)
+		y -= int(d.OriginY * sy)
+	}
+	w, h := d.destSize()
+	logicalDst := image.Rect(x, y, x+w, y+h)
 	clipped := logicalDst.Intersect(rendergeom.LogicalBounds())
 	if clipped.Empty() {
 		return
@@ -36,20 +65,110 @@ func RenderSprite(fb *texture.Texture, src image.Image, x, y int,
 		return
 	}
 
+	if !r.loaded || !d.sameTexture(r.last) {
+		r.uploads++
+		r.loaded = true
+	}
+	r.last = d
+
+	src := transformed(d)
+	srcBounds := src.Bounds()
 	srcPt := image.Pt(
 		srcBounds.Min.X+(clipped.Min.X-logicalDst.Min.X),
 		srcBounds.Min.Y+(clipped.Min.Y-logicalDst.Min.Y),
 	)
 
-	op := draw.Src
-	if blendMode >= 1 {
-		op = draw.Over
+	dstRect := image.Rect(framebufferRect.Min.X, framebufferRect.Min.Y,
+		framebufferRect.Max.X+1, framebufferRect.Max.Y+1)
+	switch d.Blend {
+	case blendAdd, blendMultiply:
+		blendPixels(r.fb, dstRect, src, srcPt, d.Blend, d.Alpha)
+	case blendAlpha:
+		a := uint8(min(max(d.Alpha, 0), 1) * 255)
+		draw.DrawMask(r.fb, dstRect, src, srcPt, image.NewUniform(color.Alpha{A: a}), image.Point{}, draw.Over)
+	case blendMasked:
+		draw.Over.Draw(r.fb, dstRect, src, srcPt)
+	default:
+		draw.Src.Draw(r.fb, dstRect, src, srcPt)
 	}
-	op.Draw(
-		fb,
-		image.Rect(framebufferRect.Min.X, framebufferRect.Min.Y,
-			framebufferRect.Max.X+1, framebufferRect.Max.Y+1),
-		src,
-		srcPt,
-	)
+}
+
+// blendPixels adds src into dst, or multiplies dst by src's alpha, with
+// src's alpha scaled by alpha, as the RDP blender does.
+func blendPixels(dst draw.Image, r image.Rectangle, src image.Image, sp image.Point, mode uint8, alpha float32) {
+	alpha = min(max(alpha, 0), 1)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			s := color.NRGBAModel.Convert(src.At(sp.X+x-r.Min.X, sp.Y+y-r.Min.Y)).(color.NRGBA)
+			c := color.NRGBAModel.Convert(dst.At(x, y)).(color.NRGBA)
+			a := float32(s.A) / 255 * alpha
+			if mode == blendAdd {
+				add := func(d, s uint8) uint8 { return uint8(min(float32(d)+float32(s)*a, 255)) }
+				c.R, c.G, c.B = add(c.R, s.R), add(c.G, s.G), add(c.B, s.B)
+			} else {
+				c.R, c.G, c.B = uint8(float32(c.R)*a), uint8(float32(c.G)*a), uint8(float32(c.B)*a)
+			}
+			dst.Set(x, y, c)
+		}
+	}
+}
+
+// shade applies a draw's flash or tint to a texel, as the combiner setup
+// of setupBlendMode does.
+func shade(c color.NRGBA, tint, flash color.RGBA) color.NRGBA {
+	switch {
+	case flash.A != 0:
+		mix := func(t, f uint8) uint8 {
+			return uint8((int(t)*(255-int(flash.A)) + int(f)*int(flash.A)) / 255)
+		}
+		c.R, c.G, c.B = mix(c.R, flash.R), mix(c.G, flash.G), mix(c.B, flash.B)
+	case tinted(tint):
+		c.R = uint8(int(c.R) * int(tint.R) / 255)
+		c.G = uint8(int(c.G) * int(tint.G) / 255)
+		c.B = uint8(int(c.B) * int(tint.B) / 255)
+	}
+	return c
+}
+
+// transformed returns the sprite's frame flipped, scaled, recolored and
+// shaded as drawn.
+func transformed(d Draw) image.Image {
+	sx, sy := d.scales()
+	pal := d.palette()
+	if src, ok := d.Src.(*texture.Texture); ok && pal == src.Palette() {
+		pal = nil
+	}
+	if !d.FlipH && !d.FlipV && sx == 1 && sy == 1 && !tinted(d.Tint) && d.Flash.A == 0 && pal == nil {
+		return d.Src
+	}
+	bounds := d.Src.Bounds()
+	w, h := d.destSize()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		v := min(int((float32(y)+0.5)/sy), bounds.Dy()-1)
+		if d.FlipV {
+			v = bounds.Dy() - 1 - v
+		}
+		for x := 0; x < w; x++ {
+			u := min(int((float32(x)+0.5)/sx), bounds.Dx()-1)
+			if d.FlipH {
+				u = bounds.Dx() - 1 - u
+			}
+			c := color.NRGBAModel.Convert(texel(d.Src, pal, bounds.Min.X+u, bounds.Min.Y+v)).(color.NRGBA)
+			dst.SetNRGBA(x, y, shade(c, d.Tint, d.Flash))
+		}
+	}
+	return dst
+}
+
+// texel returns src's color at (x, y), looking a palettized texture's
+// index up in pal when it is not nil.
+func texel(src image.Image, pal *texture.Texture, x, y int) color.Color {
+	tex, ok := src.(*texture.Texture)
+	if !ok || pal == nil {
+		return src.At(x, y)
+	}
+	b := tex.Bounds()
+	i := tex.Pix()[(y-b.Min.Y)*tex.Stride()+x-b.Min.X]
+	return pal.At(int(i), 0)
 }
@@ -0,0 +1,106 @@
+//go:build !n64
+
+package sprite
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rendergeom"
+)
+
+// testFrame returns a 3x2 frame whose pixel (x, y) has red 10*(1+x+3y).
+func testFrame() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(10 * (1 + x + 3*y)), A: 255})
+		}
+	}
+	return img
+}
+
+func TestRenderSpriteFlipsAndScalesOnHost(t *testing.T) {
+	type pt = [2]int
+	tests := []struct {
+		name         string
+		flipH, flipV bool
+		sx, sy       float32
+		w, h         int
+		// want maps screen pixels to the frame pixels drawn there.
+		want map[pt]pt
+	}{
+		{"flipH", true, false, 1, 1, 3, 2, map[pt]pt{{0, 0}: {2, 0}, {2, 1}: {0, 1}}},
+		{"flipV", false, true, 1, 1, 3, 2, map[pt]pt{{0, 0}: {0, 1}, {2, 1}: {2, 0}}},
+		{"flipHV", true, true, 1, 1, 3, 2, map[pt]pt{{0, 0}: {2, 1}, {2, 1}: {0, 0}}},
+		{"scale2 flipH", true, false, 2, 2, 6, 4, map[pt]pt{{0, 0}: {2, 0}, {1, 1}: {2, 0}, {2, 0}: {1, 0}, {5, 3}: {0, 1}}},
+		{"scale0.5", false, false, 0.5, 0.5, 2, 1, map[pt]pt{{0, 0}: {1, 1}, {1, 0}: {2, 1}}},
+		{"scale0.5 flipH", true, false, 0.5, 0.5, 2, 1, map[pt]pt{{0, 0}: {1, 1}, {1, 0}: {0, 1}}},
+		{"scale1.5", false, false, 1.5, 1.5, 5, 3, map[pt]pt{{1, 0}: {1, 0}, {2, 0}: {1, 0}, {3, 2}: {2, 1}, {4, 1}: {2, 1}, {0, 0}: {0, 0}}},
+		{"scale1.5 flipV", false, true, 1.5, 1, 5, 2, map[pt]pt{{0, 0}: {0, 1}, {4, 1}: {2, 0}}},
+	}
+
+	src := testFrame()
+	origin := rendergeom.Origin()
+	for _, tt := range tests {
+		fb := texture.NewRGBA32(rendergeom.FramebufferBounds())
+		RenderSprite(fb, src, 0, 0, tt.flipH, tt.flipV, tt.sx, tt.sy, 0, 1, 0, 0, 0)
+
+		for screen, frame := range tt.want {
+			got := color.NRGBAModel.Convert(fb.At(origin.X+screen[0], origin.Y+screen[1])).(color.NRGBA)
+			if want := src.NRGBAAt(frame[0], frame[1]); got != want {
+				t.Fatalf("%s: pixel %v = %v, want frame pixel %v (%v)", tt.name, screen, got, frame, want)
+			}
+		}
+		inside := color.NRGBAModel.Convert(fb.At(origin.X+tt.w-1, origin.Y+tt.h-1)).(color.NRGBA)
+		outside := color.NRGBAModel.Convert(fb.At(origin.X+tt.w, origin.Y+tt.h-1)).(color.NRGBA)
+		below := color.NRGBAModel.Convert(fb.At(origin.X, origin.Y+tt.h)).(color.NRGBA)
+		if inside.A == 0 || outside.A != 0 || below.A != 0 {
+			t.Fatalf("%s: drawn size is not %dx%d", tt.name, tt.w, tt.h)
+		}
+	}
+}
+
+func TestRenderShadesAndBlendsOnHost(t *testing.T) {
+	frame := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	frame.SetNRGBA(0, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	half := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	half.SetNRGBA(0, 0, color.NRGBA{R: 100, G: 100, B: 100, A: 128})
+	under := color.NRGBA{R: 100, G: 200, B: 40, A: 255}
+
+	tests := []struct {
+		name string
+		d    Draw
+		want color.NRGBA
+	}{
+		{"tint", Draw{Src: frame, Tint: color.RGBA{R: 255, G: 0, B: 51, A: 255}}, color.NRGBA{R: 200, G: 0, B: 10, A: 255}},
+		{"white tint", Draw{Src: frame, Tint: color.RGBA{R: 255, G: 255, B: 255}}, color.NRGBA{R: 200, G: 100, B: 50, A: 255}},
+		{"flash", Draw{Src: frame, Tint: color.RGBA{A: 255}, Flash: color.RGBA{R: 255, G: 255, B: 255, A: 255}}, color.NRGBA{R: 255, G: 255, B: 255, A: 255}},
+		{"half flash", Draw{Src: frame, Flash: color.RGBA{R: 0, G: 0, B: 0, A: 51}}, color.NRGBA{R: 160, G: 80, B: 40, A: 255}},
+		{"add", Draw{Src: frame, Blend: blendAdd, Alpha: 1}, color.NRGBA{R: 255, G: 255, B: 90, A: 255}},
+		{"add half alpha", Draw{Src: frame, Blend: blendAdd, Alpha: 0.5}, color.NRGBA{R: 200, G: 250, B: 65, A: 255}},
+		{"multiply", Draw{Src: half, Blend: blendMultiply, Alpha: 1}, color.NRGBA{R: 50, G: 100, B: 20, A: 255}},
+		{"flash keeps alpha", Draw{Src: half, Blend: blendAlpha, Alpha: 1, Flash: color.RGBA{R: 255, A: 255}}, color.NRGBA{R: 177, G: 100, B: 20, A: 255}},
+	}
+
+	origin := rendergeom.Origin()
+	for _, tt := range tests {
+		fb := texture.NewRGBA32(rendergeom.FramebufferBounds())
+		fb.Set(origin.X, origin.Y, under)
+		Render(fb, []Draw{tt.d})
+
+		got := color.NRGBAModel.Convert(fb.At(origin.X, origin.Y)).(color.NRGBA)
+		if diff(got.R, tt.want.R) > 1 || diff(got.G, tt.want.G) > 1 || diff(got.B, tt.want.B) > 1 {
+			t.Fatalf("%s: pixel = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func diff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
@@ -0,0 +1,183 @@
+// Package autotile picks tiles for terrain cells from their neighbours,
+// using the edge, blob and corner rule sets stored with a sheet.
+package autotile
+
+import "github.com/drpaneas/gosprite64/internal/tile2d/format"
+
+// Neighbour bits, clockwise from north. Edge16 masks use N, E, S and W as
+// bits 0 to 3; blob masks use all eight.
+const (
+	N  = 1 << 0
+	NE = 1 << 1
+	E  = 1 << 2
+	SE = 1 << 3
+	S  = 1 << 4
+	SW = 1 << 5
+	W  = 1 << 6
+	NW = 1 << 7
+)
+
+// Corner bits of corner16 masks.
+const (
+	CornerNW = 1 << 0
+	CornerNE = 1 << 1
+	CornerSE = 1 << 2
+	CornerSW = 1 << 3
+)
+
+// BlobMasks lists the 47 blob masks in tile order: every eight-neighbour
+// mask whose corners only count beside two matching edges, ascending.
+var BlobMasks = blobMasks()
+
+var blobIndex = func() [256]int {
+	var index [256]int
+	for i := range index {
+		index[i] = -1
+	}
+	for i, mask := range BlobMasks {
+		index[mask] = i
+	}
+	return index
+}()
+
+func blobMasks() []uint8 {
+	var masks []uint8
+	for m := 0; m < 256; m++ {
+		if uint8(m) == reduceBlob(uint8(m)) {
+			masks = append(masks, uint8(m))
+		}
+	}
+	return masks
+}
+
+// reduceBlob drops corner bits not next to two set edges.
+func reduceBlob(m uint8) uint8 {
+	corners := [4][3]uint8{{NE, N, E}, {SE, S, E}, {SW, S, W}, {NW, N, W}}
+	for _, c := range corners {
+		if m&c[1] == 0 || m&c[2] == 0 {
+			m &^= c[0]
+		}
+	}
+	return m
+}
+
+// Terrain returns the terrain of cell (x, y), and false outside the map.
+type Terrain func(x, y int) (uint16, bool)
+
+// Set is the rule sets of one sheet, by terrain.
+type Set struct {
+	rules  map[uint16]format.ParsedAutotile
+	byTile map[uint16]uint16
+}
+
+// New builds a set from a sheet's rules. Tiles listed by several terrains
+// belong to the first.
+func New(rules []format.ParsedAutotile) *Set {
+	s := &Set{
+		rules:  make(map[uint16]format.ParsedAutotile, len(rules)),
+		byTile: make(map[uint16]uint16),
+	}
+	for _, rule := range rules {
+		s.rules[rule.Terrain] = rule
+		for _, tile := range rule.Tiles {
+			if _, ok := s.byTile[tile]; !ok {
+				s.byTile[tile] = rule.Terrain
+			}
+		}
+	}
+	return s
+}
+
+// TerrainOf returns the terrain whose rules use tile, or 0.
+func (s *Set) TerrainOf(tile uint16) uint16 {
+	if s == nil {
+		return 0
+	}
+	return s.byTile[tile]
+}
+
+// Full returns the tile of a terrain cell surrounded by the same terrain.
+func (s *Set) Full(terrain uint16) (uint16, bool) {
+	rule, ok := s.rule(terrain)
+	if !ok {
+		return 0, false
+	}
+	return rule.Tiles[len(rule.Tiles)-1], true
+}
+
+// Tile returns the tile for cell (x, y) of terrain t. It reports false
+// when the cell has no terrain or its terrain has no rules.
+func (s *Set) Tile(t Terrain, x, y int) (uint16, bool) {
+	terrain, _ := t(x, y)
+	rule, ok := s.rule(terrain)
+	if !ok {
+		return 0, false
+	}
+	match := func(dx, dy int) bool {
+		other, in := t(x+dx, y+dy)
+		if !in {
+			return rule.Outside
+		}
+		return other == terrain
+	}
+
+	switch rule.Mode {
+	case format.AutotileBlob47:
+		var m uint8
+		offsets := [8][2]int{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+		for bit, o := range offsets {
+			if match(o[0], o[1]) {
+				m |= 1 << bit
+			}
+		}
+		return rule.Tiles[blobIndex[reduceBlob(m)]], true
+	case format.AutotileCorner16:
+		m := 0
+		corners := [4][2]int{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+		for bit, c := range corners {
+			if match(c[0], 0) && match(0, c[1]) && match(c[0], c[1]) {
+				m |= 1 << bit
+			}
+		}
+		return rule.Tiles[m], true
+	default:
+		m := 0
+		edges := [4][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+		for bit, e := range edges {
+			if match(e[0], e[1]) {
+				m |= 1 << bit
+			}
+		}
+		return rule.Tiles[m], true
+	}
+}
+
+func (s *Set) rule(terrain uint16) (format.ParsedAutotile, bool) {
+	if s == nil || terrain == 0 {
+		return format.ParsedAutotile{}, false
+	}
+	rule, ok := s.rules[terrain]
+	return rule, ok
+}
+
+// Fill sets each cell of a width-wide grid whose terrain has rules to its
+// tile, leaving other cells alone.
+func (s *Set) Fill(terrain, cells []uint16, width int) {
+	if width <= 0 {
+		return
+	}
+	height := len(terrain) / width
+	t := func(x, y int) (uint16, bool) {
+		if x < 0 || y < 0 || x >= width || y >= height {
+			return 0, false
+		}
+		return terrain[y*width+x], true
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if tile, ok := s.Tile(t, x, y); ok {
+				cells[y*width+x] = tile
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package autotile
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// seq returns tiles first, first+1, ... so a tile shows its mask index.
+func seq(first uint16, n int) []uint16 {
+	tiles := make([]uint16, n)
+	for i := range tiles {
+		tiles[i] = first + uint16(i)
+	}
+	return tiles
+}
+
+func TestBlobMasksAreTheFortySevenReducedMasks(t *testing.T) {
+	if len(BlobMasks) != 47 {
+		t.Fatalf("len(BlobMasks) = %d, want 47", len(BlobMasks))
+	}
+	if BlobMasks[0] != 0 || BlobMasks[46] != 255 || BlobMasks[3] != N|E {
+		t.Fatalf("BlobMasks = %v", BlobMasks)
+	}
+	// NE without N and E is dropped.
+	if got := reduceBlob(NE | S); got != S {
+		t.Fatalf("reduceBlob(NE|S) = %d, want S", got)
+	}
+}
+
+func TestFillPicksTilesByNeighbours(t *testing.T) {
+	set := New([]format.ParsedAutotile{
+		{Terrain: 1, Mode: format.AutotileEdge16, Tiles: seq(100, 16)},
+		{Terrain: 2, Mode: format.AutotileBlob47, Tiles: seq(200, 47)},
+		{Terrain: 3, Mode: format.AutotileCorner16, Tiles: seq(300, 16), Outside: true},
+	})
+
+	// A 3x1 strip of terrain 1: W end, middle, E end.
+	terrain := []uint16{1, 1, 1}
+	cells := make([]uint16, 3)
+	set.Fill(terrain, cells, 3)
+	// Edge16 masks are N=1, E=2, S=4, W=8.
+	if cells[0] != 102 || cells[1] != 110 || cells[2] != 108 {
+		t.Fatalf("edge16 strip = %v, want [102 110 108]", cells)
+	}
+
+	// A 3x3 block of terrain 2: the centre is full, a corner sees only its
+	// two edges and the diagonal between them.
+	terrain = []uint16{2, 2, 2, 2, 2, 2, 2, 2, 2}
+	cells = make([]uint16, 9)
+	set.Fill(terrain, cells, 3)
+	if cells[4] != 200+46 {
+		t.Fatalf("blob centre = %d, want %d", cells[4], 200+46)
+	}
+	if want := 200 + uint16(blobIndex[E|SE|S]); cells[0] != want {
+		t.Fatalf("blob top-left = %d, want %d", cells[0], want)
+	}
+
+	// Terrain 3 counts the outside as terrain, so only the corner next to
+	// the hole at (1,1) is open for cell (0,0).
+	terrain = []uint16{3, 3, 3, 3, 0, 3, 3, 3, 3}
+	cells = []uint16{0, 0, 0, 0, 9, 0, 0, 0, 0}
+	set.Fill(terrain, cells, 3)
+	if cells[0] != 300+(CornerNW|CornerNE|CornerSW) {
+		t.Fatalf("corner16 top-left = %d, want %d", cells[0], 300+(CornerNW|CornerNE|CornerSW))
+	}
+	if cells[4] != 9 {
+		t.Fatalf("cell without terrain changed to %d", cells[4])
+	}
+
+	if set.TerrainOf(205) != 2 || set.TerrainOf(99) != 0 {
+		t.Fatalf("TerrainOf() = %d, %d", set.TerrainOf(205), set.TerrainOf(99))
+	}
+	if full, ok := set.Full(1); !ok || full != 115 {
+		t.Fatalf("Full(1) = %d, %v", full, ok)
+	}
+}
@@ -0,0 +1,122 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Autotile modes, by how a cell's neighbours pick its tile.
+const (
+	// AutotileEdge16 looks at the four edge neighbours: 16 tiles.
+	AutotileEdge16 uint8 = iota
+	// AutotileBlob47 also looks at corner neighbours next to two matching
+	// edges: 47 tiles.
+	AutotileBlob47
+	// AutotileCorner16 picks the tile by which of the cell's four corners
+	// are surrounded by the terrain, as a corner Wang set does: 16 tiles.
+	AutotileCorner16
+)
+
+// AutotileModeNames holds the JSON names of the autotile modes, by mode.
+var AutotileModeNames = []string{"edge16", "blob47", "corner16"}
+
+// AutotileTileCounts holds the number of tiles each mode needs, by mode.
+var AutotileTileCounts = []int{16, 47, 16}
+
+// AutotileConfig is the rule set of one terrain. Terrain is the non-zero ID
+// used in terrain grids, Tiles lists the mode's tiles in mask order, and
+// Outside makes cells beyond the map count as the same terrain.
+type AutotileConfig struct {
+	Terrain uint16   `json:"terrain"`
+	Mode    string   `json:"mode"`
+	Tiles   []uint16 `json:"tiles"`
+	Outside bool     `json:"outside,omitempty"`
+}
+
+type ParsedAutotile struct {
+	Terrain uint16
+	Mode    uint8
+	Tiles   []uint16
+	Outside bool
+}
+
+// AutotileRules validates cfgs and converts them to parsed rules. A zero
+// tileCount skips the check that tiles exist in the sheet.
+func AutotileRules(cfgs []AutotileConfig, tileCount int) ([]ParsedAutotile, error) {
+	rules := make([]ParsedAutotile, 0, len(cfgs))
+	seen := make(map[uint16]bool, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Terrain == 0 {
+			return nil, fmt.Errorf("format: autotile terrain must be non-zero")
+		}
+		if seen[cfg.Terrain] {
+			return nil, fmt.Errorf("format: duplicate autotile terrain %d", cfg.Terrain)
+		}
+		seen[cfg.Terrain] = true
+
+		mode := -1
+		for i, name := range AutotileModeNames {
+			if cfg.Mode == name {
+				mode = i
+			}
+		}
+		if mode < 0 {
+			return nil, fmt.Errorf("format: terrain %d has unknown autotile mode %q", cfg.Terrain, cfg.Mode)
+		}
+		if want := AutotileTileCounts[mode]; len(cfg.Tiles) != want {
+			return nil, fmt.Errorf("format: terrain %d %s rules list %d tiles, want %d", cfg.Terrain, cfg.Mode, len(cfg.Tiles), want)
+		}
+		for _, tile := range cfg.Tiles {
+			if tile == 0 || tileCount > 0 && int(tile) > tileCount {
+				return nil, fmt.Errorf("format: terrain %d autotile tile %d outside 1..%d", cfg.Terrain, tile, tileCount)
+			}
+		}
+		rules = append(rules, ParsedAutotile{
+			Terrain: cfg.Terrain,
+			Mode:    uint8(mode),
+			Tiles:   append([]uint16(nil), cfg.Tiles...),
+			Outside: cfg.Outside,
+		})
+	}
+	return rules, nil
+}
+
+func buildAutotile(cfgs []AutotileConfig, tileCount int) ([]byte, error) {
+	rules, err := AutotileRules(cfgs, tileCount)
+	if err != nil {
+		return nil, err
+	}
+	data := binary.LittleEndian.AppendUint16(nil, uint16(len(rules)))
+	for _, rule := range rules {
+		var outside uint8
+		if rule.Outside {
+			outside = 1
+		}
+		data = binary.LittleEndian.AppendUint16(data, rule.Terrain)
+		data = append(data, rule.Mode, outside)
+		for _, tile := range rule.Tiles {
+			data = binary.LittleEndian.AppendUint16(data, tile)
+		}
+	}
+	return data, nil
+}
+
+func parseAutotile(data []byte) ([]ParsedAutotile, error) {
+	r := sectionReader{data: data}
+	count := int(r.u16())
+	rules := make([]ParsedAutotile, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		rule := ParsedAutotile{Terrain: r.u16(), Mode: r.u8(), Outside: r.u8() != 0}
+		if int(rule.Mode) >= len(AutotileTileCounts) {
+			return nil, fmt.Errorf("format: terrain %d has unknown autotile mode %d", rule.Terrain, rule.Mode)
+		}
+		for j := 0; j < AutotileTileCounts[rule.Mode] && r.err == nil; j++ {
+			rule.Tiles = append(rule.Tiles, r.u16())
+		}
+		rules = append(rules, rule)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("format: autotile section truncated")
+	}
+	return rules, nil
+}
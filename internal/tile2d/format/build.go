@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"math"
 )
 
 type MapConfig struct {
@@ -16,11 +17,43 @@ type MapConfig struct {
 	ChunkWidth  uint16 `json:"chunk_width"`
 	ChunkHeight uint16 `json:"chunk_height"`
 	Layers      []MapLayerConfig `json:"layers"`
+
+	// ObjectLayers holds named points, rects and polylines, such as spawn
+	// points and trigger regions.
+	ObjectLayers []MapObjectLayerConfig `json:"object_layers,omitempty"`
+
+	// Links points at neighbouring maps.
+	Links []MapLinkConfig `json:"links,omitempty"`
+
+	// Streamed stores the cells chunk by chunk, so a scene can load only
+	// the chunks near the camera.
+	Streamed bool `json:"streamed,omitempty"`
 }
 
 type MapLayerConfig struct {
 	SheetID uint16   `json:"sheet_id"`
 	Cells   []uint16 `json:"cells"`
+
+	// ScrollX and ScrollY are parallax factors; nil means 1 (scroll with
+	// the camera). RepeatX and RepeatY tile the layer endlessly.
+	ScrollX *float32 `json:"scroll_x,omitempty"`
+	ScrollY *float32 `json:"scroll_y,omitempty"`
+	RepeatX bool     `json:"repeat_x,omitempty"`
+	RepeatY bool     `json:"repeat_y,omitempty"`
+
+	// Flips holds CellFlip bits per cell, parallel to Cells. Any set bit
+	// stores the map's cells with flip bits, which needs 16-bit cells and
+	// tile IDs up to CellIDMask.
+	Flips []uint8 `json:"flips,omitempty"`
+
+	// Name lets games find the layer with Map.LayerByName. Hidden layers,
+	// such as collision layers, load hidden but still collide.
+	Name   string `json:"name,omitempty"`
+	Hidden bool   `json:"hidden,omitempty"`
+
+	// Terrain holds a terrain ID per cell, parallel to Cells. mk2dmap
+	// -rules turns it into tiles; BuildMap does not store it.
+	Terrain []uint16 `json:"terrain,omitempty"`
 }
 
 type AnimConfig struct {
@@ -34,6 +67,22 @@ type AnimClipConfig struct {
 }
 
 func BuildSheet(img image.Image, tileWidth, tileHeight int) ([]byte, error) {
+	return BuildSheetWithProps(img, tileWidth, tileHeight, TilePropsConfig{})
+}
+
+// BuildSheetWithProps builds a sheet and stores props in its TPRP section.
+// An empty props config leaves the section out.
+func BuildSheetWithProps(img image.Image, tileWidth, tileHeight int, props TilePropsConfig) ([]byte, error) {
+	return BuildSheetWithEncoding(img, tileWidth, tileHeight, props, SheetRGBA32)
+}
+
+// BuildSheetWithEncoding builds a sheet like BuildSheetWithProps with its
+// pixels stored as enc. The palettized encodings store the palette in a
+// TLUT section and fail if the image needs more colors than enc indexes,
+// or has partly transparent pixels. The other encodings round each pixel
+// to the nearest color they hold; Dither the image first to spread the
+// error. I4 and IA4 need an even tile width.
+func BuildSheetWithEncoding(img image.Image, tileWidth, tileHeight int, props TilePropsConfig, enc SheetEncoding) ([]byte, error) {
 	if img == nil {
 		return nil, fmt.Errorf("format: nil image")
 	}
@@ -56,17 +105,63 @@ func BuildSheet(img image.Image, tileWidth, tileHeight int) ([]byte, error) {
 		return nil, fmt.Errorf("format: palette entries %d exceeds uint16", paletteEntries)
 	}
 
-	payload := make([]byte, sheetPayloadSize)
+	if int(enc) >= len(sheetEncodingNames) {
+		return nil, fmt.Errorf("format: unsupported sheet encoding %s", enc)
+	}
+	if enc.Bits() == 4 && !enc.Palettized() && tileWidth%2 != 0 {
+		return nil, fmt.Errorf("format: %s needs an even tile width, got %d", enc, tileWidth)
+	}
+	pixels, palette, err := encodePixels(img, enc)
+	if err != nil {
+		return nil, err
+	}
+	var sections []Section
+	if enc.Palettized() {
+		paletteEntries = len(palette)
+		sections = append(sections, Section{Tag: sectionTag("TLUT"), Data: buildTLUT(palette)})
+	}
+
+	// Sheets other than RGBA32 record their encoding after the fixed
+	// fields, which moves the pixel data back.
+	payloadSize := sheetPayloadSize
+	if enc != SheetRGBA32 {
+		payloadSize = encodedSheetPayloadSize
+	}
+	payload := make([]byte, payloadSize)
 	binary.LittleEndian.PutUint16(payload[0:2], uint16(tileWidth))
 	binary.LittleEndian.PutUint16(payload[2:4], uint16(tileHeight))
 	binary.LittleEndian.PutUint16(payload[4:6], uint16(tileCount))
 	binary.LittleEndian.PutUint16(payload[6:8], uint16(paletteEntries))
 	binary.LittleEndian.PutUint16(payload[8:10], uint16(bounds.Dx()))
 	binary.LittleEndian.PutUint16(payload[10:12], uint16(bounds.Dy()))
-	binary.LittleEndian.PutUint32(payload[12:16], headerSize+sheetPayloadSize)
+	binary.LittleEndian.PutUint32(payload[12:16], uint32(headerSize+payloadSize))
+	if enc != SheetRGBA32 {
+		payload[16] = uint8(enc)
+	}
+
+	if len(props.Flags) > 0 || len(props.Tiles) > 0 {
+		data, err := buildTileProps(props, tileCount)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, Section{Tag: sectionTag("TPRP"), Data: data})
+	}
+	if len(props.Anims) > 0 {
+		data, err := buildTileAnims(props.Anims, tileCount)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, Section{Tag: sectionTag("TANM"), Data: data})
+	}
+	if len(props.Autotile) > 0 {
+		data, err := buildAutotile(props.Autotile, tileCount)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, Section{Tag: sectionTag("ATIL"), Data: data})
+	}
 
-	nrgba := toNRGBA(img)
-	return encodeAsset("SHT2", append(payload, nrgba.Pix...)), nil
+	return encodeAssetWithSections("SHT2", append(payload, pixels...), sections), nil
 }
 
 func BuildMap(cfg MapConfig) ([]byte, error) {
@@ -95,16 +190,26 @@ func BuildMap(cfg MapConfig) ([]byte, error) {
 	binary.LittleEndian.PutUint16(payload[10:12], cfg.ChunkHeight)
 
 	cellCount := int(cfg.Width) * int(cfg.Height)
+	cellFlips, err := mapHasFlips(cfg, cellCount)
+	if err != nil {
+		return nil, err
+	}
+	if cellFlips {
+		payload[7] = mapCellFlips
+	}
 	var cellPayload bytes.Buffer
 	var layerPayload bytes.Buffer
+	packed := make([][]uint16, int(cfg.LayerCount))
 	for layerIdx := 0; layerIdx < int(cfg.LayerCount); layerIdx++ {
 		var cells []uint16
+		var flips []uint8
 		sheetID := uint16(1)
 		if layerIdx < len(cfg.Layers) {
 			if cfg.Layers[layerIdx].SheetID != 0 {
 				sheetID = cfg.Layers[layerIdx].SheetID
 			}
 			cells = cfg.Layers[layerIdx].Cells
+			flips = cfg.Layers[layerIdx].Flips
 		}
 		if err := binary.Write(&layerPayload, binary.LittleEndian, sheetID); err != nil {
 			return nil, err
@@ -115,7 +220,17 @@ func BuildMap(cfg MapConfig) ([]byte, error) {
 		if len(cells) != cellCount {
 			return nil, fmt.Errorf("format: layer %d has %d cells, want %d", layerIdx, len(cells), cellCount)
 		}
-		for _, cell := range cells {
+		packed[layerIdx] = make([]uint16, cellCount)
+		for i, cell := range cells {
+			if cellFlips {
+				if cell > CellIDMask {
+					return nil, fmt.Errorf("format: cell value %d exceeds %d in a map with flip bits", cell, CellIDMask)
+				}
+				if len(flips) > 0 {
+					cell = packCell(cell, flips[i])
+				}
+			}
+			packed[layerIdx][i] = cell
 			if cfg.CellBits == 8 {
 				if cell > 0xFF {
 					return nil, fmt.Errorf("format: cell value %d exceeds 8-bit storage", cell)
@@ -129,7 +244,101 @@ func BuildMap(cfg MapConfig) ([]byte, error) {
 		}
 	}
 
-	return encodeAsset("MAP2", append(append(payload, layerPayload.Bytes()...), cellPayload.Bytes()...)), nil
+	var sections []Section
+	if parallax, ok := buildMapParallax(cfg); ok {
+		sections = append(sections, Section{Tag: sectionTag("PRLX"), Data: parallax})
+	}
+	if len(cfg.ObjectLayers) > 0 {
+		objects, err := buildMapObjects(cfg.ObjectLayers)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, Section{Tag: sectionTag("OBJS"), Data: objects})
+	}
+	names, named, err := buildMapLayerNames(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if named {
+		sections = append(sections, Section{Tag: sectionTag("LNAM"), Data: names})
+	}
+	if len(cfg.Links) > 0 {
+		links, err := buildMapLinks(cfg.Links)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, Section{Tag: sectionTag("LINK"), Data: links})
+	}
+
+	if cfg.Streamed {
+		payload[7] |= mapChunked
+		sections = append(sections, Section{Tag: sectionTag("CHNK"), Data: buildMapChunks(cfg, packed)})
+		return encodeAssetWithSections("MAP2", append(payload, layerPayload.Bytes()...), sections), nil
+	}
+	return encodeAssetWithSections("MAP2", append(append(payload, layerPayload.Bytes()...), cellPayload.Bytes()...), sections), nil
+}
+
+// mapHasFlips reports whether any layer sets a flip bit, and checks that
+// the flip arrays fit the map.
+func mapHasFlips(cfg MapConfig, cellCount int) (bool, error) {
+	found := false
+	for i, layer := range cfg.Layers {
+		if len(layer.Flips) == 0 {
+			continue
+		}
+		if len(layer.Flips) != cellCount {
+			return false, fmt.Errorf("format: layer %d has %d flips, want %d", i, len(layer.Flips), cellCount)
+		}
+		for _, flip := range layer.Flips {
+			if flip&^(CellFlipH|CellFlipV|CellFlipD) != 0 {
+				return false, fmt.Errorf("format: layer %d has invalid flip bits %#x", i, flip)
+			}
+			if flip != 0 {
+				found = true
+			}
+		}
+	}
+	if found && cfg.CellBits != 16 {
+		return false, fmt.Errorf("format: cell flip bits need 16-bit cells")
+	}
+	return found, nil
+}
+
+// buildMapParallax encodes the PRLX section, or reports false when every
+// layer uses the defaults and the section can be left out.
+func buildMapParallax(cfg MapConfig) ([]byte, bool) {
+	data := make([]byte, int(cfg.LayerCount)*mapParallaxEntrySize)
+	custom := false
+	for i := 0; i < int(cfg.LayerCount); i++ {
+		scrollX, scrollY := float32(1), float32(1)
+		var flags uint8
+		if i < len(cfg.Layers) {
+			layer := cfg.Layers[i]
+			if layer.ScrollX != nil {
+				scrollX = *layer.ScrollX
+			}
+			if layer.ScrollY != nil {
+				scrollY = *layer.ScrollY
+			}
+			if layer.RepeatX {
+				flags |= mapRepeatX
+			}
+			if layer.RepeatY {
+				flags |= mapRepeatY
+			}
+			if layer.Hidden {
+				flags |= mapLayerHidden
+			}
+		}
+		if scrollX != 1 || scrollY != 1 || flags != 0 {
+			custom = true
+		}
+		entry := data[i*mapParallaxEntrySize:]
+		binary.LittleEndian.PutUint32(entry[0:4], math.Float32bits(scrollX))
+		binary.LittleEndian.PutUint32(entry[4:8], math.Float32bits(scrollY))
+		entry[8] = flags
+	}
+	return data, custom
 }
 
 func BuildAnim(cfg AnimConfig) ([]byte, error) {
@@ -0,0 +1,139 @@
+package format
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// DitherMode selects how Dither spreads the error of a lossy encoding.
+type DitherMode uint8
+
+const (
+	DitherNone DitherMode = iota
+	// DitherOrdered adds a 4x4 Bayer pattern before rounding.
+	DitherOrdered
+	// DitherFloydSteinberg pushes each pixel's rounding error onto the
+	// pixels right of and below it.
+	DitherFloydSteinberg
+)
+
+var ditherModeNames = [...]string{
+	DitherNone:           "none",
+	DitherOrdered:        "ordered",
+	DitherFloydSteinberg: "floyd-steinberg",
+}
+
+func (m DitherMode) String() string {
+	if int(m) < len(ditherModeNames) {
+		return ditherModeNames[m]
+	}
+	return fmt.Sprintf("DitherMode(%d)", m)
+}
+
+// ParseDitherMode returns the mode called name, as printed by
+// DitherMode.String.
+func ParseDitherMode(name string) (DitherMode, error) {
+	for i, n := range ditherModeNames {
+		if strings.EqualFold(name, n) {
+			return DitherMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("format: unknown dither mode %q", name)
+}
+
+var bayer4 = [4][4]float32{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// Dither returns img with every pixel already stored as enc would store
+// it, with the rounding error spread by mode. Each tileWidth x tileHeight
+// tile is dithered on its own, so identical tiles stay identical. RGBA32
+// and the palettized encodings do not round, and img is returned as is.
+func Dither(img image.Image, tileWidth, tileHeight int, enc SheetEncoding, mode DitherMode) image.Image {
+	if mode == DitherNone || enc == SheetRGBA32 || enc.Palettized() || tileWidth <= 0 || tileHeight <= 0 {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for ty := 0; ty < b.Dy(); ty += tileHeight {
+		for tx := 0; tx < b.Dx(); tx += tileWidth {
+			tile := image.Rect(tx, ty, min(tx+tileWidth, b.Dx()), min(ty+tileHeight, b.Dy()))
+			ditherTile(dst, img, b.Min, tile, enc, mode)
+		}
+	}
+	return dst
+}
+
+// ditherTile dithers the tile rectangle of dst from img, which starts at
+// origin.
+func ditherTile(dst *image.NRGBA, img image.Image, origin image.Point, tile image.Rectangle, enc SheetEncoding, mode DitherMode) {
+	step := enc.steps()
+	// errs holds the error pushed onto the current and the next row.
+	w := tile.Dx()
+	errs := [2][][4]float32{make([][4]float32, w+2), make([][4]float32, w+2)}
+	for y := tile.Min.Y; y < tile.Max.Y; y++ {
+		cur, next := errs[0], errs[1]
+		clear(next)
+		for x := tile.Min.X; x < tile.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(origin.X+x, origin.Y+y)).(color.NRGBA)
+			i := x - tile.Min.X + 1
+			want := [4]float32{float32(c.R), float32(c.G), float32(c.B), float32(c.A)}
+			for ch := range want {
+				if mode == DitherOrdered {
+					bias := (bayer4[(y-tile.Min.Y)%4][(x-tile.Min.X)%4]+0.5)/16 - 0.5
+					want[ch] += bias * step[ch]
+				} else {
+					want[ch] += cur[i][ch]
+				}
+			}
+			got := enc.decodeTexel(enc.encodeTexel(color.NRGBA{
+				R: clamp8(want[0]), G: clamp8(want[1]), B: clamp8(want[2]), A: clamp8(want[3]),
+			}))
+			dst.SetNRGBA(x, y, got)
+			if mode != DitherFloydSteinberg {
+				continue
+			}
+			have := [4]float32{float32(got.R), float32(got.G), float32(got.B), float32(got.A)}
+			for ch := range want {
+				e := want[ch] - have[ch]
+				cur[i+1][ch] += e * 7 / 16
+				next[i-1][ch] += e * 3 / 16
+				next[i][ch] += e * 5 / 16
+				next[i+1][ch] += e * 1 / 16
+			}
+		}
+		errs[0], errs[1] = next, cur
+	}
+}
+
+// steps returns the distance between two levels of the R, G, B and A
+// channels of e. Intensity encodings round R, G and B together.
+func (e SheetEncoding) steps() [4]float32 {
+	level := func(bits int) float32 { return 255 / float32(int(1)<<bits-1) }
+	switch e {
+	case SheetRGBA16:
+		return [4]float32{level(5), level(5), level(5), level(1)}
+	case SheetIA8:
+		return [4]float32{level(4), level(4), level(4), level(4)}
+	case SheetIA4:
+		return [4]float32{level(3), level(3), level(3), level(1)}
+	case SheetI4:
+		return [4]float32{level(4), level(4), level(4), 0}
+	}
+	return [4]float32{1, 1, 1, 0}
+}
+
+func clamp8(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
@@ -0,0 +1,410 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// SheetEncoding is how a sheet stores its atlas pixels. Sizes below 8 bits
+// pack two pixels per byte, high nibble first, and every row is padded to
+// a whole byte. 16-bit texels are big-endian, as the RDP reads them.
+type SheetEncoding uint8
+
+const (
+	// SheetRGBA32 stores 32-bit NRGBA pixels.
+	SheetRGBA32 SheetEncoding = iota
+	// SheetCI8 stores one 8-bit palette index per pixel.
+	SheetCI8
+	// SheetCI4 stores 4-bit palette indices.
+	SheetCI4
+	// SheetRGBA16 stores 5 bits per color channel and a 1-bit alpha.
+	SheetRGBA16
+	// SheetIA8 stores a 4-bit intensity and a 4-bit alpha.
+	SheetIA8
+	// SheetIA4 stores a 3-bit intensity and a 1-bit alpha.
+	SheetIA4
+	// SheetI8 stores an 8-bit intensity; pixels are opaque.
+	SheetI8
+	// SheetI4 stores a 4-bit intensity; pixels are opaque.
+	SheetI4
+)
+
+var sheetEncodingNames = [...]string{
+	SheetRGBA32: "rgba32",
+	SheetCI8:    "ci8",
+	SheetCI4:    "ci4",
+	SheetRGBA16: "rgba16",
+	SheetIA8:    "ia8",
+	SheetIA4:    "ia4",
+	SheetI8:     "i8",
+	SheetI4:     "i4",
+}
+
+// autoSheetEncodings lists the encodings AutoSheetEncoding tries, smallest
+// first.
+var autoSheetEncodings = []SheetEncoding{
+	SheetCI4, SheetI4, SheetIA4,
+	SheetCI8, SheetI8, SheetIA8,
+	SheetRGBA16,
+}
+
+func (e SheetEncoding) String() string {
+	if int(e) < len(sheetEncodingNames) {
+		return sheetEncodingNames[e]
+	}
+	return fmt.Sprintf("SheetEncoding(%d)", e)
+}
+
+// ParseSheetEncoding returns the encoding called name, as printed by
+// SheetEncoding.String.
+func ParseSheetEncoding(name string) (SheetEncoding, error) {
+	for i, n := range sheetEncodingNames {
+		if strings.EqualFold(name, n) {
+			return SheetEncoding(i), nil
+		}
+	}
+	return 0, fmt.Errorf("format: unknown sheet encoding %q", name)
+}
+
+// Palettized reports whether e stores palette indices.
+func (e SheetEncoding) Palettized() bool {
+	return e == SheetCI8 || e == SheetCI4
+}
+
+// Bits returns the size of one pixel stored as e.
+func (e SheetEncoding) Bits() int {
+	switch e {
+	case SheetCI4, SheetIA4, SheetI4:
+		return 4
+	case SheetCI8, SheetIA8, SheetI8:
+		return 8
+	case SheetRGBA16:
+		return 16
+	}
+	return 32
+}
+
+// paletteSize returns how many colors an index of e can address.
+func (e SheetEncoding) paletteSize() int {
+	if e == SheetCI4 {
+		return 16
+	}
+	return 256
+}
+
+// rowBytes returns the size of a width-pixel row stored as e.
+func (e SheetEncoding) rowBytes(width int) int {
+	return (width*e.Bits() + 7) / 8
+}
+
+// pixelBytes returns the size of a width x height atlas stored as e.
+func (e SheetEncoding) pixelBytes(width, height int) int {
+	return e.rowBytes(width) * height
+}
+
+// AutoSheetEncoding returns the smallest encoding that stores img without
+// changing a pixel, or RGBA32. Palettes hold only fully opaque and fully
+// transparent colors that RGBA16 stores exactly, since the TLUT is RGBA16
+// at runtime, and I4 and IA4 need an even tileWidth so that every tile
+// starts on a byte.
+func AutoSheetEncoding(img image.Image, tileWidth int) SheetEncoding {
+	for _, enc := range autoSheetEncodings {
+		if enc.Bits() == 4 && !enc.Palettized() && tileWidth%2 != 0 {
+			continue
+		}
+		if enc.Palettized() {
+			if palette, _, err := paletteIndices(img, enc); err == nil && tlutLossless(palette) {
+				return enc
+			}
+			continue
+		}
+		if lossless(img, enc) {
+			return enc
+		}
+	}
+	return SheetRGBA32
+}
+
+// lossless reports whether every pixel of img survives being stored as enc.
+func lossless(img image.Image, enc SheetEncoding) bool {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := visible(img.At(x, y))
+			if visible(enc.decodeTexel(enc.encodeTexel(c))) != c {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tlutLossless reports whether every entry of palette survives being
+// loaded into the RGBA16 TLUT.
+func tlutLossless(palette []color.NRGBA) bool {
+	for _, c := range palette {
+		if visible(SheetRGBA16.decodeTexel(SheetRGBA16.encodeTexel(c))) != c {
+			return false
+		}
+	}
+	return true
+}
+
+// visible returns c as NRGBA with fully transparent colors folded into
+// transparent black, since their RGB is never seen.
+func visible(c color.Color) color.NRGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A == 0 {
+		return color.NRGBA{}
+	}
+	return n
+}
+
+// encodePixels stores img as enc, returning the palette for the
+// palettized encodings.
+func encodePixels(img image.Image, enc SheetEncoding) ([]byte, []color.NRGBA, error) {
+	switch {
+	case enc == SheetRGBA32:
+		return toNRGBA(img).Pix, nil, nil
+	case enc.Palettized():
+		palette, indices, err := paletteIndices(img, enc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return packTexels(indices, img.Bounds().Dx(), enc), palette, nil
+	}
+
+	b := img.Bounds()
+	texels := make([]uint16, 0, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			texels = append(texels, enc.encodeTexel(color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)))
+		}
+	}
+	return packTexels(texels, b.Dx(), enc), nil, nil
+}
+
+// packTexels stores one texel per pixel of a width-wide image as enc.
+func packTexels[T uint8 | uint16](texels []T, width int, enc SheetEncoding) []byte {
+	height := len(texels) / width
+	stride := enc.rowBytes(width)
+	packed := make([]byte, stride*height)
+	for y := 0; y < height; y++ {
+		row := packed[y*stride:]
+		for x := 0; x < width; x++ {
+			v := texels[y*width+x]
+			switch enc.Bits() {
+			case 4:
+				if x&1 == 0 {
+					row[x/2] |= byte(v&0x0F) << 4
+				} else {
+					row[x/2] |= byte(v & 0x0F)
+				}
+			case 8:
+				row[x] = byte(v)
+			case 16:
+				binary.BigEndian.PutUint16(row[x*2:], uint16(v))
+			}
+		}
+	}
+	return packed
+}
+
+// encodeTexel returns c as a texel of e, rounding each channel to the
+// nearest level. It is not used for RGBA32 and the palettized encodings.
+func (e SheetEncoding) encodeTexel(c color.NRGBA) uint16 {
+	switch e {
+	case SheetRGBA16:
+		return quantize(c.R, 5)<<11 | quantize(c.G, 5)<<6 | quantize(c.B, 5)<<1 | quantize(c.A, 1)
+	case SheetIA8:
+		return quantize(intensity(c), 4)<<4 | quantize(c.A, 4)
+	case SheetIA4:
+		return quantize(intensity(c), 3)<<1 | quantize(c.A, 1)
+	case SheetI8:
+		return uint16(intensity(c))
+	case SheetI4:
+		return quantize(intensity(c), 4)
+	}
+	return 0
+}
+
+// decodeTexel returns the color of a texel of e, expanding each channel as
+// the RDP does.
+func (e SheetEncoding) decodeTexel(v uint16) color.NRGBA {
+	switch e {
+	case SheetRGBA16:
+		return color.NRGBA{R: expand(v>>11, 5), G: expand(v>>6, 5), B: expand(v>>1, 5), A: expand(v, 1)}
+	case SheetIA8:
+		i := expand(v>>4, 4)
+		return color.NRGBA{R: i, G: i, B: i, A: expand(v, 4)}
+	case SheetIA4:
+		i := expand(v>>1, 3)
+		return color.NRGBA{R: i, G: i, B: i, A: expand(v, 1)}
+	case SheetI8:
+		return color.NRGBA{R: uint8(v), G: uint8(v), B: uint8(v), A: 0xFF}
+	case SheetI4:
+		i := expand(v, 4)
+		return color.NRGBA{R: i, G: i, B: i, A: 0xFF}
+	}
+	return color.NRGBA{}
+}
+
+// quantize rounds an 8-bit channel to bits bits.
+func quantize(v uint8, bits uint) uint16 {
+	levels := 1<<bits - 1
+	return uint16((int(v)*levels + 127) / 255)
+}
+
+// expand widens the low bits bits of q to 8 bits.
+func expand(q uint16, bits uint) uint8 {
+	levels := 1<<bits - 1
+	return uint8((int(q)&levels*255 + levels/2) / levels)
+}
+
+// intensity returns the luma of c, as color.GrayModel computes it.
+func intensity(c color.NRGBA) uint8 {
+	return uint8((19595*uint32(c.R) + 38470*uint32(c.G) + 7471*uint32(c.B) + 1<<15) >> 16)
+}
+
+// paletteIndices returns img's palette and one index per pixel. A
+// paletted image keeps its palette order, so artists control which
+// entries a palette cycle moves; other images list their colors in order
+// of first appearance. Every fully transparent pixel shares one entry.
+func paletteIndices(img image.Image, enc SheetEncoding) ([]color.NRGBA, []uint8, error) {
+	bounds := img.Bounds()
+	indices := make([]uint8, 0, bounds.Dx()*bounds.Dy())
+	var palette []color.NRGBA
+
+	if p, ok := img.(*image.Paletted); ok && len(p.Palette) <= 256 {
+		used := 0
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i := p.ColorIndexAt(x, y)
+				used = max(used, int(i)+1)
+				indices = append(indices, i)
+			}
+		}
+		for _, c := range p.Palette[:used] {
+			palette = append(palette, visible(c))
+		}
+	} else {
+		seen := make(map[color.NRGBA]uint8)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				c := visible(img.At(x, y))
+				i, ok := seen[c]
+				if !ok {
+					if len(palette) == 256 {
+						return nil, nil, fmt.Errorf("format: image has more than 256 colors")
+					}
+					i = uint8(len(palette))
+					seen[c] = i
+					palette = append(palette, c)
+				}
+				indices = append(indices, i)
+			}
+		}
+	}
+
+	if len(palette) > enc.paletteSize() {
+		return nil, nil, fmt.Errorf("format: image needs %d palette entries, %s holds %d", len(palette), enc, enc.paletteSize())
+	}
+	for _, c := range palette {
+		if c.A != 0 && c.A != 0xFF {
+			return nil, nil, fmt.Errorf("format: palette color %v is partly transparent", c)
+		}
+	}
+	return palette, indices, nil
+}
+
+// texel returns the raw texel of pixel (x, y) of the atlas.
+func (s ParsedSheet) texel(x, y int) uint16 {
+	row := s.Pixels[y*s.Encoding.rowBytes(int(s.AtlasWidth)):]
+	switch s.Encoding.Bits() {
+	case 4:
+		if x&1 == 0 {
+			return uint16(row[x/2] >> 4)
+		}
+		return uint16(row[x/2] & 0x0F)
+	case 8:
+		return uint16(row[x])
+	}
+	return binary.BigEndian.Uint16(row[x*2:])
+}
+
+// PaletteIndex returns the palette index of pixel (x, y) of a palettized
+// sheet's atlas.
+func (s ParsedSheet) PaletteIndex(x, y int) uint8 {
+	return uint8(s.texel(x, y))
+}
+
+// PixelAt returns the color of pixel (x, y) of the atlas, decoded from any
+// encoding.
+func (s ParsedSheet) PixelAt(x, y int) color.NRGBA {
+	switch {
+	case s.Encoding == SheetRGBA32:
+		i := (y*int(s.AtlasWidth) + x) * 4
+		p := s.Pixels[i : i+4 : i+4]
+		return color.NRGBA{R: p[0], G: p[1], B: p[2], A: p[3]}
+	case s.Encoding.Palettized():
+		// The TLUT holds the palette as RGBA16.
+		return SheetRGBA16.decodeTexel(SheetRGBA16.encodeTexel(s.Palette[s.PaletteIndex(x, y)]))
+	}
+	return s.Encoding.decodeTexel(s.texel(x, y))
+}
+
+// QuantizationError returns the root mean square difference, in 8-bit
+// channel steps over R, G, B and A, between img and the sheet built from
+// it, as the RDP draws it. Fully transparent pixels match whatever their
+// RGB.
+func QuantizationError(img image.Image, sheet ParsedSheet) float64 {
+	b := img.Bounds()
+	if b.Dx() != int(sheet.AtlasWidth) || b.Dy() != int(sheet.AtlasHeight) || b.Empty() {
+		return 0
+	}
+	var sum float64
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			want := visible(img.At(b.Min.X+x, b.Min.Y+y))
+			got := visible(sheet.PixelAt(x, y))
+			for _, d := range [4]float64{
+				float64(want.R) - float64(got.R), float64(want.G) - float64(got.G),
+				float64(want.B) - float64(got.B), float64(want.A) - float64(got.A),
+			} {
+				sum += d * d
+			}
+		}
+	}
+	return math.Sqrt(sum / float64(b.Dx()*b.Dy()*4))
+}
+
+func buildTLUT(palette []color.NRGBA) []byte {
+	data := binary.LittleEndian.AppendUint16(nil, uint16(len(palette)))
+	for _, c := range palette {
+		data = append(data, c.R, c.G, c.B, c.A)
+	}
+	return data
+}
+
+func parseTLUT(data []byte) ([]color.NRGBA, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("format: TLUT section too short")
+	}
+	count := int(binary.LittleEndian.Uint16(data[0:2]))
+	data = data[2:]
+	if count == 0 || count > 256 {
+		return nil, fmt.Errorf("format: TLUT has %d colors, want 1 to 256", count)
+	}
+	if len(data) < count*4 {
+		return nil, fmt.Errorf("format: TLUT section truncated")
+	}
+	palette := make([]color.NRGBA, count)
+	for i := range palette {
+		palette[i] = color.NRGBA{R: data[i*4], G: data[i*4+1], B: data[i*4+2], A: data[i*4+3]}
+	}
+	return palette, nil
+}
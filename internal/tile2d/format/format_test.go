@@ -1,8 +1,12 @@
 package format
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
 	"image"
 	"image/color"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -84,6 +88,378 @@ func TestBuildAndParseMapPreservesLayerSheetIDs(t *testing.T) {
 	}
 }
 
+func TestBuildAndParseMapPreservesParallax(t *testing.T) {
+	half := float32(0.5)
+	fixed := float32(0)
+	raw, err := BuildMap(MapConfig{
+		Width:       1,
+		Height:      1,
+		LayerCount:  2,
+		CellBits:    8,
+		ChunkWidth:  1,
+		ChunkHeight: 1,
+		Layers: []MapLayerConfig{
+			{Cells: []uint16{1}, ScrollX: &half, ScrollY: &fixed, RepeatX: true},
+			{Cells: []uint16{1}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	sky := parsed.Layers[0]
+	if sky.ScrollX != 0.5 || sky.ScrollY != 0 || !sky.RepeatX || sky.RepeatY {
+		t.Fatalf("layer 0 parallax = %+v", sky)
+	}
+	ground := parsed.Layers[1]
+	if ground.ScrollX != 1 || ground.ScrollY != 1 || ground.RepeatX || ground.RepeatY {
+		t.Fatalf("layer 1 parallax = %+v, want defaults", ground)
+	}
+}
+
+func TestBuildAndParseMapPreservesCellFlips(t *testing.T) {
+	raw, err := BuildMap(MapConfig{
+		Width:       3,
+		Height:      1,
+		LayerCount:  2,
+		CellBits:    16,
+		ChunkWidth:  3,
+		ChunkHeight: 1,
+		Layers: []MapLayerConfig{
+			{Cells: []uint16{5, 5, CellIDMask}, Flips: []uint8{0, CellFlipH | CellFlipV, CellFlipD}},
+			{Cells: []uint16{1, 2, 3}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	if got := binary.LittleEndian.Uint16(raw[headerSize+mapPayloadSize+4+2:]); got != 5|1<<15|1<<14 {
+		t.Fatalf("stored cell = %#x, want ID 5 with H and V bits", got)
+	}
+
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	layer := parsed.Layers[0]
+	if got := layer.Cells; got[0] != 5 || got[1] != 5 || got[2] != CellIDMask {
+		t.Fatalf("Cells = %v, want IDs without flip bits", got)
+	}
+	if got := layer.Flips; len(got) != 3 || got[0] != 0 || got[1] != CellFlipH|CellFlipV || got[2] != CellFlipD {
+		t.Fatalf("Flips = %v", got)
+	}
+	if got := parsed.Layers[1].Flips; len(got) != 3 || got[0] != 0 {
+		t.Fatalf("layer 1 Flips = %v, want all zero", got)
+	}
+}
+
+func TestParseMapKeepsHighBitsWithoutFlipFlag(t *testing.T) {
+	raw, err := BuildMap(MapConfig{
+		Width:       1,
+		Height:      1,
+		LayerCount:  1,
+		CellBits:    16,
+		ChunkWidth:  1,
+		ChunkHeight: 1,
+		Layers:      []MapLayerConfig{{Cells: []uint16{0x9000}}},
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if got := parsed.Layers[0]; got.Cells[0] != 0x9000 || got.Flips != nil {
+		t.Fatalf("layer = %+v, want cell 0x9000 and no flips", got)
+	}
+}
+
+func TestBuildMapRejectsInvalidCellFlips(t *testing.T) {
+	base := MapConfig{Width: 2, Height: 1, LayerCount: 1, CellBits: 16, ChunkWidth: 2, ChunkHeight: 1}
+	cases := map[string]MapLayerConfig{
+		"8-bit cells":  {Cells: []uint16{1, 2}, Flips: []uint8{CellFlipH, 0}},
+		"length":       {Cells: []uint16{1, 2}, Flips: []uint8{CellFlipH}},
+		"unknown bit":  {Cells: []uint16{1, 2}, Flips: []uint8{8, 0}},
+		"id too large": {Cells: []uint16{CellIDMask + 1, 2}, Flips: []uint8{CellFlipH, 0}},
+	}
+	for name, layer := range cases {
+		cfg := base
+		if name == "8-bit cells" {
+			cfg.CellBits = 8
+		}
+		cfg.Layers = []MapLayerConfig{layer}
+		if _, err := BuildMap(cfg); err == nil {
+			t.Fatalf("%s: BuildMap() error = nil, want error", name)
+		}
+	}
+}
+
+func TestBuildAndParseMapPreservesObjectLayers(t *testing.T) {
+	var cfg MapConfig
+	err := json.Unmarshal([]byte(`{"width":1,"height":1,"layer_count":1,"cell_bits":8,"chunk_width":1,"chunk_height":1,
+		"object_layers":[{"name":"spawns","objects":[
+			{"name":"player","type":"spawn","kind":"point","x":16,"y":32},
+			{"name":"door","type":"trigger","kind":"rect","x":8,"y":8,"w":16,"h":24,
+			 "props":{"hp":3,"speed":1.5,"big":2.0,"to":"cave","locked":true}},
+			{"kind":"polyline","x":4,"y":4,"points":[[0,0],[10,0],[10,5]]}
+		]}]}`), &cfg)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	raw, err := BuildMap(cfg)
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+
+	if len(parsed.ObjectLayers) != 1 || parsed.ObjectLayers[0].Name != "spawns" {
+		t.Fatalf("ObjectLayers = %+v", parsed.ObjectLayers)
+	}
+	objs := parsed.ObjectLayers[0].Objects
+	if len(objs) != 3 {
+		t.Fatalf("len(Objects) = %d, want 3", len(objs))
+	}
+	if p := objs[0]; p.Name != "player" || p.Type != "spawn" || p.Kind != ObjectPoint || p.X != 16 || p.Y != 32 {
+		t.Fatalf("point = %+v", p)
+	}
+	door := objs[1]
+	if door.Kind != ObjectRect || door.W != 16 || door.H != 24 || len(door.Props) != 5 {
+		t.Fatalf("rect = %+v", door)
+	}
+	want := []ParsedObjectProp{
+		{Key: "big", Kind: PropFloat, Float: 2},
+		{Key: "hp", Kind: PropInt, Int: 3},
+		{Key: "locked", Kind: PropBool, Bool: true},
+		{Key: "speed", Kind: PropFloat, Float: 1.5},
+		{Key: "to", Kind: PropString, Str: "cave"},
+	}
+	for i, w := range want {
+		if door.Props[i] != w {
+			t.Fatalf("prop %d = %+v, want %+v", i, door.Props[i], w)
+		}
+	}
+	if line := objs[2]; line.Kind != ObjectPolyline || len(line.Points) != 3 || line.Points[2] != [2]float32{10, 5} {
+		t.Fatalf("polyline = %+v", line)
+	}
+}
+
+func TestBuildMapRejectsBadObjects(t *testing.T) {
+	for _, obj := range []MapObjectConfig{
+		{Kind: "circle"},
+		{Kind: "rect", W: -1},
+		{Kind: "polyline", Points: [][2]float32{{0, 0}}},
+		{Kind: "point", Props: map[string]any{"list": []int{1}}},
+	} {
+		cfg := MapConfig{Width: 1, Height: 1, LayerCount: 1, CellBits: 8, ChunkWidth: 1, ChunkHeight: 1,
+			ObjectLayers: []MapObjectLayerConfig{{Name: "a", Objects: []MapObjectConfig{obj}}}}
+		if _, err := BuildMap(cfg); err == nil {
+			t.Fatalf("expected error for %+v", obj)
+		}
+	}
+}
+
+func TestParseMapRejectsTruncatedObjects(t *testing.T) {
+	data, err := buildMapObjects([]MapObjectLayerConfig{{Name: "a", Objects: []MapObjectConfig{{Kind: "point", Props: map[string]any{"n": "text"}}}}})
+	if err != nil {
+		t.Fatalf("buildMapObjects() error = %v", err)
+	}
+	if _, err := parseMapObjects(data[:len(data)-1]); err == nil {
+		t.Fatal("expected error for a truncated object section")
+	}
+}
+
+func TestBuildAndParseMapPreservesLayerNamesHiddenAndLinks(t *testing.T) {
+	raw, err := BuildMap(MapConfig{
+		Width: 1, Height: 1, LayerCount: 2, CellBits: 8, ChunkWidth: 1, ChunkHeight: 1,
+		Layers: []MapLayerConfig{{Name: "ground"}, {Name: "collision", Hidden: true}},
+		Links: []MapLinkConfig{
+			{Dir: "e", Map: "cave", OffsetX: 320},
+			{Dir: "n", Map: "sky", OffsetY: -240},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+
+	if parsed.Layers[0].Name != "ground" || parsed.Layers[0].Hidden {
+		t.Fatalf("layer 0 = %+v, want a visible layer named ground", parsed.Layers[0])
+	}
+	if parsed.Layers[1].Name != "collision" || !parsed.Layers[1].Hidden {
+		t.Fatalf("layer 1 = %+v, want a hidden layer named collision", parsed.Layers[1])
+	}
+	want := []ParsedMapLink{{Dir: "e", Map: "cave", OffsetX: 320}, {Dir: "n", Map: "sky", OffsetY: -240}}
+	if len(parsed.Links) != 2 || parsed.Links[0] != want[0] || parsed.Links[1] != want[1] {
+		t.Fatalf("Links = %+v, want %+v", parsed.Links, want)
+	}
+
+	if _, err := BuildMap(MapConfig{Width: 1, Height: 1, LayerCount: 1, CellBits: 8, ChunkWidth: 1, ChunkHeight: 1,
+		Links: []MapLinkConfig{{Dir: "w"}}}); err == nil {
+		t.Fatal("BuildMap() accepted a link without a map")
+	}
+}
+
+func TestBuildMapStreamedRoundTripsThroughChunks(t *testing.T) {
+	// A 5x3 map in 2x2 chunks: the right column and bottom row of chunks
+	// are clipped, and the middle chunk of the top row is empty.
+	cfg := MapConfig{
+		Width: 5, Height: 3, LayerCount: 2, CellBits: 16, ChunkWidth: 2, ChunkHeight: 2,
+		Layers: []MapLayerConfig{
+			{SheetID: 1, Cells: []uint16{1, 2, 0, 0, 3, 4, 5, 0, 0, 6, 7, 8, 9, 10, 11}, Name: "ground"},
+			{SheetID: 2, Cells: []uint16{0, 0, 0, 0, 12, 0, 0, 0, 0, 0, 0, 0, 0, 0, 13}, Flips: []uint8{0, 0, 0, 0, CellFlipH, 0, 0, 0, 0, 0, 0, 0, 0, 0, CellFlipV | CellFlipD}},
+		},
+		Streamed: true,
+	}
+	raw, err := BuildMap(cfg)
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if !parsed.Streamed || parsed.Layers[0].Name != "ground" || parsed.Layers[1].SheetID != 2 {
+		t.Fatalf("ParseMap() = %+v, want a streamed map with its layer names and sheets", parsed)
+	}
+	for l, layer := range cfg.Layers {
+		for i, cell := range layer.Cells {
+			if parsed.Layers[l].Cells[i] != cell {
+				t.Fatalf("layer %d cells = %v, want %v", l, parsed.Layers[l].Cells, layer.Cells)
+			}
+		}
+	}
+	if parsed.Layers[1].Flips[14] != CellFlipV|CellFlipD {
+		t.Fatalf("layer 1 flips = %v, want V|D on the last cell", parsed.Layers[1].Flips)
+	}
+
+	m, index, err := ParseMapAt(io.NewSectionReader(bytes.NewReader(raw), 0, int64(len(raw))))
+	if err != nil {
+		t.Fatalf("ParseMapAt() error = %v", err)
+	}
+	if index == nil || m.Layers[0].Cells != nil || index.Cols != 3 || index.Rows != 2 {
+		t.Fatalf("ParseMapAt() index = %+v, want 3x2 chunks and no cells loaded", index)
+	}
+	if index.NonZero[0] != 11 || index.NonZero[1] != 2 {
+		t.Fatalf("NonZero = %v, want [11 2]", index.NonZero)
+	}
+	if _, size := index.Span(1, 0); size != 0 {
+		t.Fatalf("empty chunk is %d bytes, want 0", size)
+	}
+	off, size := index.Span(2, 1)
+	chunk, err := index.Decode(2, 1, raw[off:off+int64(size)])
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if chunk.X != 4 || chunk.Y != 2 || chunk.W != 1 || chunk.H != 1 {
+		t.Fatalf("chunk bounds = %d,%d %dx%d, want 4,2 1x1", chunk.X, chunk.Y, chunk.W, chunk.H)
+	}
+	if chunk.Cells[0][0] != 11 || chunk.Cells[1][0] != 13 || chunk.Flips[1][0] != CellFlipV|CellFlipD {
+		t.Fatalf("chunk = %+v, want tiles 11 and 13 with 13 flipped", chunk)
+	}
+}
+
+func TestParseMapAtRejectsSizesPastTheInput(t *testing.T) {
+	raw, err := BuildMap(MapConfig{Width: 4, Height: 2, LayerCount: 1, CellBits: 8, ChunkWidth: 2, ChunkHeight: 2,
+		Layers: []MapLayerConfig{{Cells: []uint16{1, 2, 3, 4, 5, 6, 7, 8}}}, Streamed: true})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	parse := func(raw []byte) error {
+		_, _, err := ParseMapAt(io.NewSectionReader(bytes.NewReader(raw), 0, int64(len(raw))))
+		return err
+	}
+	if err := parse(raw); err != nil {
+		t.Fatalf("ParseMapAt() error = %v", err)
+	}
+
+	huge := append([]byte(nil), raw...)
+	binary.LittleEndian.PutUint32(huge[12:16], 0xFFFFFFF0)
+	if err := parse(huge); err == nil {
+		t.Fatal("ParseMapAt() accepted a payload larger than the map")
+	}
+	huge = append([]byte(nil), raw...)
+	binary.LittleEndian.PutUint32(huge[bytes.Index(huge, []byte("CHNK"))+4:], 0xFFFFFFF0)
+	if err := parse(huge); err == nil {
+		t.Fatal("ParseMapAt() accepted a section larger than the map")
+	}
+	if err := parse(raw[:len(raw)-1]); err == nil {
+		t.Fatal("ParseMapAt() accepted chunk data past the end of the map")
+	}
+}
+
+func TestParseMapAtReadsUnchunkedMapsWhole(t *testing.T) {
+	raw, err := BuildMap(MapConfig{Width: 2, Height: 1, LayerCount: 1, CellBits: 8, ChunkWidth: 1, ChunkHeight: 1,
+		Layers: []MapLayerConfig{{Cells: []uint16{3, 4}, Name: "only"}}})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	m, index, err := ParseMapAt(io.NewSectionReader(bytes.NewReader(raw), 0, int64(len(raw))))
+	if err != nil {
+		t.Fatalf("ParseMapAt() error = %v", err)
+	}
+	if index != nil || m.Streamed || m.Layers[0].Cells[1] != 4 || m.Layers[0].Name != "only" {
+		t.Fatalf("ParseMapAt() = %+v, %v; want the whole map and no index", m, index)
+	}
+	if _, _, err := ParseMapAt(io.NewSectionReader(bytes.NewReader(raw), 0, int64(len(raw)-2))); err == nil {
+		t.Fatal("ParseMapAt() accepted a truncated map")
+	}
+}
+
+func TestBuildMapOmitsDefaultParallaxSection(t *testing.T) {
+	raw, err := BuildMap(MapConfig{
+		Width:       1,
+		Height:      1,
+		LayerCount:  1,
+		CellBits:    8,
+		ChunkWidth:  1,
+		ChunkHeight: 1,
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	h, err := ParseHeader(raw, "MAP2")
+	if err != nil {
+		t.Fatalf("ParseHeader() error = %v", err)
+	}
+	if h.Flags&FlagSections != 0 {
+		t.Fatalf("Flags = %#x, want no sections for a default map", h.Flags)
+	}
+	parsed, err := ParseMap(raw)
+	if err != nil {
+		t.Fatalf("ParseMap() error = %v", err)
+	}
+	if parsed.Layers[0].ScrollX != 1 || parsed.Layers[0].ScrollY != 1 {
+		t.Fatalf("default scroll = (%v,%v), want (1,1)", parsed.Layers[0].ScrollX, parsed.Layers[0].ScrollY)
+	}
+}
+
+func TestParseSectionsRejectsTruncatedSection(t *testing.T) {
+	raw := encodeAssetWithSections("MAP2", nil, []Section{{Tag: sectionTag("TEST"), Data: []byte{1, 2, 3, 4}}})
+	h, err := ParseHeader(raw, "MAP2")
+	if err != nil {
+		t.Fatalf("ParseHeader() error = %v", err)
+	}
+	if _, err := ParseSections(raw[:len(raw)-1], h); err == nil {
+		t.Fatal("ParseSections() error = nil, want truncation error")
+	}
+	sections, err := ParseSections(raw, h)
+	if err != nil || len(sections) != 1 || string(sections[0].Tag[:]) != "TEST" || len(sections[0].Data) != 4 {
+		t.Fatalf("ParseSections() = %v, %v", sections, err)
+	}
+}
+
 func TestBuildAndParseSheetPreservesPixelData(t *testing.T) {
 	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
 	img.Set(0, 0, color.NRGBA{R: 255, A: 255})
@@ -109,6 +485,343 @@ func TestBuildAndParseSheetPreservesPixelData(t *testing.T) {
 	}
 }
 
+func TestBuildAndParseSheetPreservesTileProps(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 24, 8))
+	raw, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{
+		Flags: []string{"water", "breakable"},
+		Tiles: []TilePropConfig{
+			{ID: 3, Flags: []string{"breakable"}, Props: map[string]any{"damage": 2.0, "tag": "crate", "icy": true}},
+			{ID: 1, Shape: "one_way", Flags: []string{"water", "breakable"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSheetWithProps() error = %v", err)
+	}
+
+	sheet, err := ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	props := sheet.TileProps
+	if len(props.FlagNames) != 2 || props.FlagNames[1] != "breakable" {
+		t.Fatalf("FlagNames = %v, want [water breakable]", props.FlagNames)
+	}
+	one, ok := props.Find(1)
+	if !ok || one.Shape != 3 || one.Flags != 3 {
+		t.Fatalf("tile 1 = %+v, want shape one_way (3) and flags 3", one)
+	}
+	three, ok := props.Find(3)
+	if !ok || three.Shape != 0 || three.Flags != 2 || len(three.Props) != 3 {
+		t.Fatalf("tile 3 = %+v", three)
+	}
+	if kv := three.Props[0]; kv.Key != "damage" || kv.Value != "2" {
+		t.Fatalf("first prop = %+v, want damage=2", kv)
+	}
+	if _, ok := props.Find(2); ok {
+		t.Fatal("tile 2 has no props and should not be found")
+	}
+}
+
+func TestBuildAndParseSheetPreservesAutotileRules(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8*16, 8))
+	tiles := make([]uint16, 16)
+	for i := range tiles {
+		tiles[i] = uint16(16 - i)
+	}
+	raw, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{
+		Autotile: []AutotileConfig{{Terrain: 4, Mode: "corner16", Tiles: tiles, Outside: true}},
+	})
+	if err != nil {
+		t.Fatalf("BuildSheetWithProps() error = %v", err)
+	}
+	sheet, err := ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	if len(sheet.Autotile) != 1 {
+		t.Fatalf("Autotile = %+v, want one rule set", sheet.Autotile)
+	}
+	rule := sheet.Autotile[0]
+	if rule.Terrain != 4 || rule.Mode != AutotileCorner16 || !rule.Outside || rule.Tiles[0] != 16 || rule.Tiles[15] != 1 {
+		t.Fatalf("rule = %+v", rule)
+	}
+
+	for _, bad := range [][]AutotileConfig{
+		{{Terrain: 0, Mode: "edge16", Tiles: tiles}},
+		{{Terrain: 1, Mode: "wang", Tiles: tiles}},
+		{{Terrain: 1, Mode: "blob47", Tiles: tiles}},
+		{{Terrain: 1, Mode: "edge16", Tiles: append(tiles[:15:15], 17)}},
+		{{Terrain: 1, Mode: "edge16", Tiles: tiles}, {Terrain: 1, Mode: "edge16", Tiles: tiles}},
+	} {
+		if _, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{Autotile: bad}); err == nil {
+			t.Fatalf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestBuildSheetOmitsEmptyTileProps(t *testing.T) {
+	raw, err := BuildSheet(image.NewNRGBA(image.Rect(0, 0, 8, 8)), 8, 8)
+	if err != nil {
+		t.Fatalf("BuildSheet() error = %v", err)
+	}
+	h, err := ParseHeader(raw, "SHT2")
+	if err != nil {
+		t.Fatalf("ParseHeader() error = %v", err)
+	}
+	if h.Flags&FlagSections != 0 {
+		t.Fatal("sheet without props should not carry sections")
+	}
+}
+
+func TestBuildSheetRejectsBadTileProps(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for _, cfg := range []TilePropsConfig{
+		{Tiles: []TilePropConfig{{ID: 3}}},
+		{Tiles: []TilePropConfig{{ID: 1, Shape: "cloud"}}},
+		{Tiles: []TilePropConfig{{ID: 1, Flags: []string{"water"}}}},
+		{Tiles: []TilePropConfig{{ID: 1}, {ID: 1}}},
+	} {
+		if _, err := BuildSheetWithProps(img, 8, 8, cfg); err == nil {
+			t.Fatalf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestBuildAndParsePalettizedSheets(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 6, 2))
+	red := color.NRGBA{R: 255, A: 255}
+	blue := color.NRGBA{B: 255, A: 255}
+	img.Set(1, 0, red)
+	img.Set(4, 1, blue)
+	img.Set(5, 1, color.NRGBA{R: 9, A: 0})
+
+	if got := AutoSheetEncoding(img, 2); got != SheetCI4 {
+		t.Fatalf("AutoSheetEncoding() = %v, want ci4", got)
+	}
+	for _, enc := range []SheetEncoding{SheetCI4, SheetCI8} {
+		raw, err := BuildSheetWithEncoding(img, 2, 2, TilePropsConfig{}, enc)
+		if err != nil {
+			t.Fatalf("BuildSheetWithEncoding(%v) error = %v", enc, err)
+		}
+		sheet, err := ParseSheet(raw)
+		if err != nil {
+			t.Fatalf("ParseSheet(%v) error = %v", enc, err)
+		}
+		if sheet.Encoding != enc || sheet.PaletteEntries != 3 || len(sheet.Palette) != 3 {
+			t.Fatalf("%v sheet = encoding %v, %d entries, palette %v", enc, sheet.Encoding, sheet.PaletteEntries, sheet.Palette)
+		}
+		if want := enc.pixelBytes(6, 2); len(sheet.Pixels) != want {
+			t.Fatalf("%v len(Pixels) = %d, want %d", enc, len(sheet.Pixels), want)
+		}
+		// Transparent pixels share entry 0, the first color seen.
+		if sheet.PaletteIndex(0, 0) != 0 || sheet.PaletteIndex(5, 1) != 0 {
+			t.Fatalf("%v transparent pixels do not share index 0", enc)
+		}
+		if sheet.Palette[sheet.PaletteIndex(1, 0)] != red || sheet.Palette[sheet.PaletteIndex(4, 1)] != blue {
+			t.Fatalf("%v indices do not map to the image colors", enc)
+		}
+	}
+
+	paletted := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{blue, red, color.NRGBA{G: 255, A: 255}})
+	paletted.SetColorIndex(1, 1, 1)
+	raw, err := BuildSheetWithEncoding(paletted, 2, 2, TilePropsConfig{}, SheetCI8)
+	if err != nil {
+		t.Fatalf("BuildSheetWithEncoding(paletted) error = %v", err)
+	}
+	sheet, err := ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet(paletted) error = %v", err)
+	}
+	// A paletted image keeps its order, up to the last used entry.
+	if len(sheet.Palette) != 2 || sheet.Palette[0] != blue || sheet.PaletteIndex(1, 1) != 1 {
+		t.Fatalf("paletted sheet palette = %v", sheet.Palette)
+	}
+}
+
+func TestBuildSheetWithEncodingRejectsUnfitImages(t *testing.T) {
+	many := image.NewNRGBA(image.Rect(0, 0, 32, 1))
+	for x := 0; x < 32; x++ {
+		many.Set(x, 0, color.NRGBA{R: expand(uint16(x), 5), A: 255})
+	}
+	if _, err := BuildSheetWithEncoding(many, 1, 1, TilePropsConfig{}, SheetCI4); err == nil {
+		t.Fatal("expected error for 32 colors in ci4")
+	}
+	if got := AutoSheetEncoding(many, 1); got != SheetCI8 {
+		t.Fatalf("AutoSheetEncoding(32 colors) = %v, want ci8", got)
+	}
+
+	translucent := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	translucent.Set(0, 0, color.NRGBA{R: 255, A: 128})
+	if _, err := BuildSheetWithEncoding(translucent, 1, 1, TilePropsConfig{}, SheetCI8); err == nil {
+		t.Fatal("expected error for a partly transparent pixel")
+	}
+	if got := AutoSheetEncoding(translucent, 1); got != SheetRGBA32 {
+		t.Fatalf("AutoSheetEncoding(translucent, 1) = %v, want rgba32", got)
+	}
+	offGrid := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	offGrid.Set(0, 0, color.NRGBA{R: 100, A: 255})
+	if got := AutoSheetEncoding(offGrid, 2); got != SheetRGBA32 {
+		t.Fatalf("AutoSheetEncoding(color off the rgba16 grid) = %v, want rgba32", got)
+	}
+	if _, err := ParseSheetEncoding("ci5"); err == nil {
+		t.Fatal("expected error for an unknown encoding name")
+	}
+}
+
+func TestBuildAndParseDirectSheetEncodings(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+	src.Set(0, 0, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+	src.Set(1, 0, color.NRGBA{R: 90, G: 90, B: 90, A: 255})
+	src.Set(2, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 160})
+
+	for _, enc := range []SheetEncoding{SheetRGBA16, SheetIA8, SheetIA4, SheetI8, SheetI4} {
+		// Store the image as enc would, so the round trip is exact.
+		img := image.NewNRGBA(src.Bounds())
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				img.SetNRGBA(x, y, enc.decodeTexel(enc.encodeTexel(src.NRGBAAt(x, y))))
+			}
+		}
+		raw, err := BuildSheetWithEncoding(img, 2, 2, TilePropsConfig{}, enc)
+		if err != nil {
+			t.Fatalf("BuildSheetWithEncoding(%v) error = %v", enc, err)
+		}
+		sheet, err := ParseSheet(raw)
+		if err != nil {
+			t.Fatalf("ParseSheet(%v) error = %v", enc, err)
+		}
+		if sheet.Encoding != enc || sheet.Palette != nil {
+			t.Fatalf("%v sheet = encoding %v, palette %v", enc, sheet.Encoding, sheet.Palette)
+		}
+		if want := enc.pixelBytes(4, 2); len(sheet.Pixels) != want {
+			t.Fatalf("%v len(Pixels) = %d, want %d", enc, len(sheet.Pixels), want)
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				if got, want := visible(sheet.PixelAt(x, y)), visible(img.NRGBAAt(x, y)); got != want {
+					t.Fatalf("%v PixelAt(%d, %d) = %v, want %v", enc, x, y, got, want)
+				}
+			}
+		}
+		if rms := QuantizationError(img, sheet); rms != 0 {
+			t.Fatalf("%v QuantizationError() = %v, want 0", enc, rms)
+		}
+	}
+
+	if _, err := BuildSheetWithEncoding(src, 1, 2, TilePropsConfig{}, SheetI4); err == nil {
+		t.Fatal("expected error for i4 with an odd tile width")
+	}
+
+	many := image.NewNRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			many.Set(x, y, color.NRGBA{R: expand(uint16(x), 5), G: expand(uint16(y), 5), A: 255})
+		}
+	}
+	if got := AutoSheetEncoding(many, 8); got != SheetRGBA16 {
+		t.Fatalf("AutoSheetEncoding(512 rgba16 colors) = %v, want rgba16", got)
+	}
+	many.Set(0, 0, color.NRGBA{R: 1, A: 255})
+	if got := AutoSheetEncoding(many, 8); got != SheetRGBA32 {
+		t.Fatalf("AutoSheetEncoding(513 colors) = %v, want rgba32", got)
+	}
+}
+
+func TestDitherStoresEncodablePixels(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 16), G: uint8(x * 16), B: uint8(x * 16), A: 255})
+		}
+	}
+	plain, err := BuildSheetWithEncoding(img, 8, 8, TilePropsConfig{}, SheetIA4)
+	if err != nil {
+		t.Fatalf("BuildSheetWithEncoding() error = %v", err)
+	}
+	sheet, _ := ParseSheet(plain)
+	if rms := QuantizationError(img, sheet); rms == 0 {
+		t.Fatal("QuantizationError() = 0 for 16 grays in ia4")
+	}
+
+	for _, mode := range []DitherMode{DitherOrdered, DitherFloydSteinberg} {
+		dithered := Dither(img, 8, 8, SheetIA4, mode)
+		if !lossless(dithered, SheetIA4) {
+			t.Fatalf("Dither(%v) left pixels ia4 cannot store", mode)
+		}
+		if dithered.At(0, 0) == dithered.At(7, 0) {
+			t.Fatalf("Dither(%v) flattened the gradient", mode)
+		}
+	}
+	if Dither(img, 8, 8, SheetRGBA32, DitherOrdered) != image.Image(img) || Dither(img, 8, 8, SheetIA4, DitherNone) != image.Image(img) {
+		t.Fatal("Dither() changed an image it does not need to round")
+	}
+	if m, err := ParseDitherMode("Floyd-Steinberg"); err != nil || m != DitherFloydSteinberg {
+		t.Fatalf("ParseDitherMode() = %v, %v", m, err)
+	}
+	if _, err := ParseDitherMode("random"); err == nil {
+		t.Fatal("expected error for an unknown dither mode")
+	}
+}
+
+func TestParseSheetRejectsTruncatedTileProps(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	raw, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{
+		Tiles: []TilePropConfig{{ID: 1, Props: map[string]any{"friction": 0.5}}},
+	})
+	if err != nil {
+		t.Fatalf("BuildSheetWithProps() error = %v", err)
+	}
+	h, _ := ParseHeader(raw, "SHT2")
+	sections, _ := ParseSections(raw, h)
+	data, _ := findSection(sections, "TPRP")
+	if _, err := parseTileProps(data[:len(data)-2]); err == nil {
+		t.Fatal("expected error for truncated tile props")
+	}
+}
+
+func TestBuildAndParseSheetPreservesTileAnims(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 8))
+	raw, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{
+		Anims: []TileAnimConfig{
+			{Tile: 3, FPS: 4, Frames: []uint16{3, 4}},
+			{Tile: 1, FPS: 8, Frames: []uint16{1, 2, 3, 4}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("BuildSheetWithProps() error = %v", err)
+	}
+
+	sheet, err := ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet() error = %v", err)
+	}
+	if len(sheet.TileProps.Tiles) != 0 {
+		t.Fatalf("TileProps = %+v, want none", sheet.TileProps)
+	}
+	anims := sheet.TileAnims
+	if len(anims) != 2 || anims[0].Tile != 1 || anims[0].FPS != 8 || len(anims[0].Frames) != 4 || anims[1].Frames[1] != 4 {
+		t.Fatalf("TileAnims = %+v, want tiles 1 and 3 in order", anims)
+	}
+}
+
+func TestBuildSheetRejectsBadTileAnims(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for _, anim := range []TileAnimConfig{
+		{Tile: 3, FPS: 8, Frames: []uint16{1}},
+		{Tile: 1, FPS: 0, Frames: []uint16{1, 2}},
+		{Tile: 1, FPS: 8},
+		{Tile: 1, FPS: 8, Frames: []uint16{1, 5}},
+	} {
+		if _, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{Anims: []TileAnimConfig{anim}}); err == nil {
+			t.Fatalf("expected error for %+v", anim)
+		}
+	}
+	dup := []TileAnimConfig{{Tile: 1, FPS: 8, Frames: []uint16{1}}, {Tile: 1, FPS: 4, Frames: []uint16{2}}}
+	if _, err := BuildSheetWithProps(img, 8, 8, TilePropsConfig{Anims: dup}); err == nil {
+		t.Fatal("expected error for duplicate tile animation")
+	}
+}
+
 func TestBuildSheetRejectsNonDivisibleTileSize(t *testing.T) {
 	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
 	_, err := BuildSheet(img, 3, 3)
@@ -180,6 +893,17 @@ func TestParseSheetRejectsTruncatedPayload(t *testing.T) {
 	}
 }
 
+func TestParseSheetRejectsMissingEncodingByte(t *testing.T) {
+	payload := make([]byte, sheetPayloadSize)
+	binary.LittleEndian.PutUint16(payload[8:10], 1)
+	binary.LittleEndian.PutUint16(payload[10:12], 1)
+	binary.LittleEndian.PutUint32(payload[12:16], headerSize+encodedSheetPayloadSize)
+	raw := append(encodeAsset("SHT2", payload), make([]byte, 8)...)
+	if _, err := ParseSheet(raw); err == nil {
+		t.Fatal("expected error for a data offset past a 16-byte payload")
+	}
+}
+
 func TestParseMapRejectsTruncatedPayload(t *testing.T) {
 	raw := encodeAsset("MAP2", []byte{1, 2})
 	_, err := ParseMap(raw)
@@ -219,3 +943,14 @@ func mustReadFixture(t *testing.T, name string) []byte {
 	}
 	return raw
 }
+
+func TestCheckFileNames(t *testing.T) {
+	if err := CheckFileNames("sheet", []string{"tiles", "Walls", "my level"}); err != nil {
+		t.Fatalf("CheckFileNames() error = %v", err)
+	}
+	for _, names := range [][]string{{""}, {".."}, {"../x"}, {`a\b`}, {"c:x"}, {"tiles", "Tiles"}} {
+		if err := CheckFileNames("sheet", names); err == nil {
+			t.Fatalf("CheckFileNames(%q) = nil, want an error", names)
+		}
+	}
+}
@@ -3,10 +3,75 @@ package format
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 )
 
 const mapPayloadSize = 12
 
+// mapParallaxEntrySize is the per-layer record size of the PRLX section:
+// scroll X and Y factors as float32, a repeat and hidden flag byte and
+// padding.
+const mapParallaxEntrySize = 12
+
+const (
+	mapRepeatX     uint8 = 1 << 0
+	mapRepeatY     uint8 = 1 << 1
+	mapLayerHidden uint8 = 1 << 2
+)
+
+// Payload byte 7 flags. mapCellFlips is set when 16-bit cells carry flip
+// bits; mapChunked when the cells live in the CHNK section, chunk by chunk,
+// instead of after the layer headers.
+const (
+	mapCellFlips uint8 = 1 << 0
+	mapChunked   uint8 = 1 << 1
+)
+
+// Cell flip bits, as stored in ParsedMapLayer.Flips. D transposes the tile
+// before H and V mirror it, as in Tiled.
+const (
+	CellFlipH uint8 = 1 << 0
+	CellFlipV uint8 = 1 << 1
+	CellFlipD uint8 = 1 << 2
+)
+
+// CellIDMask masks the tile ID out of a 16-bit cell in a map with flip
+// bits. The top three bits hold horizontal, vertical and diagonal flip.
+const CellIDMask uint16 = 1<<13 - 1
+
+const (
+	cellBitH uint16 = 1 << 15
+	cellBitV uint16 = 1 << 14
+	cellBitD uint16 = 1 << 13
+)
+
+func packCell(id uint16, flip uint8) uint16 {
+	if flip&CellFlipH != 0 {
+		id |= cellBitH
+	}
+	if flip&CellFlipV != 0 {
+		id |= cellBitV
+	}
+	if flip&CellFlipD != 0 {
+		id |= cellBitD
+	}
+	return id
+}
+
+func unpackCell(cell uint16) (uint16, uint8) {
+	var flip uint8
+	if cell&cellBitH != 0 {
+		flip |= CellFlipH
+	}
+	if cell&cellBitV != 0 {
+		flip |= CellFlipV
+	}
+	if cell&cellBitD != 0 {
+		flip |= CellFlipD
+	}
+	return cell & CellIDMask, flip
+}
+
 type ParsedMap struct {
 	Width       uint16
 	Height      uint16
@@ -15,40 +80,87 @@ type ParsedMap struct {
 	ChunkWidth  uint16
 	ChunkHeight uint16
 	Layers      []ParsedMapLayer
+
+	// ObjectLayers holds the map's objects from the OBJS section.
+	ObjectLayers []ParsedObjectLayer
+
+	// Links holds the neighbouring maps from the LINK section.
+	Links []ParsedMapLink
+
+	// Streamed is set when the cells are stored chunk by chunk. ParseMap
+	// still fills every layer's Cells; ParseMapAt leaves them nil.
+	Streamed bool
 }
 
 type ParsedMapLayer struct {
 	SheetID uint16
 	Cells   []uint16
+
+	// Flips holds each cell's CellFlip bits, parallel to Cells. It is nil
+	// when the map stores no flip bits.
+	Flips []uint8
+
+	// ScrollX and ScrollY scale the camera position for this layer;
+	// 1 scrolls with the camera and 0 stays fixed on screen.
+	ScrollX float32
+	ScrollY float32
+	RepeatX bool
+	RepeatY bool
+
+	// Name comes from the LNAM section and Hidden from the PRLX flags.
+	Name   string
+	Hidden bool
 }
 
 func ParseMap(raw []byte) (ParsedMap, error) {
-	var m ParsedMap
-
 	h, err := ParseHeader(raw, "MAP2")
 	if err != nil {
+		return ParsedMap{}, err
+	}
+	sections, err := ParseSections(raw, h)
+	if err != nil {
+		return ParsedMap{}, err
+	}
+
+	m, index, err := parseMap(raw[h.HeaderBytes:h.HeaderBytes+h.PayloadBytes], sections)
+	if err != nil || index == nil {
 		return m, err
 	}
+	data, _ := findSection(sections, "CHNK")
+	if err := index.fill(&m, data[index.tableBytes():]); err != nil {
+		return m, err
+	}
+	return m, nil
+}
 
-	payload := raw[h.HeaderBytes : h.HeaderBytes+h.PayloadBytes]
+// parseMap decodes a map's payload and sections. For a chunked map it
+// leaves the layers' Cells nil and returns the chunk index instead.
+func parseMap(payload []byte, sections []Section) (ParsedMap, *MapChunkIndex, error) {
+	var m ParsedMap
 	if len(payload) < mapPayloadSize {
-		return m, fmt.Errorf("format: map payload too short: got %d bytes", len(payload))
+		return m, nil, fmt.Errorf("format: map payload too short: got %d bytes", len(payload))
 	}
 
 	m.Width = binary.LittleEndian.Uint16(payload[0:2])
 	m.Height = binary.LittleEndian.Uint16(payload[2:4])
 	m.LayerCount = binary.LittleEndian.Uint16(payload[4:6])
 	m.CellBits = payload[6]
+	cellFlips := payload[7]&mapCellFlips != 0
 	m.ChunkWidth = binary.LittleEndian.Uint16(payload[8:10])
 	m.ChunkHeight = binary.LittleEndian.Uint16(payload[10:12])
 
 	if m.CellBits != 8 && m.CellBits != 16 {
-		return m, fmt.Errorf("format: unsupported cell width %d", m.CellBits)
+		return m, nil, fmt.Errorf("format: unsupported cell width %d", m.CellBits)
+	}
+	if cellFlips && m.CellBits != 16 {
+		return m, nil, fmt.Errorf("format: cell flip bits need 16-bit cells")
 	}
 
+	m.Streamed = payload[7]&mapChunked != 0
+
 	cellCount := int(m.Width) * int(m.Height)
 	if cellCount == 0 {
-		return m, nil
+		return m, nil, nil
 	}
 
 	cellBytes := 1
@@ -60,10 +172,12 @@ func ParseMap(raw []byte) (ParsedMap, error) {
 	if len(remaining) == 0 {
 		m.Layers = make([]ParsedMapLayer, int(m.LayerCount))
 		for i := range m.Layers {
-			m.Layers[i].SheetID = 1
-			m.Layers[i].Cells = make([]uint16, cellCount)
+			m.Layers[i] = ParsedMapLayer{SheetID: 1, Cells: make([]uint16, cellCount), ScrollX: 1, ScrollY: 1}
 		}
-		return m, nil
+		return m, nil, parseMapSections(&m, sections)
+	}
+	if m.Streamed {
+		return parseChunkedMap(m, remaining, cellFlips, sections)
 	}
 
 	layerHeaderBytes := int(m.LayerCount) * 2
@@ -77,9 +191,9 @@ func ParseMap(raw []byte) (ParsedMap, error) {
 		hasLayerHeaders = true
 	default:
 		if len(remaining) < wantBytes {
-			return m, fmt.Errorf("format: map cell payload too short: got %d bytes, want at least %d", len(remaining), wantBytes)
+			return m, nil, fmt.Errorf("format: map cell payload too short: got %d bytes, want at least %d", len(remaining), wantBytes)
 		}
-		return m, fmt.Errorf("format: unexpected map payload size %d", len(remaining))
+		return m, nil, fmt.Errorf("format: unexpected map payload size %d", len(remaining))
 	}
 
 	m.Layers = make([]ParsedMapLayer, int(m.LayerCount))
@@ -92,7 +206,7 @@ func ParseMap(raw []byte) (ParsedMap, error) {
 		}
 	}
 	for layerIdx := range m.Layers {
-		layer := ParsedMapLayer{SheetID: 1, Cells: make([]uint16, cellCount)}
+		layer := ParsedMapLayer{SheetID: 1, Cells: make([]uint16, cellCount), ScrollX: 1, ScrollY: 1}
 		if hasLayerHeaders {
 			layer.SheetID = sheetIDs[layerIdx]
 		}
@@ -105,8 +219,58 @@ func ParseMap(raw []byte) (ParsedMap, error) {
 			layer.Cells[i] = binary.LittleEndian.Uint16(remaining[offset : offset+2])
 			offset += 2
 		}
+		if cellFlips {
+			layer.Flips = make([]uint8, cellCount)
+			for i, cell := range layer.Cells {
+				layer.Cells[i], layer.Flips[i] = unpackCell(cell)
+			}
+		}
 		m.Layers[layerIdx] = layer
 	}
 
-	return m, nil
+	return m, nil, parseMapSections(&m, sections)
+}
+
+func parseMapSections(m *ParsedMap, sections []Section) error {
+	if data, ok := findSection(sections, "PRLX"); ok {
+		if err := parseMapParallax(m, data); err != nil {
+			return err
+		}
+	}
+	if data, ok := findSection(sections, "OBJS"); ok {
+		layers, err := parseMapObjects(data)
+		if err != nil {
+			return err
+		}
+		m.ObjectLayers = layers
+	}
+	if data, ok := findSection(sections, "LNAM"); ok {
+		if err := parseMapLayerNames(m, data); err != nil {
+			return err
+		}
+	}
+	if data, ok := findSection(sections, "LINK"); ok {
+		links, err := parseMapLinks(data)
+		if err != nil {
+			return err
+		}
+		m.Links = links
+	}
+	return nil
+}
+
+func parseMapParallax(m *ParsedMap, data []byte) error {
+	if len(data) != len(m.Layers)*mapParallaxEntrySize {
+		return fmt.Errorf("format: parallax section is %d bytes, want %d", len(data), len(m.Layers)*mapParallaxEntrySize)
+	}
+	for i := range m.Layers {
+		entry := data[i*mapParallaxEntrySize:]
+		layer := &m.Layers[i]
+		layer.ScrollX = math.Float32frombits(binary.LittleEndian.Uint32(entry[0:4]))
+		layer.ScrollY = math.Float32frombits(binary.LittleEndian.Uint32(entry[4:8]))
+		layer.RepeatX = entry[8]&mapRepeatX != 0
+		layer.RepeatY = entry[8]&mapRepeatY != 0
+		layer.Hidden = entry[8]&mapLayerHidden != 0
+	}
+	return nil
 }
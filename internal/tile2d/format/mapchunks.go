@@ -0,0 +1,363 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The CHNK section of a streamed map starts with a table: each layer's
+// non-empty cell count as uint32, then one uint32 offset per chunk in row
+// order plus an end offset, relative to the end of the table. Each chunk's
+// data holds, layer by layer, the rows of cells inside the chunk, stored
+// like the cells of an unchunked map. A chunk with no tiles in any layer
+// has no data.
+
+// MapChunkIndex locates the chunks of a streamed map.
+type MapChunkIndex struct {
+	// Cols and Rows count the chunks across and down the map; chunks on
+	// the right and bottom edges may be smaller than ChunkWidth by
+	// ChunkHeight.
+	Cols, Rows int
+
+	// NonZero counts each layer's non-empty cells across the whole map.
+	NonZero []int
+
+	width, height  int
+	chunkW, chunkH int
+	layers         int
+	cellBytes      int
+	flips          bool
+	offsets        []uint32
+
+	// base is the file offset of the first chunk's data, set by ParseMapAt.
+	base int64
+}
+
+// MapChunk holds the cells of one chunk, layer by layer. X and Y are the
+// chunk's top-left cell and W and H its size in cells; each layer's cells
+// are W*H long in row order.
+type MapChunk struct {
+	X, Y, W, H int
+	Cells      [][]uint16
+
+	// Flips is nil when the map stores no flip bits.
+	Flips [][]uint8
+}
+
+func newMapChunkIndex(m ParsedMap, flips bool) *MapChunkIndex {
+	ix := &MapChunkIndex{
+		width:  int(m.Width),
+		height: int(m.Height),
+		chunkW: int(m.ChunkWidth),
+		chunkH: int(m.ChunkHeight),
+		layers: int(m.LayerCount),
+		flips:  flips,
+	}
+	ix.Cols = (ix.width + ix.chunkW - 1) / ix.chunkW
+	ix.Rows = (ix.height + ix.chunkH - 1) / ix.chunkH
+	ix.cellBytes = 1
+	if m.CellBits == 16 {
+		ix.cellBytes = 2
+	}
+	return ix
+}
+
+func (ix *MapChunkIndex) tableBytes() int {
+	return ix.layers*4 + (ix.Cols*ix.Rows+1)*4
+}
+
+func (ix *MapChunkIndex) parseTable(data []byte) error {
+	if len(data) < ix.tableBytes() {
+		return fmt.Errorf("format: chunk table is %d bytes, want %d", len(data), ix.tableBytes())
+	}
+	r := sectionReader{data: data}
+	ix.NonZero = make([]int, ix.layers)
+	for i := range ix.NonZero {
+		ix.NonZero[i] = int(r.u32())
+	}
+	ix.offsets = make([]uint32, ix.Cols*ix.Rows+1)
+	for i := range ix.offsets {
+		ix.offsets[i] = r.u32()
+		if i > 0 && ix.offsets[i] < ix.offsets[i-1] {
+			return fmt.Errorf("format: chunk %d offset %d is before the previous chunk's", i, ix.offsets[i])
+		}
+	}
+	return nil
+}
+
+// Bounds returns the top-left cell and size of chunk (cx, cy).
+func (ix *MapChunkIndex) Bounds(cx, cy int) (x, y, w, h int) {
+	x, y = cx*ix.chunkW, cy*ix.chunkH
+	return x, y, min(ix.chunkW, ix.width-x), min(ix.chunkH, ix.height-y)
+}
+
+// Span returns the file offset and size of chunk (cx, cy)'s data. A size
+// of 0 means the chunk is empty.
+func (ix *MapChunkIndex) Span(cx, cy int) (int64, int) {
+	i := cy*ix.Cols + cx
+	return ix.base + int64(ix.offsets[i]), int(ix.offsets[i+1] - ix.offsets[i])
+}
+
+// Decode unpacks chunk (cx, cy) from the data at its Span. Empty data
+// gives an empty chunk.
+func (ix *MapChunkIndex) Decode(cx, cy int, data []byte) (MapChunk, error) {
+	x, y, w, h := ix.Bounds(cx, cy)
+	c := MapChunk{X: x, Y: y, W: w, H: h, Cells: make([][]uint16, ix.layers)}
+	if ix.flips {
+		c.Flips = make([][]uint8, ix.layers)
+	}
+	n := w * h
+	if len(data) != 0 && len(data) != ix.layers*n*ix.cellBytes {
+		return c, fmt.Errorf("format: chunk %d,%d is %d bytes, want %d", cx, cy, len(data), ix.layers*n*ix.cellBytes)
+	}
+	for l := range c.Cells {
+		cells := make([]uint16, n)
+		if len(data) != 0 {
+			for i := range cells {
+				if ix.cellBytes == 1 {
+					cells[i] = uint16(data[0])
+				} else {
+					cells[i] = binary.LittleEndian.Uint16(data)
+				}
+				data = data[ix.cellBytes:]
+			}
+		}
+		c.Cells[l] = cells
+		if ix.flips {
+			flips := make([]uint8, n)
+			for i, cell := range cells {
+				cells[i], flips[i] = unpackCell(cell)
+			}
+			c.Flips[l] = flips
+		}
+	}
+	return c, nil
+}
+
+// fill decodes every chunk from data, the CHNK section after its table,
+// into m's layers.
+func (ix *MapChunkIndex) fill(m *ParsedMap, data []byte) error {
+	if int(ix.offsets[len(ix.offsets)-1]) > len(data) {
+		return fmt.Errorf("format: chunk data is %d bytes, want %d", len(data), ix.offsets[len(ix.offsets)-1])
+	}
+	cellCount := ix.width * ix.height
+	for l := range m.Layers {
+		m.Layers[l].Cells = make([]uint16, cellCount)
+		if ix.flips {
+			m.Layers[l].Flips = make([]uint8, cellCount)
+		}
+	}
+	for cy := 0; cy < ix.Rows; cy++ {
+		for cx := 0; cx < ix.Cols; cx++ {
+			off, size := ix.Span(cx, cy)
+			c, err := ix.Decode(cx, cy, data[off:off+int64(size)])
+			if err != nil {
+				return err
+			}
+			for l := range m.Layers {
+				layer := &m.Layers[l]
+				for row := 0; row < c.H; row++ {
+					start := (c.Y+row)*ix.width + c.X
+					copy(layer.Cells[start:start+c.W], c.Cells[l][row*c.W:])
+					if ix.flips {
+						copy(layer.Flips[start:start+c.W], c.Flips[l][row*c.W:])
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseChunkedMap reads the layer headers of a streamed map, which carry
+// no cells, and its sections, and returns the chunk index from CHNK.
+func parseChunkedMap(m ParsedMap, remaining []byte, flips bool, sections []Section) (ParsedMap, *MapChunkIndex, error) {
+	if len(remaining) != int(m.LayerCount)*2 {
+		return m, nil, fmt.Errorf("format: chunked map has %d bytes of layer headers, want %d", len(remaining), int(m.LayerCount)*2)
+	}
+	if m.ChunkWidth == 0 || m.ChunkHeight == 0 {
+		return m, nil, fmt.Errorf("format: chunked map has zero chunk size")
+	}
+	m.Layers = make([]ParsedMapLayer, int(m.LayerCount))
+	for i := range m.Layers {
+		m.Layers[i] = ParsedMapLayer{SheetID: binary.LittleEndian.Uint16(remaining[i*2:]), ScrollX: 1, ScrollY: 1}
+	}
+	if err := parseMapSections(&m, sections); err != nil {
+		return m, nil, err
+	}
+
+	data, ok := findSection(sections, "CHNK")
+	if !ok {
+		return m, nil, fmt.Errorf("format: chunked map has no chunk section")
+	}
+	index := newMapChunkIndex(m, flips)
+	if err := index.parseTable(data); err != nil {
+		return m, nil, err
+	}
+	return m, index, nil
+}
+
+// buildMapChunks encodes the CHNK section from each layer's packed cells.
+func buildMapChunks(cfg MapConfig, cells [][]uint16) []byte {
+	index := newMapChunkIndex(ParsedMap{
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		LayerCount:  cfg.LayerCount,
+		CellBits:    cfg.CellBits,
+		ChunkWidth:  cfg.ChunkWidth,
+		ChunkHeight: cfg.ChunkHeight,
+	}, false)
+
+	table := make([]byte, 0, index.tableBytes())
+	for _, layer := range cells {
+		n := 0
+		for _, cell := range layer {
+			if cell != 0 {
+				n++
+			}
+		}
+		table = binary.LittleEndian.AppendUint32(table, uint32(n))
+	}
+
+	var data []byte
+	table = binary.LittleEndian.AppendUint32(table, 0)
+	for cy := 0; cy < index.Rows; cy++ {
+		for cx := 0; cx < index.Cols; cx++ {
+			x, y, w, h := index.Bounds(cx, cy)
+			start := len(data)
+			empty := true
+			for _, layer := range cells {
+				for row := y; row < y+h; row++ {
+					for _, cell := range layer[row*index.width+x : row*index.width+x+w] {
+						empty = empty && cell == 0
+						if index.cellBytes == 1 {
+							data = append(data, byte(cell))
+						} else {
+							data = binary.LittleEndian.AppendUint16(data, cell)
+						}
+					}
+				}
+			}
+			if empty {
+				data = data[:start]
+			}
+			table = binary.LittleEndian.AppendUint32(table, uint32(len(data)))
+		}
+	}
+	return append(table, data...)
+}
+
+// ParseMapAt reads a map through r without loading its chunks. For a
+// streamed map the layers' Cells are nil and the returned index locates
+// each chunk in r; other maps are read whole and the index is nil. Sizes
+// declared by the map are checked against r's size before anything is
+// allocated for them.
+func ParseMapAt(r *io.SectionReader) (ParsedMap, *MapChunkIndex, error) {
+	head := make([]byte, headerSize)
+	if err := readFullAt(r, head, 0); err != nil {
+		return ParsedMap{}, nil, fmt.Errorf("format: reading header: %w", err)
+	}
+	headerBytes := binary.LittleEndian.Uint32(head[8:12])
+	payloadBytes := binary.LittleEndian.Uint32(head[12:16])
+	if end := int64(headerBytes) + int64(payloadBytes); end > r.Size() {
+		return ParsedMap{}, nil, fmt.Errorf("format: header declares %d bytes, map has %d", end, r.Size())
+	}
+	raw := make([]byte, int64(headerBytes)+int64(payloadBytes))
+	if err := readFullAt(r, raw, 0); err != nil {
+		return ParsedMap{}, nil, fmt.Errorf("format: reading payload: %w", err)
+	}
+	h, err := ParseHeader(raw, "MAP2")
+	if err != nil {
+		return ParsedMap{}, nil, err
+	}
+	payload := raw[h.HeaderBytes:]
+
+	// The chunk table's size depends on the payload, so CHNK can only be
+	// located once the payload is known.
+	var index *MapChunkIndex
+	if len(payload) >= mapPayloadSize && payload[7]&mapChunked != 0 {
+		m := ParsedMap{
+			Width:       binary.LittleEndian.Uint16(payload[0:2]),
+			Height:      binary.LittleEndian.Uint16(payload[2:4]),
+			LayerCount:  binary.LittleEndian.Uint16(payload[4:6]),
+			CellBits:    payload[6],
+			ChunkWidth:  binary.LittleEndian.Uint16(payload[8:10]),
+			ChunkHeight: binary.LittleEndian.Uint16(payload[10:12]),
+		}
+		if m.ChunkWidth == 0 || m.ChunkHeight == 0 {
+			return ParsedMap{}, nil, fmt.Errorf("format: chunked map has zero chunk size")
+		}
+		index = newMapChunkIndex(m, payload[7]&mapCellFlips != 0)
+	}
+
+	var sections []Section
+	if h.Flags&FlagSections != 0 {
+		sections, err = readSectionsAt(r, int64(len(raw)), index)
+		if err != nil {
+			return ParsedMap{}, nil, err
+		}
+	}
+	m, parsedIndex, err := parseMap(payload, sections)
+	if err != nil {
+		return m, nil, err
+	}
+	if parsedIndex != nil {
+		parsedIndex.base = index.base
+		if end := parsedIndex.base + int64(parsedIndex.offsets[len(parsedIndex.offsets)-1]); end > r.Size() {
+			return m, nil, fmt.Errorf("format: chunk data ends at %d, map has %d bytes", end, r.Size())
+		}
+	}
+	return m, parsedIndex, nil
+}
+
+// readSectionsAt reads the section table at off. Only the table at the
+// start of CHNK is read; index.base is set to where its chunk data begins.
+func readSectionsAt(r *io.SectionReader, off int64, index *MapChunkIndex) ([]Section, error) {
+	var count [2]byte
+	if err := readFullAt(r, count[:], off); err != nil {
+		return nil, fmt.Errorf("format: reading section table: %w", err)
+	}
+	off += 2
+
+	sections := make([]Section, 0, binary.LittleEndian.Uint16(count[:]))
+	for i := 0; i < cap(sections); i++ {
+		var head [sectionHeaderSize]byte
+		if err := readFullAt(r, head[:], off); err != nil {
+			return nil, fmt.Errorf("format: section %d header truncated", i)
+		}
+		var s Section
+		copy(s.Tag[:], head[0:4])
+		size := int64(binary.LittleEndian.Uint32(head[4:8]))
+		off += sectionHeaderSize
+		if size > r.Size()-off {
+			return nil, fmt.Errorf("format: section %q is %d bytes, %d remain", string(s.Tag[:]), size, r.Size()-off)
+		}
+
+		read := size
+		if s.Tag == sectionTag("CHNK") && index != nil {
+			read = min(size, int64(index.tableBytes()))
+			index.base = off + read
+		}
+		s.Data = make([]byte, read)
+		if err := readFullAt(r, s.Data, off); err != nil {
+			return nil, fmt.Errorf("format: section %q truncated", string(s.Tag[:]))
+		}
+		off += size
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+// readFullAt fills p from off. A reader may report io.EOF along with the
+// last bytes of its input, which still counts as a full read.
+func readFullAt(r io.ReaderAt, p []byte, off int64) error {
+	n, err := r.ReadAt(p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,89 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MapLinkConfig points at a neighbouring map, such as the next room of a
+// world. Dir names the side it lies on ("n", "s", "e", "w" or any other
+// text) and OffsetX and OffsetY place its top-left corner relative to this
+// map's, in pixels.
+type MapLinkConfig struct {
+	Dir     string `json:"dir"`
+	Map     string `json:"map"`
+	OffsetX int32  `json:"offset_x"`
+	OffsetY int32  `json:"offset_y"`
+}
+
+type ParsedMapLink struct {
+	Dir     string
+	Map     string
+	OffsetX int32
+	OffsetY int32
+}
+
+// buildMapLayerNames writes the LNAM section, one name per layer. It
+// reports false when no layer is named.
+func buildMapLayerNames(cfg MapConfig) ([]byte, bool, error) {
+	var data []byte
+	named := false
+	for i := 0; i < int(cfg.LayerCount); i++ {
+		var name string
+		if i < len(cfg.Layers) {
+			name = cfg.Layers[i].Name
+		}
+		if len(name) > 0xFF {
+			return nil, false, fmt.Errorf("format: layer %d name %q too long", i, name)
+		}
+		named = named || name != ""
+		data = appendStr8(data, name)
+	}
+	return data, named, nil
+}
+
+func parseMapLayerNames(m *ParsedMap, data []byte) error {
+	r := sectionReader{data: data}
+	for i := range m.Layers {
+		m.Layers[i].Name = r.str(int(r.u8()))
+	}
+	if r.err == nil && len(r.data) != 0 {
+		return fmt.Errorf("format: layer name section has %d trailing bytes", len(r.data))
+	}
+	return r.err
+}
+
+func buildMapLinks(links []MapLinkConfig) ([]byte, error) {
+	if len(links) > 0xFFFF {
+		return nil, fmt.Errorf("format: %d map links exceed uint16", len(links))
+	}
+	data := binary.LittleEndian.AppendUint16(nil, uint16(len(links)))
+	for _, link := range links {
+		if link.Map == "" {
+			return nil, fmt.Errorf("format: map link %q has no map", link.Dir)
+		}
+		if len(link.Dir) > 0xFF || len(link.Map) > 0xFF {
+			return nil, fmt.Errorf("format: map link %q to %q too long", link.Dir, link.Map)
+		}
+		data = appendStr8(data, link.Dir)
+		data = appendStr8(data, link.Map)
+		data = binary.LittleEndian.AppendUint32(data, uint32(link.OffsetX))
+		data = binary.LittleEndian.AppendUint32(data, uint32(link.OffsetY))
+	}
+	return data, nil
+}
+
+func parseMapLinks(data []byte) ([]ParsedMapLink, error) {
+	r := sectionReader{data: data}
+	links := make([]ParsedMapLink, int(r.u16()))
+	for i := range links {
+		links[i].Dir = r.str(int(r.u8()))
+		links[i].Map = r.str(int(r.u8()))
+		links[i].OffsetX = int32(r.u32())
+		links[i].OffsetY = int32(r.u32())
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return links, nil
+}
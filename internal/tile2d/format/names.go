@@ -0,0 +1,25 @@
+package format
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CheckFileNames checks names taken from an editor's project before each
+// becomes an output file name. A name must be a plain file name, and no two
+// may share a file, even on a case-insensitive file system. kind, such as
+// "tileset", names what is named in errors.
+func CheckFileNames(kind string, names []string) error {
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\:`) {
+			return fmt.Errorf("format: %s %q: name is not a valid file name", kind, name)
+		}
+		key := strings.ToLower(name)
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("format: %ss %q and %q would both be written to the same file", kind, other, name)
+		}
+		seen[key] = name
+	}
+	return nil
+}
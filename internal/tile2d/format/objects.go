@@ -0,0 +1,264 @@
+package format
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ObjectKindNames lists the object kinds accepted in a map file. A kind's
+// index is its value in ParsedObject.Kind.
+var ObjectKindNames = []string{"point", "rect", "polyline"}
+
+const (
+	ObjectPoint uint8 = iota
+	ObjectRect
+	ObjectPolyline
+)
+
+// Object property types, as stored in ParsedObjectProp.Kind.
+const (
+	PropInt uint8 = iota + 1
+	PropFloat
+	PropString
+	PropBool
+)
+
+// MapObjectLayerConfig is a named layer of objects in a map file.
+type MapObjectLayerConfig struct {
+	Name    string            `json:"name"`
+	Objects []MapObjectConfig `json:"objects"`
+}
+
+// MapObjectConfig is one object. X and Y place a point, the top-left
+// corner of a rect, or the origin of a polyline whose Points are relative
+// to it. Props values may be ints, floats, strings or booleans; in JSON a
+// number without a fraction or exponent is an int.
+type MapObjectConfig struct {
+	Name   string         `json:"name,omitempty"`
+	Type   string         `json:"type,omitempty"`
+	Kind   string         `json:"kind"`
+	X      float32        `json:"x"`
+	Y      float32        `json:"y"`
+	W      float32        `json:"w,omitempty"`
+	H      float32        `json:"h,omitempty"`
+	Points [][2]float32   `json:"points,omitempty"`
+	Props  map[string]any `json:"props,omitempty"`
+}
+
+// UnmarshalJSON keeps numeric props as json.Number so ints and floats stay
+// apart.
+func (o *MapObjectConfig) UnmarshalJSON(data []byte) error {
+	type plain MapObjectConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode((*plain)(o))
+}
+
+type ParsedObjectLayer struct {
+	Name    string
+	Objects []ParsedObject
+}
+
+type ParsedObject struct {
+	Name   string
+	Type   string
+	Kind   uint8
+	X, Y   float32
+	W, H   float32
+	Points [][2]float32
+	Props  []ParsedObjectProp
+}
+
+// ParsedObjectProp is one typed property; only the field for Kind is set.
+type ParsedObjectProp struct {
+	Key   string
+	Kind  uint8
+	Int   int32
+	Float float32
+	Str   string
+	Bool  bool
+}
+
+func buildMapObjects(layers []MapObjectLayerConfig) ([]byte, error) {
+	if len(layers) > 0xFFFF {
+		return nil, fmt.Errorf("format: %d object layers exceed uint16", len(layers))
+	}
+	data := binary.LittleEndian.AppendUint16(nil, uint16(len(layers)))
+	for _, layer := range layers {
+		if len(layer.Name) > 0xFF {
+			return nil, fmt.Errorf("format: object layer name %q too long", layer.Name)
+		}
+		if len(layer.Objects) > 0xFFFF {
+			return nil, fmt.Errorf("format: object layer %q has too many objects", layer.Name)
+		}
+		data = appendStr8(data, layer.Name)
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(layer.Objects)))
+		for _, obj := range layer.Objects {
+			var err error
+			if data, err = appendMapObject(data, obj); err != nil {
+				return nil, fmt.Errorf("format: object layer %q: %w", layer.Name, err)
+			}
+		}
+	}
+	return data, nil
+}
+
+func appendMapObject(data []byte, obj MapObjectConfig) ([]byte, error) {
+	kind := slices.Index(ObjectKindNames, obj.Kind)
+	switch {
+	case kind < 0:
+		return nil, fmt.Errorf("object %q has unknown kind %q", obj.Name, obj.Kind)
+	case len(obj.Name) > 0xFF || len(obj.Type) > 0xFF:
+		return nil, fmt.Errorf("object %q name or type too long", obj.Name)
+	case uint8(kind) == ObjectRect && (obj.W < 0 || obj.H < 0):
+		return nil, fmt.Errorf("object %q has negative size", obj.Name)
+	case uint8(kind) == ObjectPolyline && len(obj.Points) < 2:
+		return nil, fmt.Errorf("object %q polyline needs at least 2 points", obj.Name)
+	case len(obj.Points) > 0xFFFF || len(obj.Props) > 0xFF:
+		return nil, fmt.Errorf("object %q has too many points or props", obj.Name)
+	}
+
+	data = appendStr8(data, obj.Name)
+	data = appendStr8(data, obj.Type)
+	data = append(data, uint8(kind))
+	for _, v := range []float32{obj.X, obj.Y, obj.W, obj.H} {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+	}
+	points := obj.Points
+	if uint8(kind) != ObjectPolyline {
+		points = nil
+	}
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(points)))
+	for _, p := range points {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(p[0]))
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(p[1]))
+	}
+
+	keys := make([]string, 0, len(obj.Props))
+	for key := range obj.Props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data = append(data, byte(len(keys)))
+	for _, key := range keys {
+		prop, err := objectProp(key, obj.Props[key])
+		if err != nil {
+			return nil, fmt.Errorf("object %q prop %q: %w", obj.Name, key, err)
+		}
+		if key == "" || len(key) > 0xFF || len(prop.Str) > 0xFFFF {
+			return nil, fmt.Errorf("object %q prop %q too long", obj.Name, key)
+		}
+		data = appendStr8(data, key)
+		data = append(data, prop.Kind)
+		switch prop.Kind {
+		case PropInt:
+			data = binary.LittleEndian.AppendUint32(data, uint32(prop.Int))
+		case PropFloat:
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(prop.Float))
+		case PropString:
+			data = binary.LittleEndian.AppendUint16(data, uint16(len(prop.Str)))
+			data = append(data, prop.Str...)
+		case PropBool:
+			var b byte
+			if prop.Bool {
+				b = 1
+			}
+			data = append(data, b)
+		}
+	}
+	return data, nil
+}
+
+func objectProp(key string, v any) (ParsedObjectProp, error) {
+	p := ParsedObjectProp{Key: key}
+	switch v := v.(type) {
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			n, err := strconv.ParseInt(string(v), 10, 32)
+			if err != nil {
+				return p, err
+			}
+			p.Kind, p.Int = PropInt, int32(n)
+			return p, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return p, err
+		}
+		p.Kind, p.Float = PropFloat, float32(f)
+	case int:
+		if v < math.MinInt32 || v > math.MaxInt32 {
+			return p, fmt.Errorf("int %d exceeds int32", v)
+		}
+		p.Kind, p.Int = PropInt, int32(v)
+	case int32:
+		p.Kind, p.Int = PropInt, v
+	case float32:
+		p.Kind, p.Float = PropFloat, v
+	case float64:
+		p.Kind, p.Float = PropFloat, float32(v)
+	case string:
+		p.Kind, p.Str = PropString, v
+	case bool:
+		p.Kind, p.Bool = PropBool, v
+	default:
+		return p, fmt.Errorf("unsupported value type %T", v)
+	}
+	return p, nil
+}
+
+func parseMapObjects(data []byte) ([]ParsedObjectLayer, error) {
+	r := sectionReader{data: data}
+	layers := make([]ParsedObjectLayer, int(r.u16()))
+	for i := range layers {
+		layers[i].Name = r.str(int(r.u8()))
+		count := int(r.u16())
+		for j := 0; j < count && r.err == nil; j++ {
+			obj := ParsedObject{Name: r.str(int(r.u8()))}
+			obj.Type = r.str(int(r.u8()))
+			obj.Kind = r.u8()
+			obj.X, obj.Y, obj.W, obj.H = r.f32(), r.f32(), r.f32(), r.f32()
+			if int(obj.Kind) >= len(ObjectKindNames) {
+				return nil, fmt.Errorf("format: object %q has unknown kind %d", obj.Name, obj.Kind)
+			}
+			points := int(r.u16())
+			for k := 0; k < points && r.err == nil; k++ {
+				obj.Points = append(obj.Points, [2]float32{r.f32(), r.f32()})
+			}
+			props := int(r.u8())
+			for k := 0; k < props && r.err == nil; k++ {
+				prop := ParsedObjectProp{Key: r.str(int(r.u8())), Kind: r.u8()}
+				switch prop.Kind {
+				case PropInt:
+					prop.Int = int32(r.u32())
+				case PropFloat:
+					prop.Float = r.f32()
+				case PropString:
+					prop.Str = r.str(int(r.u16()))
+				case PropBool:
+					prop.Bool = r.u8() != 0
+				default:
+					return nil, fmt.Errorf("format: object %q prop %q has unknown type %d", obj.Name, prop.Key, prop.Kind)
+				}
+				obj.Props = append(obj.Props, prop)
+			}
+			layers[i].Objects = append(layers[i].Objects, obj)
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("format: object section truncated")
+	}
+	return layers, nil
+}
+
+func appendStr8(data []byte, s string) []byte {
+	data = append(data, byte(len(s)))
+	return append(data, s...)
+}
@@ -0,0 +1,82 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FlagSections marks an asset whose payload is followed by tagged
+// sections. Readers that predate a section skip it by length.
+const FlagSections uint16 = 1 << 0
+
+const sectionHeaderSize = 8
+
+type Section struct {
+	Tag  [4]byte
+	Data []byte
+}
+
+func sectionTag(tag string) [4]byte {
+	var t [4]byte
+	copy(t[:], tag)
+	return t
+}
+
+func encodeAssetWithSections(magic string, payload []byte, sections []Section) []byte {
+	if len(sections) == 0 {
+		return encodeAsset(magic, payload)
+	}
+
+	raw := encodeAsset(magic, payload)
+	binary.LittleEndian.PutUint16(raw[6:8], FlagSections)
+	raw = binary.LittleEndian.AppendUint16(raw, uint16(len(sections)))
+	for _, s := range sections {
+		raw = append(raw, s.Tag[:]...)
+		raw = binary.LittleEndian.AppendUint32(raw, uint32(len(s.Data)))
+		raw = append(raw, s.Data...)
+	}
+	return raw
+}
+
+// ParseSections returns the tagged sections that follow the payload, or nil
+// when the header does not set FlagSections.
+func ParseSections(raw []byte, h Header) ([]Section, error) {
+	if h.Flags&FlagSections == 0 {
+		return nil, nil
+	}
+
+	rest := raw[h.HeaderBytes+h.PayloadBytes:]
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("format: section table too short: got %d bytes", len(rest))
+	}
+	count := int(binary.LittleEndian.Uint16(rest[0:2]))
+	rest = rest[2:]
+
+	sections := make([]Section, 0, count)
+	for i := 0; i < count; i++ {
+		if len(rest) < sectionHeaderSize {
+			return nil, fmt.Errorf("format: section %d header truncated", i)
+		}
+		var s Section
+		copy(s.Tag[:], rest[0:4])
+		size := binary.LittleEndian.Uint32(rest[4:8])
+		rest = rest[sectionHeaderSize:]
+		if uint64(size) > uint64(len(rest)) {
+			return nil, fmt.Errorf("format: section %q size %d exceeds input %d", string(s.Tag[:]), size, len(rest))
+		}
+		s.Data = rest[:size]
+		rest = rest[size:]
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+func findSection(sections []Section, tag string) ([]byte, bool) {
+	want := sectionTag(tag)
+	for _, s := range sections {
+		if s.Tag == want {
+			return s.Data, true
+		}
+	}
+	return nil, false
+}
@@ -3,11 +3,15 @@ package format
 import (
 	"encoding/binary"
 	"fmt"
+	"image/color"
 )
 
 const sheetPayloadSize = 16
 const legacySheetPayloadSize = 12
 
+// encodedSheetPayloadSize adds the encoding byte and three reserved bytes.
+const encodedSheetPayloadSize = 20
+
 type ParsedSheet struct {
 	TileWidth      uint16
 	TileHeight     uint16
@@ -17,6 +21,21 @@ type ParsedSheet struct {
 	AtlasHeight    uint16
 	DataOffset     uint32
 	Pixels         []byte
+
+	// Encoding is how Pixels stores the atlas. Palettized sheets hold
+	// their colors in Palette, from the TLUT section.
+	Encoding SheetEncoding
+	Palette  []color.NRGBA
+
+	// TileProps holds the optional per-tile metadata from the TPRP section.
+	TileProps ParsedTileProps
+
+	// TileAnims holds the in-place tile animations from the TANM section,
+	// sorted by tile.
+	TileAnims []ParsedTileAnim
+
+	// Autotile holds the terrain rule sets from the ATIL section.
+	Autotile []ParsedAutotile
 }
 
 func ParseSheet(raw []byte) (ParsedSheet, error) {
@@ -48,18 +67,80 @@ func ParseSheet(raw []byte) (ParsedSheet, error) {
 		return sheet, fmt.Errorf("format: invalid sheet atlas size %dx%d", sheet.AtlasWidth, sheet.AtlasHeight)
 	}
 
-	pixelCount := int(sheet.AtlasWidth) * int(sheet.AtlasHeight) * 4
-	if pixelCount == 0 {
-		return sheet, nil
-	}
 	if int(sheet.DataOffset) < int(h.HeaderBytes)+sheetPayloadSize {
 		return sheet, fmt.Errorf("format: invalid sheet data offset %d", sheet.DataOffset)
 	}
+	if int(sheet.DataOffset) >= int(h.HeaderBytes)+encodedSheetPayloadSize {
+		if len(payload) < encodedSheetPayloadSize {
+			return sheet, fmt.Errorf("format: sheet payload too short for its encoding: got %d bytes", len(payload))
+		}
+		sheet.Encoding = SheetEncoding(payload[16])
+		if int(sheet.Encoding) >= len(sheetEncodingNames) {
+			return sheet, fmt.Errorf("format: unknown sheet encoding %d", payload[16])
+		}
+	}
+	if err := parseSheetSections(&sheet, raw, h); err != nil {
+		return sheet, err
+	}
+	if sheet.Encoding.Palettized() {
+		if len(sheet.Palette) == 0 {
+			return sheet, fmt.Errorf("format: %s sheet has no TLUT section", sheet.Encoding)
+		}
+		if len(sheet.Palette) > sheet.Encoding.paletteSize() {
+			return sheet, fmt.Errorf("format: %s sheet has %d palette entries", sheet.Encoding, len(sheet.Palette))
+		}
+	}
+
+	pixelCount := sheet.Encoding.pixelBytes(int(sheet.AtlasWidth), int(sheet.AtlasHeight))
 	if int(sheet.DataOffset)+pixelCount > len(raw) {
 		return sheet, fmt.Errorf("format: sheet pixel payload too short: got %d bytes, want %d", len(raw)-int(sheet.DataOffset), pixelCount)
 	}
 
 	sheet.Pixels = append([]byte(nil), raw[sheet.DataOffset:int(sheet.DataOffset)+pixelCount]...)
-
+	if sheet.Encoding.Palettized() {
+		for y := 0; y < int(sheet.AtlasHeight); y++ {
+			for x := 0; x < int(sheet.AtlasWidth); x++ {
+				if i := sheet.PaletteIndex(x, y); int(i) >= len(sheet.Palette) {
+					return sheet, fmt.Errorf("format: pixel index %d outside the %d-color palette", i, len(sheet.Palette))
+				}
+			}
+		}
+	}
 	return sheet, nil
 }
+
+func parseSheetSections(sheet *ParsedSheet, raw []byte, h Header) error {
+	sections, err := ParseSections(raw, h)
+	if err != nil {
+		return err
+	}
+	if data, ok := findSection(sections, "TPRP"); ok {
+		props, err := parseTileProps(data)
+		if err != nil {
+			return err
+		}
+		sheet.TileProps = props
+	}
+	if data, ok := findSection(sections, "TANM"); ok {
+		anims, err := parseTileAnims(data)
+		if err != nil {
+			return err
+		}
+		sheet.TileAnims = anims
+	}
+	if data, ok := findSection(sections, "ATIL"); ok {
+		rules, err := parseAutotile(data)
+		if err != nil {
+			return err
+		}
+		sheet.Autotile = rules
+	}
+	if data, ok := findSection(sections, "TLUT"); ok {
+		palette, err := parseTLUT(data)
+		if err != nil {
+			return err
+		}
+		sheet.Palette = palette
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package format
+
+import (
+	"fmt"
+	"image"
+)
+
+// MaxQuantizationError is the RMS error, in 8-bit channel steps, above
+// which BuildSheetWithOptions warns that a lossy encoding visibly changes
+// the image. Rounding arbitrary colors to RGBA16's 5 bits per channel
+// costs about 2.
+const MaxQuantizationError = 2
+
+// SheetOptions choose how the sheet tools store a sheet's pixels.
+type SheetOptions struct {
+	// Auto picks AutoSheetEncoding for each image instead of Encoding.
+	Auto     bool
+	Encoding SheetEncoding
+	Dither   DitherMode
+}
+
+// ParseSheetOptions parses the -format and -dither flags shared by the
+// sheet tools. The encoding "auto" sets Auto.
+func ParseSheetOptions(encoding, dither string) (SheetOptions, error) {
+	var opts SheetOptions
+	var err error
+	if opts.Dither, err = ParseDitherMode(dither); err != nil {
+		return opts, err
+	}
+	if encoding == "auto" {
+		opts.Auto = true
+		return opts, nil
+	}
+	opts.Encoding, err = ParseSheetEncoding(encoding)
+	return opts, err
+}
+
+// BuildSheetWithOptions builds img like BuildSheetWithEncoding, in the
+// encoding opts picks and dithered as they say. It also returns a warning
+// when that encoding changes the image noticeably, or "".
+func BuildSheetWithOptions(img image.Image, tileWidth, tileHeight int, props TilePropsConfig, opts SheetOptions) ([]byte, string, error) {
+	enc := opts.Encoding
+	if opts.Auto {
+		enc = AutoSheetEncoding(img, tileWidth)
+	}
+	raw, err := BuildSheetWithEncoding(Dither(img, tileWidth, tileHeight, enc, opts.Dither), tileWidth, tileHeight, props, enc)
+	if err != nil {
+		return nil, "", err
+	}
+	parsed, err := ParseSheet(raw)
+	if err != nil {
+		return nil, "", err
+	}
+	if rms := QuantizationError(img, parsed); rms > MaxQuantizationError {
+		return raw, fmt.Sprintf("%s loses detail (RMS error %.1f); try -dither or a larger encoding", enc, rms), nil
+	}
+	return raw, "", nil
+}
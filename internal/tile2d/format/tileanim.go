@@ -0,0 +1,73 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// TileAnimConfig animates a tile in place: every map cell holding Tile
+// shows Frames in turn at FPS frames per second.
+type TileAnimConfig struct {
+	Tile   uint16   `json:"tile"`
+	FPS    uint16   `json:"fps"`
+	Frames []uint16 `json:"frames"`
+}
+
+type ParsedTileAnim struct {
+	Tile   uint16
+	FPS    uint16
+	Frames []uint16
+}
+
+func buildTileAnims(anims []TileAnimConfig, tileCount int) ([]byte, error) {
+	sorted := slices.Clone(anims)
+	slices.SortStableFunc(sorted, func(a, b TileAnimConfig) int { return int(a.Tile) - int(b.Tile) })
+
+	data := binary.LittleEndian.AppendUint16(nil, uint16(len(sorted)))
+	for i, anim := range sorted {
+		if anim.Tile == 0 || int(anim.Tile) > tileCount {
+			return nil, fmt.Errorf("format: animated tile %d outside 1..%d", anim.Tile, tileCount)
+		}
+		if i > 0 && sorted[i-1].Tile == anim.Tile {
+			return nil, fmt.Errorf("format: duplicate animation for tile %d", anim.Tile)
+		}
+		if anim.FPS == 0 {
+			return nil, fmt.Errorf("format: tile %d animation fps must be positive", anim.Tile)
+		}
+		if len(anim.Frames) == 0 || len(anim.Frames) > 0xFFFF {
+			return nil, fmt.Errorf("format: tile %d animation has %d frames", anim.Tile, len(anim.Frames))
+		}
+		data = binary.LittleEndian.AppendUint16(data, anim.Tile)
+		data = binary.LittleEndian.AppendUint16(data, anim.FPS)
+		data = binary.LittleEndian.AppendUint16(data, uint16(len(anim.Frames)))
+		for _, frame := range anim.Frames {
+			if frame == 0 || int(frame) > tileCount {
+				return nil, fmt.Errorf("format: tile %d animation frame %d outside 1..%d", anim.Tile, frame, tileCount)
+			}
+			data = binary.LittleEndian.AppendUint16(data, frame)
+		}
+	}
+	return data, nil
+}
+
+func parseTileAnims(data []byte) ([]ParsedTileAnim, error) {
+	r := sectionReader{data: data}
+	count := int(r.u16())
+	anims := make([]ParsedTileAnim, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		anim := ParsedTileAnim{Tile: r.u16(), FPS: r.u16()}
+		frames := int(r.u16())
+		for j := 0; j < frames && r.err == nil; j++ {
+			anim.Frames = append(anim.Frames, r.u16())
+		}
+		if anim.FPS == 0 || len(anim.Frames) == 0 {
+			return nil, fmt.Errorf("format: tile %d animation is empty", anim.Tile)
+		}
+		anims = append(anims, anim)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("format: tile animation section truncated")
+	}
+	return anims, nil
+}
@@ -0,0 +1,224 @@
+package format
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// TileShapeNames lists the collision shape names accepted in a tile
+// properties file. A shape's index is its value in the runtime TileShape
+// enum.
+var TileShapeNames = []string{
+	"empty",
+	"solid",
+	"one_way",
+	"slope_up_45",
+	"slope_down_45",
+	"slope_up_22_low",
+	"slope_up_22_high",
+	"slope_down_22_high",
+	"slope_down_22_low",
+	"ladder",
+	"hazard",
+}
+
+// TilePropsConfig is the JSON tile properties file read beside a sheet
+// PNG. Flags names up to 32 flag bits, in bit order. Anims lists the tiles
+// that animate in place.
+type TilePropsConfig struct {
+	Flags []string         `json:"flags,omitempty"`
+	Tiles []TilePropConfig `json:"tiles"`
+	Anims []TileAnimConfig `json:"anims,omitempty"`
+
+	// Autotile holds the terrain rule sets mk2dmap and Map.ApplyAutotile
+	// use to pick tiles from terrain grids.
+	Autotile []AutotileConfig `json:"autotile,omitempty"`
+}
+
+// TilePropConfig describes one tile. Props values may be strings, numbers
+// or booleans; they are stored as text.
+type TilePropConfig struct {
+	ID    uint16         `json:"id"`
+	Shape string         `json:"shape,omitempty"`
+	Flags []string       `json:"flags,omitempty"`
+	Props map[string]any `json:"props,omitempty"`
+}
+
+type ParsedTileProps struct {
+	FlagNames []string
+	Tiles     []ParsedTileProp
+}
+
+// ParsedTileProp is one tile's metadata. Shape is 0 when the file did not
+// set one, and the shape's TileShapeNames index plus one otherwise.
+type ParsedTileProp struct {
+	ID    uint16
+	Shape uint8
+	Flags uint32
+	Props []ParsedTileKV
+}
+
+type ParsedTileKV struct {
+	Key   string
+	Value string
+}
+
+// Find returns the metadata for tile id. Tiles are sorted by ID.
+func (p ParsedTileProps) Find(id uint16) (ParsedTileProp, bool) {
+	i, ok := slices.BinarySearchFunc(p.Tiles, id, func(t ParsedTileProp, id uint16) int {
+		return int(t.ID) - int(id)
+	})
+	if !ok {
+		return ParsedTileProp{}, false
+	}
+	return p.Tiles[i], true
+}
+
+func buildTileProps(cfg TilePropsConfig, tileCount int) ([]byte, error) {
+	if len(cfg.Flags) > 32 {
+		return nil, fmt.Errorf("format: %d tile flags exceed 32", len(cfg.Flags))
+	}
+	bits := make(map[string]uint32, len(cfg.Flags))
+	data := []byte{byte(len(cfg.Flags))}
+	for i, name := range cfg.Flags {
+		if name == "" || len(name) > 0xFF {
+			return nil, fmt.Errorf("format: invalid tile flag name %q", name)
+		}
+		if _, dup := bits[name]; dup {
+			return nil, fmt.Errorf("format: duplicate tile flag %q", name)
+		}
+		bits[name] = 1 << i
+		data = append(data, byte(len(name)))
+		data = append(data, name...)
+	}
+
+	tiles := slices.Clone(cfg.Tiles)
+	slices.SortStableFunc(tiles, func(a, b TilePropConfig) int { return int(a.ID) - int(b.ID) })
+	data = binary.LittleEndian.AppendUint16(data, uint16(len(tiles)))
+	for i, tile := range tiles {
+		if tile.ID == 0 || int(tile.ID) > tileCount {
+			return nil, fmt.Errorf("format: tile props id %d outside 1..%d", tile.ID, tileCount)
+		}
+		if i > 0 && tiles[i-1].ID == tile.ID {
+			return nil, fmt.Errorf("format: duplicate tile props id %d", tile.ID)
+		}
+
+		var shape uint8
+		if tile.Shape != "" {
+			idx := slices.Index(TileShapeNames, tile.Shape)
+			if idx < 0 {
+				return nil, fmt.Errorf("format: tile %d has unknown shape %q", tile.ID, tile.Shape)
+			}
+			shape = uint8(idx + 1)
+		}
+		var flags uint32
+		for _, name := range tile.Flags {
+			bit, ok := bits[name]
+			if !ok {
+				return nil, fmt.Errorf("format: tile %d uses undeclared flag %q", tile.ID, name)
+			}
+			flags |= bit
+		}
+		if len(tile.Props) > 0xFF {
+			return nil, fmt.Errorf("format: tile %d has too many props", tile.ID)
+		}
+
+		data = binary.LittleEndian.AppendUint16(data, tile.ID)
+		data = append(data, shape)
+		data = binary.LittleEndian.AppendUint32(data, flags)
+		data = append(data, byte(len(tile.Props)))
+
+		keys := make([]string, 0, len(tile.Props))
+		for key := range tile.Props {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value, err := propText(tile.Props[key])
+			if err != nil {
+				return nil, fmt.Errorf("format: tile %d prop %q: %w", tile.ID, key, err)
+			}
+			if key == "" || len(key) > 0xFF || len(value) > 0xFFFF {
+				return nil, fmt.Errorf("format: tile %d prop %q too long", tile.ID, key)
+			}
+			data = append(data, byte(len(key)))
+			data = append(data, key...)
+			data = binary.LittleEndian.AppendUint16(data, uint16(len(value)))
+			data = append(data, value...)
+		}
+	}
+	return data, nil
+}
+
+func propText(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
+
+func parseTileProps(data []byte) (ParsedTileProps, error) {
+	var props ParsedTileProps
+	r := sectionReader{data: data}
+
+	flagCount := int(r.u8())
+	for i := 0; i < flagCount; i++ {
+		props.FlagNames = append(props.FlagNames, r.str(int(r.u8())))
+	}
+
+	count := int(r.u16())
+	props.Tiles = make([]ParsedTileProp, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		tile := ParsedTileProp{ID: r.u16(), Shape: r.u8(), Flags: r.u32()}
+		if tile.Shape > uint8(len(TileShapeNames)) {
+			return props, fmt.Errorf("format: tile %d has unknown shape %d", tile.ID, tile.Shape)
+		}
+		propCount := int(r.u8())
+		for j := 0; j < propCount; j++ {
+			key := r.str(int(r.u8()))
+			tile.Props = append(tile.Props, ParsedTileKV{Key: key, Value: r.str(int(r.u16()))})
+		}
+		if i > 0 && props.Tiles[i-1].ID >= tile.ID {
+			return props, fmt.Errorf("format: tile props not sorted at id %d", tile.ID)
+		}
+		props.Tiles = append(props.Tiles, tile)
+	}
+	if r.err != nil {
+		return props, fmt.Errorf("format: tile props section truncated")
+	}
+	return props, nil
+}
+
+// sectionReader reads little-endian values from a section and remembers
+// the first overrun instead of panicking.
+type sectionReader struct {
+	data []byte
+	err  error
+}
+
+func (r *sectionReader) take(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = fmt.Errorf("format: section truncated")
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *sectionReader) u8() uint8        { return r.take(1)[0] }
+func (r *sectionReader) u16() uint16      { return binary.LittleEndian.Uint16(r.take(2)) }
+func (r *sectionReader) u32() uint32      { return binary.LittleEndian.Uint32(r.take(4)) }
+func (r *sectionReader) str(n int) string { return string(r.take(n)) }
+func (r *sectionReader) f32() float32     { return math.Float32frombits(r.u32()) }
@@ -0,0 +1,650 @@
+// Package ldtk converts LDtk projects (.ldtk files) into the map and sheet
+// configs that mk2dldtk compiles into one bundle per level.
+package ldtk
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+const chunkCells = 8
+
+// Import is a converted project. Sheets holds every tileset and IntGrid
+// sheet once; each level names the sheets it uses in sheet ID order.
+// Warnings name LDtk data the map format has no place for.
+type Import struct {
+	Sheets   []Sheet
+	Levels   []Level
+	Warnings []string
+}
+
+// Sheet is a converted tileset, or the generated sheet of an IntGrid
+// layer, ready for format.BuildSheetWithProps.
+type Sheet struct {
+	Name       string
+	Image      image.Image
+	TileWidth  int
+	TileHeight int
+	Props      format.TilePropsConfig
+}
+
+// Level is one converted level. Sheets[0] is sheet ID 1 of Map.
+type Level struct {
+	Name   string
+	Map    format.MapConfig
+	Sheets []string
+}
+
+type project struct {
+	Defs struct {
+		Layers   []layerDef   `json:"layers"`
+		Tilesets []tilesetDef `json:"tilesets"`
+	} `json:"defs"`
+	Levels []level `json:"levels"`
+	Worlds []struct {
+		Levels []level `json:"levels"`
+	} `json:"worlds"`
+}
+
+type layerDef struct {
+	UID           int            `json:"uid"`
+	Identifier    string         `json:"identifier"`
+	Type          string         `json:"type"`
+	IntGridValues []intGridValue `json:"intGridValues"`
+}
+
+type intGridValue struct {
+	Value      int    `json:"value"`
+	Identifier string `json:"identifier"`
+	Color      string `json:"color"`
+}
+
+type tilesetDef struct {
+	UID          int     `json:"uid"`
+	Identifier   string  `json:"identifier"`
+	RelPath      *string `json:"relPath"`
+	EmbedAtlas   *string `json:"embedAtlas"`
+	TileGridSize int     `json:"tileGridSize"`
+	Spacing      int     `json:"spacing"`
+	Padding      int     `json:"padding"`
+	CWid         int     `json:"__cWid"`
+	CHei         int     `json:"__cHei"`
+	CustomData   []struct {
+		TileID int    `json:"tileId"`
+		Data   string `json:"data"`
+	} `json:"customData"`
+	EnumTags []struct {
+		EnumValueID string `json:"enumValueId"`
+		TileIDs     []int  `json:"tileIds"`
+	} `json:"enumTags"`
+}
+
+type level struct {
+	Identifier      string          `json:"identifier"`
+	IID             string          `json:"iid"`
+	WorldX          int             `json:"worldX"`
+	WorldY          int             `json:"worldY"`
+	PxWid           int             `json:"pxWid"`
+	PxHei           int             `json:"pxHei"`
+	ExternalRelPath *string         `json:"externalRelPath"`
+	LayerInstances  []layerInstance `json:"layerInstances"`
+	FieldInstances  []field         `json:"fieldInstances"`
+	Neighbours      []struct {
+		LevelIID string `json:"levelIid"`
+		Dir      string `json:"dir"`
+	} `json:"__neighbours"`
+}
+
+type layerInstance struct {
+	Identifier     string     `json:"__identifier"`
+	Type           string     `json:"__type"`
+	CWid           int        `json:"__cWid"`
+	CHei           int        `json:"__cHei"`
+	GridSize       int        `json:"__gridSize"`
+	TilesetDefUID  *int       `json:"__tilesetDefUid"`
+	LayerDefUID    int        `json:"layerDefUid"`
+	PxOffsetX      int        `json:"pxOffsetX"`
+	PxOffsetY      int        `json:"pxOffsetY"`
+	Visible        bool       `json:"visible"`
+	IntGridCSV     []int      `json:"intGridCsv"`
+	AutoLayerTiles []tileInst `json:"autoLayerTiles"`
+	GridTiles      []tileInst `json:"gridTiles"`
+	Entities       []entity   `json:"entityInstances"`
+}
+
+type tileInst struct {
+	Px [2]int `json:"px"`
+	F  int    `json:"f"`
+	T  int    `json:"t"`
+}
+
+type entity struct {
+	Identifier string     `json:"__identifier"`
+	IID        string     `json:"iid"`
+	Pivot      [2]float64 `json:"__pivot"`
+	Px         [2]float64 `json:"px"`
+	Width      float64    `json:"width"`
+	Height     float64    `json:"height"`
+	Fields     []field    `json:"fieldInstances"`
+}
+
+type field struct {
+	Identifier string `json:"__identifier"`
+	Type       string `json:"__type"`
+	Value      any    `json:"__value"`
+}
+
+// importer carries the project state shared by all levels. Converted
+// sheets are indexed by tileset uid and IntGrid layer uid.
+type importer struct {
+	dir           string
+	imp           Import
+	tilesets      map[int]*tilesetDef
+	layers        map[int]*layerDef
+	tilesetSheets map[int]int
+	intGridSheets map[int]int
+	names         map[string]bool
+	iids          map[string]*level
+}
+
+// ImportProject reads an .ldtk project, its external levels and its
+// tileset images.
+func ImportProject(path string) (Import, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Import{}, err
+	}
+	var p project
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return Import{}, fmt.Errorf("ldtk: %s: %w", path, err)
+	}
+	imp, err := convertProject(p, filepath.Dir(path))
+	if err != nil {
+		return Import{}, fmt.Errorf("ldtk: %s: %w", path, err)
+	}
+	return imp, nil
+}
+
+func convertProject(p project, dir string) (Import, error) {
+	im := &importer{
+		dir:           dir,
+		tilesets:      map[int]*tilesetDef{},
+		layers:        map[int]*layerDef{},
+		tilesetSheets: map[int]int{},
+		intGridSheets: map[int]int{},
+		names:         map[string]bool{},
+		iids:          map[string]*level{},
+	}
+	for i := range p.Defs.Tilesets {
+		im.tilesets[p.Defs.Tilesets[i].UID] = &p.Defs.Tilesets[i]
+	}
+	for i := range p.Defs.Layers {
+		im.layers[p.Defs.Layers[i].UID] = &p.Defs.Layers[i]
+	}
+
+	levels := slices.Clone(p.Levels)
+	for _, w := range p.Worlds {
+		levels = append(levels, w.Levels...)
+	}
+	for i := range levels {
+		lv := &levels[i]
+		if lv.ExternalRelPath != nil && *lv.ExternalRelPath != "" {
+			raw, err := os.ReadFile(filepath.Join(dir, *lv.ExternalRelPath))
+			if err != nil {
+				return Import{}, err
+			}
+			if err := json.Unmarshal(raw, lv); err != nil {
+				return Import{}, fmt.Errorf("%s: %w", *lv.ExternalRelPath, err)
+			}
+		}
+		if slices.ContainsFunc(levels[:i], func(o level) bool { return o.Identifier == lv.Identifier }) {
+			return Import{}, fmt.Errorf("two levels are named %q", lv.Identifier)
+		}
+		im.iids[lv.IID] = lv
+	}
+	if len(levels) == 0 {
+		return Import{}, fmt.Errorf("project has no levels")
+	}
+
+	for i := range levels {
+		out, err := im.convertLevel(&levels[i])
+		if err != nil {
+			return Import{}, fmt.Errorf("level %q: %w", levels[i].Identifier, err)
+		}
+		im.imp.Levels = append(im.imp.Levels, out)
+	}
+	return im.imp, nil
+}
+
+func (im *importer) warn(msg string, args ...any) {
+	im.imp.Warnings = append(im.imp.Warnings, fmt.Sprintf(msg, args...))
+}
+
+func (im *importer) convertLevel(lv *level) (Level, error) {
+	out := Level{Name: lv.Identifier}
+	grid := 0
+	for _, li := range lv.LayerInstances {
+		if grid == 0 {
+			grid = li.GridSize
+		}
+		if li.GridSize != grid {
+			return out, fmt.Errorf("layer %q grid size %d differs from %d; use one grid size per level", li.Identifier, li.GridSize, grid)
+		}
+	}
+	if grid == 0 {
+		return out, fmt.Errorf("level has no layers")
+	}
+	if lv.PxWid%grid != 0 || lv.PxHei%grid != 0 {
+		return out, fmt.Errorf("level size %dx%d is not a multiple of the %dpx grid", lv.PxWid, lv.PxHei, grid)
+	}
+	w, h := lv.PxWid/grid, lv.PxHei/grid
+	if w <= 0 || h <= 0 || w > 0xFFFF || h > 0xFFFF {
+		return out, fmt.Errorf("level size %dx%d cells out of range", w, h)
+	}
+
+	cfg := format.MapConfig{
+		Width:       uint16(w),
+		Height:      uint16(h),
+		CellBits:    8,
+		ChunkWidth:  uint16(min(chunkCells, w)),
+		ChunkHeight: uint16(min(chunkCells, h)),
+	}
+	sheetID := func(name string) uint16 {
+		if i := slices.Index(out.Sheets, name); i >= 0 {
+			return uint16(i + 1)
+		}
+		out.Sheets = append(out.Sheets, name)
+		return uint16(len(out.Sheets))
+	}
+
+	// LDtk lists layers top first; maps draw the first layer at the back.
+	for i := len(lv.LayerInstances) - 1; i >= 0; i-- {
+		li := lv.LayerInstances[i]
+		if li.PxOffsetX != 0 || li.PxOffsetY != 0 {
+			return out, fmt.Errorf("layer %q: layer offsets are not supported", li.Identifier)
+		}
+		if li.CWid != w || li.CHei != h {
+			return out, fmt.Errorf("layer %q is %dx%d cells, want %dx%d", li.Identifier, li.CWid, li.CHei, w, h)
+		}
+		switch li.Type {
+		case "Entities":
+			objects, err := convertEntities(li)
+			if err != nil {
+				return out, err
+			}
+			cfg.ObjectLayers = append(cfg.ObjectLayers, objects)
+		case "IntGrid":
+			if len(li.AutoLayerTiles) > 0 {
+				layers, err := im.tileLayers(li, li.Identifier+"_tiles", li.AutoLayerTiles, sheetID)
+				if err != nil {
+					return out, err
+				}
+				cfg.Layers = append(cfg.Layers, layers...)
+			}
+			layer, err := im.intGridLayer(li, sheetID)
+			if err != nil {
+				return out, err
+			}
+			cfg.Layers = append(cfg.Layers, layer)
+		case "Tiles", "AutoLayer":
+			tiles := li.GridTiles
+			if li.Type == "AutoLayer" {
+				tiles = li.AutoLayerTiles
+			}
+			layers, err := im.tileLayers(li, li.Identifier, tiles, sheetID)
+			if err != nil {
+				return out, err
+			}
+			cfg.Layers = append(cfg.Layers, layers...)
+		default:
+			return out, fmt.Errorf("layer %q: %s layers are not supported", li.Identifier, li.Type)
+		}
+	}
+	if len(cfg.Layers) == 0 {
+		return out, fmt.Errorf("level has no tile or IntGrid layers")
+	}
+	for _, layer := range cfg.Layers {
+		if len(layer.Flips) > 0 || slices.ContainsFunc(layer.Cells, func(c uint16) bool { return c > 0xFF }) {
+			cfg.CellBits = 16
+		}
+	}
+	cfg.LayerCount = uint16(len(cfg.Layers))
+
+	for _, n := range lv.Neighbours {
+		other, ok := im.iids[n.LevelIID]
+		if !ok {
+			return out, fmt.Errorf("neighbour %s is not in the project", n.LevelIID)
+		}
+		cfg.Links = append(cfg.Links, format.MapLinkConfig{
+			Dir:     n.Dir,
+			Map:     other.Identifier,
+			OffsetX: int32(other.WorldX - lv.WorldX),
+			OffsetY: int32(other.WorldY - lv.WorldY),
+		})
+	}
+	if len(lv.FieldInstances) > 0 {
+		im.warn("level %q: level fields are not stored in maps", lv.Identifier)
+	}
+	out.Map = cfg
+	return out, nil
+}
+
+// tileLayers places tiles on a layer. Cells that stack several tiles, as
+// auto-layer rules often do, spill into extra layers above it.
+func (im *importer) tileLayers(li layerInstance, name string, tiles []tileInst, sheetID func(string) uint16) ([]format.MapLayerConfig, error) {
+	if len(tiles) == 0 {
+		return nil, nil
+	}
+	if li.TilesetDefUID == nil {
+		return nil, fmt.Errorf("layer %q has tiles but no tileset", li.Identifier)
+	}
+	sheet, err := im.tilesetSheet(*li.TilesetDefUID, li.GridSize)
+	if err != nil {
+		return nil, fmt.Errorf("layer %q: %w", li.Identifier, err)
+	}
+	id := sheetID(sheet.Name)
+	var layers []format.MapLayerConfig
+	count := li.CWid * li.CHei
+	tileCount := (sheet.Image.Bounds().Dx() / sheet.TileWidth) * (sheet.Image.Bounds().Dy() / sheet.TileHeight)
+
+	for _, t := range tiles {
+		if t.Px[0]%li.GridSize != 0 || t.Px[1]%li.GridSize != 0 {
+			return nil, fmt.Errorf("layer %q: tile at %d,%d is off the grid", li.Identifier, t.Px[0], t.Px[1])
+		}
+		cx, cy := t.Px[0]/li.GridSize, t.Px[1]/li.GridSize
+		if cx < 0 || cy < 0 || cx >= li.CWid || cy >= li.CHei {
+			continue
+		}
+		if t.T < 0 || t.T >= tileCount {
+			return nil, fmt.Errorf("layer %q: tile %d is beyond tileset %q's %d tiles", li.Identifier, t.T, sheet.Name, tileCount)
+		}
+		cell := cy*li.CWid + cx
+		n := 0
+		for n < len(layers) && layers[n].Cells[cell] != 0 {
+			n++
+		}
+		if n == len(layers) {
+			layerName := name
+			if n > 0 {
+				layerName = name + "_" + strconv.Itoa(n+1)
+			}
+			layers = append(layers, format.MapLayerConfig{
+				Name:    layerName,
+				SheetID: id,
+				Hidden:  !li.Visible,
+				Cells:   make([]uint16, count),
+			})
+		}
+		layers[n].Cells[cell] = uint16(t.T + 1)
+		if t.F != 0 {
+			if layers[n].Flips == nil {
+				layers[n].Flips = make([]uint8, count)
+			}
+			// LDtk flips: bit 0 mirrors X, bit 1 mirrors Y.
+			if t.F&1 != 0 {
+				layers[n].Flips[cell] |= format.CellFlipH
+			}
+			if t.F&2 != 0 {
+				layers[n].Flips[cell] |= format.CellFlipV
+			}
+		}
+	}
+	return layers, nil
+}
+
+// intGridLayer stores IntGrid values as a hidden layer. Tile N of its
+// generated sheet stands for the layer's Nth value, filled with the value's
+// color; its identifier becomes the tile's collision shape when it names
+// one, such as "solid" or "one_way".
+func (im *importer) intGridLayer(li layerInstance, sheetID func(string) uint16) (format.MapLayerConfig, error) {
+	layer := format.MapLayerConfig{Name: li.Identifier, Hidden: true}
+	def, ok := im.layers[li.LayerDefUID]
+	if !ok {
+		return layer, fmt.Errorf("layer %q has no definition", li.Identifier)
+	}
+	if len(def.IntGridValues) == 0 {
+		return layer, fmt.Errorf("layer %q: IntGrid layer defines no values", li.Identifier)
+	}
+	if len(li.IntGridCSV) != li.CWid*li.CHei {
+		return layer, fmt.Errorf("layer %q has %d IntGrid cells, want %d", li.Identifier, len(li.IntGridCSV), li.CWid*li.CHei)
+	}
+
+	if _, ok := im.intGridSheets[def.UID]; !ok {
+		sheet, err := intGridSheet(def, li.GridSize)
+		if err != nil {
+			return layer, fmt.Errorf("layer %q: %w", li.Identifier, err)
+		}
+		if err := im.addSheet(sheet); err != nil {
+			return layer, err
+		}
+		im.intGridSheets[def.UID] = len(im.imp.Sheets) - 1
+	}
+	sheet := im.imp.Sheets[im.intGridSheets[def.UID]]
+	if sheet.TileWidth != li.GridSize {
+		return layer, fmt.Errorf("layer %q: sheet %q is already %dpx", li.Identifier, sheet.Name, sheet.TileWidth)
+	}
+	layer.SheetID = sheetID(sheet.Name)
+
+	layer.Cells = make([]uint16, len(li.IntGridCSV))
+	for i, v := range li.IntGridCSV {
+		if v == 0 {
+			continue
+		}
+		idx := slices.IndexFunc(def.IntGridValues, func(d intGridValue) bool { return d.Value == v })
+		if idx < 0 {
+			return layer, fmt.Errorf("layer %q: IntGrid value %d is not defined", li.Identifier, v)
+		}
+		layer.Cells[i] = uint16(idx + 1)
+	}
+	return layer, nil
+}
+
+func intGridSheet(def *layerDef, grid int) (Sheet, error) {
+	sheet := Sheet{Name: def.Identifier, TileWidth: grid, TileHeight: grid}
+	img := image.NewNRGBA(image.Rect(0, 0, grid*len(def.IntGridValues), grid))
+	for i, v := range def.IntGridValues {
+		c, err := parseColor(v.Color)
+		if err != nil {
+			return sheet, fmt.Errorf("IntGrid value %d: %w", v.Value, err)
+		}
+		for y := 0; y < grid; y++ {
+			for x := i * grid; x < (i+1)*grid; x++ {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+		tile := format.TilePropConfig{
+			ID:    uint16(i + 1),
+			Props: map[string]any{"value": v.Value},
+		}
+		if v.Identifier != "" {
+			tile.Props["name"] = v.Identifier
+		}
+		if slices.Contains(format.TileShapeNames, v.Identifier) {
+			tile.Shape = v.Identifier
+		}
+		sheet.Props.Tiles = append(sheet.Props.Tiles, tile)
+	}
+	sheet.Image = img
+	return sheet, nil
+}
+
+// tilesetSheet converts a tileset the first time a layer uses it. Enum
+// tags become tile flags and custom data the tile's "data" prop.
+func (im *importer) tilesetSheet(uid, grid int) (Sheet, error) {
+	def, ok := im.tilesets[uid]
+	if !ok {
+		return Sheet{}, fmt.Errorf("tileset %d is not defined", uid)
+	}
+	if idx, ok := im.tilesetSheets[uid]; ok {
+		return im.imp.Sheets[idx], nil
+	}
+	switch {
+	case def.EmbedAtlas != nil:
+		return Sheet{}, fmt.Errorf("tileset %q: the embedded LDtk atlas is not supported", def.Identifier)
+	case def.RelPath == nil || *def.RelPath == "":
+		return Sheet{}, fmt.Errorf("tileset %q has no image", def.Identifier)
+	case def.Spacing != 0 || def.Padding != 0:
+		return Sheet{}, fmt.Errorf("tileset %q: tile spacing and padding are not supported", def.Identifier)
+	case def.TileGridSize != grid:
+		return Sheet{}, fmt.Errorf("tileset %q tile size %d differs from the %dpx layer grid", def.Identifier, def.TileGridSize, grid)
+	}
+
+	f, err := os.Open(filepath.Join(im.dir, *def.RelPath))
+	if err != nil {
+		return Sheet{}, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return Sheet{}, fmt.Errorf("tileset %q: %s: %w", def.Identifier, *def.RelPath, err)
+	}
+	// LDtk ignores a partial tile at the right or bottom edge.
+	b := img.Bounds()
+	if w, h := def.CWid*grid, def.CHei*grid; w > 0 && h > 0 && (w != b.Dx() || h != b.Dy()) {
+		if sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			img = sub.SubImage(image.Rect(b.Min.X, b.Min.Y, b.Min.X+w, b.Min.Y+h))
+		}
+	}
+	sheet := Sheet{Name: def.Identifier, Image: img, TileWidth: grid, TileHeight: grid}
+
+	tiles := map[int]*format.TilePropConfig{}
+	tile := func(id int) *format.TilePropConfig {
+		if tiles[id] == nil {
+			tiles[id] = &format.TilePropConfig{ID: uint16(id + 1)}
+		}
+		return tiles[id]
+	}
+	for _, tag := range def.EnumTags {
+		if len(tag.TileIDs) == 0 {
+			continue
+		}
+		sheet.Props.Flags = append(sheet.Props.Flags, tag.EnumValueID)
+		for _, id := range tag.TileIDs {
+			t := tile(id)
+			t.Flags = append(t.Flags, tag.EnumValueID)
+		}
+	}
+	for _, cd := range def.CustomData {
+		t := tile(cd.TileID)
+		t.Props = map[string]any{"data": cd.Data}
+	}
+	ids := make([]int, 0, len(tiles))
+	for id := range tiles {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		sheet.Props.Tiles = append(sheet.Props.Tiles, *tiles[id])
+	}
+
+	if err := im.addSheet(sheet); err != nil {
+		return Sheet{}, err
+	}
+	im.tilesetSheets[uid] = len(im.imp.Sheets) - 1
+	return sheet, nil
+}
+
+// addSheet keeps sheet names unique, ignoring case, since each becomes a
+// file name.
+func (im *importer) addSheet(sheet Sheet) error {
+	key := strings.ToLower(sheet.Name)
+	if im.names[key] {
+		return fmt.Errorf("two sheets are named %q; rename a tileset or IntGrid layer", sheet.Name)
+	}
+	im.names[key] = true
+	im.imp.Sheets = append(im.imp.Sheets, sheet)
+	return nil
+}
+
+// convertEntities turns entities into rect objects covering their bounds.
+// An entity's iid is its object name, so EntityRef fields, which hold the
+// target's iid, work with Map.ObjectByName.
+func convertEntities(li layerInstance) (format.MapObjectLayerConfig, error) {
+	out := format.MapObjectLayerConfig{Name: li.Identifier, Objects: make([]format.MapObjectConfig, 0, len(li.Entities))}
+	for _, e := range li.Entities {
+		obj := format.MapObjectConfig{
+			Name: e.IID,
+			Type: e.Identifier,
+			Kind: "rect",
+			X:    float32(e.Px[0] - e.Pivot[0]*e.Width),
+			Y:    float32(e.Px[1] - e.Pivot[1]*e.Height),
+			W:    float32(e.Width),
+			H:    float32(e.Height),
+		}
+		for _, f := range e.Fields {
+			v, ok, err := fieldValue(f)
+			if err != nil {
+				return out, fmt.Errorf("layer %q: entity %s %s: %w", li.Identifier, e.Identifier, e.IID, err)
+			}
+			if !ok {
+				continue
+			}
+			if obj.Props == nil {
+				obj.Props = map[string]any{}
+			}
+			obj.Props[f.Identifier] = v
+		}
+		out.Objects = append(out.Objects, obj)
+	}
+	return out, nil
+}
+
+// fieldValue types an entity field. Unset (null) fields report false.
+func fieldValue(f field) (any, bool, error) {
+	if f.Value == nil {
+		return nil, false, nil
+	}
+	typ := f.Type
+	if strings.HasPrefix(typ, "LocalEnum.") || strings.HasPrefix(typ, "ExternEnum.") {
+		typ = "String"
+	}
+	switch typ {
+	case "Int":
+		if n, ok := f.Value.(float64); ok {
+			return int(n), true, nil
+		}
+	case "Float":
+		if n, ok := f.Value.(float64); ok {
+			return n, true, nil
+		}
+	case "Bool":
+		if b, ok := f.Value.(bool); ok {
+			return b, true, nil
+		}
+	case "String", "Multilines", "Color", "FilePath":
+		if s, ok := f.Value.(string); ok {
+			return s, true, nil
+		}
+	case "EntityRef":
+		if ref, ok := f.Value.(map[string]any); ok {
+			if iid, ok := ref["entityIid"].(string); ok {
+				return iid, true, nil
+			}
+		}
+	default:
+		return nil, false, fmt.Errorf("field %q: %s fields are not supported", f.Identifier, f.Type)
+	}
+	return nil, false, fmt.Errorf("field %q: unexpected %T value for %s", f.Identifier, f.Value, f.Type)
+}
+
+// parseColor reads an LDtk "#RRGGBB" color.
+func parseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.NRGBA{}, fmt.Errorf("bad color %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}, nil
+}
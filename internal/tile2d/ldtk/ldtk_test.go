@@ -0,0 +1,232 @@
+package ldtk
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// testProject has two 2x2-cell levels side by side. Level_0 holds an
+// entity layer, an IntGrid layer with auto-tiles and a tile layer whose
+// top-left cell stacks two tiles, listed top layer first as LDtk does.
+const testProject = `{
+ "defs": {
+  "layers": [
+   {"uid": 1, "identifier": "Collisions", "type": "IntGrid", "intGridValues": [
+    {"value": 1, "identifier": "solid", "color": "#FF0000"},
+    {"value": 3, "identifier": "water", "color": "#0000FF"}]}
+  ],
+  "tilesets": [
+   {"uid": 7, "identifier": "Tiles", "relPath": "tiles.png", "tileGridSize": 8, "__cWid": 2, "__cHei": 2,
+    "enumTags": [{"enumValueId": "Grass", "tileIds": [1, 2]}, {"enumValueId": "Unused", "tileIds": []}],
+    "customData": [{"tileId": 3, "data": "door"}]}
+  ]
+ },
+ "levels": [
+  {"identifier": "Level_0", "iid": "a", "worldX": 0, "worldY": 0, "pxWid": 16, "pxHei": 16,
+   "fieldInstances": [{"__identifier": "music", "__type": "String", "__value": "cave"}],
+   "__neighbours": [{"levelIid": "b", "dir": "e"}],
+   "layerInstances": [
+    {"__identifier": "Entities", "__type": "Entities", "__cWid": 2, "__cHei": 2, "__gridSize": 8, "visible": true,
+     "entityInstances": [
+      {"__identifier": "Player", "iid": "p1", "__pivot": [0.5, 1], "px": [8, 16], "width": 8, "height": 16,
+       "fieldInstances": [
+        {"__identifier": "hp", "__type": "Int", "__value": 3},
+        {"__identifier": "speed", "__type": "Float", "__value": 1.5},
+        {"__identifier": "kind", "__type": "LocalEnum.Kind", "__value": "Hero"},
+        {"__identifier": "target", "__type": "EntityRef", "__value": {"entityIid": "d1"}},
+        {"__identifier": "unset", "__type": "String", "__value": null}]}]},
+    {"__identifier": "Collisions", "__type": "IntGrid", "__cWid": 2, "__cHei": 2, "__gridSize": 8,
+     "layerDefUid": 1, "__tilesetDefUid": 7, "visible": true, "intGridCsv": [1, 0, 3, 1],
+     "autoLayerTiles": [{"px": [0, 0], "f": 0, "t": 0}]},
+    {"__identifier": "Ground", "__type": "Tiles", "__cWid": 2, "__cHei": 2, "__gridSize": 8,
+     "__tilesetDefUid": 7, "visible": true,
+     "gridTiles": [{"px": [0, 0], "f": 0, "t": 1}, {"px": [8, 8], "f": 3, "t": 3}, {"px": [0, 0], "f": 1, "t": 2}]}
+   ]},
+  {"identifier": "Level_1", "iid": "b", "worldX": 16, "worldY": -8, "pxWid": 16, "pxHei": 16,
+   "__neighbours": [{"levelIid": "a", "dir": "w"}],
+   "layerInstances": [
+    {"__identifier": "Collisions", "__type": "IntGrid", "__cWid": 2, "__cHei": 2, "__gridSize": 8,
+     "layerDefUid": 1, "visible": true, "intGridCsv": [0, 0, 1, 1]}
+   ]}
+ ]
+}`
+
+func writeProject(t *testing.T, project string) string {
+	t.Helper()
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, 16, 16))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tiles.png"), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "world.ldtk")
+	if err := os.WriteFile(path, []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportProjectConvertsLevels(t *testing.T) {
+	imp, err := ImportProject(writeProject(t, testProject))
+	if err != nil {
+		t.Fatalf("ImportProject() error = %v", err)
+	}
+
+	if len(imp.Sheets) != 2 || imp.Sheets[0].Name != "Tiles" || imp.Sheets[1].Name != "Collisions" {
+		t.Fatalf("sheets = %+v, want Tiles then Collisions", imp.Sheets)
+	}
+	if len(imp.Levels) != 2 {
+		t.Fatalf("len(Levels) = %d, want 2", len(imp.Levels))
+	}
+	lv := imp.Levels[0]
+	if lv.Name != "Level_0" || !slices.Equal(lv.Sheets, []string{"Tiles", "Collisions"}) {
+		t.Fatalf("level = %s with sheets %v, want Level_0 with Tiles, Collisions", lv.Name, lv.Sheets)
+	}
+
+	m := lv.Map
+	var names []string
+	for _, l := range m.Layers {
+		names = append(names, l.Name)
+	}
+	if want := []string{"Ground", "Ground_2", "Collisions_tiles", "Collisions"}; !slices.Equal(names, want) {
+		t.Fatalf("layers = %v, want %v", names, want)
+	}
+	if m.Width != 2 || m.LayerCount != 4 || m.CellBits != 16 {
+		t.Fatalf("map = %d wide, %d layers, %d-bit; want 2, 4, 16-bit", m.Width, m.LayerCount, m.CellBits)
+	}
+	ground, stacked := m.Layers[0], m.Layers[1]
+	if !slices.Equal(ground.Cells, []uint16{2, 0, 0, 4}) || ground.Flips[3] != format.CellFlipH|format.CellFlipV {
+		t.Fatalf("ground = %v flips %v, want tiles 2 and 4 with 4 flipped both ways", ground.Cells, ground.Flips)
+	}
+	if !slices.Equal(stacked.Cells, []uint16{3, 0, 0, 0}) || stacked.Flips[0] != format.CellFlipH {
+		t.Fatalf("stacked = %v flips %v, want tile 3 mirrored in the corner", stacked.Cells, stacked.Flips)
+	}
+	grid := m.Layers[3]
+	if !grid.Hidden || grid.SheetID != 2 || !slices.Equal(grid.Cells, []uint16{1, 0, 2, 1}) {
+		t.Fatalf("IntGrid layer = %+v, want hidden on sheet 2 with values as tiles 1 and 2", grid)
+	}
+	if m.Layers[2].Hidden || m.Layers[2].SheetID != 1 {
+		t.Fatalf("auto-tile layer = %+v, want visible on sheet 1", m.Layers[2])
+	}
+
+	objs := m.ObjectLayers[0].Objects
+	if m.ObjectLayers[0].Name != "Entities" || len(objs) != 1 {
+		t.Fatalf("object layers = %+v, want one entity", m.ObjectLayers)
+	}
+	p := objs[0]
+	if p.Name != "p1" || p.Type != "Player" || p.Kind != "rect" || p.X != 4 || p.Y != 0 || p.H != 16 {
+		t.Fatalf("player = %+v, want a Player rect at 4,0 16 high", p)
+	}
+	if p.Props["hp"] != 3 || p.Props["speed"] != 1.5 || p.Props["kind"] != "Hero" || p.Props["target"] != "d1" {
+		t.Fatalf("player props = %v", p.Props)
+	}
+	if _, ok := p.Props["unset"]; ok {
+		t.Fatal("null field was stored")
+	}
+
+	if want := (format.MapLinkConfig{Dir: "e", Map: "Level_1", OffsetX: 16, OffsetY: -8}); len(m.Links) != 1 || m.Links[0] != want {
+		t.Fatalf("links = %+v, want %+v", m.Links, want)
+	}
+	if back := imp.Levels[1].Map.Links; len(back) != 1 || back[0].OffsetX != -16 || back[0].OffsetY != 8 {
+		t.Fatalf("Level_1 links = %+v, want one back to Level_0", back)
+	}
+	if !slices.Equal(imp.Levels[1].Sheets, []string{"Collisions"}) {
+		t.Fatalf("Level_1 sheets = %v, want only Collisions", imp.Levels[1].Sheets)
+	}
+
+	tiles := imp.Sheets[0].Props
+	if !slices.Equal(tiles.Flags, []string{"Grass"}) || len(tiles.Tiles) != 3 || tiles.Tiles[2].Props["data"] != "door" {
+		t.Fatalf("tileset props = %+v, want the Grass flag and door data", tiles)
+	}
+	collisions := imp.Sheets[1]
+	if collisions.Props.Tiles[0].Shape != "solid" || collisions.Props.Tiles[1].Shape != "" || collisions.Props.Tiles[1].Props["value"] != 3 {
+		t.Fatalf("IntGrid props = %+v, want solid then water with value 3", collisions.Props.Tiles)
+	}
+	if r, _, _, _ := collisions.Image.At(0, 0).RGBA(); r != 0xFFFF {
+		t.Fatal("IntGrid tile 1 is not filled with its color")
+	}
+	if len(imp.Warnings) != 1 || !strings.Contains(imp.Warnings[0], "level fields") {
+		t.Fatalf("warnings = %v, want one about level fields", imp.Warnings)
+	}
+
+	for _, l := range imp.Levels {
+		if _, err := format.BuildMap(l.Map); err != nil {
+			t.Fatalf("BuildMap(%s) error = %v", l.Name, err)
+		}
+	}
+	for _, s := range imp.Sheets {
+		if _, err := format.BuildSheetWithProps(s.Image, s.TileWidth, s.TileHeight, s.Props); err != nil {
+			t.Fatalf("BuildSheetWithProps(%s) error = %v", s.Name, err)
+		}
+	}
+}
+
+func TestImportProjectReadsExternalLevels(t *testing.T) {
+	path := writeProject(t, `{"defs": {"layers": [{"uid": 1, "identifier": "Walls", "type": "IntGrid",
+  "intGridValues": [{"value": 1, "identifier": "solid", "color": "#FFFFFF"}]}]},
+ "worlds": [{"levels": [{"identifier": "Room", "iid": "r", "externalRelPath": "world/Room.ldtkl"}]}]}`)
+	dir := filepath.Join(filepath.Dir(path), "world")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	level := `{"identifier": "Room", "iid": "r", "pxWid": 8, "pxHei": 8, "layerInstances": [
+ {"__identifier": "Walls", "__type": "IntGrid", "__cWid": 1, "__cHei": 1, "__gridSize": 8, "layerDefUid": 1, "intGridCsv": [1]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "Room.ldtkl"), []byte(level), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	imp, err := ImportProject(path)
+	if err != nil {
+		t.Fatalf("ImportProject() error = %v", err)
+	}
+	if len(imp.Levels) != 1 || imp.Levels[0].Map.Layers[0].Cells[0] != 1 {
+		t.Fatalf("levels = %+v, want Room with one solid cell", imp.Levels)
+	}
+}
+
+func TestImportProjectRejectsUnsupportedFeatures(t *testing.T) {
+	level := func(layers string) string {
+		return `{"defs": {"layers": [{"uid": 1, "identifier": "Walls", "type": "IntGrid", "intGridValues": [{"value": 1, "color": "#FFFFFF"}]}],
+  "tilesets": [{"uid": 7, "identifier": "Tiles", "relPath": "tiles.png", "tileGridSize": 8},
+               {"uid": 8, "identifier": "Icons", "embedAtlas": "LdtkIcons", "tileGridSize": 8},
+               {"uid": 9, "identifier": "Big", "relPath": "tiles.png", "tileGridSize": 16},
+               {"uid": 10, "identifier": "Walls", "relPath": "tiles.png", "tileGridSize": 8}]},
+ "levels": [{"identifier": "L", "iid": "l", "pxWid": 16, "pxHei": 8, "layerInstances": [` + layers + `]}]}`
+	}
+	const cells = `"__cWid": 2, "__cHei": 1, "__gridSize": 8`
+
+	tests := []struct {
+		name, project, want string
+	}{
+		{"no levels", `{"levels": []}`, "project has no levels"},
+		{"offset", level(`{"__identifier": "G", "__type": "Tiles", ` + cells + `, "pxOffsetX": 4}`), "layer offsets are not supported"},
+		{"grid sizes", level(`{"__identifier": "A", "__type": "Tiles", ` + cells + `}, {"__identifier": "B", "__type": "Tiles", "__gridSize": 16}`), "use one grid size per level"},
+		{"embedded atlas", level(`{"__identifier": "G", "__type": "Tiles", ` + cells + `, "__tilesetDefUid": 8, "gridTiles": [{"px": [0, 0], "t": 0}]}`), "embedded LDtk atlas is not supported"},
+		{"tileset size", level(`{"__identifier": "G", "__type": "Tiles", ` + cells + `, "__tilesetDefUid": 9, "gridTiles": [{"px": [0, 0], "t": 0}]}`), "differs from the 8px layer grid"},
+		{"off grid", level(`{"__identifier": "G", "__type": "Tiles", ` + cells + `, "__tilesetDefUid": 7, "gridTiles": [{"px": [3, 0], "t": 0}]}`), "is off the grid"},
+		{"undefined value", level(`{"__identifier": "W", "__type": "IntGrid", ` + cells + `, "layerDefUid": 1, "intGridCsv": [1, 2]}`), "IntGrid value 2 is not defined"},
+		{"point field", level(`{"__identifier": "E", "__type": "Entities", ` + cells + `, "entityInstances": [{"__identifier": "Npc", "iid": "n",
+			"fieldInstances": [{"__identifier": "goal", "__type": "Point", "__value": {"cx": 1, "cy": 0}}]}]}`), `field "goal": Point fields are not supported`},
+		{"only entities", level(`{"__identifier": "E", "__type": "Entities", ` + cells + `}`), "level has no tile or IntGrid layers"},
+		{"sheet names", level(`{"__identifier": "G", "__type": "Tiles", ` + cells + `, "__tilesetDefUid": 10, "gridTiles": [{"px": [0, 0], "t": 0}]},
+			{"__identifier": "W", "__type": "IntGrid", ` + cells + `, "layerDefUid": 1, "intGridCsv": [1, 0]}`), `two sheets are named "Walls"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ImportProject(writeProject(t, tt.project))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ImportProject() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package loader
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
 )
@@ -30,6 +31,43 @@ func LoadMap(path string, l Loader) (format.ParsedMap, error) {
 	return format.ParseMap(raw)
 }
 
+// OpenMap reads a map through l without loading the chunks of a streamed
+// map. The index is nil for other maps, which are read whole.
+func OpenMap(path string, l RangeLoader) (format.ParsedMap, *format.MapChunkIndex, error) {
+	size, err := l.AssetSize(path)
+	if err != nil {
+		return format.ParsedMap{}, nil, err
+	}
+	return format.ParseMapAt(io.NewSectionReader(assetReader{path: path, loader: l}, 0, size))
+}
+
+// ReadChunk reads the data of chunk (cx, cy) of the streamed map at path.
+func ReadChunk(path string, l RangeLoader, index *format.MapChunkIndex, cx, cy int) ([]byte, error) {
+	off, size := index.Span(cx, cy)
+	if size == 0 {
+		return nil, nil
+	}
+	data := make([]byte, size)
+	n, err := l.ReadAssetAt(path, data, off)
+	if n == size {
+		return data, nil
+	}
+	if err == nil || err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return nil, fmt.Errorf("loader: chunk %d,%d of %q: %w", cx, cy, path, err)
+}
+
+// assetReader adapts one asset of a RangeLoader to io.ReaderAt.
+type assetReader struct {
+	path   string
+	loader RangeLoader
+}
+
+func (r assetReader) ReadAt(p []byte, off int64) (int, error) {
+	return r.loader.ReadAssetAt(r.path, p, off)
+}
+
 func LoadAnim(path string, l Loader) (format.ParsedAnim, error) {
 	raw, err := l.ReadAsset(path)
 	if err != nil {
@@ -1,11 +1,22 @@
 package loader
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+)
 
 type Loader interface {
 	ReadAsset(path string) ([]byte, error)
 }
 
+// RangeLoader is a Loader that can also read part of an asset. Streamed
+// maps opened through one load a chunk at a time instead of whole.
+type RangeLoader interface {
+	Loader
+	ReadAssetAt(path string, p []byte, off int64) (int, error)
+	AssetSize(path string) (int64, error)
+}
+
 type MemoryLoader struct {
 	assets map[string][]byte
 }
@@ -25,3 +36,26 @@ func (m MemoryLoader) ReadAsset(path string) ([]byte, error) {
 	}
 	return append([]byte(nil), raw...), nil
 }
+
+func (m MemoryLoader) AssetSize(path string) (int64, error) {
+	raw, ok := m.assets[path]
+	if !ok {
+		return 0, fmt.Errorf("loader: asset %q not found", path)
+	}
+	return int64(len(raw)), nil
+}
+
+func (m MemoryLoader) ReadAssetAt(path string, p []byte, off int64) (int, error) {
+	raw, ok := m.assets[path]
+	if !ok {
+		return 0, fmt.Errorf("loader: asset %q not found", path)
+	}
+	if off < 0 || off > int64(len(raw)) {
+		return 0, fmt.Errorf("loader: offset %d outside asset %q", off, path)
+	}
+	n := copy(p, raw[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
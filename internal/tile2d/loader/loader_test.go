@@ -294,3 +294,43 @@ func manifestPath(t *testing.T, b format.ParsedBundle, kind uint8) string {
 	t.Fatalf("missing manifest entry kind %d", kind)
 	return ""
 }
+
+func TestOpenMapReadsStreamedChunksOnDemand(t *testing.T) {
+	cells := make([]uint16, 16*8)
+	for i := range cells {
+		cells[i] = uint16(i%16) + 1
+	}
+	raw, err := format.BuildMap(format.MapConfig{
+		Width: 16, Height: 8, LayerCount: 1, CellBits: 8, ChunkWidth: 8, ChunkHeight: 8,
+		Streamed: true,
+		Layers:   []format.MapLayerConfig{{Cells: cells}},
+	})
+	if err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	l := NewMemoryLoader(map[string][]byte{"level.map": raw})
+
+	m, index, err := OpenMap("level.map", l)
+	if err != nil {
+		t.Fatalf("OpenMap() error = %v", err)
+	}
+	if index == nil || !m.Streamed || m.Layers[0].Cells != nil {
+		t.Fatalf("OpenMap() = %+v, %v; want an index and no cells", m, index)
+	}
+	data, err := ReadChunk("level.map", l, index, 1, 0)
+	if err != nil {
+		t.Fatalf("ReadChunk() error = %v", err)
+	}
+	chunk, err := index.Decode(1, 0, data)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if chunk.X != 8 || chunk.Cells[0][0] != 9 || chunk.Cells[0][7] != 16 {
+		t.Fatalf("chunk = %+v, want cells 9..16 at x=8", chunk)
+	}
+
+	l.assets["level.map"] = raw[:len(raw)-4]
+	if _, err := ReadChunk("level.map", l, index, 1, 0); err == nil {
+		t.Fatal("expected error for a truncated chunk")
+	}
+}
@@ -2,6 +2,8 @@ package render
 
 import (
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/clktmr/n64/rcp/texture"
 	"github.com/drpaneas/gosprite64/internal/tile2d/visibility"
@@ -12,6 +14,20 @@ type ExecutionBridge interface {
 	DrawPreparedTile(x, y, width, height int, tile PreparedTile)
 }
 
+// LayerStyleBridge is implemented by executors that can tint a layer.
+// The renderer calls SetLayerTint before each layer it draws; a zero
+// tint means the layer is drawn untinted.
+type LayerStyleBridge interface {
+	SetLayerTint(tint color.RGBA)
+}
+
+// QuadBridge is implemented by executors that can draw a tile scaled and
+// rotated into an arbitrary screen quad. Corners run top-left, top-right,
+// bottom-right, bottom-left in logical screen pixels.
+type QuadBridge interface {
+	DrawPreparedQuad(corners [4][2]float32, tile PreparedTile)
+}
+
 type RenderHooks struct {
 	Executor ExecutionBridge
 }
@@ -35,12 +51,26 @@ type PreparedLayer struct {
 	Map   visibility.MapInfo
 	Draws []PreparedDraw
 	Runs  []PreparedRun
+
+	Hidden  bool
+	OffsetX int
+	OffsetY int
+	Tint    color.RGBA
+
+	// When Parallax is set, the camera position is scaled by ScrollX and
+	// ScrollY before the layer is culled and drawn.
+	Parallax bool
+	ScrollX  float32
+	ScrollY  float32
 }
 
 type PreparedTile struct {
 	TileID  uint16
 	SheetID uint16
 	Source  image.Image
+
+	// Flip holds the cell's FlipH, FlipV and FlipD bits.
+	Flip uint8
 }
 
 type PreparedDraw struct {
@@ -68,82 +98,186 @@ func (r *Renderer) SetHooks(hooks RenderHooks) {
 }
 
 func (r *Renderer) DrawPreparedScene(scene PreparedScene, cam visibility.Camera) DrawStats {
+	return r.DrawPreparedLayers(scene, cam, 0, len(scene.Layers)-1)
+}
+
+func (r *Renderer) DrawPreparedLayers(scene PreparedScene, cam visibility.Camera, from, to int) DrawStats {
 	var stats DrawStats
-	var lastSource *texture.Texture
-	havePreparedSource := false
-
-	for _, layer := range scene.Layers {
-		bounds := visibility.VisibleCellBounds(cam, layer.Map)
-		if len(layer.Runs) > 0 {
-			for _, run := range layer.Runs {
-				if run.CellY < bounds.MinY || run.CellY >= bounds.MaxY {
-					continue
-				}
-				runMinX := run.CellX
-				runMaxX := run.CellX + run.Count
-				if runMaxX <= bounds.MinX || runMinX >= bounds.MaxX {
-					continue
-				}
-				if run.Tile.TileID == 0 {
-					continue
-				}
-				if src, ok := (TexturedExecutor{}).SourceTexture(run.Tile); ok {
-					if !havePreparedSource || src != lastSource {
-						stats.Uploads++
-						lastSource = src
-						havePreparedSource = true
-					}
-				}
+	if r == nil {
+		return stats
+	}
+	from = max(from, 0)
+	to = min(to, len(scene.Layers)-1)
 
-				startX := max(runMinX, bounds.MinX)
-				endX := min(runMaxX, bounds.MaxX)
-				clippedRun := run
-				clippedRun.CellX = startX
-				clippedRun.Count = endX - startX
-				if r.hooks.Executor != nil {
-					r.hooks.Executor.DrawPreparedRun(
-						(startX*layer.Map.TileWidth)-cam.X,
-						(run.CellY*layer.Map.TileHeight)-cam.Y,
-						layer.Map.TileWidth,
-						layer.Map.TileHeight,
-						clippedRun,
-					)
-				}
-				stats.VisibleTiles += clippedRun.Count
-			}
+	uploads := uploadTracker{}
+	for i := from; i <= to; i++ {
+		layer := scene.Layers[i]
+		if layer.Hidden {
 			continue
 		}
-		if len(layer.Draws) > 0 {
-			for _, draw := range layer.Draws {
-				if draw.CellX < bounds.MinX || draw.CellX >= bounds.MaxX || draw.CellY < bounds.MinY || draw.CellY >= bounds.MaxY {
-					continue
-				}
-				if draw.Tile.TileID == 0 {
-					continue
-				}
-				if src, ok := (TexturedExecutor{}).SourceTexture(draw.Tile); ok {
-					if !havePreparedSource || src != lastSource {
-						stats.Uploads++
-						lastSource = src
-						havePreparedSource = true
-					}
-				}
-				if r.hooks.Executor != nil {
-					r.hooks.Executor.DrawPreparedTile(
-						(draw.CellX*layer.Map.TileWidth)-cam.X,
-						(draw.CellY*layer.Map.TileHeight)-cam.Y,
-						layer.Map.TileWidth,
-						layer.Map.TileHeight,
-						draw.Tile,
-					)
+		if styler, ok := r.hooks.Executor.(LayerStyleBridge); ok {
+			styler.SetLayerTint(layer.Tint)
+		}
+		r.drawLayer(layer, cam, &stats, &uploads)
+	}
+	return stats
+}
+
+type uploadTracker struct {
+	last *texture.Texture
+	have bool
+}
+
+func (u *uploadTracker) track(tile PreparedTile, stats *DrawStats) {
+	src, ok := (TexturedExecutor{}).SourceTexture(tile)
+	if !ok {
+		return
+	}
+	if !u.have || src != u.last {
+		stats.Uploads++
+		u.last = src
+		u.have = true
+	}
+}
+
+// LayerCamera returns cam as the layer sees it, after parallax scrolling
+// and the layer offset.
+func (l PreparedLayer) LayerCamera(cam visibility.Camera) visibility.Camera {
+	if l.Parallax {
+		cam.X = int(float32(cam.X) * l.ScrollX)
+		cam.Y = int(float32(cam.Y) * l.ScrollY)
+	}
+	// The layer offset moves the layer in world space; culling against a
+	// camera shifted the other way keeps the cell math offset-free.
+	cam.X -= l.OffsetX
+	cam.Y -= l.OffsetY
+	return cam
+}
+
+func (r *Renderer) drawLayer(layer PreparedLayer, cam visibility.Camera, stats *DrawStats, uploads *uploadTracker) {
+	cam = layer.LayerCamera(cam)
+	bounds := visibility.VisibleCellBounds(cam, layer.Map)
+	if bounds.MinX >= bounds.MaxX || bounds.MinY >= bounds.MaxY {
+		return
+	}
+	if !layer.Map.RepeatX && !layer.Map.RepeatY {
+		r.drawCells(layer, cam, bounds, stats, uploads)
+		return
+	}
+
+	// A repeating layer is drawn once per map copy the camera overlaps,
+	// each with the camera moved into that copy's cell space.
+	copiesX := [2]int{0, 0}
+	copiesY := [2]int{0, 0}
+	if layer.Map.RepeatX {
+		copiesX = [2]int{visibility.FloorDiv(bounds.MinX, layer.Map.Width), visibility.FloorDiv(bounds.MaxX-1, layer.Map.Width)}
+	}
+	if layer.Map.RepeatY {
+		copiesY = [2]int{visibility.FloorDiv(bounds.MinY, layer.Map.Height), visibility.FloorDiv(bounds.MaxY-1, layer.Map.Height)}
+	}
+	for cy := copiesY[0]; cy <= copiesY[1]; cy++ {
+		for cx := copiesX[0]; cx <= copiesX[1]; cx++ {
+			baseX := cx * layer.Map.Width
+			baseY := cy * layer.Map.Height
+			local := visibility.VisibleBounds{
+				MinX: max(bounds.MinX-baseX, 0),
+				MinY: max(bounds.MinY-baseY, 0),
+				MaxX: min(bounds.MaxX-baseX, layer.Map.Width),
+				MaxY: min(bounds.MaxY-baseY, layer.Map.Height),
+			}
+			if local.MinX >= local.MaxX || local.MinY >= local.MaxY {
+				continue
+			}
+			copyCam := cam
+			copyCam.X -= baseX * layer.Map.TileWidth
+			copyCam.Y -= baseY * layer.Map.TileHeight
+			r.drawCells(layer, copyCam, local, stats, uploads)
+		}
+	}
+}
+
+func (r *Renderer) drawCells(layer PreparedLayer, cam visibility.Camera, bounds visibility.VisibleBounds, stats *DrawStats, uploads *uploadTracker) {
+	transformed := cam.Transformed()
+	if len(layer.Runs) > 0 {
+		for _, run := range layer.Runs {
+			if run.CellY < bounds.MinY || run.CellY >= bounds.MaxY {
+				continue
+			}
+			runMinX := run.CellX
+			runMaxX := run.CellX + run.Count
+			if runMaxX <= bounds.MinX || runMinX >= bounds.MaxX {
+				continue
+			}
+			if run.Tile.TileID == 0 {
+				continue
+			}
+			uploads.track(run.Tile, stats)
+
+			startX := max(runMinX, bounds.MinX)
+			endX := min(runMaxX, bounds.MaxX)
+			clippedRun := run
+			clippedRun.CellX = startX
+			clippedRun.Count = endX - startX
+			if transformed {
+				for x := startX; x < endX; x++ {
+					r.drawTransformedTile(layer, cam, x, run.CellY, run.Tile)
 				}
-				stats.VisibleTiles++
+			} else if r.hooks.Executor != nil {
+				r.hooks.Executor.DrawPreparedRun(
+					(startX*layer.Map.TileWidth)-cam.X,
+					(run.CellY*layer.Map.TileHeight)-cam.Y,
+					layer.Map.TileWidth,
+					layer.Map.TileHeight,
+					clippedRun,
+				)
 			}
+			stats.VisibleTiles += clippedRun.Count
+		}
+		return
+	}
+
+	for _, draw := range layer.Draws {
+		if draw.CellX < bounds.MinX || draw.CellX >= bounds.MaxX || draw.CellY < bounds.MinY || draw.CellY >= bounds.MaxY {
+			continue
+		}
+		if draw.Tile.TileID == 0 {
 			continue
 		}
+		uploads.track(draw.Tile, stats)
+		if transformed {
+			r.drawTransformedTile(layer, cam, draw.CellX, draw.CellY, draw.Tile)
+		} else if r.hooks.Executor != nil {
+			r.hooks.Executor.DrawPreparedTile(
+				(draw.CellX*layer.Map.TileWidth)-cam.X,
+				(draw.CellY*layer.Map.TileHeight)-cam.Y,
+				layer.Map.TileWidth,
+				layer.Map.TileHeight,
+				draw.Tile,
+			)
+		}
+		stats.VisibleTiles++
 	}
+}
 
-	return stats
+// drawTransformedTile draws one tile through a zoomed or rotated camera.
+// Executors without QuadBridge get the tile unscaled at its top-left corner.
+func (r *Renderer) drawTransformedTile(layer PreparedLayer, cam visibility.Camera, cellX, cellY int, tile PreparedTile) {
+	if r.hooks.Executor == nil {
+		return
+	}
+	tw, th := layer.Map.TileWidth, layer.Map.TileHeight
+	x0, y0 := float32(cellX*tw), float32(cellY*th)
+	x1, y1 := x0+float32(tw), y0+float32(th)
+
+	var corners [4][2]float32
+	for i, p := range [4][2]float32{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}} {
+		corners[i][0], corners[i][1] = cam.WorldToScreen(p[0], p[1])
+	}
+	if quad, ok := r.hooks.Executor.(QuadBridge); ok {
+		quad.DrawPreparedQuad(corners, tile)
+		return
+	}
+	r.hooks.Executor.DrawPreparedTile(int(math.Floor(float64(corners[0][0]))), int(math.Floor(float64(corners[0][1]))), tw, th, tile)
 }
 
 func (r *Renderer) DrawTileLayer(scene PreparedScene, cam visibility.Camera) DrawStats {
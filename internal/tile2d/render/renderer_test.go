@@ -2,6 +2,7 @@ package render
 
 import (
 	"image"
+	"image/color"
 	"testing"
 
 	"github.com/clktmr/n64/rcp/texture"
@@ -375,6 +376,239 @@ func TestRendererUploadCountDoesNotRestartAcrossLayersForSamePreparedSource(t *t
 	}
 }
 
+type tintRecordingBridge struct {
+	testExecutionBridge
+	tints *[]color.RGBA
+}
+
+func (t tintRecordingBridge) SetLayerTint(tint color.RGBA) {
+	*t.tints = append(*t.tints, tint)
+}
+
+func TestRendererDrawPreparedLayersDrawsOnlyRequestedRange(t *testing.T) {
+	var sheets []uint16
+	r := NewRenderer(RenderHooks{
+		Executor: testExecutionBridge{
+			tileFunc: func(x, y, width, height int, tile PreparedTile) {
+				sheets = append(sheets, tile.SheetID)
+			},
+		},
+	})
+
+	got := r.DrawPreparedLayers(threeLayerScene(), visibility.Camera{Width: 8, Height: 8}, 1, 2)
+	if got.VisibleTiles != 2 {
+		t.Fatalf("VisibleTiles = %d, want 2", got.VisibleTiles)
+	}
+	if len(sheets) != 2 || sheets[0] != 2 || sheets[1] != 3 {
+		t.Fatalf("drawn sheets = %v, want [2 3]", sheets)
+	}
+}
+
+func TestRendererDrawPreparedLayersClampsRange(t *testing.T) {
+	r := NewRenderer(RenderHooks{})
+	if got := r.DrawPreparedLayers(threeLayerScene(), visibility.Camera{Width: 8, Height: 8}, -5, 10); got.VisibleTiles != 3 {
+		t.Fatalf("VisibleTiles = %d, want 3", got.VisibleTiles)
+	}
+	if got := r.DrawPreparedLayers(threeLayerScene(), visibility.Camera{Width: 8, Height: 8}, 2, 1); got.VisibleTiles != 0 {
+		t.Fatalf("VisibleTiles = %d, want 0 for empty range", got.VisibleTiles)
+	}
+}
+
+func TestRendererSkipsHiddenLayers(t *testing.T) {
+	scene := threeLayerScene()
+	scene.Layers[1].Hidden = true
+
+	r := NewRenderer(RenderHooks{})
+	if got := r.DrawPreparedScene(scene, visibility.Camera{Width: 8, Height: 8}); got.VisibleTiles != 2 {
+		t.Fatalf("VisibleTiles = %d, want 2", got.VisibleTiles)
+	}
+}
+
+func TestRendererAppliesLayerOffset(t *testing.T) {
+	var gotX, gotY int
+	r := NewRenderer(RenderHooks{
+		Executor: testExecutionBridge{
+			tileFunc: func(x, y, width, height int, tile PreparedTile) {
+				gotX, gotY = x, y
+			},
+		},
+	})
+
+	scene := threeLayerScene()
+	scene.Layers = scene.Layers[:1]
+	scene.Layers[0].OffsetX = 3
+	scene.Layers[0].OffsetY = -2
+
+	if got := r.DrawPreparedScene(scene, visibility.Camera{X: 1, Width: 8, Height: 8}); got.VisibleTiles != 1 {
+		t.Fatalf("VisibleTiles = %d, want 1", got.VisibleTiles)
+	}
+	if gotX != 2 || gotY != -2 {
+		t.Fatalf("tile position = (%d,%d), want (2,-2)", gotX, gotY)
+	}
+
+	scene.Layers[0].OffsetX = 20
+	if got := r.DrawPreparedScene(scene, visibility.Camera{Width: 8, Height: 8}); got.VisibleTiles != 0 {
+		t.Fatalf("VisibleTiles = %d, want 0 once the offset moves the layer off camera", got.VisibleTiles)
+	}
+}
+
+func TestRendererSetsLayerTintBeforeEachLayer(t *testing.T) {
+	var tints []color.RGBA
+	r := NewRenderer(RenderHooks{Executor: tintRecordingBridge{tints: &tints}})
+
+	scene := threeLayerScene()
+	red := color.RGBA{R: 255, A: 255}
+	scene.Layers[1].Tint = red
+	scene.Layers[2].Hidden = true
+
+	r.DrawPreparedScene(scene, visibility.Camera{Width: 8, Height: 8})
+	if len(tints) != 2 || tints[0] != (color.RGBA{}) || tints[1] != red {
+		t.Fatalf("tints = %v, want [{0 0 0 0} %v]", tints, red)
+	}
+}
+
+func TestRendererScalesCameraForParallaxLayers(t *testing.T) {
+	var gotX int
+	r := NewRenderer(RenderHooks{
+		Executor: testExecutionBridge{
+			tileFunc: func(x, y, width, height int, tile PreparedTile) {
+				gotX = x
+			},
+		},
+	})
+
+	scene := threeLayerScene()
+	scene.Layers = scene.Layers[:1]
+	scene.Layers[0].Map.Width = 8
+	scene.Layers[0].Parallax = true
+	scene.Layers[0].ScrollX = 0.5
+
+	// The camera is 20px in, but the layer only scrolled 10px.
+	if got := r.DrawPreparedScene(scene, visibility.Camera{X: 20, Width: 8, Height: 8}); got.VisibleTiles != 0 {
+		t.Fatalf("VisibleTiles = %d, want 0 (tile at x=0 is 10px off screen)", got.VisibleTiles)
+	}
+	scene.Layers[0].ScrollX = 0.2
+	if got := r.DrawPreparedScene(scene, visibility.Camera{X: 20, Width: 8, Height: 8}); got.VisibleTiles != 1 {
+		t.Fatalf("VisibleTiles = %d, want 1", got.VisibleTiles)
+	}
+	if gotX != -4 {
+		t.Fatalf("tile x = %d, want -4", gotX)
+	}
+}
+
+func TestRendererRepeatsWrappedLayers(t *testing.T) {
+	var xs []int
+	r := NewRenderer(RenderHooks{
+		Executor: testExecutionBridge{
+			runFunc: func(x, y, tileWidth, tileHeight int, run PreparedRun) {
+				for i := 0; i < run.Count; i++ {
+					xs = append(xs, x+i*tileWidth)
+				}
+			},
+		},
+	})
+
+	scene := PreparedScene{
+		Layers: []PreparedLayer{
+			{
+				Map: visibility.MapInfo{
+					Width:      2,
+					Height:     1,
+					TileWidth:  8,
+					TileHeight: 8,
+					RepeatX:    true,
+				},
+				Runs: []PreparedRun{
+					{CellX: 0, CellY: 0, Count: 2, Tile: PreparedTile{TileID: 1, SheetID: 1}},
+				},
+			},
+		},
+	}
+
+	got := r.DrawPreparedScene(scene, visibility.Camera{X: -12, Width: 40, Height: 8})
+	if got.VisibleTiles != 6 {
+		t.Fatalf("VisibleTiles = %d, want 6", got.VisibleTiles)
+	}
+	want := []int{-4, 4, 12, 20, 28, 36}
+	if len(xs) != len(want) {
+		t.Fatalf("tile xs = %v, want %v", xs, want)
+	}
+	for i := range want {
+		if xs[i] != want[i] {
+			t.Fatalf("tile xs = %v, want %v", xs, want)
+		}
+	}
+}
+
+type quadRecordingBridge struct {
+	testExecutionBridge
+	quads *[][4][2]float32
+}
+
+func (q quadRecordingBridge) DrawPreparedQuad(corners [4][2]float32, tile PreparedTile) {
+	*q.quads = append(*q.quads, corners)
+}
+
+func TestRendererDrawsZoomedTilesAsQuads(t *testing.T) {
+	var quads [][4][2]float32
+	r := NewRenderer(RenderHooks{Executor: quadRecordingBridge{quads: &quads}})
+
+	scene := threeLayerScene()
+	scene.Layers = scene.Layers[:1]
+	scene.Layers[0].Map.Width = 2
+	scene.Layers[0].Draws = append(scene.Layers[0].Draws, PreparedDraw{CellX: 1, Tile: PreparedTile{TileID: 1, SheetID: 1}})
+
+	got := r.DrawPreparedScene(scene, visibility.Camera{Width: 32, Height: 16, Zoom: 2})
+	if got.VisibleTiles != 2 {
+		t.Fatalf("VisibleTiles = %d, want 2", got.VisibleTiles)
+	}
+	want := [4][2]float32{{16, 0}, {32, 0}, {32, 16}, {16, 16}}
+	if len(quads) != 2 || quads[1] != want {
+		t.Fatalf("quads = %v, want second quad %v", quads, want)
+	}
+}
+
+func TestRendererFallsBackToTileDrawsWithoutQuadBridge(t *testing.T) {
+	var gotX int
+	calls := 0
+	r := NewRenderer(RenderHooks{
+		Executor: testExecutionBridge{
+			tileFunc: func(x, y, width, height int, tile PreparedTile) {
+				gotX = x
+				calls++
+			},
+		},
+	})
+
+	scene := threeLayerScene()
+	scene.Layers = scene.Layers[:1]
+	scene.Layers[0].Map.Width = 2
+	scene.Layers[0].Draws[0].CellX = 1
+
+	r.DrawPreparedScene(scene, visibility.Camera{Width: 32, Height: 16, Zoom: 2})
+	if calls != 1 || gotX != 16 {
+		t.Fatalf("DrawPreparedTile calls = %d at x=%d, want 1 at x=16", calls, gotX)
+	}
+}
+
+func threeLayerScene() PreparedScene {
+	var scene PreparedScene
+	for sheet := uint16(1); sheet <= 3; sheet++ {
+		scene.Layers = append(scene.Layers, PreparedLayer{
+			Map: visibility.MapInfo{
+				Width:      1,
+				Height:     1,
+				TileWidth:  8,
+				TileHeight: 8,
+			},
+			Draws: []PreparedDraw{
+				{CellX: 0, CellY: 0, Tile: PreparedTile{TileID: 1, SheetID: sheet}},
+			},
+		})
+	}
+	return scene
+}
+
 func testScene() PreparedScene {
 	return PreparedScene{
 		Layers: []PreparedLayer{
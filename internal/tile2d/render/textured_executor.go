@@ -1,18 +1,36 @@
 package render
 
 import (
+	"image"
+	"image/color"
+
 	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rdpcpu"
+)
+
+// Tile flip bits, matching the MAP2 cell flip bits. FlipD transposes the
+// tile before FlipH and FlipV mirror it.
+const (
+	FlipH uint8 = 1 << 0
+	FlipV uint8 = 1 << 1
+	FlipD uint8 = 1 << 2
 )
 
 type TexturedSetupState struct {
 	Source  *texture.Texture
 	DrawIdx uint8
 	Ready   bool
+	Tint    color.RGBA
 }
 
 type TexturedExecutor struct {
 	Framebuffer *texture.Texture
 	State       *TexturedSetupState
+	Tint        color.RGBA
+
+	// Flip mirrors or transposes the blitted tiles through their texture
+	// coordinates.
+	Flip uint8
 }
 
 func (e TexturedExecutor) Ready() bool {
@@ -24,8 +42,52 @@ func (e TexturedExecutor) SourceTexture(tile PreparedTile) (*texture.Texture, bo
 	if !ok || src == nil {
 		return nil, false
 	}
-	if src.Palette() != nil {
-		return nil, false
-	}
 	return src, true
 }
+
+// flippedTexRect returns the texture rectangle that draws src into the
+// framebuffer rectangle (x0, y0)-(x1, y1) with flip applied. A transposed
+// tile swaps the texture axes, so S runs down the rectangle.
+func flippedTexRect(src image.Rectangle, flip uint8, x0, y0, x1, y1 float32) rdpcpu.TexRect {
+	r := rdpcpu.TexRect{X0: x0, Y0: y0, X1: x1, Y1: y1, Flip: flip&FlipD != 0}
+	sSpan, tSpan := x1-x0, y1-y0
+	mirrorS, mirrorT := flip&FlipH != 0, flip&FlipV != 0
+	if r.Flip {
+		sSpan, tSpan = tSpan, sSpan
+		mirrorS, mirrorT = mirrorT, mirrorS
+	}
+	r.S, r.DsDx = texAxis(float32(src.Min.X), float32(src.Dx()), sSpan, mirrorS)
+	r.T, r.DtDy = texAxis(float32(src.Min.Y), float32(src.Dy()), tSpan, mirrorT)
+	return r
+}
+
+// texAxis returns the first texel and per-pixel step that spread size
+// texels from min over span pixels. A mirrored axis starts one step short
+// of the far edge and walks back.
+func texAxis(min, size, span float32, mirror bool) (float32, float32) {
+	step := size / span
+	if mirror {
+		return min + size - step, -step
+	}
+	return min, step
+}
+
+// flippedCorners returns the texel coordinates of the top-left, top-right,
+// bottom-right and bottom-left corners of src drawn with flip.
+func flippedCorners(src image.Rectangle, flip uint8) [4][2]float32 {
+	var st [4][2]float32
+	for i, c := range [4][2]float32{{0, 0}, {1, 0}, {1, 1}, {0, 1}} {
+		u, v := c[0], c[1]
+		if flip&FlipH != 0 {
+			u = 1 - u
+		}
+		if flip&FlipV != 0 {
+			v = 1 - v
+		}
+		if flip&FlipD != 0 {
+			u, v = v, u
+		}
+		st[i] = [2]float32{float32(src.Min.X) + u*float32(src.Dx()), float32(src.Min.Y) + v*float32(src.Dy())}
+	}
+	return st
+}
@@ -4,8 +4,12 @@ package render
 
 import (
 	"image"
+	"image/color"
 
 	"github.com/clktmr/n64/rcp/rdp"
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/gfx"
+	"github.com/drpaneas/gosprite64/internal/rdpcpu"
 	"github.com/drpaneas/gosprite64/internal/rendergeom"
 )
 
@@ -16,6 +20,13 @@ var blendSrcTiles = rdp.BlendMode{
 	B1: rdp.BlenderBZero,
 }
 
+var blendOverTiles = rdp.BlendMode{
+	P1: rdp.BlenderPMColorCombiner,
+	A1: rdp.BlenderAColorCombinerAlpha,
+	M1: rdp.BlenderPMFramebuffer,
+	B1: rdp.BlenderBOneMinusAlphaA,
+}
+
 func (e TexturedExecutor) EnsurePrepared(tile PreparedTile) bool {
 	if e.Framebuffer == nil || e.State == nil {
 		return false
@@ -25,31 +36,24 @@ func (e TexturedExecutor) EnsurePrepared(tile PreparedTile) bool {
 	if !ok {
 		return false
 	}
-	max := rdp.MaxTileSize(src.Format())
-	if src.Bounds().Dx() > max.Dx() || src.Bounds().Dy() > max.Dy() {
+	if !rdpcpu.FitsTMEM(src) {
 		return false
 	}
 
 	if e.State.Ready && e.State.Source == src {
+		if e.State.Tint != e.Tint {
+			setupTileCombiner(src, e.Tint)
+			e.State.Tint = e.Tint
+		}
 		return true
 	}
 
 	rdp.RDP.SetColorImage(e.Framebuffer)
 	rdp.RDP.SetScissor(image.Rectangle{Max: e.Framebuffer.Bounds().Size()}, rdp.InterlaceNone)
-	alphaSource := rdp.CombineTex0
-	if !src.HasAlpha() {
-		alphaSource = rdp.CombineDAlphaOne
+	setupTileCombiner(src, e.Tint)
+	if pal := src.Palette(); pal != nil {
+		rdpcpu.LoadTLUT(pal)
 	}
-	rdp.RDP.SetOtherModes(
-		rdp.ForceBlend|rdp.BiLerp0,
-		rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone, rdp.ZmodeOpaque, rdp.CvgDestClamp, blendSrcTiles,
-	)
-	rdp.RDP.SetCombineMode(rdp.CombineMode{
-		Two: rdp.CombinePass{
-			RGB:   rdp.CombineParams{0, 0, 0, rdp.CombineTex0},
-			Alpha: rdp.CombineParams{0, 0, 0, alphaSource},
-		},
-	})
 	rdp.RDP.SetTextureImage(src)
 	loadIdx, drawIdx := rdp.RDP.SetTile(rdp.TileDescriptor{
 		Format: src.Format(),
@@ -61,9 +65,61 @@ func (e TexturedExecutor) EnsurePrepared(tile PreparedTile) bool {
 	e.State.Source = src
 	e.State.DrawIdx = drawIdx
 	e.State.Ready = true
+	e.State.Tint = e.Tint
 	return true
 }
 
+// setupTileCombiner selects the plain texture combiner, or for a non-zero
+// tint multiplies texels by the primitive color and blends the result over
+// the framebuffer using the tint alpha as layer opacity. Palettized tiles
+// look their texels up in the loaded TLUT.
+func setupTileCombiner(src *texture.Texture, tint color.RGBA) {
+	alphaSource := rdp.CombineTex0
+	if !src.HasAlpha() {
+		alphaSource = rdp.CombineDAlphaOne
+	}
+	tlut := rdpcpu.TLUTMode(src)
+	if tint == (color.RGBA{}) {
+		rdp.RDP.SetOtherModes(
+			rdp.ForceBlend|rdp.BiLerp0|tlut,
+			rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone, rdp.ZmodeOpaque, rdp.CvgDestClamp, blendSrcTiles,
+		)
+		rdp.RDP.SetCombineMode(rdp.CombineMode{
+			Two: rdp.CombinePass{
+				RGB:   rdp.CombineParams{0, 0, 0, rdp.CombineTex0},
+				Alpha: rdp.CombineParams{0, 0, 0, alphaSource},
+			},
+		})
+		return
+	}
+
+	texAlpha := rdp.CombineTex0
+	if !src.HasAlpha() {
+		texAlpha = rdp.CombineAAlphaOne
+	}
+	rdp.RDP.SetPrimitiveColor(tint)
+	rdp.RDP.SetOtherModes(
+		rdp.ForceBlend|rdp.ImageRead|rdp.BiLerp0|tlut,
+		rdp.CycleTypeOne, rdp.RGBDitherNone, rdp.AlphaDitherNone, rdp.ZmodeOpaque, rdp.CvgDestClamp, blendOverTiles,
+	)
+	rdp.RDP.SetCombineMode(rdp.CombineMode{
+		Two: rdp.CombinePass{
+			RGB: rdp.CombineParams{
+				A: rdp.CombineTex0,
+				B: rdp.CombineBColorZero,
+				C: rdp.CombinePrimitive,
+				D: rdp.CombineDColorZero,
+			},
+			Alpha: rdp.CombineParams{
+				A: texAlpha,
+				B: rdp.CombineBAlphaZero,
+				C: rdp.CombinePrimitive,
+				D: rdp.CombineDAlphaZero,
+			},
+		},
+	})
+}
+
 func (e TexturedExecutor) BlitRun(x, y, tileWidth int, count int) {
 	for i := 0; i < count; i++ {
 		e.BlitTile(x+(i*tileWidth), y)
@@ -76,6 +132,10 @@ func (e TexturedExecutor) BlitTile(x, y int) {
 	}
 
 	srcBounds := e.State.Source.Bounds()
+	if e.Flip != 0 {
+		e.blitFlipped(x, y, srcBounds)
+		return
+	}
 	logicalDst := image.Rect(x, y, x+srcBounds.Dx(), y+srcBounds.Dy())
 	clipped := logicalDst.Intersect(rendergeom.LogicalBounds())
 	if clipped.Empty() {
@@ -101,3 +161,64 @@ func (e TexturedExecutor) BlitTile(x, y int) {
 		e.State.DrawIdx,
 	)
 }
+
+// blitFlipped draws the prepared tile 1:1 at (x, y) with e.Flip applied
+// through mirrored or swapped texture coordinates.
+func (e TexturedExecutor) blitFlipped(x, y int, src image.Rectangle) {
+	w, h := src.Dx(), src.Dy()
+	if e.Flip&FlipD != 0 {
+		w, h = h, w
+	}
+	origin := rendergeom.Origin()
+	x0, y0 := float32(x+origin.X), float32(y+origin.Y)
+	rect := flippedTexRect(src, e.Flip, x0, y0, x0+float32(w), y0+float32(h))
+	logical := rendergeom.LogicalBounds().Add(origin)
+	rect, ok := rect.Clip(float32(logical.Min.X), float32(logical.Min.Y), float32(logical.Max.X), float32(logical.Max.Y))
+	if !ok {
+		return
+	}
+	gfx.PushRaw(rdpcpu.BuildTextureRectangle(e.State.DrawIdx, rect)...)
+}
+
+// BlitQuad draws the prepared tile into a screen quad given in logical
+// pixels. Axis-aligned quads use a scaled texture rectangle; rotated ones
+// are split into two textured triangles.
+func (e TexturedExecutor) BlitQuad(corners [4][2]float32) {
+	if !e.Ready() {
+		return
+	}
+
+	src := e.State.Source.Bounds()
+	origin := rendergeom.Origin()
+	ox, oy := float32(origin.X), float32(origin.Y)
+
+	if corners[0][1] == corners[1][1] && corners[0][0] == corners[3][0] &&
+		corners[1][0] > corners[0][0] && corners[3][1] > corners[0][1] {
+		logical := rendergeom.LogicalBounds()
+		rect := flippedTexRect(src, e.Flip,
+			corners[0][0]+ox, corners[0][1]+oy,
+			corners[1][0]+ox, corners[3][1]+oy,
+		)
+		rect, ok := rect.Clip(
+			float32(logical.Min.X)+ox, float32(logical.Min.Y)+oy,
+			float32(logical.Max.X)+ox, float32(logical.Max.Y)+oy,
+		)
+		if !ok {
+			return
+		}
+		gfx.PushRaw(rdpcpu.BuildTextureRectangle(e.State.DrawIdx, rect)...)
+		return
+	}
+
+	st := flippedCorners(src, e.Flip)
+	var v [4]rdpcpu.TexVertex
+	for i := range v {
+		v[i] = rdpcpu.TexVertex{X: corners[i][0] + ox, Y: corners[i][1] + oy, S: st[i][0], T: st[i][1], InvW: 1}
+	}
+	// Triangles are not clipped on the CPU, so the scissor keeps them out
+	// of the border around the logical canvas.
+	rdp.RDP.SetScissor(rendergeom.LogicalBounds().Add(origin), rdp.InterlaceNone)
+	gfx.PushRaw(rdpcpu.BuildTexturedTriangle(e.State.DrawIdx, 0, v[0], v[1], v[2])...)
+	gfx.PushRaw(rdpcpu.BuildTexturedTriangle(e.State.DrawIdx, 0, v[0], v[2], v[3])...)
+	rdp.RDP.SetScissor(image.Rectangle{Max: e.Framebuffer.Bounds().Size()}, rdp.InterlaceNone)
+}
@@ -9,3 +9,5 @@ func (e TexturedExecutor) EnsurePrepared(tile PreparedTile) bool {
 func (e TexturedExecutor) BlitRun(x, y, tileWidth int, count int) {}
 
 func (e TexturedExecutor) BlitTile(x, y int) {}
+
+func (e TexturedExecutor) BlitQuad(corners [4][2]float32) {}
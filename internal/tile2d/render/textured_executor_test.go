@@ -26,3 +26,41 @@ func TestTexturedExecutorSourceTextureRejectsGenericImages(t *testing.T) {
 		t.Fatal("expected generic image source to be rejected")
 	}
 }
+
+func TestFlippedTexRectMirrorsAxes(t *testing.T) {
+	src := image.Rect(8, 0, 16, 8)
+	r := flippedTexRect(src, FlipH, 0, 0, 8, 8)
+	if r.S != 15 || r.DsDx != -1 || r.T != 0 || r.DtDy != 1 || r.Flip {
+		t.Fatalf("FlipH rect = %+v, want S from the right edge stepping back", r)
+	}
+	r = flippedTexRect(src, FlipV, 0, 0, 16, 16)
+	if r.S != 8 || r.DsDx != 0.5 || r.T != 7.5 || r.DtDy != -0.5 {
+		t.Fatalf("scaled FlipV rect = %+v", r)
+	}
+}
+
+func TestFlippedTexRectTransposesForDiagonalFlip(t *testing.T) {
+	src := image.Rect(0, 0, 8, 4)
+	r := flippedTexRect(src, FlipD|FlipH, 0, 0, 4, 8)
+	if !r.Flip {
+		t.Fatal("FlipD rect does not swap texture axes")
+	}
+	// S runs down the 8 rows; T runs across the 4 columns, mirrored by H.
+	if r.S != 0 || r.DsDx != 1 || r.T != 3 || r.DtDy != -1 {
+		t.Fatalf("FlipD|FlipH rect = %+v", r)
+	}
+}
+
+func TestFlippedCornersMatchTiledOrder(t *testing.T) {
+	src := image.Rect(0, 0, 8, 8)
+	got := flippedCorners(src, 0)
+	if got != [4][2]float32{{0, 0}, {8, 0}, {8, 8}, {0, 8}} {
+		t.Fatalf("unflipped corners = %v", got)
+	}
+	// A diagonal flip followed by a horizontal one is a clockwise turn:
+	// the top-left corner shows the source's bottom-left texel.
+	got = flippedCorners(src, FlipD|FlipH)
+	if got[0] != [2]float32{0, 8} || got[1] != [2]float32{0, 0} {
+		t.Fatalf("rotated corners = %v", got)
+	}
+}
@@ -3,6 +3,11 @@ package residency
 type ChunkPolicy struct {
 	Radius   int
 	Capacity int
+
+	// Cols and Rows bound the chunk grid; chunks outside it are never
+	// loaded. Zero leaves that axis unbounded.
+	Cols int
+	Rows int
 }
 
 type ChunkCoord struct {
@@ -10,30 +15,112 @@ type ChunkCoord struct {
 	Y int
 }
 
+// ChunkRange is an inclusive rectangle of chunks.
+type ChunkRange struct {
+	Min ChunkCoord
+	Max ChunkCoord
+}
+
+// ChunkHooks are called as chunks enter and leave the cache.
+type ChunkHooks struct {
+	Load  func(ChunkCoord)
+	Evict func(ChunkCoord)
+}
+
+// ChunkCache tracks which chunks are resident. Past Capacity it evicts the
+// least recently used chunks, but never those asked for by the current
+// call, so Capacity is exceeded rather than evicting a chunk in view.
 type ChunkCache struct {
 	policy ChunkPolicy
-	loaded map[ChunkCoord]struct{}
-	order  []ChunkCoord
+	hooks  ChunkHooks
+	loaded map[ChunkCoord]uint64
+	tick   uint64
 }
 
 func NewChunkCache(policy ChunkPolicy) *ChunkCache {
 	return &ChunkCache{
 		policy: policy,
-		loaded: make(map[ChunkCoord]struct{}),
+		loaded: make(map[ChunkCoord]uint64),
 	}
 }
 
+func (c *ChunkCache) SetHooks(hooks ChunkHooks) {
+	if c == nil {
+		return
+	}
+	c.hooks = hooks
+}
+
+func (c *ChunkCache) Policy() ChunkPolicy {
+	if c == nil {
+		return ChunkPolicy{}
+	}
+	return c.policy
+}
+
+// SetPolicy replaces the policy, evicting chunks past the new bounds or
+// capacity.
+func (c *ChunkCache) SetPolicy(policy ChunkPolicy) {
+	if c == nil {
+		return
+	}
+	c.policy = policy
+	for coord := range c.loaded {
+		if !c.inBounds(coord) {
+			c.evict(coord)
+		}
+	}
+	c.trim(c.tick + 1)
+}
+
 func (c *ChunkCache) EnsureVisible(center ChunkCoord) {
+	c.EnsureRanges(ChunkRange{Min: center, Max: center})
+}
+
+// EnsureRanges loads every chunk in ranges, grown by the policy radius,
+// and marks them most recently used.
+func (c *ChunkCache) EnsureRanges(ranges ...ChunkRange) {
 	if c == nil {
 		return
 	}
 
+	c.tick++
+	var added []ChunkCoord
 	radius := max(0, c.policy.Radius)
-	for y := center.Y - radius; y <= center.Y+radius; y++ {
-		for x := center.X - radius; x <= center.X+radius; x++ {
-			c.touch(ChunkCoord{X: x, Y: y})
+	for _, r := range ranges {
+		for y := r.Min.Y - radius; y <= r.Max.Y+radius; y++ {
+			for x := r.Min.X - radius; x <= r.Max.X+radius; x++ {
+				if coord := (ChunkCoord{X: x, Y: y}); c.mark(coord) {
+					added = append(added, coord)
+				}
+			}
 		}
 	}
+	c.trim(c.tick)
+	c.load(added)
+}
+
+// Touch loads a single chunk, ignoring the radius, and reports whether it
+// is resident; chunks out of bounds never are.
+func (c *ChunkCache) Touch(coord ChunkCoord) bool {
+	if c == nil || !c.inBounds(coord) {
+		return false
+	}
+	c.tick++
+	added := c.mark(coord)
+	c.trim(c.tick)
+	if added {
+		c.load([]ChunkCoord{coord})
+	}
+	return true
+}
+
+func (c *ChunkCache) Contains(coord ChunkCoord) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.loaded[coord]
+	return ok
 }
 
 func (c *ChunkCache) Len() int {
@@ -43,24 +130,72 @@ func (c *ChunkCache) Len() int {
 	return len(c.loaded)
 }
 
-func (c *ChunkCache) touch(coord ChunkCoord) {
-	if _, ok := c.loaded[coord]; ok {
+// Clear evicts every chunk.
+func (c *ChunkCache) Clear() {
+	if c == nil {
+		return
+	}
+	for coord := range c.loaded {
+		c.evict(coord)
+	}
+}
+
+// mark stamps coord with the current tick and reports whether it is new.
+func (c *ChunkCache) mark(coord ChunkCoord) bool {
+	if !c.inBounds(coord) {
+		return false
+	}
+	_, ok := c.loaded[coord]
+	c.loaded[coord] = c.tick
+	return !ok
+}
+
+func (c *ChunkCache) inBounds(coord ChunkCoord) bool {
+	if c.policy.Cols > 0 && (coord.X < 0 || coord.X >= c.policy.Cols) {
+		return false
+	}
+	if c.policy.Rows > 0 && (coord.Y < 0 || coord.Y >= c.policy.Rows) {
+		return false
+	}
+	return true
+}
+
+func (c *ChunkCache) load(coords []ChunkCoord) {
+	if c.hooks.Load == nil {
 		return
 	}
+	for _, coord := range coords {
+		c.hooks.Load(coord)
+	}
+}
 
-	c.loaded[coord] = struct{}{}
-	c.order = append(c.order, coord)
-	c.trim()
+func (c *ChunkCache) evict(coord ChunkCoord) {
+	delete(c.loaded, coord)
+	if c.hooks.Evict != nil {
+		c.hooks.Evict(coord)
+	}
 }
 
-func (c *ChunkCache) trim() {
+// trim evicts least recently used chunks until the cache fits its
+// capacity, sparing chunks stamped at or after keep.
+func (c *ChunkCache) trim(keep uint64) {
 	if c.policy.Capacity <= 0 {
 		return
 	}
 
-	for len(c.order) > c.policy.Capacity {
-		evict := c.order[0]
-		c.order = c.order[1:]
-		delete(c.loaded, evict)
+	for len(c.loaded) > c.policy.Capacity {
+		var oldest ChunkCoord
+		stamp := keep
+		for coord, used := range c.loaded {
+			// Ties go to the topmost, then leftmost chunk, so eviction does
+			// not depend on map order.
+			if used < stamp || used == stamp && stamp < keep && (coord.Y < oldest.Y || coord.Y == oldest.Y && coord.X < oldest.X) {
+				oldest, stamp = coord, used
+			}
+		}
+		if stamp == keep {
+			return
+		}
+		c.evict(oldest)
 	}
 }
@@ -17,3 +17,50 @@ func TestResidencyStoreTracksLifetimeClass(t *testing.T) {
 		t.Fatalf("ClassOf() = %v, want %v", got, ResidencyPermanent)
 	}
 }
+
+func TestChunkCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewChunkCache(ChunkPolicy{Capacity: 2})
+	var evicted []ChunkCoord
+	c.SetHooks(ChunkHooks{Evict: func(coord ChunkCoord) { evicted = append(evicted, coord) }})
+
+	c.Touch(ChunkCoord{X: 0})
+	c.Touch(ChunkCoord{X: 1})
+	c.Touch(ChunkCoord{X: 0})
+	c.Touch(ChunkCoord{X: 2})
+	if len(evicted) != 1 || evicted[0] != (ChunkCoord{X: 1}) {
+		t.Fatalf("evicted = %v, want chunk 1,0", evicted)
+	}
+	if !c.Contains(ChunkCoord{X: 0}) || !c.Contains(ChunkCoord{X: 2}) {
+		t.Fatal("recently used chunks were evicted")
+	}
+}
+
+func TestChunkCacheKeepsRequestedRangesAndBounds(t *testing.T) {
+	c := NewChunkCache(ChunkPolicy{Radius: 1, Capacity: 2, Cols: 4, Rows: 3})
+	var loaded []ChunkCoord
+	c.SetHooks(ChunkHooks{Load: func(coord ChunkCoord) { loaded = append(loaded, coord) }})
+
+	c.EnsureRanges(ChunkRange{Min: ChunkCoord{X: 0, Y: 0}, Max: ChunkCoord{X: 1, Y: 0}})
+	// Radius 1 around 0..1 x 0..0, clipped to the grid: 3x2 chunks, all
+	// kept even though they exceed the capacity.
+	if got := c.Len(); got != 6 || len(loaded) != 6 {
+		t.Fatalf("Len() = %d after %d loads, want 6", got, len(loaded))
+	}
+	if c.Touch(ChunkCoord{X: 4, Y: 0}) || c.Contains(ChunkCoord{X: -1, Y: 0}) {
+		t.Fatal("chunks outside the grid were loaded")
+	}
+
+	c.EnsureVisible(ChunkCoord{X: 3, Y: 2})
+	if c.Contains(ChunkCoord{X: 0, Y: 0}) || !c.Contains(ChunkCoord{X: 3, Y: 2}) {
+		t.Fatal("the old range was not evicted in favour of the new one")
+	}
+
+	c.SetPolicy(ChunkPolicy{Capacity: 1, Cols: 4, Rows: 3})
+	if got := c.Len(); got != 1 {
+		t.Fatalf("Len() after SetPolicy = %d, want 1", got)
+	}
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatal("Clear() left chunks behind")
+	}
+}
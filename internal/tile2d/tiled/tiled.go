@@ -0,0 +1,558 @@
+// Package tiled converts maps and tilesets saved by the Tiled editor
+// (.tmx, .tmj, .tsx and .tsj files) into the format configs that mk2dmap
+// and mk2dsheet compile.
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// Tiled stores flips in the top bits of a global tile ID.
+const (
+	gidFlipH   uint32 = 1 << 31
+	gidFlipV   uint32 = 1 << 30
+	gidFlipD   uint32 = 1 << 29
+	gidRotate  uint32 = 1 << 28
+	gidIDMask         = gidRotate - 1
+	chunkCells        = 8
+)
+
+// Import is a converted map. Tilesets are in sheet ID order: a layer with
+// SheetID 1 draws from Tilesets[0]. Warnings name Tiled data the map
+// format has no place for.
+type Import struct {
+	Map      format.MapConfig
+	Tilesets []Tileset
+	Warnings []string
+}
+
+// Tileset is a converted tileset, ready for format.BuildSheetWithProps.
+type Tileset struct {
+	Name       string
+	Image      image.Image
+	TileWidth  int
+	TileHeight int
+	Props      format.TilePropsConfig
+}
+
+// The decoders for both file flavours fill these documents.
+type mapDoc struct {
+	Orientation           string
+	Infinite              bool
+	Width, Height         int
+	TileWidth, TileHeight int
+	Tilesets              []tilesetRef
+	Layers                []layerDoc
+	Props                 []propDoc
+}
+
+type tilesetRef struct {
+	FirstGID int
+	Source   string
+	Tileset  *tilesetDoc
+}
+
+type tilesetDoc struct {
+	Name                  string
+	TileWidth, TileHeight int
+	Spacing, Margin       int
+	TileCount, Columns    int
+	Image                 string
+	Tiles                 []tileDoc
+}
+
+type tileDoc struct {
+	ID     int
+	Image  string
+	Props  []propDoc
+	Frames []frameDoc
+}
+
+type frameDoc struct {
+	TileID, Duration int
+}
+
+// propDoc holds a property value as text, whatever its Tiled type.
+type propDoc struct {
+	Name, Type, Value string
+}
+
+type layerDoc struct {
+	Kind                 string
+	Name                 string
+	Hidden               bool
+	GIDs                 []uint32
+	OffsetX, OffsetY     float64
+	ParallaxX, ParallaxY float64
+	Objects              []objectDoc
+	Props                []propDoc
+}
+
+// Layer kinds, named as in .tmj files.
+const (
+	kindTiles   = "tilelayer"
+	kindObjects = "objectgroup"
+)
+
+type objectDoc struct {
+	ID                int
+	Name, Type        string
+	X, Y, W, H        float64
+	Rotation          float64
+	GID               uint32
+	Point, Ellipse    bool
+	Text              bool
+	Polygon, Polyline [][2]float64
+	Props             []propDoc
+}
+
+// ImportMap reads a .tmx or .tmj map with its tilesets and images.
+func ImportMap(path string) (Import, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Import{}, err
+	}
+	var doc mapDoc
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tmx":
+		doc, err = parseTMX(raw)
+	case ".tmj", ".json":
+		doc, err = parseTMJ(raw)
+	default:
+		return Import{}, fmt.Errorf("tiled: %s: want a .tmx or .tmj map", path)
+	}
+	if err != nil {
+		return Import{}, fmt.Errorf("tiled: %s: %w", path, err)
+	}
+	imp, err := convertMap(doc, filepath.Dir(path))
+	if err != nil {
+		return Import{}, fmt.Errorf("tiled: %s: %w", path, err)
+	}
+	return imp, nil
+}
+
+// ImportTileset reads a .tsx or .tsj tileset and its image.
+func ImportTileset(path string) (Tileset, error) {
+	doc, err := readTileset(path)
+	if err != nil {
+		return Tileset{}, err
+	}
+	ts, err := convertTileset(doc, filepath.Dir(path))
+	if err != nil {
+		return Tileset{}, fmt.Errorf("tiled: %s: %w", path, err)
+	}
+	if ts.Name == "" {
+		ts.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return ts, nil
+}
+
+func readTileset(path string) (tilesetDoc, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return tilesetDoc{}, err
+	}
+	var doc tilesetDoc
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsx":
+		doc, err = parseTSX(raw)
+	case ".tsj", ".json":
+		doc, err = parseTSJ(raw)
+	default:
+		return tilesetDoc{}, fmt.Errorf("tiled: %s: want a .tsx or .tsj tileset", path)
+	}
+	if err != nil {
+		return tilesetDoc{}, fmt.Errorf("tiled: %s: %w", path, err)
+	}
+	return doc, nil
+}
+
+func convertMap(doc mapDoc, dir string) (Import, error) {
+	var imp Import
+	switch {
+	case doc.Orientation != "orthogonal":
+		return imp, fmt.Errorf("%s maps are not supported, only orthogonal", doc.Orientation)
+	case doc.Infinite:
+		return imp, fmt.Errorf("infinite maps are not supported")
+	case doc.Width <= 0 || doc.Height <= 0 || doc.Width > 0xFFFF || doc.Height > 0xFFFF:
+		return imp, fmt.Errorf("map size %dx%d out of range", doc.Width, doc.Height)
+	}
+
+	refs := slices.Clone(doc.Tilesets)
+	sort.SliceStable(refs, func(i, j int) bool { return refs[i].FirstGID < refs[j].FirstGID })
+	names := map[string]bool{}
+	for _, ref := range refs {
+		tsDoc, tsDir := ref.Tileset, dir
+		if ref.Source != "" {
+			path := filepath.Join(dir, ref.Source)
+			loaded, err := readTileset(path)
+			if err != nil {
+				return imp, err
+			}
+			tsDoc, tsDir = &loaded, filepath.Dir(path)
+		}
+		if tsDoc == nil {
+			return imp, fmt.Errorf("tileset at firstgid %d has no data", ref.FirstGID)
+		}
+		ts, err := convertTileset(*tsDoc, tsDir)
+		if err != nil {
+			return imp, err
+		}
+		if ts.Name == "" {
+			ts.Name = strings.TrimSuffix(filepath.Base(ref.Source), filepath.Ext(ref.Source))
+		}
+		if ts.TileWidth != doc.TileWidth || ts.TileHeight != doc.TileHeight {
+			return imp, fmt.Errorf("tileset %q tile size %dx%d differs from map tile size %dx%d", ts.Name, ts.TileWidth, ts.TileHeight, doc.TileWidth, doc.TileHeight)
+		}
+		if names[ts.Name] {
+			return imp, fmt.Errorf("two tilesets are named %q", ts.Name)
+		}
+		names[ts.Name] = true
+		imp.Tilesets = append(imp.Tilesets, ts)
+	}
+
+	cfg := format.MapConfig{
+		Width:       uint16(doc.Width),
+		Height:      uint16(doc.Height),
+		CellBits:    8,
+		ChunkWidth:  uint16(min(chunkCells, doc.Width)),
+		ChunkHeight: uint16(min(chunkCells, doc.Height)),
+	}
+	for _, layer := range doc.Layers {
+		switch layer.Kind {
+		case kindTiles, kindObjects:
+		default:
+			return imp, fmt.Errorf("layer %q: %s layers are not supported", layer.Name, layer.Kind)
+		}
+		if layer.OffsetX != 0 || layer.OffsetY != 0 {
+			return imp, fmt.Errorf("layer %q: layer offsets are not supported", layer.Name)
+		}
+		if len(layer.Props) > 0 {
+			imp.Warnings = append(imp.Warnings, fmt.Sprintf("layer %q: properties are not stored in maps", layer.Name))
+		}
+		if layer.Kind == kindObjects {
+			objects, err := convertObjects(layer)
+			if err != nil {
+				return imp, err
+			}
+			cfg.ObjectLayers = append(cfg.ObjectLayers, objects)
+			continue
+		}
+		out, err := convertTileLayer(layer, doc.Width*doc.Height, refs, imp.Tilesets)
+		if err != nil {
+			return imp, err
+		}
+		if len(out.Flips) > 0 || slices.ContainsFunc(out.Cells, func(c uint16) bool { return c > 0xFF }) {
+			cfg.CellBits = 16
+		}
+		cfg.Layers = append(cfg.Layers, out)
+	}
+	if len(cfg.Layers) == 0 {
+		return imp, fmt.Errorf("map has no tile layers")
+	}
+	cfg.LayerCount = uint16(len(cfg.Layers))
+	if len(doc.Props) > 0 {
+		imp.Warnings = append(imp.Warnings, "map properties are not stored in maps")
+	}
+	imp.Map = cfg
+	return imp, nil
+}
+
+// convertTileLayer turns global tile IDs into sheet tile IDs. A layer
+// draws from one sheet, so all its tiles must share a tileset.
+func convertTileLayer(layer layerDoc, cellCount int, refs []tilesetRef, tilesets []Tileset) (format.MapLayerConfig, error) {
+	out := format.MapLayerConfig{SheetID: 1, Name: layer.Name, Hidden: layer.Hidden}
+	if len(layer.GIDs) != cellCount {
+		return out, fmt.Errorf("layer %q has %d cells, want %d", layer.Name, len(layer.GIDs), cellCount)
+	}
+	if layer.ParallaxX != 1 {
+		x := float32(layer.ParallaxX)
+		out.ScrollX = &x
+	}
+	if layer.ParallaxY != 1 {
+		y := float32(layer.ParallaxY)
+		out.ScrollY = &y
+	}
+
+	out.Cells = make([]uint16, cellCount)
+	flips := make([]uint8, cellCount)
+	sheet := -1
+	flipped := false
+	for i, gid := range layer.GIDs {
+		if gid&gidRotate != 0 {
+			return out, fmt.Errorf("layer %q: hexagonal rotation is not supported", layer.Name)
+		}
+		id := int(gid & gidIDMask)
+		if id == 0 {
+			continue
+		}
+		ts := -1
+		for j, ref := range refs {
+			if ref.FirstGID <= id {
+				ts = j
+			}
+		}
+		if ts < 0 {
+			return out, fmt.Errorf("layer %q: tile %d has no tileset", layer.Name, id)
+		}
+		if sheet >= 0 && ts != sheet {
+			return out, fmt.Errorf("layer %q mixes tilesets %q and %q; use one tileset per layer", layer.Name, tilesets[sheet].Name, tilesets[ts].Name)
+		}
+		sheet = ts
+		local := id - refs[ts].FirstGID + 1
+		if count := tileCount(tilesets[ts]); local > count {
+			return out, fmt.Errorf("layer %q: tile %d is beyond tileset %q's %d tiles", layer.Name, id, tilesets[ts].Name, count)
+		}
+		out.Cells[i] = uint16(local)
+		if gid&gidFlipH != 0 {
+			flips[i] |= format.CellFlipH
+		}
+		if gid&gidFlipV != 0 {
+			flips[i] |= format.CellFlipV
+		}
+		if gid&gidFlipD != 0 {
+			flips[i] |= format.CellFlipD
+		}
+		flipped = flipped || flips[i] != 0
+	}
+	if sheet > 0 {
+		out.SheetID = uint16(sheet + 1)
+	}
+	if flipped {
+		out.Flips = flips
+	}
+	return out, nil
+}
+
+func tileCount(ts Tileset) int {
+	b := ts.Image.Bounds()
+	return (b.Dx() / ts.TileWidth) * (b.Dy() / ts.TileHeight)
+}
+
+func convertObjects(layer layerDoc) (format.MapObjectLayerConfig, error) {
+	out := format.MapObjectLayerConfig{Name: layer.Name, Objects: make([]format.MapObjectConfig, 0, len(layer.Objects))}
+	for _, obj := range layer.Objects {
+		where := fmt.Sprintf("layer %q: object %d", layer.Name, obj.ID)
+		switch {
+		case obj.Rotation != 0:
+			return out, fmt.Errorf("%s: rotated objects are not supported", where)
+		case obj.GID != 0:
+			return out, fmt.Errorf("%s: tile objects are not supported", where)
+		case obj.Ellipse:
+			return out, fmt.Errorf("%s: ellipse objects are not supported", where)
+		case obj.Text:
+			return out, fmt.Errorf("%s: text objects are not supported", where)
+		}
+
+		cfg := format.MapObjectConfig{
+			Name: obj.Name,
+			Type: obj.Type,
+			Kind: "rect",
+			X:    float32(obj.X),
+			Y:    float32(obj.Y),
+			W:    float32(obj.W),
+			H:    float32(obj.H),
+		}
+		points := obj.Polyline
+		if len(obj.Polygon) > 0 {
+			// Polygons become closed polylines.
+			points = append(slices.Clone(obj.Polygon), obj.Polygon[0])
+		}
+		switch {
+		case obj.Point:
+			cfg.Kind, cfg.W, cfg.H = "point", 0, 0
+		case obj.Polygon != nil || obj.Polyline != nil:
+			cfg.Kind, cfg.W, cfg.H = "polyline", 0, 0
+			for _, p := range points {
+				cfg.Points = append(cfg.Points, [2]float32{float32(p[0]), float32(p[1])})
+			}
+		}
+
+		if len(obj.Props) > 0 {
+			cfg.Props = make(map[string]any, len(obj.Props))
+		}
+		for _, p := range obj.Props {
+			v, err := propValue(p)
+			if err != nil {
+				return out, fmt.Errorf("%s: %w", where, err)
+			}
+			cfg.Props[p.Name] = v
+		}
+		out.Objects = append(out.Objects, cfg)
+	}
+	return out, nil
+}
+
+// convertTileset maps a tile's "shape" property to its collision shape and
+// its true bool properties to flags; other properties stay props. A tile
+// animation needs one duration for all its frames.
+func convertTileset(doc tilesetDoc, dir string) (Tileset, error) {
+	ts := Tileset{Name: doc.Name, TileWidth: doc.TileWidth, TileHeight: doc.TileHeight}
+	switch {
+	case doc.Image == "":
+		return ts, fmt.Errorf("tileset %q: image collection tilesets are not supported", doc.Name)
+	case doc.Spacing != 0 || doc.Margin != 0:
+		return ts, fmt.Errorf("tileset %q: tile spacing and margins are not supported", doc.Name)
+	case doc.TileWidth <= 0 || doc.TileHeight <= 0:
+		return ts, fmt.Errorf("tileset %q: tile size must be positive", doc.Name)
+	}
+
+	f, err := os.Open(filepath.Join(dir, doc.Image))
+	if err != nil {
+		return ts, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return ts, fmt.Errorf("tileset %q: %s: %w", doc.Name, doc.Image, err)
+	}
+	// Tiled ignores a partial tile at the right or bottom edge.
+	b := img.Bounds()
+	w, h := b.Dx()-b.Dx()%doc.TileWidth, b.Dy()-b.Dy()%doc.TileHeight
+	if w != b.Dx() || h != b.Dy() {
+		if sub, ok := img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}); ok {
+			img = sub.SubImage(image.Rect(b.Min.X, b.Min.Y, b.Min.X+w, b.Min.Y+h))
+		}
+	}
+	ts.Image = img
+
+	flags := map[string]bool{}
+	for _, tile := range doc.Tiles {
+		if tile.Image != "" {
+			return ts, fmt.Errorf("tileset %q: image collection tilesets are not supported", doc.Name)
+		}
+		id := uint16(tile.ID + 1)
+		cfg := format.TilePropConfig{ID: id}
+		for _, p := range tile.Props {
+			v, err := propValue(p)
+			if err != nil {
+				return ts, fmt.Errorf("tileset %q: tile %d: %w", doc.Name, tile.ID, err)
+			}
+			switch v := v.(type) {
+			case bool:
+				if v {
+					cfg.Flags = append(cfg.Flags, p.Name)
+					flags[p.Name] = true
+				}
+			case string:
+				if p.Name == "shape" {
+					cfg.Shape = v
+					continue
+				}
+				cfg.Props = setProp(cfg.Props, p.Name, v)
+			default:
+				cfg.Props = setProp(cfg.Props, p.Name, v)
+			}
+		}
+		if cfg.Shape != "" || len(cfg.Flags) > 0 || len(cfg.Props) > 0 {
+			ts.Props.Tiles = append(ts.Props.Tiles, cfg)
+		}
+
+		if len(tile.Frames) == 0 {
+			continue
+		}
+		anim := format.TileAnimConfig{Tile: id}
+		for _, fr := range tile.Frames {
+			if fr.Duration != tile.Frames[0].Duration {
+				return ts, fmt.Errorf("tileset %q: tile %d animation has uneven frame durations", doc.Name, tile.ID)
+			}
+			anim.Frames = append(anim.Frames, uint16(fr.TileID+1))
+		}
+		if d := tile.Frames[0].Duration; d > 0 {
+			anim.FPS = uint16(max(1, math.Round(1000/float64(d))))
+		}
+		ts.Props.Anims = append(ts.Props.Anims, anim)
+	}
+	for name := range flags {
+		ts.Props.Flags = append(ts.Props.Flags, name)
+	}
+	sort.Strings(ts.Props.Flags)
+	return ts, nil
+}
+
+func setProp(props map[string]any, key string, v any) map[string]any {
+	if props == nil {
+		props = map[string]any{}
+	}
+	props[key] = v
+	return props
+}
+
+// propValue types a property's text by its Tiled type.
+func propValue(p propDoc) (any, error) {
+	switch p.Type {
+	case "", "string", "file", "color":
+		return p.Value, nil
+	case "int", "object":
+		n, err := strconv.Atoi(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", p.Name, err)
+		}
+		return n, nil
+	case "float":
+		f, err := strconv.ParseFloat(p.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", p.Name, err)
+		}
+		return f, nil
+	case "bool":
+		b, err := strconv.ParseBool(p.Value)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", p.Name, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("property %q: %s properties are not supported", p.Name, p.Type)
+}
+
+// decodeData decodes base64 layer data, inflating it when compressed.
+func decodeData(text, compression string) ([]uint32, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil {
+		return nil, err
+	}
+	var r io.Reader = bytes.NewReader(raw)
+	switch compression {
+	case "":
+	case "zlib":
+		if r, err = zlib.NewReader(r); err != nil {
+			return nil, err
+		}
+	case "gzip":
+		if r, err = gzip.NewReader(r); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s compression is not supported", compression)
+	}
+	if raw, err = io.ReadAll(r); err != nil {
+		return nil, err
+	}
+	if len(raw)%4 != 0 {
+		return nil, fmt.Errorf("layer data is %d bytes, not a multiple of 4", len(raw))
+	}
+	gids := make([]uint32, len(raw)/4)
+	for i := range gids {
+		gids[i] = binary.LittleEndian.Uint32(raw[i*4:])
+	}
+	return gids, nil
+}
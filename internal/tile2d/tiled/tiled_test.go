@@ -0,0 +1,305 @@
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// writeFixture writes files into a temp dir and a 4-tile 8x8 PNG as
+// tiles.png and terrain.png.
+func writeFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"tiles.png", "terrain.png"} {
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func encodeGIDs(t *testing.T, compression string, gids ...uint32) string {
+	t.Helper()
+	raw := make([]byte, 0, len(gids)*4)
+	for _, gid := range gids {
+		raw = binary.LittleEndian.AppendUint32(raw, gid)
+	}
+	var buf bytes.Buffer
+	switch compression {
+	case "zlib":
+		w := zlib.NewWriter(&buf)
+		w.Write(raw)
+		w.Close()
+	case "gzip":
+		w := gzip.NewWriter(&buf)
+		w.Write(raw)
+		w.Close()
+	default:
+		buf.Write(raw)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+const tilesTSX = `<?xml version="1.0" encoding="UTF-8"?>
+<tileset name="tiles" tilewidth="8" tileheight="8" tilecount="4" columns="2">
+ <image source="tiles.png" width="16" height="16"/>
+ <tile id="1">
+  <properties>
+   <property name="shape" value="slope_up_45"/>
+   <property name="solid" type="bool" value="true"/>
+   <property name="damage" type="int" value="2"/>
+  </properties>
+ </tile>
+ <tile id="2">
+  <animation>
+   <frame tileid="2" duration="100"/>
+   <frame tileid="3" duration="100"/>
+  </animation>
+ </tile>
+</tileset>
+`
+
+func TestImportMapConvertsTMX(t *testing.T) {
+	gid := func(id uint32, flips uint32) uint32 { return id | flips }
+	dir := writeFixture(t, map[string]string{
+		"tiles.tsx": tilesTSX,
+		"level.tmx": `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="3" height="2" tilewidth="8" tileheight="8" infinite="0">
+ <properties><property name="music" value="cave"/></properties>
+ <tileset firstgid="1" source="tiles.tsx"/>
+ <tileset firstgid="5" name="terrain" tilewidth="8" tileheight="8" tilecount="4" columns="2">
+  <image source="terrain.png" width="16" height="16"/>
+ </tileset>
+ <layer id="1" name="ground" width="3" height="2">
+  <data encoding="csv">
+1,2,0,
+` + strconv.FormatUint(uint64(gid(3, gidFlipH)), 10) + `,` + strconv.FormatUint(uint64(gid(4, gidFlipD|gidFlipV)), 10) + `,1
+</data>
+ </layer>
+ <layer id="2" name="back" width="3" height="2" visible="0" parallaxx="0.5">
+  <data encoding="base64" compression="zlib">` + encodeGIDs(t, "zlib", 5, 0, 0, 0, 0, 8) + `</data>
+ </layer>
+ <objectgroup id="3" name="spawns">
+  <object id="1" name="player" type="spawn" x="8" y="16"><point/></object>
+  <object id="2" name="exit" class="warp" x="16" y="0" width="8" height="16">
+   <properties>
+    <property name="to" value="cave"/>
+    <property name="hp" type="int" value="3"/>
+    <property name="speed" type="float" value="1.5"/>
+    <property name="open" type="bool" value="false"/>
+   </properties>
+  </object>
+  <object id="3" name="fence" x="0" y="0"><polygon points="0,0 8,0 8,8"/></object>
+ </objectgroup>
+</map>
+`,
+	})
+
+	imp, err := ImportMap(filepath.Join(dir, "level.tmx"))
+	if err != nil {
+		t.Fatalf("ImportMap() error = %v", err)
+	}
+
+	if len(imp.Tilesets) != 2 || imp.Tilesets[0].Name != "tiles" || imp.Tilesets[1].Name != "terrain" {
+		t.Fatalf("tilesets = %+v, want tiles then terrain", imp.Tilesets)
+	}
+	m := imp.Map
+	if m.Width != 3 || m.Height != 2 || m.LayerCount != 2 || m.CellBits != 16 {
+		t.Fatalf("map = %dx%d, %d layers, %d-bit cells; want 3x2, 2 layers, 16-bit", m.Width, m.Height, m.LayerCount, m.CellBits)
+	}
+	ground := m.Layers[0]
+	if want := []uint16{1, 2, 0, 3, 4, 1}; !slices.Equal(ground.Cells, want) {
+		t.Fatalf("ground cells = %v, want %v", ground.Cells, want)
+	}
+	if want := []uint8{0, 0, 0, format.CellFlipH, format.CellFlipV | format.CellFlipD, 0}; !slices.Equal(ground.Flips, want) {
+		t.Fatalf("ground flips = %v, want %v", ground.Flips, want)
+	}
+	back := m.Layers[1]
+	if back.SheetID != 2 || back.Cells[0] != 1 || back.Cells[5] != 4 || back.Flips != nil {
+		t.Fatalf("back layer = %+v, want sheet 2 with tiles 1 and 4 and no flips", back)
+	}
+	if ground.Name != "ground" || ground.Hidden || back.Name != "back" || !back.Hidden {
+		t.Fatalf("layers = %q hidden %v, %q hidden %v; want ground shown and back hidden", ground.Name, ground.Hidden, back.Name, back.Hidden)
+	}
+	if back.ScrollX == nil || *back.ScrollX != 0.5 || back.ScrollY != nil {
+		t.Fatalf("back scroll = %v/%v, want 0.5 and default", back.ScrollX, back.ScrollY)
+	}
+
+	objs := m.ObjectLayers[0].Objects
+	if m.ObjectLayers[0].Name != "spawns" || len(objs) != 3 {
+		t.Fatalf("object layers = %+v, want spawns with 3 objects", m.ObjectLayers)
+	}
+	if objs[0].Kind != "point" || objs[0].Type != "spawn" || objs[0].X != 8 || objs[0].Y != 16 {
+		t.Fatalf("player = %+v, want a spawn point at 8,16", objs[0])
+	}
+	exit := objs[1]
+	if exit.Kind != "rect" || exit.Type != "warp" || exit.H != 16 {
+		t.Fatalf("exit = %+v, want a warp rect 16 high", exit)
+	}
+	if exit.Props["to"] != "cave" || exit.Props["hp"] != 3 || exit.Props["speed"] != 1.5 || exit.Props["open"] != false {
+		t.Fatalf("exit props = %v, want typed to, hp, speed and open", exit.Props)
+	}
+	if objs[2].Kind != "polyline" || len(objs[2].Points) != 4 || objs[2].Points[3] != [2]float32{0, 0} {
+		t.Fatalf("fence = %+v, want a closed 4-point polyline", objs[2])
+	}
+
+	props := imp.Tilesets[0].Props
+	if len(props.Flags) != 1 || props.Flags[0] != "solid" {
+		t.Fatalf("flags = %v, want [solid]", props.Flags)
+	}
+	tile := props.Tiles[0]
+	if tile.ID != 2 || tile.Shape != "slope_up_45" || tile.Props["damage"] != 2 || len(tile.Flags) != 1 {
+		t.Fatalf("tile props = %+v, want tile 2 with a slope, the solid flag and damage", tile)
+	}
+	if anim := props.Anims[0]; anim.Tile != 3 || anim.FPS != 10 || !slices.Equal(anim.Frames, []uint16{3, 4}) {
+		t.Fatalf("anim = %+v, want tile 3 at 10 fps over 3,4", anim)
+	}
+	if len(imp.Warnings) != 1 || !strings.Contains(imp.Warnings[0], "map properties") {
+		t.Fatalf("warnings = %v, want one about map properties", imp.Warnings)
+	}
+
+	if _, err := format.BuildMap(m); err != nil {
+		t.Fatalf("BuildMap() error = %v", err)
+	}
+	ts := imp.Tilesets[0]
+	if _, err := format.BuildSheetWithProps(ts.Image, ts.TileWidth, ts.TileHeight, ts.Props); err != nil {
+		t.Fatalf("BuildSheetWithProps() error = %v", err)
+	}
+}
+
+func TestImportMapConvertsTMJ(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"level.tmj": `{"orientation":"orthogonal","infinite":false,"width":2,"height":2,"tilewidth":8,"tileheight":8,
+ "tilesets":[{"firstgid":1,"name":"tiles","tilewidth":8,"tileheight":8,"tilecount":4,"columns":2,"image":"tiles.png",
+  "tiles":[{"id":0,"properties":[{"name":"solid","type":"bool","value":true}]}]}],
+ "layers":[
+  {"type":"tilelayer","name":"ground","width":2,"height":2,"encoding":"base64","compression":"gzip","data":"` + encodeGIDs(t, "gzip", 1, 0, 2|gidFlipV, 4) + `"},
+  {"type":"tilelayer","name":"top","width":2,"height":2,"visible":false,"data":[0,0,0,3]},
+  {"type":"objectgroup","name":"paths","objects":[
+   {"id":1,"name":"patrol","type":"path","x":4,"y":4,"polyline":[{"x":0,"y":0},{"x":16,"y":0}],
+    "properties":[{"name":"loop","type":"bool","value":true},{"name":"wait","type":"float","value":2}]}]}
+ ]}`,
+	})
+
+	imp, err := ImportMap(filepath.Join(dir, "level.tmj"))
+	if err != nil {
+		t.Fatalf("ImportMap() error = %v", err)
+	}
+	m := imp.Map
+	if m.LayerCount != 2 || m.CellBits != 16 || m.ChunkWidth != 2 {
+		t.Fatalf("map = %d layers, %d-bit cells, chunk %d; want 2, 16-bit, 2", m.LayerCount, m.CellBits, m.ChunkWidth)
+	}
+	if want := []uint16{1, 0, 2, 4}; !slices.Equal(m.Layers[0].Cells, want) || m.Layers[0].Flips[2] != format.CellFlipV {
+		t.Fatalf("ground = %+v, want cells %v with tile 2 flipped vertically", m.Layers[0], want)
+	}
+	if m.Layers[1].Flips != nil || m.Layers[1].Cells[3] != 3 || m.Layers[1].Name != "top" || !m.Layers[1].Hidden {
+		t.Fatalf("top = %+v, want a hidden layer with tile 3 and no flips", m.Layers[1])
+	}
+	obj := m.ObjectLayers[0].Objects[0]
+	if obj.Kind != "polyline" || obj.Points[1] != [2]float32{16, 0} || obj.Props["loop"] != true || obj.Props["wait"] != 2.0 {
+		t.Fatalf("patrol = %+v, want a polyline with loop and a float wait", obj)
+	}
+	if flags := imp.Tilesets[0].Props.Flags; len(flags) != 1 || flags[0] != "solid" {
+		t.Fatalf("flags = %v, want [solid]", flags)
+	}
+}
+
+func TestImportTilesetReadsTSX(t *testing.T) {
+	dir := writeFixture(t, map[string]string{"tiles.tsx": tilesTSX})
+
+	ts, err := ImportTileset(filepath.Join(dir, "tiles.tsx"))
+	if err != nil {
+		t.Fatalf("ImportTileset() error = %v", err)
+	}
+	if ts.Name != "tiles" || ts.TileWidth != 8 || ts.Image.Bounds().Dx() != 16 {
+		t.Fatalf("tileset = %+v, want tiles with 8px tiles and a 16px image", ts)
+	}
+	if len(ts.Props.Tiles) != 1 || len(ts.Props.Anims) != 1 {
+		t.Fatalf("props = %+v, want one tile and one anim", ts.Props)
+	}
+}
+
+func TestImportMapRejectsUnsupportedFeatures(t *testing.T) {
+	const tileset = `<tileset firstgid="1" name="tiles" tilewidth="8" tileheight="8" tilecount="4" columns="2"><image source="tiles.png"/></tileset>`
+	tmx := func(attrs, body string) string {
+		return `<map orientation="orthogonal" width="1" height="1" tilewidth="8" tileheight="8" ` + attrs + `>` + tileset + body + `</map>`
+	}
+	layer := `<layer name="l"><data encoding="csv">1</data></layer>`
+	objects := func(obj string) string { return layer + `<objectgroup name="o">` + obj + `</objectgroup>` }
+
+	tests := []struct {
+		name, body, want string
+	}{
+		{"isometric", strings.Replace(tmx("", layer), "orthogonal", "isometric", 1), "isometric maps are not supported"},
+		{"infinite", tmx(`infinite="1"`, ""), "infinite maps are not supported"},
+		{"group", tmx("", layer+`<group name="g"/>`), `layer "g": group layers are not supported`},
+		{"image layer", tmx("", layer+`<imagelayer name="sky"/>`), `layer "sky": image layers are not supported`},
+		{"offset", tmx("", `<layer name="l" offsetx="4"><data encoding="csv">1</data></layer>`), "layer offsets are not supported"},
+		{"zstd", tmx("", `<layer name="l"><data encoding="base64" compression="zstd">AAAA</data></layer>`), "zstd compression is not supported"},
+		{"hex rotation", tmx("", `<layer name="l"><data encoding="csv">268435457</data></layer>`), "hexagonal rotation is not supported"},
+		{"tile beyond tileset", tmx("", `<layer name="l"><data encoding="csv">9</data></layer>`), "beyond tileset"},
+		{"ellipse", tmx("", objects(`<object id="1" width="4" height="4"><ellipse/></object>`)), "ellipse objects are not supported"},
+		{"tile object", tmx("", objects(`<object id="1" gid="1"/>`)), "tile objects are not supported"},
+		{"rotated", tmx("", objects(`<object id="1" rotation="45"/>`)), "rotated objects are not supported"},
+		{"class prop", tmx("", objects(`<object id="1"><properties><property name="c" type="class"/></properties></object>`)), "class properties are not supported"},
+		{"no tile layers", tmx("", `<objectgroup name="o"/>`), "map has no tile layers"},
+		{"tile size", strings.Replace(tmx("", layer), `tilewidth="8" tileheight="8" >`, `tilewidth="16" tileheight="16" >`, 1), "differs from map tile size"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFixture(t, map[string]string{"m.tmx": tt.body})
+			_, err := ImportMap(filepath.Join(dir, "m.tmx"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ImportMap() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportMapRejectsMixedTilesetsAndUnevenAnims(t *testing.T) {
+	dir := writeFixture(t, map[string]string{
+		"mixed.tmj": `{"orientation":"orthogonal","width":2,"height":1,"tilewidth":8,"tileheight":8,
+ "tilesets":[{"firstgid":1,"name":"a","tilewidth":8,"tileheight":8,"image":"tiles.png"},
+             {"firstgid":5,"name":"b","tilewidth":8,"tileheight":8,"image":"terrain.png"}],
+ "layers":[{"type":"tilelayer","name":"l","data":[1,5]}]}`,
+		"anim.tmj": `{"orientation":"orthogonal","width":1,"height":1,"tilewidth":8,"tileheight":8,
+ "tilesets":[{"firstgid":1,"name":"a","tilewidth":8,"tileheight":8,"image":"tiles.png",
+  "tiles":[{"id":0,"animation":[{"tileid":0,"duration":100},{"tileid":1,"duration":200}]}]}],
+ "layers":[{"type":"tilelayer","name":"l","data":[1]}]}`,
+	})
+
+	if _, err := ImportMap(filepath.Join(dir, "mixed.tmj")); err == nil || !strings.Contains(err.Error(), `mixes tilesets "a" and "b"`) {
+		t.Fatalf("mixed ImportMap() error = %v, want a mixed tileset error", err)
+	}
+	if _, err := ImportMap(filepath.Join(dir, "anim.tmj")); err == nil || !strings.Contains(err.Error(), "uneven frame durations") {
+		t.Fatalf("anim ImportMap() error = %v, want an uneven durations error", err)
+	}
+}
@@ -0,0 +1,280 @@
+package tiled
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type tmjMap struct {
+	Orientation string        `json:"orientation"`
+	Infinite    bool          `json:"infinite"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	TileWidth   int           `json:"tilewidth"`
+	TileHeight  int           `json:"tileheight"`
+	Tilesets    []tmjTileset  `json:"tilesets"`
+	Layers      []tmjLayer    `json:"layers"`
+	Properties  []tmjProperty `json:"properties"`
+}
+
+type tmjTileset struct {
+	FirstGID   int       `json:"firstgid"`
+	Source     string    `json:"source"`
+	Name       string    `json:"name"`
+	TileWidth  int       `json:"tilewidth"`
+	TileHeight int       `json:"tileheight"`
+	Spacing    int       `json:"spacing"`
+	Margin     int       `json:"margin"`
+	TileCount  int       `json:"tilecount"`
+	Columns    int       `json:"columns"`
+	Image      string    `json:"image"`
+	Tiles      []tmjTile `json:"tiles"`
+}
+
+type tmjTile struct {
+	ID         int           `json:"id"`
+	Image      string        `json:"image"`
+	Properties []tmjProperty `json:"properties"`
+	Animation  []struct {
+		TileID   int `json:"tileid"`
+		Duration int `json:"duration"`
+	} `json:"animation"`
+}
+
+// tmjProperty decodes with UseNumber, so numeric values are json.Number.
+type tmjProperty struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value any    `json:"value"`
+}
+
+type tmjLayer struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Visible     *bool           `json:"visible"`
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	OffsetX     float64         `json:"offsetx"`
+	OffsetY     float64         `json:"offsety"`
+	ParallaxX   *float64        `json:"parallaxx"`
+	ParallaxY   *float64        `json:"parallaxy"`
+	Objects     []tmjObject     `json:"objects"`
+	Properties  []tmjProperty   `json:"properties"`
+}
+
+type tmjObject struct {
+	ID         int             `json:"id"`
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Class      string          `json:"class"`
+	X          float64         `json:"x"`
+	Y          float64         `json:"y"`
+	Width      float64         `json:"width"`
+	Height     float64         `json:"height"`
+	Rotation   float64         `json:"rotation"`
+	GID        uint32          `json:"gid"`
+	Point      bool            `json:"point"`
+	Ellipse    bool            `json:"ellipse"`
+	Text       json.RawMessage `json:"text"`
+	Polygon    []tmjPoint      `json:"polygon"`
+	Polyline   []tmjPoint      `json:"polyline"`
+	Properties []tmjProperty   `json:"properties"`
+}
+
+type tmjPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+func decodeJSON(raw []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func parseTMJ(raw []byte) (mapDoc, error) {
+	var m tmjMap
+	if err := decodeJSON(raw, &m); err != nil {
+		return mapDoc{}, err
+	}
+	doc := mapDoc{
+		Orientation: m.Orientation,
+		Infinite:    m.Infinite,
+		Width:       m.Width,
+		Height:      m.Height,
+		TileWidth:   m.TileWidth,
+		TileHeight:  m.TileHeight,
+	}
+	if doc.Infinite {
+		// Infinite maps store chunks; convertMap rejects them.
+		return doc, nil
+	}
+	var err error
+	if doc.Props, err = tmjProps(m.Properties); err != nil {
+		return mapDoc{}, err
+	}
+	for _, ts := range m.Tilesets {
+		ref := tilesetRef{FirstGID: ts.FirstGID, Source: ts.Source}
+		if ts.Source == "" {
+			t, err := tmjTilesetDoc(ts)
+			if err != nil {
+				return mapDoc{}, err
+			}
+			ref.Tileset = &t
+		}
+		doc.Tilesets = append(doc.Tilesets, ref)
+	}
+
+	for _, l := range m.Layers {
+		layer := layerDoc{
+			Kind:      l.Type,
+			Name:      l.Name,
+			Hidden:    l.Visible != nil && !*l.Visible,
+			OffsetX:   l.OffsetX,
+			OffsetY:   l.OffsetY,
+			ParallaxX: 1,
+			ParallaxY: 1,
+		}
+		if l.ParallaxX != nil {
+			layer.ParallaxX = *l.ParallaxX
+		}
+		if l.ParallaxY != nil {
+			layer.ParallaxY = *l.ParallaxY
+		}
+		if layer.Props, err = tmjProps(l.Properties); err != nil {
+			return mapDoc{}, fmt.Errorf("layer %q: %w", l.Name, err)
+		}
+		switch l.Type {
+		case kindTiles:
+			if layer.GIDs, err = tmjData(l); err != nil {
+				return mapDoc{}, fmt.Errorf("layer %q: %w", l.Name, err)
+			}
+		case kindObjects:
+			for _, o := range l.Objects {
+				obj, err := tmjObjectDoc(o)
+				if err != nil {
+					return mapDoc{}, fmt.Errorf("layer %q: object %d: %w", l.Name, o.ID, err)
+				}
+				layer.Objects = append(layer.Objects, obj)
+			}
+		case "imagelayer":
+			layer.Kind = "image"
+		}
+		doc.Layers = append(doc.Layers, layer)
+	}
+	return doc, nil
+}
+
+func parseTSJ(raw []byte) (tilesetDoc, error) {
+	var ts tmjTileset
+	if err := decodeJSON(raw, &ts); err != nil {
+		return tilesetDoc{}, err
+	}
+	return tmjTilesetDoc(ts)
+}
+
+func tmjTilesetDoc(ts tmjTileset) (tilesetDoc, error) {
+	doc := tilesetDoc{
+		Name:       ts.Name,
+		TileWidth:  ts.TileWidth,
+		TileHeight: ts.TileHeight,
+		Spacing:    ts.Spacing,
+		Margin:     ts.Margin,
+		TileCount:  ts.TileCount,
+		Columns:    ts.Columns,
+		Image:      ts.Image,
+	}
+	for _, t := range ts.Tiles {
+		props, err := tmjProps(t.Properties)
+		if err != nil {
+			return doc, fmt.Errorf("tile %d: %w", t.ID, err)
+		}
+		tile := tileDoc{ID: t.ID, Image: t.Image, Props: props}
+		for _, fr := range t.Animation {
+			tile.Frames = append(tile.Frames, frameDoc{TileID: fr.TileID, Duration: fr.Duration})
+		}
+		doc.Tiles = append(doc.Tiles, tile)
+	}
+	return doc, nil
+}
+
+func tmjProps(props []tmjProperty) ([]propDoc, error) {
+	var out []propDoc
+	for _, p := range props {
+		doc := propDoc{Name: p.Name, Type: p.Type}
+		switch v := p.Value.(type) {
+		case string:
+			doc.Value = v
+		case json.Number:
+			doc.Value = v.String()
+		case bool:
+			doc.Value = fmt.Sprint(v)
+		default:
+			if p.Type != "class" {
+				return nil, fmt.Errorf("property %q has an unexpected %T value", p.Name, v)
+			}
+		}
+		out = append(out, doc)
+	}
+	return out, nil
+}
+
+// tmjData reads a tile layer's data, a JSON array or base64 text.
+func tmjData(l tmjLayer) ([]uint32, error) {
+	if len(l.Data) == 0 {
+		return nil, fmt.Errorf("layer has no data")
+	}
+	if l.Encoding == "base64" {
+		var text string
+		if err := json.Unmarshal(l.Data, &text); err != nil {
+			return nil, err
+		}
+		return decodeData(text, l.Compression)
+	}
+	var gids []uint32
+	if err := json.Unmarshal(l.Data, &gids); err != nil {
+		return nil, err
+	}
+	return gids, nil
+}
+
+func tmjObjectDoc(o tmjObject) (objectDoc, error) {
+	obj := objectDoc{
+		ID:       o.ID,
+		Name:     o.Name,
+		Type:     o.Type,
+		X:        o.X,
+		Y:        o.Y,
+		W:        o.Width,
+		H:        o.Height,
+		Rotation: o.Rotation,
+		GID:      o.GID,
+		Point:    o.Point,
+		Ellipse:  o.Ellipse,
+		Text:     len(o.Text) > 0,
+	}
+	if obj.Type == "" {
+		obj.Type = o.Class
+	}
+	var err error
+	if obj.Props, err = tmjProps(o.Properties); err != nil {
+		return obj, err
+	}
+	if o.Polygon != nil {
+		obj.Polygon = tmjPointList(o.Polygon)
+	}
+	if o.Polyline != nil {
+		obj.Polyline = tmjPointList(o.Polyline)
+	}
+	return obj, nil
+}
+
+func tmjPointList(points []tmjPoint) [][2]float64 {
+	out := make([][2]float64, len(points))
+	for i, p := range points {
+		out[i] = [2]float64{p.X, p.Y}
+	}
+	return out
+}
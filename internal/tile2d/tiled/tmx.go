@@ -0,0 +1,292 @@
+package tiled
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tmxMap struct {
+	Orientation string        `xml:"orientation,attr"`
+	Infinite    int           `xml:"infinite,attr"`
+	Width       int           `xml:"width,attr"`
+	Height      int           `xml:"height,attr"`
+	TileWidth   int           `xml:"tilewidth,attr"`
+	TileHeight  int           `xml:"tileheight,attr"`
+	Tilesets    []tmxTileset  `xml:"tileset"`
+	Properties  []tmxProperty `xml:"properties>property"`
+	// Layers keeps tile, object, image and group layers in file order.
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTileset struct {
+	FirstGID   int    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Margin     int    `xml:"margin,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Image      struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	Tiles []tmxTile `xml:"tile"`
+}
+
+type tmxTile struct {
+	ID    int `xml:"id,attr"`
+	Image struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Animation  []struct {
+		TileID   int `xml:"tileid,attr"`
+		Duration int `xml:"duration,attr"`
+	} `xml:"animation>frame"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	// Text holds multi-line string values.
+	Text string `xml:",chardata"`
+}
+
+type tmxLayer struct {
+	XMLName    xml.Name
+	Name       string        `xml:"name,attr"`
+	Visible    *int          `xml:"visible,attr"`
+	OffsetX    float64       `xml:"offsetx,attr"`
+	OffsetY    float64       `xml:"offsety,attr"`
+	ParallaxX  *float64      `xml:"parallaxx,attr"`
+	ParallaxY  *float64      `xml:"parallaxy,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Data       struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Text        string `xml:",chardata"`
+		Tiles       []struct {
+			GID uint32 `xml:"gid,attr"`
+		} `xml:"tile"`
+	} `xml:"data"`
+	Objects []tmxObject `xml:"object"`
+}
+
+type tmxObject struct {
+	ID         int           `xml:"id,attr"`
+	Name       string        `xml:"name,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr"`
+	Height     float64       `xml:"height,attr"`
+	Rotation   float64       `xml:"rotation,attr"`
+	GID        uint32        `xml:"gid,attr"`
+	Point      *struct{}     `xml:"point"`
+	Ellipse    *struct{}     `xml:"ellipse"`
+	Text       *struct{}     `xml:"text"`
+	Polygon    *tmxPoints    `xml:"polygon"`
+	Polyline   *tmxPoints    `xml:"polyline"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxPoints struct {
+	Points string `xml:"points,attr"`
+}
+
+func parseTMX(raw []byte) (mapDoc, error) {
+	var m tmxMap
+	if err := xml.Unmarshal(raw, &m); err != nil {
+		return mapDoc{}, err
+	}
+	doc := mapDoc{
+		Orientation: m.Orientation,
+		Infinite:    m.Infinite != 0,
+		Width:       m.Width,
+		Height:      m.Height,
+		TileWidth:   m.TileWidth,
+		TileHeight:  m.TileHeight,
+		Props:       tmxProps(m.Properties),
+	}
+	if doc.Infinite {
+		// Infinite maps store chunks; convertMap rejects them.
+		return doc, nil
+	}
+	for _, ts := range m.Tilesets {
+		ref := tilesetRef{FirstGID: ts.FirstGID, Source: ts.Source}
+		if ts.Source == "" {
+			t := tmxTilesetDoc(ts)
+			ref.Tileset = &t
+		}
+		doc.Tilesets = append(doc.Tilesets, ref)
+	}
+
+	for _, l := range m.Layers {
+		layer := layerDoc{
+			Name:      l.Name,
+			Hidden:    l.Visible != nil && *l.Visible == 0,
+			OffsetX:   l.OffsetX,
+			OffsetY:   l.OffsetY,
+			ParallaxX: 1,
+			ParallaxY: 1,
+			Props:     tmxProps(l.Properties),
+		}
+		if l.ParallaxX != nil {
+			layer.ParallaxX = *l.ParallaxX
+		}
+		if l.ParallaxY != nil {
+			layer.ParallaxY = *l.ParallaxY
+		}
+		switch l.XMLName.Local {
+		case "layer":
+			layer.Kind = kindTiles
+			gids, err := tmxData(l)
+			if err != nil {
+				return mapDoc{}, fmt.Errorf("layer %q: %w", l.Name, err)
+			}
+			layer.GIDs = gids
+		case "objectgroup":
+			layer.Kind = kindObjects
+			for _, o := range l.Objects {
+				obj, err := tmxObjectDoc(o)
+				if err != nil {
+					return mapDoc{}, fmt.Errorf("layer %q: object %d: %w", l.Name, o.ID, err)
+				}
+				layer.Objects = append(layer.Objects, obj)
+			}
+		case "imagelayer":
+			layer.Kind = "image"
+		case "group":
+			layer.Kind = "group"
+		default:
+			continue
+		}
+		doc.Layers = append(doc.Layers, layer)
+	}
+	return doc, nil
+}
+
+func parseTSX(raw []byte) (tilesetDoc, error) {
+	var ts tmxTileset
+	if err := xml.Unmarshal(raw, &ts); err != nil {
+		return tilesetDoc{}, err
+	}
+	return tmxTilesetDoc(ts), nil
+}
+
+func tmxTilesetDoc(ts tmxTileset) tilesetDoc {
+	doc := tilesetDoc{
+		Name:       ts.Name,
+		TileWidth:  ts.TileWidth,
+		TileHeight: ts.TileHeight,
+		Spacing:    ts.Spacing,
+		Margin:     ts.Margin,
+		TileCount:  ts.TileCount,
+		Columns:    ts.Columns,
+		Image:      ts.Image.Source,
+	}
+	for _, t := range ts.Tiles {
+		tile := tileDoc{ID: t.ID, Image: t.Image.Source, Props: tmxProps(t.Properties)}
+		for _, fr := range t.Animation {
+			tile.Frames = append(tile.Frames, frameDoc{TileID: fr.TileID, Duration: fr.Duration})
+		}
+		doc.Tiles = append(doc.Tiles, tile)
+	}
+	return doc
+}
+
+func tmxProps(props []tmxProperty) []propDoc {
+	var out []propDoc
+	for _, p := range props {
+		value := p.Value
+		if value == "" {
+			value = p.Text
+		}
+		out = append(out, propDoc{Name: p.Name, Type: p.Type, Value: value})
+	}
+	return out
+}
+
+func tmxData(l tmxLayer) ([]uint32, error) {
+	data := l.Data
+	switch data.Encoding {
+	case "csv":
+		var gids []uint32
+		for _, field := range strings.Split(data.Text, ",") {
+			n, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			gids = append(gids, uint32(n))
+		}
+		return gids, nil
+	case "base64":
+		return decodeData(data.Text, data.Compression)
+	case "":
+		gids := make([]uint32, 0, len(data.Tiles))
+		for _, t := range data.Tiles {
+			gids = append(gids, t.GID)
+		}
+		return gids, nil
+	}
+	return nil, fmt.Errorf("%s encoding is not supported", data.Encoding)
+}
+
+func tmxObjectDoc(o tmxObject) (objectDoc, error) {
+	obj := objectDoc{
+		ID:       o.ID,
+		Name:     o.Name,
+		Type:     o.Type,
+		X:        o.X,
+		Y:        o.Y,
+		W:        o.Width,
+		H:        o.Height,
+		Rotation: o.Rotation,
+		GID:      o.GID,
+		Point:    o.Point != nil,
+		Ellipse:  o.Ellipse != nil,
+		Text:     o.Text != nil,
+		Props:    tmxProps(o.Properties),
+	}
+	if obj.Type == "" {
+		obj.Type = o.Class
+	}
+	var err error
+	if o.Polygon != nil {
+		if obj.Polygon, err = tmxPointList(o.Polygon.Points); err != nil {
+			return obj, err
+		}
+	}
+	if o.Polyline != nil {
+		if obj.Polyline, err = tmxPointList(o.Polyline.Points); err != nil {
+			return obj, err
+		}
+	}
+	return obj, nil
+}
+
+// tmxPointList parses "x,y x,y ..." into points.
+func tmxPointList(s string) ([][2]float64, error) {
+	points := [][2]float64{}
+	for _, pair := range strings.Fields(s) {
+		xs, ys, ok := strings.Cut(pair, ",")
+		if !ok {
+			return nil, fmt.Errorf("bad point %q", pair)
+		}
+		x, err := strconv.ParseFloat(xs, 64)
+		if err != nil {
+			return nil, err
+		}
+		y, err := strconv.ParseFloat(ys, 64)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, [2]float64{x, y})
+	}
+	return points, nil
+}
@@ -0,0 +1,75 @@
+package visibility
+
+import "math"
+
+// Transformed reports whether the camera zooms or rotates. Untransformed
+// cameras map world pixels to screen pixels by subtracting X and Y.
+func (c Camera) Transformed() bool {
+	return (c.Zoom != 0 && c.Zoom != 1) || c.Rotation != 0
+}
+
+func (c Camera) zoom() float32 {
+	if c.Zoom == 0 {
+		return 1
+	}
+	return c.Zoom
+}
+
+// center returns the world point shown at the middle of the screen.
+func (c Camera) center() (float32, float32) {
+	z := c.zoom()
+	return float32(c.X) + float32(c.Width)/(2*z), float32(c.Y) + float32(c.Height)/(2*z)
+}
+
+// WorldToScreen maps a world position to screen pixels. Zoom scales around
+// the camera's top-left corner and rotation turns the view around the
+// middle of the screen.
+func (c Camera) WorldToScreen(x, y float32) (float32, float32) {
+	z := c.zoom()
+	if c.Rotation == 0 {
+		return (x - float32(c.X)) * z, (y - float32(c.Y)) * z
+	}
+	cx, cy := c.center()
+	sin, cos := math.Sincos(float64(c.Rotation))
+	dx, dy := float64(x-cx), float64(y-cy)
+	sx := float64(c.Width)/2 + float64(z)*(dx*cos+dy*sin)
+	sy := float64(c.Height)/2 + float64(z)*(-dx*sin+dy*cos)
+	return float32(sx), float32(sy)
+}
+
+// ScreenToWorld is the inverse of WorldToScreen.
+func (c Camera) ScreenToWorld(x, y float32) (float32, float32) {
+	z := c.zoom()
+	if c.Rotation == 0 {
+		return x/z + float32(c.X), y/z + float32(c.Y)
+	}
+	cx, cy := c.center()
+	sin, cos := math.Sincos(float64(c.Rotation))
+	dx := float64(x) - float64(c.Width)/2
+	dy := float64(y) - float64(c.Height)/2
+	wx := float64(cx) + (dx*cos-dy*sin)/float64(z)
+	wy := float64(cy) + (dx*sin+dy*cos)/float64(z)
+	return float32(wx), float32(wy)
+}
+
+// ViewBounds returns the world-space rectangle that encloses everything the
+// camera shows, rounded outward to whole pixels.
+func (c Camera) ViewBounds() Camera {
+	if !c.Transformed() {
+		return Camera{X: c.X, Y: c.Y, Width: c.Width, Height: c.Height}
+	}
+	minX, minY := float32(math.MaxFloat32), float32(math.MaxFloat32)
+	maxX, maxY := float32(-math.MaxFloat32), float32(-math.MaxFloat32)
+	for _, corner := range [4][2]float32{
+		{0, 0}, {float32(c.Width), 0}, {float32(c.Width), float32(c.Height)}, {0, float32(c.Height)},
+	} {
+		wx, wy := c.ScreenToWorld(corner[0], corner[1])
+		minX, maxX = min(minX, wx), max(maxX, wx)
+		minY, maxY = min(minY, wy), max(maxY, wy)
+	}
+	x0 := int(math.Floor(float64(minX)))
+	y0 := int(math.Floor(float64(minY)))
+	x1 := int(math.Ceil(float64(maxX)))
+	y1 := int(math.Ceil(float64(maxY)))
+	return Camera{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
@@ -0,0 +1,58 @@
+package visibility
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 0.01
+}
+
+func TestCameraWorldToScreenZoomsFromTopLeft(t *testing.T) {
+	cam := Camera{X: 100, Y: 50, Width: 288, Height: 216, Zoom: 2}
+	if x, y := cam.WorldToScreen(150, 75); !near(x, 100) || !near(y, 50) {
+		t.Fatalf("WorldToScreen() = (%v,%v), want (100,50)", x, y)
+	}
+}
+
+func TestCameraRotationTurnsAroundScreenCenter(t *testing.T) {
+	cam := Camera{Width: 200, Height: 100, Rotation: math.Pi / 2}
+	// The world point at the screen center does not move.
+	if x, y := cam.WorldToScreen(100, 50); !near(x, 100) || !near(y, 50) {
+		t.Fatalf("center maps to (%v,%v), want (100,50)", x, y)
+	}
+	// A point right of center ends up above it.
+	if x, y := cam.WorldToScreen(110, 50); !near(x, 100) || !near(y, 40) {
+		t.Fatalf("WorldToScreen(110,50) = (%v,%v), want (100,40)", x, y)
+	}
+}
+
+func TestCameraScreenToWorldInvertsWorldToScreen(t *testing.T) {
+	cam := Camera{X: -30, Y: 12, Width: 288, Height: 216, Zoom: 1.5, Rotation: 0.7}
+	sx, sy := cam.WorldToScreen(40, -25)
+	if x, y := cam.ScreenToWorld(sx, sy); !near(x, 40) || !near(y, -25) {
+		t.Fatalf("round trip = (%v,%v), want (40,-25)", x, y)
+	}
+}
+
+func TestCameraViewBounds(t *testing.T) {
+	zoomedOut := Camera{X: 0, Y: 0, Width: 100, Height: 50, Zoom: 0.5}.ViewBounds()
+	if zoomedOut != (Camera{Width: 200, Height: 100}) {
+		t.Fatalf("zoomed-out ViewBounds() = %+v, want 200x100 at the origin", zoomedOut)
+	}
+
+	turned := Camera{Width: 100, Height: 50, Rotation: math.Pi / 2}.ViewBounds()
+	if turned.Width < 50 || turned.Width > 52 || turned.Height < 100 || turned.Height > 102 {
+		t.Fatalf("rotated ViewBounds() = %+v, want about 50x100", turned)
+	}
+}
+
+func TestVisibleCellBoundsGrowWhenZoomedOut(t *testing.T) {
+	m := MapInfo{Width: 64, Height: 64, TileWidth: 8, TileHeight: 8}
+	got := VisibleCellBounds(Camera{Width: 64, Height: 64, Zoom: 0.5}, m)
+	want := VisibleBounds{MaxX: 16, MaxY: 16}
+	if got != want {
+		t.Fatalf("VisibleCellBounds() = %+v, want %+v", got, want)
+	}
+}
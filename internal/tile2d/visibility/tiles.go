@@ -1,21 +1,33 @@
 package visibility
 
+// Camera is the visible region in world pixels. Zoom (0 means 1) and
+// Rotation (radians) transform the view; see WorldToScreen.
 type Camera struct {
 	X, Y          int
 	Width, Height int
+
+	Zoom     float32
+	Rotation float32
 }
 
 type MapInfo struct {
 	Width, Height         int
 	TileWidth, TileHeight int
+
+	// RepeatX and RepeatY tile the map endlessly along that axis.
+	RepeatX, RepeatY bool
 }
 
+// VisibleBounds is a half-open cell range. On a repeating axis the range is
+// not clamped to the map and may hold negative or out-of-map cells; use
+// WrapCell to find the map cell they show.
 type VisibleBounds struct {
 	MinX, MinY int
 	MaxX, MaxY int
 }
 
 func VisibleCellBounds(cam Camera, m MapInfo) VisibleBounds {
+	cam = cam.ViewBounds()
 	if m.Width <= 0 || m.Height <= 0 || m.TileWidth <= 0 || m.TileHeight <= 0 || cam.Width <= 0 || cam.Height <= 0 {
 		return VisibleBounds{}
 	}
@@ -26,10 +38,19 @@ func VisibleCellBounds(cam Camera, m MapInfo) VisibleBounds {
 	maxX := min(m.Width, (cam.X+cam.Width+m.TileWidth-1)/m.TileWidth)
 	maxY := min(m.Height, (cam.Y+cam.Height+m.TileHeight-1)/m.TileHeight)
 
-	if minX > m.Width {
+	if m.RepeatX {
+		minX = FloorDiv(cam.X, m.TileWidth)
+		maxX = FloorDiv(cam.X+cam.Width+m.TileWidth-1, m.TileWidth)
+	}
+	if m.RepeatY {
+		minY = FloorDiv(cam.Y, m.TileHeight)
+		maxY = FloorDiv(cam.Y+cam.Height+m.TileHeight-1, m.TileHeight)
+	}
+
+	if minX > m.Width && !m.RepeatX {
 		minX = m.Width
 	}
-	if minY > m.Height {
+	if minY > m.Height && !m.RepeatY {
 		minY = m.Height
 	}
 	if maxX < minX {
@@ -46,3 +67,26 @@ func VisibleCellBounds(cam Camera, m MapInfo) VisibleBounds {
 		MaxY: maxY,
 	}
 }
+
+// WrapCell maps a cell coordinate on a repeating axis of size n back into
+// 0..n-1.
+func WrapCell(v, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	v %= n
+	if v < 0 {
+		v += n
+	}
+	return v
+}
+
+// FloorDiv divides rounding toward negative infinity, so cells left of or
+// above the origin stay on the correct side of zero.
+func FloorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
@@ -11,3 +11,35 @@ func TestVisibleCellBoundsClampToMap(t *testing.T) {
 		t.Fatalf("unexpected min bounds: %+v", got)
 	}
 }
+
+func TestVisibleCellBoundsRepeatXIsNotClamped(t *testing.T) {
+	got := VisibleCellBounds(
+		Camera{X: -12, Y: 0, Width: 40, Height: 8},
+		MapInfo{Width: 2, Height: 1, TileWidth: 8, TileHeight: 8, RepeatX: true},
+	)
+	want := VisibleBounds{MinX: -2, MinY: 0, MaxX: 4, MaxY: 1}
+	if got != want {
+		t.Fatalf("VisibleCellBounds() = %+v, want %+v", got, want)
+	}
+}
+
+func TestVisibleCellBoundsRepeatYPastMapEnd(t *testing.T) {
+	got := VisibleCellBounds(
+		Camera{X: 0, Y: 100, Width: 8, Height: 16},
+		MapInfo{Width: 1, Height: 2, TileWidth: 8, TileHeight: 8, RepeatY: true},
+	)
+	want := VisibleBounds{MinX: 0, MinY: 12, MaxX: 1, MaxY: 15}
+	if got != want {
+		t.Fatalf("VisibleCellBounds() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWrapCell(t *testing.T) {
+	for _, tc := range []struct{ v, n, want int }{
+		{0, 4, 0}, {5, 4, 1}, {-1, 4, 3}, {-8, 4, 0}, {3, 0, 0},
+	} {
+		if got := WrapCell(tc.v, tc.n); got != tc.want {
+			t.Fatalf("WrapCell(%d, %d) = %d, want %d", tc.v, tc.n, got, tc.want)
+		}
+	}
+}
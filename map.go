@@ -10,18 +10,43 @@ type Map struct {
 	cachedLayerInfo []MapLayerInfo
 	tileW           int
 	tileH           int
+	shapes          []TileShape
+	layerSheets     []*Sheet
+	onChange        []TileChangeFunc
+	objects         [][]MapObject
+
+	// stream is set for streamed maps, whose layers hold no cells.
+	stream *mapStream
 }
 
 type MapLayerInfo struct {
 	SheetID      uint16
 	NonZeroTiles int
+
+	// Parallax holds the layer's scroll factors from the map asset;
+	// {1, 1} scrolls with the camera.
+	Parallax ParallaxLayer
+	RepeatX  bool
+	RepeatY  bool
+
+	// Name is the layer's name in the map asset, if any. Hidden layers
+	// start hidden in a Scene; Scene.SetLayerVisible shows them.
+	Name   string
+	Hidden bool
 }
 
 func newMap(parsed format.ParsedMap) *Map {
-	m := &Map{parsed: parsed}
+	m := &Map{parsed: parsed, objects: newMapObjects(parsed.ObjectLayers)}
 	m.cachedLayerInfo = make([]MapLayerInfo, len(parsed.Layers))
 	for i, layer := range parsed.Layers {
-		info := MapLayerInfo{SheetID: layer.SheetID}
+		info := MapLayerInfo{
+			SheetID:  layer.SheetID,
+			Parallax: ParallaxLayer{SpeedX: layer.ScrollX, SpeedY: layer.ScrollY},
+			RepeatX:  layer.RepeatX,
+			RepeatY:  layer.RepeatY,
+			Name:     layer.Name,
+			Hidden:   layer.Hidden,
+		}
 		if info.SheetID == 0 {
 			info.SheetID = 1
 		}
@@ -77,6 +102,19 @@ func (m *Map) LayerInfo(layer int) (MapLayerInfo, bool) {
 	return m.cachedLayerInfo[layer], true
 }
 
+// LayerByName returns the index of the first layer called name.
+func (m *Map) LayerByName(name string) (int, bool) {
+	if m == nil {
+		return 0, false
+	}
+	for i, info := range m.cachedLayerInfo {
+		if info.Name == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 func (m *Map) LayerSheetID(layer int) (uint16, bool) {
 	info, ok := m.LayerInfo(layer)
 	if !ok {
@@ -85,15 +123,26 @@ func (m *Map) LayerSheetID(layer int) (uint16, bool) {
 	return info.SheetID, true
 }
 
-func (m *Map) TileAt(layer, x, y int) (uint16, bool) {
+// TileAt returns the tile ID and flip bits of cell (x, y) in layer. The
+// ID never includes the flip bits.
+func (m *Map) TileAt(layer, x, y int) (uint16, TileFlip, bool) {
 	if m == nil || layer < 0 || layer >= len(m.parsed.Layers) {
-		return 0, false
+		return 0, 0, false
 	}
 	if x < 0 || x >= m.Width() || y < 0 || y >= m.Height() {
-		return 0, false
+		return 0, 0, false
+	}
+	if m.stream != nil {
+		tile, flip := m.stream.cell(layer, x, y)
+		return tile, TileFlip(flip), true
 	}
 	idx := y*m.Width() + x
-	return m.parsed.Layers[layer].Cells[idx], true
+	parsed := m.parsed.Layers[layer]
+	var flip TileFlip
+	if parsed.Flips != nil {
+		flip = TileFlip(parsed.Flips[idx])
+	}
+	return parsed.Cells[idx], flip, true
 }
 
 func (m *Map) PixelWidth() int {
@@ -121,24 +170,59 @@ func (m *Map) renderLayers(tileWidth, tileHeight int) []sceneRenderLayerSource {
 	}
 	if len(m.parsed.Layers) == 0 {
 		return []sceneRenderLayerSource{{
-			Map:   mapInfo,
-			Tiles: make([][]uint16, h),
+			Map:      mapInfo,
+			Tiles:    make([][]uint16, h),
+			Parallax: ParallaxLayer{SpeedX: 1, SpeedY: 1},
 		}}
 	}
 
 	layers := make([]sceneRenderLayerSource, 0, len(m.parsed.Layers))
 	for _, parsedLayer := range m.parsed.Layers {
 		layer := sceneRenderLayerSource{
-			Map:     mapInfo,
-			SheetID: parsedLayer.SheetID,
-			Tiles:   make([][]uint16, h),
+			Map:      mapInfo,
+			SheetID:  parsedLayer.SheetID,
+			Tiles:    make([][]uint16, h),
+			Parallax: ParallaxLayer{SpeedX: parsedLayer.ScrollX, SpeedY: parsedLayer.ScrollY},
 		}
+		layer.Map.RepeatX = parsedLayer.RepeatX
+		layer.Map.RepeatY = parsedLayer.RepeatY
+		if m.stream != nil {
+			// Streamed layers start empty and fill in as chunks load.
+			layers = append(layers, layer)
+			continue
+		}
+		// Rows share the map's cells; the preparer only reads them.
 		for y := range layer.Tiles {
 			start := y * w
 			end := start + w
-			layer.Tiles[y] = append([]uint16(nil), parsedLayer.Cells[start:end]...)
+			layer.Tiles[y] = parsedLayer.Cells[start:end:end]
+		}
+		if parsedLayer.Flips != nil {
+			layer.Flips = make([][]uint8, h)
+			for y := range layer.Flips {
+				layer.Flips[y] = parsedLayer.Flips[y*w : (y+1)*w : (y+1)*w]
+			}
 		}
 		layers = append(layers, layer)
 	}
 	return layers
 }
+
+// rowSpans returns the cells of row y of layer held in RAM, with their
+// flip bits (nil when the map has none), and the layer's sheet ID. A map
+// loaded whole has one span covering the row.
+func (m *Map) rowSpans(layer, y int) ([]mapRowSpan, uint16, bool) {
+	if m == nil || layer < 0 || layer >= len(m.parsed.Layers) || y < 0 || y >= m.Height() {
+		return nil, 0, false
+	}
+	parsed := m.parsed.Layers[layer]
+	if m.stream != nil {
+		return m.stream.rowSpans(layer, y), parsed.SheetID, true
+	}
+	start, end := y*m.Width(), (y+1)*m.Width()
+	span := mapRowSpan{tiles: parsed.Cells[start:end]}
+	if parsed.Flips != nil {
+		span.flips = parsed.Flips[start:end]
+	}
+	return []mapRowSpan{span}, parsed.SheetID, true
+}
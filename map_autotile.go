@@ -0,0 +1,100 @@
+package gosprite64
+
+import (
+	"image"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/autotile"
+)
+
+// autotileSet returns the sheet's terrain rules, or nil if it has none.
+func (s *Sheet) autotileSet() *autotile.Set {
+	if s == nil || len(s.parsed.Autotile) == 0 {
+		return nil
+	}
+	if s.autotile == nil {
+		s.autotile = autotile.New(s.parsed.Autotile)
+	}
+	return s.autotile
+}
+
+// layerAutotile returns the terrain rules of layer's sheet. Maps get their
+// sheets from a scene, so maps outside one have none.
+func (m *Map) layerAutotile(layer int) *autotile.Set {
+	if m == nil || layer < 0 || layer >= len(m.layerSheets) {
+		return nil
+	}
+	return m.layerSheets[layer].autotileSet()
+}
+
+// TerrainAt returns the terrain of a cell: the terrain whose autotile rules
+// in the layer's sheet use the cell's tile, or 0.
+func (m *Map) TerrainAt(layer, x, y int) uint16 {
+	set := m.layerAutotile(layer)
+	if set == nil {
+		return 0
+	}
+	tile, _, _ := m.TileAt(layer, x, y)
+	return set.TerrainOf(tile)
+}
+
+// SetTerrainAt paints a cell with terrain, or empties it for terrain 0,
+// and re-tiles it and its neighbours with ApplyAutotile. It reports false
+// for cells outside the map and terrains the layer's sheet has no rules
+// for.
+func (m *Map) SetTerrainAt(layer, x, y int, terrain uint16) bool {
+	set := m.layerAutotile(layer)
+	if set == nil {
+		return false
+	}
+	var tile uint16
+	if terrain != 0 {
+		full, ok := set.Full(terrain)
+		if !ok {
+			return false
+		}
+		tile = full
+	}
+	if !m.SetTileAt(layer, x, y, tile) {
+		return false
+	}
+	m.ApplyAutotile(layer, image.Rect(x, y, x+1, y+1))
+	return true
+}
+
+// ApplyAutotile re-picks the tiles of the terrain cells in region, in
+// cells, and of the cells bordering it, from the autotile rules of the
+// layer's sheet. Call it after editing cells with SetTileAt. Cells whose
+// tile belongs to no terrain are left alone. It returns the number of
+// cells changed.
+func (m *Map) ApplyAutotile(layer int, region image.Rectangle) int {
+	set := m.layerAutotile(layer)
+	if set == nil {
+		return 0
+	}
+	region = region.Inset(-1).Intersect(image.Rect(0, 0, m.Width(), m.Height()))
+	terrain := func(x, y int) (uint16, bool) {
+		tile, _, ok := m.TileAt(layer, x, y)
+		return set.TerrainOf(tile), ok
+	}
+
+	type change struct {
+		x, y int
+		tile uint16
+	}
+	var changes []change
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		for x := region.Min.X; x < region.Max.X; x++ {
+			tile, ok := set.Tile(terrain, x, y)
+			if !ok {
+				continue
+			}
+			if old, flip, _ := m.TileAt(layer, x, y); old != tile || flip != 0 {
+				changes = append(changes, change{x, y, tile})
+			}
+		}
+	}
+	for _, c := range changes {
+		m.SetTileAt(layer, c.x, c.y, c.tile)
+	}
+	return len(changes)
+}
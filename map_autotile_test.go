@@ -0,0 +1,83 @@
+package gosprite64
+
+import (
+	"image"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// loadAutotileScene loads an empty 4x3 map over a sheet whose terrain 1
+// uses edge16 rules with tile 1+mask, N=1 E=2 S=4 W=8.
+func loadAutotileScene(t *testing.T) *Scene {
+	t.Helper()
+	tiles := make([]uint16, 16)
+	for i := range tiles {
+		tiles[i] = uint16(i + 1)
+	}
+	return loadTestSceneWithTiles(t, format.MapConfig{
+		Width: 4, Height: 3, LayerCount: 1, CellBits: 8, ChunkWidth: 4, ChunkHeight: 3,
+		Layers: []format.MapLayerConfig{{Cells: make([]uint16, 12)}},
+	}, format.TilePropsConfig{
+		Autotile: []format.AutotileConfig{{Terrain: 1, Mode: "edge16", Tiles: tiles}},
+	}, 17)
+}
+
+func TestSetTerrainAtRetilesNeighbours(t *testing.T) {
+	scene := loadAutotileScene(t)
+	m := scene.Map()
+
+	if !m.SetTerrainAt(0, 1, 1, 1) {
+		t.Fatal("SetTerrainAt() = false")
+	}
+	if tile, _, _ := m.TileAt(0, 1, 1); tile != 1 {
+		t.Fatalf("lone cell = tile %d, want 1", tile)
+	}
+	m.SetTerrainAt(0, 2, 1, 1)
+	left, _, _ := m.TileAt(0, 1, 1)
+	right, _, _ := m.TileAt(0, 2, 1)
+	if left != 1+2 || right != 1+8 {
+		t.Fatalf("pair = %d, %d; want %d, %d", left, right, 1+2, 1+8)
+	}
+	if m.TerrainAt(0, 2, 1) != 1 || m.TerrainAt(0, 0, 0) != 0 {
+		t.Fatal("TerrainAt() does not follow the painted cells")
+	}
+	if draws := scene.renderScene.Layers[0].Draws; len(draws) != 2 || draws[1].Tile.TileID != 1+8 {
+		t.Fatalf("draws = %+v, want the retiled pair", draws)
+	}
+
+	m.SetTerrainAt(0, 1, 1, 0)
+	if tile, _, _ := m.TileAt(0, 2, 1); tile != 1 {
+		t.Fatalf("cell left alone = tile %d, want 1", tile)
+	}
+	if m.SetTerrainAt(0, 0, 0, 7) || m.SetTerrainAt(0, 9, 0, 1) {
+		t.Fatal("SetTerrainAt() accepted an unknown terrain or a cell outside the map")
+	}
+}
+
+func TestApplyAutotileFixesSeamsAfterEdits(t *testing.T) {
+	scene := loadAutotileScene(t)
+	m := scene.Map()
+
+	// Paint a 3x1 strip with any terrain tile, then retile it at once.
+	for x := 0; x < 3; x++ {
+		m.SetTileAt(0, x, 2, 16)
+	}
+	if n := m.ApplyAutotile(0, image.Rect(0, 2, 3, 3)); n != 3 {
+		t.Fatalf("ApplyAutotile() changed %d cells, want 3", n)
+	}
+	want := []uint16{1 + 2, 1 + 2 + 8, 1 + 8}
+	for x, w := range want {
+		if tile, _, _ := m.TileAt(0, x, 2); tile != w {
+			t.Fatalf("cell %d = tile %d, want %d", x, tile, w)
+		}
+	}
+	if n := m.ApplyAutotile(0, image.Rect(0, 0, 4, 3)); n != 0 {
+		t.Fatalf("ApplyAutotile() changed %d cells of a tiled map", n)
+	}
+
+	var lone Map
+	if lone.ApplyAutotile(0, image.Rect(0, 0, 1, 1)) != 0 {
+		t.Fatal("ApplyAutotile() changed a map outside a scene")
+	}
+}
@@ -0,0 +1,53 @@
+package gosprite64
+
+// TileChangeFunc receives a cell edit made with SetTileAt: the layer and
+// cell, the tile it held and the tile it holds now.
+type TileChangeFunc func(layer, x, y int, old, tile uint16)
+
+// SetTileAt replaces the tile in cell (x, y) of layer with id and clears
+// the cell's flip bits; id 0 empties the cell. A scene drawing the map
+// re-prepares only the edited row. It reports false for cells outside the
+// map.
+func (m *Map) SetTileAt(layer, x, y int, id uint16) bool {
+	old, flip, ok := m.TileAt(layer, x, y)
+	if !ok {
+		return false
+	}
+	if old == id && flip == 0 {
+		return true
+	}
+
+	if m.stream != nil {
+		m.stream.setCell(layer, x, y, id)
+	} else {
+		parsed := &m.parsed.Layers[layer]
+		idx := y*m.Width() + x
+		parsed.Cells[idx] = id
+		if parsed.Flips != nil {
+			parsed.Flips[idx] = 0
+		}
+	}
+	if layer < len(m.cachedLayerInfo) {
+		switch {
+		case old == 0 && id != 0:
+			m.cachedLayerInfo[layer].NonZeroTiles++
+		case old != 0 && id == 0:
+			m.cachedLayerInfo[layer].NonZeroTiles--
+		}
+	}
+
+	for _, fn := range m.onChange {
+		fn(layer, x, y, old, id)
+	}
+	return true
+}
+
+// OnTileChange registers fn to run after every SetTileAt that changes a
+// cell, for example to update a collision cache. Handlers run in
+// registration order, after the scene's own render update.
+func (m *Map) OnTileChange(fn TileChangeFunc) {
+	if m == nil || fn == nil {
+		return
+	}
+	m.onChange = append(m.onChange, fn)
+}
@@ -0,0 +1,110 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func editMap() format.MapConfig {
+	return format.MapConfig{
+		Width: 4, Height: 2, LayerCount: 1, CellBits: 16, ChunkWidth: 4, ChunkHeight: 2,
+		Layers: []format.MapLayerConfig{{
+			Cells: []uint16{1, 1, 2, 0, 3, 3, 3, 3},
+			Flips: []uint8{0, 0, format.CellFlipH, 0, 0, 0, 0, 0},
+		}},
+	}
+}
+
+func TestSetTileAtRebuildsOnlyTheEditedRow(t *testing.T) {
+	scene := loadTestScene(t, editMap())
+	m := scene.Map()
+	layer := &scene.renderScene.Layers[0]
+	if len(layer.Runs) != 3 {
+		t.Fatalf("runs = %+v, want 3 before the edit", layer.Runs)
+	}
+
+	if !m.SetTileAt(0, 3, 0, 2) {
+		t.Fatal("SetTileAt() = false for a cell inside the map")
+	}
+	if tile, _, _ := m.TileAt(0, 3, 0); tile != 2 {
+		t.Fatalf("TileAt(0, 3, 0) = %d, want 2", tile)
+	}
+	if info, _ := m.LayerInfo(0); info.NonZeroTiles != 8 {
+		t.Fatalf("NonZeroTiles = %d, want 8", info.NonZeroTiles)
+	}
+
+	// Row 0 becomes [1 1][2 H][2]; row 1 stays one run of tile 3.
+	want := []struct{ x, y, count int }{{0, 0, 2}, {2, 0, 1}, {3, 0, 1}, {0, 1, 4}}
+	if len(layer.Runs) != len(want) {
+		t.Fatalf("runs = %+v, want %d", layer.Runs, len(want))
+	}
+	for i, w := range want {
+		if r := layer.Runs[i]; r.CellX != w.x || r.CellY != w.y || r.Count != w.count {
+			t.Fatalf("run %d = %+v, want x=%d y=%d count=%d", i, r, w.x, w.y, w.count)
+		}
+	}
+	if len(layer.Draws) != 8 || layer.Draws[3].CellX != 3 || layer.Draws[3].Tile.TileID != 2 {
+		t.Fatalf("draws = %+v, want the new tile at (3,0)", layer.Draws)
+	}
+
+	// Replacing a flipped cell clears its flip, so it merges with its neighbour.
+	m.SetTileAt(0, 2, 0, 2)
+	if _, flip, _ := m.TileAt(0, 2, 0); flip != 0 {
+		t.Fatalf("flip = %v after SetTileAt, want 0", flip)
+	}
+	if r := layer.Runs[1]; r.CellX != 2 || r.Count != 2 {
+		t.Fatalf("run 1 = %+v, want tiles 2 at x=2 merged", r)
+	}
+
+	m.SetTileAt(0, 0, 1, 0)
+	m.SetTileAt(0, 1, 1, 0)
+	if r := layer.Runs[len(layer.Runs)-1]; r.CellX != 2 || r.Count != 2 {
+		t.Fatalf("last run = %+v, want the remaining two tiles of row 1", r)
+	}
+}
+
+func TestSetTileAtNotifiesHandlers(t *testing.T) {
+	scene := loadTestScene(t, editMap())
+	m := scene.Map()
+
+	var got []uint16
+	m.OnTileChange(func(layer, x, y int, old, tile uint16) {
+		if layer != 0 || x != 1 || y != 1 {
+			t.Fatalf("handler got cell (%d, %d, %d)", layer, x, y)
+		}
+		got = append(got, old, tile)
+	})
+	m.SetTileAt(0, 1, 1, 4)
+	m.SetTileAt(0, 1, 1, 4)
+	if len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Fatalf("handler calls = %v, want one change from 3 to 4", got)
+	}
+
+	if m.SetTileAt(0, 4, 0, 1) || m.SetTileAt(1, 0, 0, 1) {
+		t.Fatal("SetTileAt() = true for a cell outside the map")
+	}
+	var nilMap *Map
+	nilMap.OnTileChange(func(int, int, int, uint16, uint16) {})
+	if nilMap.SetTileAt(0, 0, 0, 1) {
+		t.Fatal("SetTileAt() on a nil map = true")
+	}
+}
+
+func TestSetTileAtKeepsAnimatedTilesInStep(t *testing.T) {
+	scene := loadTestSceneWithProps(t, editMap(), format.TilePropsConfig{
+		Anims: []format.TileAnimConfig{{Tile: 4, FPS: 60, Frames: []uint16{4, 1}}},
+	})
+	sheet := scene.Sheet(0)
+	scene.Update()
+
+	scene.Map().SetTileAt(0, 3, 0, 4)
+	run := scene.renderScene.Layers[0].Runs[2]
+	if run.Tile.TileID != 4 || run.Tile.Source != sheet.tileImage(1) {
+		t.Fatalf("edited run = %+v, want tile 4 showing its current frame", run)
+	}
+	scene.Update()
+	if got := scene.renderScene.Layers[0].Runs[2].Tile.Source; got != sheet.tileImage(4) {
+		t.Fatal("edited animated tile did not advance")
+	}
+}
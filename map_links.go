@@ -0,0 +1,23 @@
+package gosprite64
+
+// MapLink points at a neighbouring map, such as the next room of a world.
+// Dir names the side it lies on, usually "n", "s", "e" or "w", and OffsetX
+// and OffsetY place its top-left corner relative to this map's, in pixels.
+type MapLink struct {
+	Dir     string
+	Map     string
+	OffsetX int
+	OffsetY int
+}
+
+// Links returns the map's links to its neighbours, in file order.
+func (m *Map) Links() []MapLink {
+	if m == nil || len(m.parsed.Links) == 0 {
+		return nil
+	}
+	links := make([]MapLink, len(m.parsed.Links))
+	for i, l := range m.parsed.Links {
+		links[i] = MapLink{Dir: l.Dir, Map: l.Map, OffsetX: int(l.OffsetX), OffsetY: int(l.OffsetY)}
+	}
+	return links
+}
@@ -0,0 +1,240 @@
+package gosprite64
+
+import (
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// ObjectKind is the shape of a map object.
+type ObjectKind uint8
+
+const (
+	ObjectPoint    ObjectKind = ObjectKind(format.ObjectPoint)
+	ObjectRect     ObjectKind = ObjectKind(format.ObjectRect)
+	ObjectPolyline ObjectKind = ObjectKind(format.ObjectPolyline)
+)
+
+// MapObject is a named point, rect or polyline from one of the map's
+// object layers, in world pixels. Type is free text, such as "spawn" or
+// "trigger", and props are read through the typed accessors, which return
+// a default when the property is missing or of another type.
+type MapObject struct {
+	Name string
+	Type string
+	Kind ObjectKind
+
+	// X and Y are the point, the rect's top-left corner or the polyline's
+	// origin. W and H are the rect's size.
+	X, Y float32
+	W, H float32
+
+	// Points holds a polyline's points in world pixels.
+	Points []math2d.Vec2
+
+	props []format.ParsedObjectProp
+}
+
+// Pos returns the object's position.
+func (o MapObject) Pos() math2d.Vec2 {
+	return math2d.Vec2{X: o.X, Y: o.Y}
+}
+
+// Rect returns the object's bounds. Points have zero size.
+func (o MapObject) Rect() math2d.Rect {
+	return math2d.Rect{X: o.X, Y: o.Y, W: o.W, H: o.H}
+}
+
+func (o MapObject) prop(key string) (format.ParsedObjectProp, bool) {
+	for _, p := range o.props {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return format.ParsedObjectProp{}, false
+}
+
+// Has reports whether the object has a property called key.
+func (o MapObject) Has(key string) bool {
+	_, ok := o.prop(key)
+	return ok
+}
+
+// Int returns the int property key, a float property truncated, or def.
+func (o MapObject) Int(key string, def int) int {
+	p, _ := o.prop(key)
+	switch p.Kind {
+	case format.PropInt:
+		return int(p.Int)
+	case format.PropFloat:
+		return int(p.Float)
+	}
+	return def
+}
+
+// Float returns the float or int property key, or def.
+func (o MapObject) Float(key string, def float32) float32 {
+	p, _ := o.prop(key)
+	switch p.Kind {
+	case format.PropFloat:
+		return p.Float
+	case format.PropInt:
+		return float32(p.Int)
+	}
+	return def
+}
+
+// Str returns the string property key, or def.
+func (o MapObject) Str(key, def string) string {
+	p, _ := o.prop(key)
+	if p.Kind != format.PropString {
+		return def
+	}
+	return p.Str
+}
+
+// Bool returns the bool property key, or def.
+func (o MapObject) Bool(key string, def bool) bool {
+	p, _ := o.prop(key)
+	if p.Kind != format.PropBool {
+		return def
+	}
+	return p.Bool
+}
+
+func newMapObjects(layers []format.ParsedObjectLayer) [][]MapObject {
+	objects := make([][]MapObject, len(layers))
+	for i, layer := range layers {
+		objects[i] = make([]MapObject, len(layer.Objects))
+		for j, parsed := range layer.Objects {
+			obj := MapObject{
+				Name:  parsed.Name,
+				Type:  parsed.Type,
+				Kind:  ObjectKind(parsed.Kind),
+				X:     parsed.X,
+				Y:     parsed.Y,
+				W:     parsed.W,
+				H:     parsed.H,
+				props: parsed.Props,
+			}
+			for _, p := range parsed.Points {
+				obj.Points = append(obj.Points, math2d.Vec2{X: parsed.X + p[0], Y: parsed.Y + p[1]})
+			}
+			objects[i][j] = obj
+		}
+	}
+	return objects
+}
+
+// ObjectLayerCount returns the number of object layers. Object layers are
+// numbered apart from tile layers.
+func (m *Map) ObjectLayerCount() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.objects)
+}
+
+// ObjectLayerName returns the name of object layer layer.
+func (m *Map) ObjectLayerName(layer int) string {
+	if m == nil || layer < 0 || layer >= len(m.parsed.ObjectLayers) {
+		return ""
+	}
+	return m.parsed.ObjectLayers[layer].Name
+}
+
+// Objects returns the objects of object layer layer in file order. The
+// slice is shared with the map and must not be modified.
+func (m *Map) Objects(layer int) []MapObject {
+	if m == nil || layer < 0 || layer >= len(m.objects) {
+		return nil
+	}
+	return m.objects[layer]
+}
+
+// ObjectByName returns the first object called name, searching object
+// layers in order.
+func (m *Map) ObjectByName(name string) (MapObject, bool) {
+	if m == nil {
+		return MapObject{}, false
+	}
+	for _, layer := range m.objects {
+		for _, obj := range layer {
+			if obj.Name == name {
+				return obj, true
+			}
+		}
+	}
+	return MapObject{}, false
+}
+
+// TriggerEvent reports that a body entered or left a trigger region.
+type TriggerEvent struct {
+	Object MapObject
+	Enter  bool
+}
+
+// Triggers tracks which rect objects one body overlaps from frame to
+// frame. Use one Triggers per body:
+//
+//	doors := gosprite64.NewTriggers(m.Objects(0))
+//	for _, ev := range doors.Update(player.Rect()) {
+//		if ev.Enter && ev.Object.Type == "door" { ... }
+//	}
+type Triggers struct {
+	regions []MapObject
+	inside  []bool
+	events  []TriggerEvent
+}
+
+// NewTriggers creates a tracker for the rect objects among objects; other
+// kinds are ignored. The body starts outside every region.
+func NewTriggers(objects []MapObject) *Triggers {
+	t := &Triggers{}
+	for _, obj := range objects {
+		if obj.Kind == ObjectRect {
+			t.regions = append(t.regions, obj)
+		}
+	}
+	t.inside = make([]bool, len(t.regions))
+	return t
+}
+
+// Update moves the body to body and returns the regions it entered or
+// left since the last Update, in region order. The returned slice is
+// reused by the next call.
+func (t *Triggers) Update(body math2d.Rect) []TriggerEvent {
+	if t == nil {
+		return nil
+	}
+	t.events = t.events[:0]
+	for i, region := range t.regions {
+		in := math2d.AABBOverlap(body, region.Rect())
+		if in != t.inside[i] {
+			t.inside[i] = in
+			t.events = append(t.events, TriggerEvent{Object: region, Enter: in})
+		}
+	}
+	return t.events
+}
+
+// Inside reports whether the body was in the region called name at the
+// last Update.
+func (t *Triggers) Inside(name string) bool {
+	if t == nil {
+		return false
+	}
+	for i, region := range t.regions {
+		if region.Name == name && t.inside[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset marks the body as outside every region without reporting exits.
+func (t *Triggers) Reset() {
+	if t == nil {
+		return
+	}
+	clear(t.inside)
+}
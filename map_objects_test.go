@@ -0,0 +1,93 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+func objectMap(t *testing.T) *Map {
+	t.Helper()
+	cfg := threeLayerMap()
+	cfg.ObjectLayers = []format.MapObjectLayerConfig{
+		{Name: "spawns", Objects: []format.MapObjectConfig{
+			{Name: "player", Type: "spawn", Kind: "point", X: 16, Y: 24},
+			{Name: "slime", Type: "enemy", Kind: "point", X: 40, Y: 24, Props: map[string]any{"hp": 3, "speed": 0.5, "boss": true, "drop": "key"}},
+		}},
+		{Name: "triggers", Objects: []format.MapObjectConfig{
+			{Name: "door", Type: "warp", Kind: "rect", X: 32, Y: 0, W: 8, H: 16},
+			{Name: "pit", Type: "hazard", Kind: "rect", X: 0, Y: 40, W: 64, H: 8},
+			{Name: "path", Kind: "polyline", X: 8, Y: 8, Points: [][2]float32{{0, 0}, {16, 0}}},
+		}},
+	}
+	return loadTestScene(t, cfg).Map()
+}
+
+func TestMapObjectsByLayerAndName(t *testing.T) {
+	m := objectMap(t)
+	if m.ObjectLayerCount() != 2 || m.ObjectLayerName(1) != "triggers" || m.ObjectLayerName(2) != "" {
+		t.Fatalf("object layers = %d, names %q", m.ObjectLayerCount(), m.ObjectLayerName(1))
+	}
+	if got := len(m.Objects(0)); got != 2 {
+		t.Fatalf("len(Objects(0)) = %d, want 2", got)
+	}
+	if m.Objects(5) != nil {
+		t.Fatal("Objects() outside the range should be nil")
+	}
+
+	slime, ok := m.ObjectByName("slime")
+	if !ok || slime.Type != "enemy" || slime.Kind != ObjectPoint || slime.Pos() != (math2d.Vec2{X: 40, Y: 24}) {
+		t.Fatalf("ObjectByName(slime) = %+v, %v", slime, ok)
+	}
+	if slime.Int("hp", 0) != 3 || slime.Float("speed", 0) != 0.5 || !slime.Bool("boss", false) || !slime.Has("drop") {
+		t.Fatalf("slime props = %+v", slime)
+	}
+	if drop := slime.Str("drop", ""); drop != "key" {
+		t.Fatalf("Str(drop) = %q, want key", drop)
+	}
+	if slime.Str("hp", "none") != "none" || !slime.Bool("hp", true) || slime.Int("missing", 7) != 7 || slime.Float("hp", 0) != 3 {
+		t.Fatal("typed accessors should not mix strings and numbers")
+	}
+
+	path, _ := m.ObjectByName("path")
+	if len(path.Points) != 2 || path.Points[1] != (math2d.Vec2{X: 24, Y: 8}) {
+		t.Fatalf("polyline points = %v, want world positions", path.Points)
+	}
+	if _, ok := m.ObjectByName("nobody"); ok {
+		t.Fatal("ObjectByName() found a missing object")
+	}
+}
+
+func TestTriggersReportEnterAndExit(t *testing.T) {
+	m := objectMap(t)
+	trig := NewTriggers(m.Objects(1))
+
+	body := math2d.Rect{X: 20, Y: 0, W: 8, H: 8}
+	if ev := trig.Update(body); len(ev) != 0 {
+		t.Fatalf("events outside = %+v", ev)
+	}
+	body.X = 28
+	ev := trig.Update(body)
+	if len(ev) != 1 || !ev[0].Enter || ev[0].Object.Name != "door" || !trig.Inside("door") {
+		t.Fatalf("events entering = %+v", ev)
+	}
+	if ev := trig.Update(body); len(ev) != 0 {
+		t.Fatalf("events while inside = %+v, want none", ev)
+	}
+	body.X = 60
+	ev = trig.Update(body)
+	if len(ev) != 1 || ev[0].Enter || trig.Inside("door") {
+		t.Fatalf("events leaving = %+v", ev)
+	}
+
+	trig.Update(math2d.Rect{X: 0, Y: 36, W: 8, H: 8})
+	trig.Reset()
+	if trig.Inside("pit") {
+		t.Fatal("Reset() should leave every region")
+	}
+	var nilTrig *Triggers
+	if nilTrig.Update(body) != nil || nilTrig.Inside("door") {
+		t.Fatal("nil Triggers should report nothing")
+	}
+}
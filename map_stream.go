@@ -0,0 +1,330 @@
+package gosprite64
+
+import (
+	"fmt"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	tileloader "github.com/drpaneas/gosprite64/internal/tile2d/loader"
+	"github.com/drpaneas/gosprite64/internal/tile2d/residency"
+	"github.com/drpaneas/gosprite64/internal/tile2d/visibility"
+)
+
+// defaultStreamPolicy preloads one ring of chunks around the view.
+var defaultStreamPolicy = StreamPolicy{Radius: 1}
+
+// mapStream holds the resident chunks of a streamed map. Chunks load when
+// a scene draws near them or TileAt reads them, and leave through the
+// cache's eviction. Once the cache is full, TileAt and SetTileAt read
+// other chunks through without caching them, so sampling far-away cells
+// never evicts the chunks in view.
+type mapStream struct {
+	path   string
+	loader tileloader.RangeLoader
+	index  *format.MapChunkIndex
+	chunkW int
+	chunkH int
+	policy StreamPolicy
+	cache  *residency.ChunkCache
+	chunks map[residency.ChunkCoord]*format.MapChunk
+
+	// edited holds chunks changed by SetTileAt. They outlive eviction,
+	// since the copy in the asset no longer matches.
+	edited map[residency.ChunkCoord]*format.MapChunk
+
+	// spare is the last chunk read through, kept so that repeated reads of
+	// one far-away chunk decode it once.
+	spare *format.MapChunk
+
+	// dirty marks the chunk rows whose chunks came or went since the scene
+	// last rebuilt them.
+	dirty []bool
+
+	// tileCount returns the tile count of a layer's sheet, or 0 when the
+	// map is not in a scene and cells are not checked.
+	tileCount func(layer int) uint16
+	err       error
+}
+
+// mapRowSpan is a stretch of one map row held in RAM, starting at cell X.
+type mapRowSpan struct {
+	x     int
+	tiles []uint16
+	flips []uint8
+}
+
+func newStreamedMap(parsed format.ParsedMap, index *format.MapChunkIndex, path string, l tileloader.RangeLoader) *Map {
+	m := newMap(parsed)
+	for i := range m.cachedLayerInfo {
+		m.cachedLayerInfo[i].NonZeroTiles = index.NonZero[i]
+	}
+	s := &mapStream{
+		path:   path,
+		loader: l,
+		index:  index,
+		chunkW: int(parsed.ChunkWidth),
+		chunkH: int(parsed.ChunkHeight),
+		chunks: make(map[residency.ChunkCoord]*format.MapChunk),
+		edited: make(map[residency.ChunkCoord]*format.MapChunk),
+		dirty:  make([]bool, index.Rows),
+	}
+	s.cache = residency.NewChunkCache(residency.ChunkPolicy{})
+	s.cache.SetHooks(residency.ChunkHooks{Load: s.load, Evict: s.evict})
+	s.setPolicy(defaultStreamPolicy)
+	m.stream = s
+	return m
+}
+
+// Streamed reports whether the map loads its chunks on demand instead of
+// holding every cell in RAM.
+func (m *Map) Streamed() bool {
+	return m != nil && m.stream != nil
+}
+
+// StreamErr returns the first error hit while loading a chunk of a
+// streamed map. A chunk that fails to load reads as empty.
+func (m *Map) StreamErr() error {
+	if m == nil || m.stream == nil {
+		return nil
+	}
+	return m.stream.err
+}
+
+// SetStreamPolicy changes how many chunks a streamed map keeps in RAM. It
+// does nothing for maps that are not streamed.
+func (m *Map) SetStreamPolicy(p StreamPolicy) {
+	if m == nil || m.stream == nil {
+		return
+	}
+	m.stream.setPolicy(p)
+}
+
+// StreamPolicy returns the policy set with SetStreamPolicy, or the default
+// of one chunk of preload and automatic capacity.
+func (m *Map) StreamPolicy() StreamPolicy {
+	if m == nil || m.stream == nil {
+		return StreamPolicy{}
+	}
+	return m.stream.policy
+}
+
+func (s *mapStream) setPolicy(p StreamPolicy) {
+	s.policy = StreamPolicy{Radius: max(p.Radius, 0), Capacity: max(p.Capacity, 0)}
+	s.cache.SetPolicy(residency.ChunkPolicy{
+		Radius:   s.policy.Radius,
+		Capacity: s.policy.Capacity,
+		Cols:     s.index.Cols,
+		Rows:     s.index.Rows,
+	})
+}
+
+func (s *mapStream) coord(x, y int) residency.ChunkCoord {
+	return residency.ChunkCoord{X: x / s.chunkW, Y: y / s.chunkH}
+}
+
+// chunk returns the chunk holding cell (x, y), loading it if the cache has
+// room and reading it through otherwise.
+func (s *mapStream) chunk(x, y int) *format.MapChunk {
+	coord := s.coord(x, y)
+	if c, ok := s.chunks[coord]; ok {
+		s.cache.Touch(coord)
+		return c
+	}
+	if capacity := s.cache.Policy().Capacity; capacity == 0 || s.cache.Len() < capacity {
+		s.cache.Touch(coord)
+		return s.chunks[coord]
+	}
+	if coord.X < 0 || coord.X >= s.index.Cols || coord.Y < 0 || coord.Y >= s.index.Rows {
+		return nil
+	}
+	if c, ok := s.edited[coord]; ok {
+		return c
+	}
+	if s.spare == nil || s.spare.X != coord.X*s.chunkW || s.spare.Y != coord.Y*s.chunkH {
+		s.spare = s.read(coord)
+	}
+	return s.spare
+}
+
+func (s *mapStream) cell(layer, x, y int) (uint16, uint8) {
+	c := s.chunk(x, y)
+	if c == nil {
+		return 0, 0
+	}
+	i := (y-c.Y)*c.W + x - c.X
+	var flip uint8
+	if c.Flips != nil {
+		flip = c.Flips[layer][i]
+	}
+	return c.Cells[layer][i], flip
+}
+
+func (s *mapStream) setCell(layer, x, y int, id uint16) {
+	c := s.chunk(x, y)
+	if c == nil {
+		return
+	}
+	i := (y-c.Y)*c.W + x - c.X
+	c.Cells[layer][i] = id
+	if c.Flips != nil {
+		c.Flips[layer][i] = 0
+	}
+	s.edited[s.coord(x, y)] = c
+}
+
+func (s *mapStream) load(coord residency.ChunkCoord) {
+	s.dirty[coord.Y] = true
+	s.chunks[coord] = s.read(coord)
+}
+
+// read returns the chunk at coord, edited or from the asset.
+func (s *mapStream) read(coord residency.ChunkCoord) *format.MapChunk {
+	if c, ok := s.edited[coord]; ok {
+		return c
+	}
+
+	data, err := tileloader.ReadChunk(s.path, s.loader, s.index, coord.X, coord.Y)
+	var c format.MapChunk
+	if err == nil {
+		c, err = s.index.Decode(coord.X, coord.Y, data)
+	}
+	if err == nil {
+		err = s.check(c)
+	}
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		c, _ = s.index.Decode(coord.X, coord.Y, nil)
+	}
+	return &c
+}
+
+// check rejects tiles past the end of their layer's sheet, as
+// ValidateSceneAssets does for maps loaded whole.
+func (s *mapStream) check(c format.MapChunk) error {
+	if s.tileCount == nil {
+		return nil
+	}
+	for layer, cells := range c.Cells {
+		count := s.tileCount(layer)
+		for _, tile := range cells {
+			if tile > count {
+				return fmt.Errorf("streamed map: layer %d chunk at cell %d,%d references tile %d beyond sheet tile count %d", layer, c.X, c.Y, tile, count)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *mapStream) evict(coord residency.ChunkCoord) {
+	delete(s.chunks, coord)
+	s.dirty[coord.Y] = true
+}
+
+// rowSpans returns the resident stretches of row y of layer, left to right.
+func (s *mapStream) rowSpans(layer, y int) []mapRowSpan {
+	var spans []mapRowSpan
+	cy := y / s.chunkH
+	for cx := 0; cx < s.index.Cols; cx++ {
+		c, ok := s.chunks[residency.ChunkCoord{X: cx, Y: cy}]
+		if !ok {
+			continue
+		}
+		start := (y - c.Y) * c.W
+		span := mapRowSpan{x: c.X, tiles: c.Cells[layer][start : start+c.W]}
+		if c.Flips != nil {
+			span.flips = c.Flips[layer][start : start+c.W]
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// ensure makes the chunks under ranges resident. With no capacity set, the
+// cache holds twice the chunks asked for.
+func (s *mapStream) ensure(ranges []residency.ChunkRange) {
+	if s.policy.Capacity == 0 {
+		want := 0
+		r := s.policy.Radius
+		for _, rg := range ranges {
+			want += (rg.Max.X - rg.Min.X + 1 + 2*r) * (rg.Max.Y - rg.Min.Y + 1 + 2*r)
+		}
+		policy := s.cache.Policy()
+		if policy.Capacity != 2*want {
+			policy.Capacity = 2 * want
+			s.cache.SetPolicy(policy)
+		}
+	}
+	s.cache.EnsureRanges(ranges...)
+}
+
+// appendViewRanges adds the chunk ranges covering bounds, a visible cell
+// range of a layer. On a repeating axis the range wraps into the map.
+func (s *mapStream) appendViewRanges(ranges []residency.ChunkRange, bounds visibility.VisibleBounds, info visibility.MapInfo) []residency.ChunkRange {
+	if bounds.MinX >= bounds.MaxX || bounds.MinY >= bounds.MaxY {
+		return ranges
+	}
+	xs := chunkSpans(bounds.MinX, bounds.MaxX, info.Width, s.chunkW, info.RepeatX)
+	ys := chunkSpans(bounds.MinY, bounds.MaxY, info.Height, s.chunkH, info.RepeatY)
+	for _, y := range ys {
+		for _, x := range xs {
+			ranges = append(ranges, residency.ChunkRange{
+				Min: residency.ChunkCoord{X: x[0], Y: y[0]},
+				Max: residency.ChunkCoord{X: x[1], Y: y[1]},
+			})
+		}
+	}
+	return ranges
+}
+
+// chunkSpans turns the half-open cell range lo..hi on an axis of size
+// cells into inclusive chunk ranges, wrapping when the axis repeats.
+func chunkSpans(lo, hi, size, chunk int, repeat bool) [][2]int {
+	if !repeat {
+		return [][2]int{{lo / chunk, (hi - 1) / chunk}}
+	}
+	if hi-lo >= size {
+		return [][2]int{{0, (size - 1) / chunk}}
+	}
+	first, last := visibility.WrapCell(lo, size), visibility.WrapCell(hi-1, size)
+	if first <= last {
+		return [][2]int{{first / chunk, last / chunk}}
+	}
+	return [][2]int{{first / chunk, (size - 1) / chunk}, {0, last / chunk}}
+}
+
+// takeDirty returns the cell rows of chunk rows marked dirty and clears
+// the marks.
+func (s *mapStream) takeDirty(height int) [][2]int {
+	var rows [][2]int
+	for cy, dirty := range s.dirty {
+		if !dirty {
+			continue
+		}
+		s.dirty[cy] = false
+		rows = append(rows, [2]int{cy * s.chunkH, min((cy+1)*s.chunkH, height)})
+	}
+	return rows
+}
+
+// ramBytes counts the cell and flip bytes held in RAM.
+func (s *mapStream) ramBytes() int {
+	n := 0
+	count := func(c *format.MapChunk) {
+		for i := range c.Cells {
+			n += len(c.Cells[i]) * 2
+			if c.Flips != nil {
+				n += len(c.Flips[i])
+			}
+		}
+	}
+	for _, c := range s.chunks {
+		count(c)
+	}
+	for coord, c := range s.edited {
+		if _, ok := s.chunks[coord]; !ok {
+			count(c)
+		}
+	}
+	return n
+}
@@ -0,0 +1,171 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/internal/tile2d/residency"
+)
+
+// streamedMap is 64x64 cells in 8x8-cell chunks, so 8x8 chunks.
+func streamedMap() format.MapConfig {
+	cells := make([]uint16, 64*64)
+	for i := range cells {
+		cells[i] = uint16((i%64+i/64)%4) + 1
+	}
+	return format.MapConfig{
+		Width: 64, Height: 64, LayerCount: 1, CellBits: 8, ChunkWidth: 8, ChunkHeight: 8,
+		Streamed: true,
+		Layers:   []format.MapLayerConfig{{Cells: cells}},
+	}
+}
+
+func residentChunks(m *Map) []residency.ChunkCoord {
+	var coords []residency.ChunkCoord
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if c := (residency.ChunkCoord{X: x, Y: y}); m.stream.cache.Contains(c) {
+				coords = append(coords, c)
+			}
+		}
+	}
+	return coords
+}
+
+func TestStreamedSceneLoadsChunksNearTheCamera(t *testing.T) {
+	scene := loadTestScene(t, streamedMap())
+	m := scene.Map()
+	if !m.Streamed() {
+		t.Fatal("Streamed() = false for a chunked map")
+	}
+	if info, _ := m.LayerInfo(0); info.NonZeroTiles != 64*64 {
+		t.Fatalf("NonZeroTiles = %d, want %d", info.NonZeroTiles, 64*64)
+	}
+	if got := scene.Stats().CachedChunks; got != 0 {
+		t.Fatalf("CachedChunks = %d before the first draw, want 0", got)
+	}
+
+	// The view covers chunk (0,0); the default radius adds its neighbours.
+	scene.Draw(&Camera{Width: 16, Height: 16})
+	stats := scene.Stats()
+	if stats.CachedChunks != 4 {
+		t.Fatalf("CachedChunks = %d, want 4; resident %v", stats.CachedChunks, residentChunks(m))
+	}
+	if stats.MapRAMBytes != 4*64*2 {
+		t.Fatalf("MapRAMBytes = %d, want %d", stats.MapRAMBytes, 4*64*2)
+	}
+	if stats.VisibleTiles == 0 {
+		t.Fatal("VisibleTiles = 0, want the loaded chunks drawn")
+	}
+	layer := scene.renderScene.Layers[0]
+	if len(layer.Draws) != 4*64 {
+		t.Fatalf("draws = %d, want the cells of 4 chunks", len(layer.Draws))
+	}
+	for _, d := range layer.Draws {
+		if d.CellX >= 16 || d.CellY >= 16 {
+			t.Fatalf("draw at (%d,%d) outside the resident chunks", d.CellX, d.CellY)
+		}
+		if want := uint16((d.CellX+d.CellY)%4) + 1; d.Tile.TileID != want {
+			t.Fatalf("draw at (%d,%d) = tile %d, want %d", d.CellX, d.CellY, d.Tile.TileID, want)
+		}
+	}
+	if err := m.StreamErr(); err != nil {
+		t.Fatalf("StreamErr() = %v", err)
+	}
+}
+
+func TestStreamedSceneEvictsChunksBehindTheCamera(t *testing.T) {
+	scene := loadTestScene(t, streamedMap())
+	m := scene.Map()
+	m.SetStreamPolicy(StreamPolicy{Capacity: 2})
+	if got := m.StreamPolicy(); got != (StreamPolicy{Capacity: 2}) {
+		t.Fatalf("StreamPolicy() = %+v", got)
+	}
+
+	cam := &Camera{Width: 8, Height: 8}
+	scene.Draw(cam)
+	cam.X, cam.Y = 3*64, 3*64
+	scene.Draw(cam)
+	cam.X, cam.Y = 6*64, 6*64
+	scene.Draw(cam)
+
+	got := residentChunks(m)
+	if len(got) != 2 || got[0] != (residency.ChunkCoord{X: 3, Y: 3}) || got[1] != (residency.ChunkCoord{X: 6, Y: 6}) {
+		t.Fatalf("resident chunks = %v, want (3,3) and (6,6)", got)
+	}
+	for _, r := range scene.renderScene.Layers[0].Runs {
+		if r.CellX < 8 && r.CellY < 8 {
+			t.Fatalf("run %+v left over from the evicted chunk", r)
+		}
+	}
+
+	// A view wider than Capacity keeps every chunk it needs.
+	scene.Draw(&Camera{Width: 2*64 + 8, Height: 8})
+	if got := scene.Stats().CachedChunks; got != 3 {
+		t.Fatalf("CachedChunks = %d, want the 3 chunks in view", got)
+	}
+}
+
+func TestStreamedMapReadsAndEditsChunksOnDemand(t *testing.T) {
+	scene := loadTestScene(t, streamedMap())
+	m := scene.Map()
+	m.SetStreamPolicy(StreamPolicy{Capacity: 1})
+
+	if tile, _, ok := m.TileAt(0, 60, 3); !ok || tile != uint16((60+3)%4)+1 {
+		t.Fatalf("TileAt(0, 60, 3) = %d, %v", tile, ok)
+	}
+	if !m.SetTileAt(0, 60, 3, 4) {
+		t.Fatal("SetTileAt() = false")
+	}
+
+	// Drawing elsewhere evicts the edited chunk; the edit must survive.
+	scene.Draw(&Camera{Width: 8, Height: 8})
+	if m.stream.cache.Contains(residency.ChunkCoord{X: 7, Y: 0}) {
+		t.Fatal("edited chunk still resident past Capacity")
+	}
+	if tile, _, _ := m.TileAt(0, 60, 3); tile != 4 {
+		t.Fatalf("TileAt after eviction = %d, want the edited 4", tile)
+	}
+}
+
+func TestStreamedMapReadsThroughWithoutEvictingTheView(t *testing.T) {
+	scene := loadTestScene(t, streamedMap())
+	m := scene.Map()
+	m.SetStreamPolicy(StreamPolicy{Capacity: 1})
+	scene.Draw(&Camera{Width: 8, Height: 8})
+	view := residency.ChunkCoord{X: 0, Y: 0}
+	if !m.stream.cache.Contains(view) || m.stream.dirty[0] {
+		t.Fatal("chunk in view not resident after Draw")
+	}
+
+	// Sampling a far-away cell reads its chunk without caching it.
+	if tile, _, ok := m.TileAt(0, 60, 3); !ok || tile != uint16((60+3)%4)+1 {
+		t.Fatalf("TileAt(0, 60, 3) = %d, %v", tile, ok)
+	}
+	if !m.stream.cache.Contains(view) || m.stream.cache.Contains(residency.ChunkCoord{X: 7, Y: 0}) || m.stream.dirty[0] {
+		t.Fatal("reading a far-away cell evicted the chunk in view")
+	}
+
+	if !m.SetTileAt(0, 60, 3, 4) {
+		t.Fatal("SetTileAt() = false")
+	}
+	m.TileAt(0, 8, 0)
+	if tile, _, _ := m.TileAt(0, 60, 3); tile != 4 {
+		t.Fatalf("TileAt after reading another chunk = %d, want the edited 4", tile)
+	}
+}
+
+func TestUnchunkedMapsAreNotStreamed(t *testing.T) {
+	scene := loadTestScene(t, editMap())
+	m := scene.Map()
+	if m.Streamed() {
+		t.Fatal("Streamed() = true for a map built without streaming")
+	}
+	m.SetStreamPolicy(StreamPolicy{Radius: 3})
+	if got := m.StreamPolicy(); got != (StreamPolicy{}) {
+		t.Fatalf("StreamPolicy() = %+v for a map loaded whole", got)
+	}
+	if got := scene.Stats().CachedChunks; got != 0 {
+		t.Fatalf("CachedChunks = %d, want 0", got)
+	}
+}
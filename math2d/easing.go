@@ -85,3 +85,162 @@ func SmoothStep(edge0, edge1, x float32) float32 {
 	t := Clamp(InvLerp(edge0, edge1, x), 0, 1)
 	return t * t * (3 - 2*t)
 }
+
+// EaseFunc maps a 0..1 progress value to a shaped value. Curves start at 0
+// and end at 1; Back and Elastic overshoot in between.
+type EaseFunc func(t float32) float32
+
+// Linear returns t unchanged.
+func Linear(t float32) float32 { return t }
+
+// EaseInSine accelerates along a quarter sine wave.
+func EaseInSine(t float32) float32 {
+	return 1 - float32(math.Cos(float64(t)*math.Pi/2))
+}
+
+// EaseOutSine decelerates along a quarter sine wave.
+func EaseOutSine(t float32) float32 {
+	return float32(math.Sin(float64(t) * math.Pi / 2))
+}
+
+// EaseInOutSine accelerates then decelerates along a half sine wave.
+func EaseInOutSine(t float32) float32 {
+	return -(float32(math.Cos(float64(t)*math.Pi)) - 1) / 2
+}
+
+// EaseInExpo accelerates exponentially.
+func EaseInExpo(t float32) float32 {
+	if t <= 0 {
+		return 0
+	}
+	return float32(math.Pow(2, 10*float64(t)-10))
+}
+
+// EaseOutExpo decelerates exponentially.
+func EaseOutExpo(t float32) float32 {
+	if t >= 1 {
+		return 1
+	}
+	return 1 - float32(math.Pow(2, -10*float64(t)))
+}
+
+// EaseInOutExpo accelerates then decelerates exponentially.
+func EaseInOutExpo(t float32) float32 {
+	switch {
+	case t <= 0:
+		return 0
+	case t >= 1:
+		return 1
+	case t < 0.5:
+		return float32(math.Pow(2, 20*float64(t)-10)) / 2
+	}
+	return (2 - float32(math.Pow(2, -20*float64(t)+10))) / 2
+}
+
+// EaseInCirc accelerates along a quarter circle.
+func EaseInCirc(t float32) float32 {
+	return 1 - float32(math.Sqrt(1-float64(t*t)))
+}
+
+// EaseOutCirc decelerates along a quarter circle.
+func EaseOutCirc(t float32) float32 {
+	t--
+	return float32(math.Sqrt(1 - float64(t*t)))
+}
+
+// EaseInOutCirc accelerates then decelerates along two quarter circles.
+func EaseInOutCirc(t float32) float32 {
+	if t < 0.5 {
+		return (1 - float32(math.Sqrt(1-float64(4*t*t)))) / 2
+	}
+	t = 2*t - 2
+	return (float32(math.Sqrt(1-float64(t*t))) + 1) / 2
+}
+
+const (
+	backC1 = 1.70158
+	backC2 = backC1 * 1.525
+	backC3 = backC1 + 1
+)
+
+// EaseInBack pulls back slightly before accelerating.
+func EaseInBack(t float32) float32 {
+	return backC3*t*t*t - backC1*t*t
+}
+
+// EaseOutBack overshoots the end slightly, then settles.
+func EaseOutBack(t float32) float32 {
+	t--
+	return 1 + backC3*t*t*t + backC1*t*t
+}
+
+// EaseInOutBack pulls back at the start and overshoots at the end.
+func EaseInOutBack(t float32) float32 {
+	if t < 0.5 {
+		return (4 * t * t * ((backC2+1)*2*t - backC2)) / 2
+	}
+	t = 2*t - 2
+	return (t*t*((backC2+1)*t+backC2) + 2) / 2
+}
+
+// EaseInElastic winds up like a spring before accelerating.
+func EaseInElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return Clamp(t, 0, 1)
+	}
+	const c4 = 2 * math.Pi / 3
+	return -float32(math.Pow(2, 10*float64(t)-10) * math.Sin((float64(t)*10-10.75)*c4))
+}
+
+// EaseOutElastic overshoots and oscillates like a spring before settling.
+func EaseOutElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return Clamp(t, 0, 1)
+	}
+	const c4 = 2 * math.Pi / 3
+	return float32(math.Pow(2, -10*float64(t))*math.Sin((float64(t)*10-0.75)*c4)) + 1
+}
+
+// EaseInOutElastic oscillates at both ends.
+func EaseInOutElastic(t float32) float32 {
+	if t <= 0 || t >= 1 {
+		return Clamp(t, 0, 1)
+	}
+	const c5 = 2 * math.Pi / 4.5
+	s := math.Sin((20*float64(t) - 11.125) * c5)
+	if t < 0.5 {
+		return -float32(math.Pow(2, 20*float64(t)-10)*s) / 2
+	}
+	return float32(math.Pow(2, -20*float64(t)+10)*s)/2 + 1
+}
+
+// EaseOutBounce decelerates like a ball bouncing to rest.
+func EaseOutBounce(t float32) float32 {
+	const n1 = 7.5625
+	const d1 = 2.75
+	switch {
+	case t < 1/d1:
+		return n1 * t * t
+	case t < 2/d1:
+		t -= 1.5 / d1
+		return n1*t*t + 0.75
+	case t < 2.5/d1:
+		t -= 2.25 / d1
+		return n1*t*t + 0.9375
+	}
+	t -= 2.625 / d1
+	return n1*t*t + 0.984375
+}
+
+// EaseInBounce bounces with growing height before accelerating.
+func EaseInBounce(t float32) float32 {
+	return 1 - EaseOutBounce(1-t)
+}
+
+// EaseInOutBounce bounces at both ends.
+func EaseInOutBounce(t float32) float32 {
+	if t < 0.5 {
+		return (1 - EaseOutBounce(1-2*t)) / 2
+	}
+	return (1 + EaseOutBounce(2*t-1)) / 2
+}
@@ -173,3 +173,83 @@ func TestClampLoGreaterThanHi(t *testing.T) {
 		t.Fatalf("Clamp(5, lo=10, hi=0) with lo>hi returns lo, got %f", v)
 	}
 }
+
+func TestPennerCurvesHitEndpoints(t *testing.T) {
+	curves := map[string]EaseFunc{
+		"Linear":           Linear,
+		"EaseInSine":       EaseInSine,
+		"EaseOutSine":      EaseOutSine,
+		"EaseInOutSine":    EaseInOutSine,
+		"EaseInExpo":       EaseInExpo,
+		"EaseOutExpo":      EaseOutExpo,
+		"EaseInOutExpo":    EaseInOutExpo,
+		"EaseInCirc":       EaseInCirc,
+		"EaseOutCirc":      EaseOutCirc,
+		"EaseInOutCirc":    EaseInOutCirc,
+		"EaseInBack":       EaseInBack,
+		"EaseOutBack":      EaseOutBack,
+		"EaseInOutBack":    EaseInOutBack,
+		"EaseInElastic":    EaseInElastic,
+		"EaseOutElastic":   EaseOutElastic,
+		"EaseInOutElastic": EaseInOutElastic,
+		"EaseInBounce":     EaseInBounce,
+		"EaseOutBounce":    EaseOutBounce,
+		"EaseInOutBounce":  EaseInOutBounce,
+	}
+	for name, ease := range curves {
+		if !almostEqual(ease(0), 0, 0.001) {
+			t.Fatalf("%s(0) = %f, want 0", name, ease(0))
+		}
+		if !almostEqual(ease(1), 1, 0.001) {
+			t.Fatalf("%s(1) = %f, want 1", name, ease(1))
+		}
+	}
+}
+
+func TestPennerInOutCurvesAreSymmetric(t *testing.T) {
+	curves := map[string]EaseFunc{
+		"EaseInOutSine":    EaseInOutSine,
+		"EaseInOutExpo":    EaseInOutExpo,
+		"EaseInOutCirc":    EaseInOutCirc,
+		"EaseInOutBack":    EaseInOutBack,
+		"EaseInOutElastic": EaseInOutElastic,
+		"EaseInOutBounce":  EaseInOutBounce,
+	}
+	for name, ease := range curves {
+		if !almostEqual(ease(0.5), 0.5, 0.001) {
+			t.Fatalf("%s(0.5) = %f, want 0.5", name, ease(0.5))
+		}
+		if !almostEqual(ease(0.25), 1-ease(0.75), 0.001) {
+			t.Fatalf("%s should be point-symmetric around 0.5", name)
+		}
+	}
+}
+
+func TestPennerCurveShapes(t *testing.T) {
+	if EaseInSine(0.5) >= 0.5 || EaseOutSine(0.5) <= 0.5 {
+		t.Fatal("sine in should lag and sine out should lead linear")
+	}
+	if EaseInExpo(0.5) >= EaseInQuad(0.5) {
+		t.Fatal("expo in should start slower than quad in")
+	}
+	if EaseInBack(0.2) >= 0 {
+		t.Fatalf("EaseInBack should dip below 0, got %f", EaseInBack(0.2))
+	}
+	if EaseOutBack(0.8) <= 1 {
+		t.Fatalf("EaseOutBack should overshoot 1, got %f", EaseOutBack(0.8))
+	}
+	if EaseOutElastic(0.1) <= 1 {
+		t.Fatalf("EaseOutElastic should overshoot early, got %f", EaseOutElastic(0.1))
+	}
+	for _, x := range []float32{0.1, 0.3, 0.5, 0.7, 0.9} {
+		if v := EaseOutBounce(x); v < 0 || v > 1 {
+			t.Fatalf("EaseOutBounce(%f) = %f should stay in 0..1", x, v)
+		}
+	}
+	if !almostEqual(EaseOutBounce(1/2.75), 1, 0.001) {
+		t.Fatal("EaseOutBounce should touch 1 at the first bounce")
+	}
+	if !almostEqual(EaseOutCirc(0.5), 0.866, 0.001) {
+		t.Fatalf("EaseOutCirc(0.5) = %f, want 0.866", EaseOutCirc(0.5))
+	}
+}
@@ -0,0 +1,106 @@
+package gosprite64
+
+import (
+	"image/color"
+
+	"github.com/clktmr/n64/rcp/texture"
+)
+
+// Palette is the color table of a palettized sheet, one built with
+// mk2dsheet -format ci4 or ci8. Entries hold colors as the RDP's TLUT
+// does: 5 bits per channel and alpha either off or on.
+//
+// A sheet's own palette, from SpriteSheet.Palette or Sheet.Palette, is
+// shared by every draw of the sheet, so changing it recolors all of them;
+// cycling a range of it animates water or fire. DrawSpriteOptions.Palette
+// draws a sprite with another palette, such as a Clone in team colors.
+type Palette struct {
+	colors *texture.ColorPalette
+	tex    *texture.Texture
+}
+
+func newPalette(colors *texture.ColorPalette) *Palette {
+	return &Palette{colors: colors, tex: texture.NewTextureFromImage(colors)}
+}
+
+// NewPalette returns a palette holding colors. It returns nil for no
+// colors or more than 256.
+func NewPalette(colors ...color.Color) *Palette {
+	if len(colors) == 0 {
+		return nil
+	}
+	p, err := texture.CopyColorPalette(colors)
+	if err != nil {
+		return nil
+	}
+	return newPalette(p)
+}
+
+// Len returns the number of entries.
+func (p *Palette) Len() int {
+	if p == nil {
+		return 0
+	}
+	return p.colors.Bounds().Dx()
+}
+
+// Color returns entry i, or transparent black if i is out of range.
+func (p *Palette) Color(i int) color.Color {
+	if i < 0 || i >= p.Len() {
+		return color.RGBA{}
+	}
+	return p.colors.At(i, 0)
+}
+
+// SetColor replaces entry i. Out-of-range entries are ignored.
+func (p *Palette) SetColor(i int, c color.Color) {
+	if i < 0 || i >= p.Len() || c == nil {
+		return
+	}
+	p.colors.Set(i, 0, c)
+	p.tex.Writeback()
+}
+
+// Cycle rotates entries first through last by step: entry i moves to
+// i+step, and entries pushed past last wrap around to first. Negative
+// steps rotate the other way. Calling it every few frames animates the
+// pixels drawn with those entries.
+func (p *Palette) Cycle(first, last, step int) {
+	first = max(first, 0)
+	last = min(last, p.Len()-1)
+	n := last - first + 1
+	if n < 2 {
+		return
+	}
+	step = ((step % n) + n) % n
+	if step == 0 {
+		return
+	}
+	pix := p.colors.Pix[first*2 : (last+1)*2]
+	rotated := make([]byte, len(pix))
+	copy(rotated[step*2:], pix[:(n-step)*2])
+	copy(rotated, pix[(n-step)*2:])
+	copy(pix, rotated)
+	p.tex.Writeback()
+}
+
+// Clone returns a copy of the palette that can be changed on its own.
+func (p *Palette) Clone() *Palette {
+	if p.Len() == 0 {
+		return nil
+	}
+	colors, err := texture.NewColorPalette(p.Len())
+	if err != nil {
+		return nil
+	}
+	copy(colors.Pix, p.colors.Pix)
+	return newPalette(colors)
+}
+
+// tlut returns the palette as the texture the renderer loads into TMEM.
+func (p *Palette) tlut() *texture.Texture {
+	if p == nil {
+		return nil
+	}
+	return p.tex
+}
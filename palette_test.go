@@ -0,0 +1,113 @@
+package gosprite64
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rendergeom"
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+var (
+	paletteRed   = color.NRGBA{R: 255, A: 255}
+	paletteGreen = color.NRGBA{G: 255, A: 255}
+	paletteBlue  = color.NRGBA{B: 255, A: 255}
+)
+
+// asRGBA16 returns c as the RGBA16 palettes and framebuffer store it.
+func asRGBA16(c color.Color) color.NRGBA {
+	return color.NRGBAModel.Convert(texture.RGBA16Model.Convert(c)).(color.NRGBA)
+}
+
+func TestPaletteCycleSetAndClone(t *testing.T) {
+	p := NewPalette(color.Black, paletteRed, paletteGreen, paletteBlue)
+	if p.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", p.Len())
+	}
+
+	p.Cycle(1, 3, 1)
+	if asRGBA16(p.Color(1)) != asRGBA16(paletteBlue) || asRGBA16(p.Color(2)) != asRGBA16(paletteRed) || asRGBA16(p.Color(3)) != asRGBA16(paletteGreen) {
+		t.Fatalf("after Cycle(1, 3, 1) = %v %v %v, want blue red green", p.Color(1), p.Color(2), p.Color(3))
+	}
+	p.Cycle(1, 3, -4)
+	if asRGBA16(p.Color(1)) != asRGBA16(paletteRed) || asRGBA16(p.Color(0)) != asRGBA16(color.Black) {
+		t.Fatalf("after Cycle(1, 3, -4) = %v %v, want black red", p.Color(0), p.Color(1))
+	}
+
+	clone := p.Clone()
+	clone.SetColor(1, paletteGreen)
+	if asRGBA16(clone.Color(1)) != asRGBA16(paletteGreen) || asRGBA16(p.Color(1)) != asRGBA16(paletteRed) {
+		t.Fatal("changing a clone changed the original")
+	}
+	if p.Color(9) != (color.RGBA{}) {
+		t.Fatalf("Color(9) = %v, want transparent black", p.Color(9))
+	}
+
+	var nilPalette *Palette
+	nilPalette.Cycle(0, 3, 1)
+	nilPalette.SetColor(0, paletteRed)
+	if nilPalette.Len() != 0 || nilPalette.Clone() != nil || NewPalette() != nil {
+		t.Fatal("nil and empty palettes are not no-ops")
+	}
+}
+
+func TestPalettizedSpriteSheetDrawsThroughPalette(t *testing.T) {
+	saved := activeRuntime
+	defer activateRuntime(saved)
+	fb := texture.NewRGBA16(rendergeom.FramebufferBounds())
+	activateRuntime(&runtimeState{video: &videoState{Framebuffer: fb}})
+
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, paletteRed)
+		}
+	}
+	img.Set(8, 0, paletteGreen)
+	raw, err := format.BuildSheetWithEncoding(img, 8, 8, format.TilePropsConfig{}, format.SheetCI4)
+	if err != nil {
+		t.Fatalf("BuildSheetWithEncoding: %v", err)
+	}
+	parsed, err := format.ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet: %v", err)
+	}
+	sheet := &SpriteSheet{sheet: &Sheet{parsed: parsed}}
+	pal := sheet.Palette()
+	if pal == nil || pal.Len() != 2 || sheet.Palette() != pal {
+		t.Fatalf("Palette() = %v, want one shared 2-color palette", pal)
+	}
+	if frame := sheet.sheet.Tile(2); asRGBA16(frame.At(frame.Bounds().Min.X, 0)) != asRGBA16(paletteGreen) {
+		t.Fatalf("frame 1 first pixel = %v, want green", frame.At(frame.Bounds().Min.X, 0))
+	}
+
+	at := func(x, y int) color.NRGBA {
+		p, _ := rendergeom.MapPoint(image.Pt(x, y))
+		return asRGBA16(fb.At(p.X, p.Y))
+	}
+	DrawSpriteWithOptions(sheet, 0, 10, 10, DrawSpriteOptions{Blend: BlendMasked})
+	if got := at(10, 10); got != asRGBA16(paletteRed) {
+		t.Fatalf("sheet palette pixel = %v, want red", got)
+	}
+
+	// An override palette recolors one draw; the sheet's palette is kept.
+	team := pal.Clone()
+	team.SetColor(0, paletteBlue)
+	DrawSpriteWithOptions(sheet, 0, 20, 10, DrawSpriteOptions{Palette: team})
+	if got := at(20, 10); got != asRGBA16(paletteBlue) {
+		t.Fatalf("override palette pixel = %v, want blue", got)
+	}
+
+	// Cycling the sheet's palette recolors every later draw of the sheet.
+	pal.Cycle(0, 1, 1)
+	DrawSpriteWithOptions(sheet, 0, 30, 10, DrawSpriteOptions{Blend: BlendMasked})
+	if got := at(30, 10); got != asRGBA16(paletteGreen) {
+		t.Fatalf("cycled palette pixel = %v, want green", got)
+	}
+
+	if loadTestSpriteSheet(t, 1).Palette() != nil {
+		t.Fatal("RGBA32 sheet has a palette")
+	}
+}
@@ -2,10 +2,12 @@ package gosprite64
 
 import (
 	"fmt"
+	"image/color"
 
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
 	tileloader "github.com/drpaneas/gosprite64/internal/tile2d/loader"
 	tilerender "github.com/drpaneas/gosprite64/internal/tile2d/render"
+	"github.com/drpaneas/gosprite64/internal/tile2d/residency"
 	tilestats "github.com/drpaneas/gosprite64/internal/tile2d/stats"
 	"github.com/drpaneas/gosprite64/internal/tile2d/visibility"
 )
@@ -23,6 +25,15 @@ type Scene struct {
 	lastDrawStats tilerender.DrawStats
 	cachedParsed  []format.ParsedSheet
 	staticStats   RuntimeStats
+	layerStyles   []sceneLayerStyle
+	statsLayer    int
+	tileTick      int
+}
+
+type sceneLayerStyle struct {
+	hidden  bool
+	tint    color.RGBA
+	opacity float32
 }
 
 func LoadScene(bundle *Bundle) (*Scene, error) {
@@ -30,7 +41,9 @@ func LoadScene(bundle *Bundle) (*Scene, error) {
 		return nil, fmt.Errorf("load scene: nil bundle")
 	}
 
-	scene := &Scene{bundle: bundle}
+	var sheets []*Sheet
+	var animations []*AnimationSet
+	var gameMap *Map
 	for _, entry := range bundle.manifest.Entries {
 		switch entry.Kind {
 		case format.BundleKindSheet:
@@ -38,25 +51,31 @@ func LoadScene(bundle *Bundle) (*Scene, error) {
 			if err != nil {
 				return nil, err
 			}
-			scene.sheets = append(scene.sheets, sheet)
+			sheets = append(sheets, sheet)
 		case format.BundleKindMap:
-			if scene.gameMap != nil {
+			if gameMap != nil {
 				return nil, fmt.Errorf("load scene: bundle has multiple maps")
 			}
 			m, err := bundle.loadMapEntry(entry)
 			if err != nil {
 				return nil, err
 			}
-			scene.gameMap = m
+			gameMap = m
 		case format.BundleKindAnim:
 			anim, err := bundle.loadAnimEntry(entry)
 			if err != nil {
 				return nil, err
 			}
-			scene.animations = append(scene.animations, anim)
+			animations = append(animations, anim)
 		}
 	}
+	return newScene(bundle, sheets, animations, gameMap)
+}
 
+// newScene builds a scene around gameMap. Worlds share sheets and
+// animations between the scenes of their rooms.
+func newScene(bundle *Bundle, sheets []*Sheet, animations []*AnimationSet, gameMap *Map) (*Scene, error) {
+	scene := &Scene{bundle: bundle, sheets: sheets, animations: animations, gameMap: gameMap}
 	scene.defaultCamera = newDefaultCamera()
 	scene.preparer = newSceneRenderPreparer(scene)
 	scene.bridge = newSceneRenderBridge()
@@ -81,9 +100,28 @@ func LoadScene(bundle *Bundle) (*Scene, error) {
 		scene.gameMap.tileW = info.TileWidth
 		scene.gameMap.tileH = info.TileHeight
 	}
+	scene.gameMap.layerSheets = make([]*Sheet, scene.gameMap.LayerCount())
+	for i := range scene.gameMap.layerSheets {
+		scene.gameMap.layerSheets[i], _ = scene.LayerSheet(i)
+	}
+	if stream := scene.gameMap.stream; stream != nil {
+		sheets := scene.gameMap.layerSheets
+		stream.tileCount = func(layer int) uint16 { return sheets[layer].parsed.TileCount }
+	}
 
 	scene.configureRenderer()
 	scene.renderScene = scene.preparer.buildScene()
+	scene.gameMap.OnTileChange(func(layer, x, y int, old, tile uint16) {
+		scene.preparer.rebuildRow(&scene.renderScene, layer, y)
+	})
+	scene.layerStyles = make([]sceneLayerStyle, len(scene.renderScene.Layers))
+	for i := range scene.layerStyles {
+		scene.layerStyles[i] = sceneLayerStyle{tint: color.RGBA{R: 255, G: 255, B: 255, A: 255}, opacity: 1}
+		if info, ok := scene.gameMap.LayerInfo(i); ok && info.Hidden {
+			scene.layerStyles[i].hidden = true
+			scene.applyLayerStyle(i)
+		}
+	}
 
 	scene.cachedParsed = collectParsedSheets(scene.sheets)
 	initSnap := tilestats.FromSceneAssets(scene.gameMap.parsed, scene.cachedParsed, tilerender.DrawStats{})
@@ -199,15 +237,167 @@ func (s *Scene) Draw(cam *Camera) {
 	if cam == nil {
 		return
 	}
-	s.lastDrawStats = s.renderer.DrawPreparedScene(
-		s.renderScene,
-		visibility.Camera{
-			X:      cam.X,
-			Y:      cam.Y,
-			Width:  cam.Width,
-			Height: cam.Height,
-		},
-	)
+	view := cam.view()
+	s.streamChunks(view)
+	currentTile().resetTexturedState()
+	s.lastDrawStats = s.renderer.DrawPreparedScene(s.renderScene, view)
+	s.statsLayer = len(s.renderScene.Layers) - 1
+}
+
+// DrawLayer draws a single map layer. Call it between sprite draws to put
+// sprites behind or in front of individual layers.
+func (s *Scene) DrawLayer(cam *Camera, layer int) {
+	s.DrawLayers(cam, layer, layer)
+}
+
+// DrawLayers draws map layers from through to, inclusive, in order.
+// Stats() sums every layer drawn since the last pass restarted at a layer
+// at or below one already drawn.
+func (s *Scene) DrawLayers(cam *Camera, from, to int) {
+	if s == nil || s.renderer == nil || s.gameMap == nil {
+		return
+	}
+	if cam == nil {
+		cam = s.defaultCamera
+	}
+	if cam == nil {
+		return
+	}
+	from = max(from, 0)
+	to = min(to, len(s.renderScene.Layers)-1)
+	if from > to {
+		return
+	}
+
+	view := cam.view()
+	s.streamChunks(view)
+	// Sprites drawn since the last layer may have replaced the texture the
+	// tile executor thinks is still loaded.
+	currentTile().resetTexturedState()
+	stats := s.renderer.DrawPreparedLayers(s.renderScene, view, from, to)
+	if from <= s.statsLayer {
+		s.lastDrawStats = tilerender.DrawStats{}
+	}
+	s.lastDrawStats.VisibleTiles += stats.VisibleTiles
+	s.lastDrawStats.Uploads += stats.Uploads
+	s.statsLayer = to
+}
+
+// SetLayerVisible shows or hides a map layer for Draw and DrawLayers.
+func (s *Scene) SetLayerVisible(layer int, visible bool) {
+	if s == nil || layer < 0 || layer >= len(s.layerStyles) {
+		return
+	}
+	s.layerStyles[layer].hidden = !visible
+	s.applyLayerStyle(layer)
+}
+
+// LayerVisible reports whether a map layer is drawn.
+func (s *Scene) LayerVisible(layer int) bool {
+	if s == nil || layer < 0 || layer >= len(s.layerStyles) {
+		return false
+	}
+	return !s.layerStyles[layer].hidden
+}
+
+// SetLayerOffset moves a map layer by x, y pixels in world space.
+func (s *Scene) SetLayerOffset(layer int, x, y int) {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return
+	}
+	s.renderScene.Layers[layer].OffsetX = x
+	s.renderScene.Layers[layer].OffsetY = y
+}
+
+// LayerOffset returns the pixel offset set by SetLayerOffset.
+func (s *Scene) LayerOffset(layer int) (int, int) {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return 0, 0
+	}
+	return s.renderScene.Layers[layer].OffsetX, s.renderScene.Layers[layer].OffsetY
+}
+
+// SetLayerParallax sets the scroll factors of a map layer, overriding the
+// ones stored in the map. {1, 1} scrolls with the camera.
+func (s *Scene) SetLayerParallax(layer int, p ParallaxLayer) {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return
+	}
+	prepared := &s.renderScene.Layers[layer]
+	prepared.Parallax = p != ParallaxLayer{SpeedX: 1, SpeedY: 1}
+	prepared.ScrollX = p.SpeedX
+	prepared.ScrollY = p.SpeedY
+}
+
+// LayerParallax returns the scroll factors a map layer is drawn with.
+func (s *Scene) LayerParallax(layer int) ParallaxLayer {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return ParallaxLayer{}
+	}
+	prepared := s.renderScene.Layers[layer]
+	if !prepared.Parallax {
+		return ParallaxLayer{SpeedX: 1, SpeedY: 1}
+	}
+	return ParallaxLayer{SpeedX: prepared.ScrollX, SpeedY: prepared.ScrollY}
+}
+
+// SetParallax applies cfg.Layers[i] to map layer i. Layers past the end of
+// cfg keep their current scroll factors.
+func (s *Scene) SetParallax(cfg ParallaxConfig) {
+	for i, p := range cfg.Layers {
+		s.SetLayerParallax(i, p)
+	}
+}
+
+// SetLayerRepeat makes a map layer tile endlessly along x, y or both, for
+// skies and backgrounds that should never run out.
+func (s *Scene) SetLayerRepeat(layer int, repeatX, repeatY bool) {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return
+	}
+	s.renderScene.Layers[layer].Map.RepeatX = repeatX
+	s.renderScene.Layers[layer].Map.RepeatY = repeatY
+}
+
+// LayerRepeat reports whether a map layer repeats along x and y.
+func (s *Scene) LayerRepeat(layer int) (bool, bool) {
+	if s == nil || layer < 0 || layer >= len(s.renderScene.Layers) {
+		return false, false
+	}
+	info := s.renderScene.Layers[layer].Map
+	return info.RepeatX, info.RepeatY
+}
+
+// SetLayerTint multiplies a map layer's texels by tint. The alpha channel
+// is ignored; use SetLayerOpacity. White restores the original colors.
+func (s *Scene) SetLayerTint(layer int, tint color.RGBA) {
+	if s == nil || layer < 0 || layer >= len(s.layerStyles) {
+		return
+	}
+	s.layerStyles[layer].tint = tint
+	s.applyLayerStyle(layer)
+}
+
+// SetLayerOpacity blends a map layer over what is already drawn, from 0
+// (invisible) to 1 (opaque, the default).
+func (s *Scene) SetLayerOpacity(layer int, opacity float32) {
+	if s == nil || layer < 0 || layer >= len(s.layerStyles) {
+		return
+	}
+	s.layerStyles[layer].opacity = min(max(opacity, 0), 1)
+	s.applyLayerStyle(layer)
+}
+
+func (s *Scene) applyLayerStyle(layer int) {
+	style := s.layerStyles[layer]
+	prepared := &s.renderScene.Layers[layer]
+	prepared.Hidden = style.hidden || style.opacity <= 0
+
+	tint := color.RGBA{R: style.tint.R, G: style.tint.G, B: style.tint.B, A: uint8(style.opacity*255 + 0.5)}
+	if tint == (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		tint = color.RGBA{}
+	}
+	prepared.Tint = tint
 }
 
 func (s *Scene) configureRenderer() {
@@ -237,5 +427,45 @@ func (s *Scene) Stats() RuntimeStats {
 	stats := s.staticStats
 	stats.VisibleTiles = s.lastDrawStats.VisibleTiles
 	stats.UploadCount = s.lastDrawStats.Uploads
+	if stream := s.gameMap.stream; stream != nil {
+		stats.CachedChunks = tilestats.FromChunkCache(stream.cache).CachedChunks
+		stats.MapRAMBytes = stream.ramBytes()
+	}
 	return stats
 }
+
+// view returns the camera as the renderer sees it, so drawing, culling and
+// coordinate conversion share one transform.
+func (c *Camera) view() visibility.Camera {
+	return visibility.Camera{
+		X:        c.X,
+		Y:        c.Y,
+		Width:    c.Width,
+		Height:   c.Height,
+		Zoom:     c.Zoom,
+		Rotation: c.Rotation,
+	}
+}
+
+// streamChunks loads the chunks of a streamed map that the visible layers
+// need for view and rebuilds the rows whose chunks came or went.
+func (s *Scene) streamChunks(view visibility.Camera) {
+	stream := s.gameMap.stream
+	if stream == nil {
+		return
+	}
+	var ranges []residency.ChunkRange
+	for _, layer := range s.renderScene.Layers {
+		if layer.Hidden {
+			continue
+		}
+		bounds := visibility.VisibleCellBounds(layer.LayerCamera(view), layer.Map)
+		ranges = stream.appendViewRanges(ranges, bounds, layer.Map)
+	}
+	stream.ensure(ranges)
+	for _, rows := range stream.takeDirty(s.gameMap.Height()) {
+		for layer := range s.renderScene.Layers {
+			s.preparer.rebuildRows(&s.renderScene, layer, rows[0], rows[1])
+		}
+	}
+}
@@ -1,38 +1,91 @@
 package gosprite64
 
 import (
+	"image"
+	"image/color"
+	"math"
+
 	tilerender "github.com/drpaneas/gosprite64/internal/tile2d/render"
 )
 
-type sceneRenderBridge struct{}
+type sceneRenderBridge struct {
+	tint color.RGBA
+
+	// flipped caches the fallback images of flipped tiles.
+	flipped map[flippedSource]image.Image
+}
+
+type flippedSource struct {
+	src  image.Image
+	flip uint8
+}
 
 func newSceneRenderBridge() *sceneRenderBridge {
 	return &sceneRenderBridge{}
 }
 
+// SetLayerTint implements tilerender.LayerStyleBridge. The host fallback
+// draws tiles untinted.
+func (b *sceneRenderBridge) SetLayerTint(tint color.RGBA) {
+	b.tint = tint
+}
+
 func (b *sceneRenderBridge) DrawPreparedRun(x, y, tileWidth, tileHeight int, run tilerender.PreparedRun) {
 	if run.Count <= 0 {
 		return
 	}
 	exec, ok := b.currentTexturedExecutor()
 	if !ok || !exec.EnsurePrepared(run.Tile) {
+		src := b.fallbackImage(run.Tile)
 		for i := 0; i < run.Count; i++ {
-			drawLogicalImage(run.Tile.Source, x+(i*tileWidth), y)
+			drawLogicalImage(src, x+(i*tileWidth), y)
 		}
 		return
 	}
+	exec.Flip = run.Tile.Flip
 	exec.BlitRun(x, y, tileWidth, run.Count)
 }
 
 func (b *sceneRenderBridge) DrawPreparedTile(x, y, width, height int, tile tilerender.PreparedTile) {
 	exec, ok := b.currentTexturedExecutor()
 	if !ok || !exec.EnsurePrepared(tile) {
-		drawLogicalImage(tile.Source, x, y)
+		drawLogicalImage(b.fallbackImage(tile), x, y)
 		return
 	}
+	exec.Flip = tile.Flip
 	exec.BlitTile(x, y)
 }
 
+// DrawPreparedQuad implements tilerender.QuadBridge for zoomed and rotated
+// cameras. The host fallback draws the tile unscaled at its first corner.
+func (b *sceneRenderBridge) DrawPreparedQuad(corners [4][2]float32, tile tilerender.PreparedTile) {
+	exec, ok := b.currentTexturedExecutor()
+	if !ok || !exec.EnsurePrepared(tile) {
+		drawLogicalImage(b.fallbackImage(tile), int(math.Floor(float64(corners[0][0]))), int(math.Floor(float64(corners[0][1]))))
+		return
+	}
+	exec.Flip = tile.Flip
+	exec.BlitQuad(corners)
+}
+
+// fallbackImage returns the tile's source, flipped when the cell asks for
+// it. Flipped copies are made once per source and flip.
+func (b *sceneRenderBridge) fallbackImage(tile tilerender.PreparedTile) image.Image {
+	if tile.Flip == 0 || tile.Source == nil {
+		return tile.Source
+	}
+	key := flippedSource{src: tile.Source, flip: tile.Flip}
+	if img, ok := b.flipped[key]; ok {
+		return img
+	}
+	if b.flipped == nil {
+		b.flipped = make(map[flippedSource]image.Image)
+	}
+	img := flippedImage(tile.Source, TileFlip(tile.Flip))
+	b.flipped[key] = img
+	return img
+}
+
 func (b *sceneRenderBridge) currentTexturedExecutor() (tilerender.TexturedExecutor, bool) {
 	video := currentVideo()
 	rt := currentTile()
@@ -42,5 +95,6 @@ func (b *sceneRenderBridge) currentTexturedExecutor() (tilerender.TexturedExecut
 	return tilerender.TexturedExecutor{
 		Framebuffer: video.Framebuffer,
 		State:       &rt.textured,
+		Tint:        b.tint,
 	}, true
 }
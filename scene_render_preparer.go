@@ -1,18 +1,34 @@
 package gosprite64
 
 import (
+	"slices"
+	"sort"
+
 	tilerender "github.com/drpaneas/gosprite64/internal/tile2d/render"
 	"github.com/drpaneas/gosprite64/internal/tile2d/visibility"
 )
 
 type sceneRenderPreparer struct {
 	scene *Scene
+	anims [][]sceneTileAnim
+}
+
+// sceneTileAnim points at a prepared run or draw whose tile animates. The
+// prepared tile keeps the animated tile's ID, so runs still merge by it,
+// and only its Source follows the current frame.
+type sceneTileAnim struct {
+	tile  *tilerender.PreparedTile
+	sheet *Sheet
+	anim  TileAnim
+	frame uint16
 }
 
 type sceneRenderLayerSource struct {
-	Map     visibility.MapInfo
-	SheetID uint16
-	Tiles   [][]uint16
+	Map      visibility.MapInfo
+	SheetID  uint16
+	Tiles    [][]uint16
+	Flips    [][]uint8
+	Parallax ParallaxLayer
 }
 
 func newSceneRenderPreparer(scene *Scene) *sceneRenderPreparer {
@@ -29,17 +45,64 @@ func (p *sceneRenderPreparer) buildScene() tilerender.PreparedScene {
 	for _, layer := range layers {
 		prepared := p.prepareLayerTiles(layer)
 		preparedLayers = append(preparedLayers, tilerender.PreparedLayer{
-			Map:   layer.Map,
-			Draws: p.prepareLayerDraws(prepared),
-			Runs:  p.prepareLayerRuns(prepared),
+			Map:      layer.Map,
+			Draws:    p.prepareLayerDraws(prepared),
+			Runs:     p.prepareLayerRuns(prepared),
+			Parallax: layer.Parallax != ParallaxLayer{SpeedX: 1, SpeedY: 1},
+			ScrollX:  layer.Parallax.SpeedX,
+			ScrollY:  layer.Parallax.SpeedY,
 		})
 	}
 
+	p.collectAnims(preparedLayers)
+	p.animate(p.scene.tileTick)
 	return tilerender.PreparedScene{
 		Layers: preparedLayers,
 	}
 }
 
+// collectAnims finds the runs and draws whose tiles animate.
+func (p *sceneRenderPreparer) collectAnims(layers []tilerender.PreparedLayer) {
+	p.anims = make([][]sceneTileAnim, len(layers))
+	for i := range layers {
+		p.anims[i] = p.collectLayerAnims(nil, &layers[i])
+	}
+}
+
+func (p *sceneRenderPreparer) collectLayerAnims(anims []sceneTileAnim, layer *tilerender.PreparedLayer) []sceneTileAnim {
+	add := func(tile *tilerender.PreparedTile) {
+		sheet := p.scene.SheetByID(tile.SheetID)
+		if anim, ok := sheet.TileAnim(tile.TileID); ok {
+			anims = append(anims, sceneTileAnim{tile: tile, sheet: sheet, anim: anim})
+		}
+	}
+	for j := range layer.Runs {
+		add(&layer.Runs[j].Tile)
+	}
+	for j := range layer.Draws {
+		add(&layer.Draws[j].Tile)
+	}
+	return anims
+}
+
+// animate points every animated tile at its frame for tick.
+func (p *sceneRenderPreparer) animate(tick int) {
+	if p == nil {
+		return
+	}
+	for _, layer := range p.anims {
+		for i := range layer {
+			a := &layer[i]
+			frame := a.anim.FrameAt(tick)
+			if frame == a.frame {
+				continue
+			}
+			a.frame = frame
+			a.tile.Source = a.sheet.tileImage(frame)
+		}
+	}
+}
+
 func (p *sceneRenderPreparer) primaryTileWidth() int {
 	if p != nil && p.scene != nil && len(p.scene.sheets) > 0 && p.scene.sheets[0] != nil && p.scene.sheets[0].parsed.TileWidth > 0 {
 		return int(p.scene.sheets[0].parsed.TileWidth)
@@ -69,43 +132,59 @@ func nonZeroCount(prepared [][]tilerender.PreparedTile) int {
 func (p *sceneRenderPreparer) prepareLayerTiles(layer sceneRenderLayerSource) [][]tilerender.PreparedTile {
 	prepared := make([][]tilerender.PreparedTile, len(layer.Tiles))
 	for y := range layer.Tiles {
-		prepared[y] = make([]tilerender.PreparedTile, len(layer.Tiles[y]))
-		for x, tileID := range layer.Tiles[y] {
-			if tileID == 0 {
-				continue
-			}
-
-			sheetID := layer.SheetID
-			if sheetID == 0 {
-				sheetID = 1
-			}
-
-			entry := tilerender.PreparedTile{
-				TileID:  tileID,
-				SheetID: sheetID,
-			}
-			if sheet := p.scene.SheetByID(sheetID); sheet != nil {
-				entry.Source = sheet.tileImage(tileID)
-			}
-			prepared[y][x] = entry
+		var flips []uint8
+		if y < len(layer.Flips) {
+			flips = layer.Flips[y]
 		}
+		prepared[y] = p.prepareRowTiles(layer.SheetID, layer.Tiles[y], flips)
 	}
 	return prepared
 }
 
+func (p *sceneRenderPreparer) prepareRowTiles(sheetID uint16, tiles []uint16, flips []uint8) []tilerender.PreparedTile {
+	if sheetID == 0 {
+		sheetID = 1
+	}
+	sheet := p.scene.SheetByID(sheetID)
+	row := make([]tilerender.PreparedTile, len(tiles))
+	for x, tileID := range tiles {
+		if tileID == 0 {
+			continue
+		}
+		entry := tilerender.PreparedTile{
+			TileID:  tileID,
+			SheetID: sheetID,
+		}
+		if x < len(flips) {
+			entry.Flip = flips[x]
+		}
+		if sheet != nil {
+			entry.Source = sheet.tileImage(tileID)
+		}
+		row[x] = entry
+	}
+	return row
+}
+
 func (p *sceneRenderPreparer) prepareLayerDraws(prepared [][]tilerender.PreparedTile) []tilerender.PreparedDraw {
 	draws := make([]tilerender.PreparedDraw, 0, nonZeroCount(prepared))
 	for y := range prepared {
-		for x, tile := range prepared[y] {
-			if tile.TileID == 0 {
-				continue
-			}
-			draws = append(draws, tilerender.PreparedDraw{
-				CellX: x,
-				CellY: y,
-				Tile:  tile,
-			})
+		draws = appendRowDraws(draws, 0, y, prepared[y])
+	}
+	return draws
+}
+
+// appendRowDraws appends the draws of row, whose first cell is (x0, y).
+func appendRowDraws(draws []tilerender.PreparedDraw, x0, y int, row []tilerender.PreparedTile) []tilerender.PreparedDraw {
+	for x, tile := range row {
+		if tile.TileID == 0 {
+			continue
 		}
+		draws = append(draws, tilerender.PreparedDraw{
+			CellX: x0 + x,
+			CellY: y,
+			Tile:  tile,
+		})
 	}
 	return draws
 }
@@ -113,31 +192,78 @@ func (p *sceneRenderPreparer) prepareLayerDraws(prepared [][]tilerender.Prepared
 func (p *sceneRenderPreparer) prepareLayerRuns(prepared [][]tilerender.PreparedTile) []tilerender.PreparedRun {
 	runs := make([]tilerender.PreparedRun, 0, nonZeroCount(prepared))
 	for y := range prepared {
-		row := prepared[y]
-		for x := 0; x < len(row); {
-			tile := row[x]
-			if tile.TileID == 0 {
-				x++
-				continue
-			}
+		runs = appendRowRuns(runs, 0, y, prepared[y])
+	}
+	return runs
+}
 
-			run := tilerender.PreparedRun{
-				CellX: x,
-				CellY: y,
-				Count: 1,
-				Tile:  tile,
-			}
-			for next := x + 1; next < len(row); next++ {
-				candidate := row[next]
-				if candidate.TileID != tile.TileID || candidate.SheetID != tile.SheetID || candidate.Source != tile.Source {
-					break
-				}
-				run.Count++
-				x = next
-			}
-			runs = append(runs, run)
+// appendRowRuns appends the runs of row, whose first cell is (x0, y).
+func appendRowRuns(runs []tilerender.PreparedRun, x0, y int, row []tilerender.PreparedTile) []tilerender.PreparedRun {
+	for x := 0; x < len(row); {
+		tile := row[x]
+		if tile.TileID == 0 {
 			x++
+			continue
 		}
+
+		run := tilerender.PreparedRun{
+			CellX: x0 + x,
+			CellY: y,
+			Count: 1,
+			Tile:  tile,
+		}
+		for next := x + 1; next < len(row); next++ {
+			candidate := row[next]
+			if candidate.TileID != tile.TileID || candidate.SheetID != tile.SheetID || candidate.Source != tile.Source || candidate.Flip != tile.Flip {
+				break
+			}
+			run.Count++
+			x = next
+		}
+		runs = append(runs, run)
+		x++
 	}
 	return runs
 }
+
+// rebuildRow re-prepares row y of layer after a cell edit and splices the
+// new runs and draws over the row's old ones. Other rows are untouched.
+func (p *sceneRenderPreparer) rebuildRow(scene *tilerender.PreparedScene, layer, y int) {
+	p.rebuildRows(scene, layer, y, y+1)
+}
+
+// rebuildRows re-prepares rows y0 up to y1 of layer, as rebuildRow does
+// for one row. Streamed maps use it when chunks load or leave.
+func (p *sceneRenderPreparer) rebuildRows(scene *tilerender.PreparedScene, layer, y0, y1 int) {
+	if p == nil || p.scene == nil || layer < 0 || layer >= len(scene.Layers) {
+		return
+	}
+	var runs []tilerender.PreparedRun
+	var draws []tilerender.PreparedDraw
+	for y := y0; y < y1; y++ {
+		spans, sheetID, ok := p.scene.gameMap.rowSpans(layer, y)
+		if !ok {
+			return
+		}
+		for _, span := range spans {
+			row := p.prepareRowTiles(sheetID, span.tiles, span.flips)
+			runs = appendRowRuns(runs, span.x, y, row)
+			draws = appendRowDraws(draws, span.x, y, row)
+		}
+	}
+	prepared := &scene.Layers[layer]
+	prepared.Runs = spliceRows(prepared.Runs, y0, y1, runs, func(r tilerender.PreparedRun) int { return r.CellY })
+	prepared.Draws = spliceRows(prepared.Draws, y0, y1, draws, func(d tilerender.PreparedDraw) int { return d.CellY })
+
+	// Splicing may have moved the layer's entries.
+	p.anims[layer] = p.collectLayerAnims(p.anims[layer][:0], prepared)
+	p.animate(p.scene.tileTick)
+}
+
+// spliceRows replaces the entries of rows y0 up to y1, which are
+// contiguous because entries are sorted by row, with rows.
+func spliceRows[T any](items []T, y0, y1 int, rows []T, rowOf func(T) int) []T {
+	lo := sort.Search(len(items), func(i int) bool { return rowOf(items[i]) >= y0 })
+	hi := lo + sort.Search(len(items)-lo, func(i int) bool { return rowOf(items[lo+i]) >= y1 })
+	return slices.Replace(items, lo, hi, rows...)
+}
@@ -0,0 +1,314 @@
+package gosprite64
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	tileloader "github.com/drpaneas/gosprite64/internal/tile2d/loader"
+)
+
+// loadTestScene builds an in-memory bundle with one 8x8 sheet of four tiles
+// and a map from cfg, and loads it as a Scene.
+func loadTestScene(t *testing.T, cfg format.MapConfig) *Scene {
+	t.Helper()
+	return loadTestSceneWithProps(t, cfg, format.TilePropsConfig{})
+}
+
+func loadTestSceneWithProps(t *testing.T, cfg format.MapConfig, props format.TilePropsConfig) *Scene {
+	t.Helper()
+	return loadTestSceneWithTiles(t, cfg, props, 4)
+}
+
+// loadTestSceneWithTiles is loadTestSceneWithProps with a sheet of tiles
+// 8x8 tiles in one row.
+func loadTestSceneWithTiles(t *testing.T, cfg format.MapConfig, props format.TilePropsConfig, tiles int) *Scene {
+	t.Helper()
+
+	img := image.NewNRGBA(image.Rect(0, 0, tiles*8, 8))
+	for x := 0; x < tiles*8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x * 8), A: 255})
+		}
+	}
+	sheet, err := format.BuildSheetWithProps(img, 8, 8, props)
+	if err != nil {
+		t.Fatalf("BuildSheetWithProps: %v", err)
+	}
+	mapRaw, err := format.BuildMap(cfg)
+	if err != nil {
+		t.Fatalf("BuildMap: %v", err)
+	}
+	bundleRaw, err := format.BuildBundle([]format.BundleEntry{
+		{Kind: format.BundleKindSheet, Name: "tiles", Path: "tiles.sht2"},
+		{Kind: format.BundleKindMap, Name: "level", Path: "level.map2"},
+	})
+	if err != nil {
+		t.Fatalf("BuildBundle: %v", err)
+	}
+
+	loader := tileloader.NewMemoryLoader(map[string][]byte{
+		"level.bnd2": bundleRaw,
+		"tiles.sht2": sheet,
+		"level.map2": mapRaw,
+	})
+	bundle, err := OpenBundleWithLoader("level.bnd2", loader)
+	if err != nil {
+		t.Fatalf("OpenBundleWithLoader: %v", err)
+	}
+	scene, err := LoadScene(bundle)
+	if err != nil {
+		t.Fatalf("LoadScene: %v", err)
+	}
+	return scene
+}
+
+func threeLayerMap() format.MapConfig {
+	return format.MapConfig{
+		Width:       2,
+		Height:      1,
+		LayerCount:  3,
+		CellBits:    16,
+		ChunkWidth:  2,
+		ChunkHeight: 1,
+		Layers: []format.MapLayerConfig{
+			{SheetID: 1, Cells: []uint16{1, 1}},
+			{SheetID: 1, Cells: []uint16{2, 0}},
+			{SheetID: 1, Cells: []uint16{3, 3}},
+		},
+	}
+}
+
+func TestSceneDrawLayersAccumulatesStatsAcrossOnePass(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+	cam := &Camera{Width: 16, Height: 8}
+
+	scene.DrawLayer(cam, 0)
+	scene.DrawLayers(cam, 1, 2)
+	if got := scene.Stats().VisibleTiles; got != 5 {
+		t.Fatalf("VisibleTiles after one pass = %d, want 5", got)
+	}
+
+	// Starting again from the bottom layer begins a new frame's stats.
+	scene.DrawLayer(cam, 0)
+	if got := scene.Stats().VisibleTiles; got != 2 {
+		t.Fatalf("VisibleTiles after restarting = %d, want 2", got)
+	}
+}
+
+func TestSceneHiddenLayerIsNotDrawn(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+	cam := &Camera{Width: 16, Height: 8}
+
+	scene.SetLayerVisible(2, false)
+	if scene.LayerVisible(2) {
+		t.Fatal("LayerVisible(2) = true after hiding it")
+	}
+	scene.Draw(cam)
+	if got := scene.Stats().VisibleTiles; got != 3 {
+		t.Fatalf("VisibleTiles = %d, want 3 with layer 2 hidden", got)
+	}
+
+	scene.SetLayerVisible(2, true)
+	scene.SetLayerOpacity(2, 0)
+	scene.Draw(cam)
+	if got := scene.Stats().VisibleTiles; got != 3 {
+		t.Fatalf("VisibleTiles = %d, want 3 with layer 2 fully transparent", got)
+	}
+}
+
+func TestSceneLayerOffsetMovesCulling(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+	cam := &Camera{Width: 16, Height: 8}
+
+	scene.SetLayerOffset(0, 8, 0)
+	if x, y := scene.LayerOffset(0); x != 8 || y != 0 {
+		t.Fatalf("LayerOffset(0) = (%d,%d), want (8,0)", x, y)
+	}
+	scene.DrawLayer(cam, 0)
+	if got := scene.Stats().VisibleTiles; got != 1 {
+		t.Fatalf("VisibleTiles = %d, want 1 after shifting layer half off camera", got)
+	}
+}
+
+func TestSceneLayerTintAndOpacity(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+
+	if got := scene.renderScene.Layers[1].Tint; got != (color.RGBA{}) {
+		t.Fatalf("default tint = %v, want zero (untinted)", got)
+	}
+
+	scene.SetLayerTint(1, color.RGBA{R: 255, G: 128, B: 0, A: 0})
+	scene.SetLayerOpacity(1, 0.5)
+	want := color.RGBA{R: 255, G: 128, B: 0, A: 128}
+	if got := scene.renderScene.Layers[1].Tint; got != want {
+		t.Fatalf("tint = %v, want %v", got, want)
+	}
+
+	scene.SetLayerTint(1, color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	scene.SetLayerOpacity(1, 2)
+	if got := scene.renderScene.Layers[1].Tint; got != (color.RGBA{}) {
+		t.Fatalf("tint after reset = %v, want zero (untinted)", got)
+	}
+}
+
+func TestSceneLayerSettersIgnoreOutOfRange(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+	scene.SetLayerVisible(9, false)
+	scene.SetLayerOffset(-1, 4, 4)
+	scene.SetLayerTint(3, color.RGBA{})
+	scene.SetLayerOpacity(3, 0)
+	if scene.LayerVisible(9) {
+		t.Fatal("LayerVisible(9) = true for missing layer")
+	}
+
+	var nilScene *Scene
+	nilScene.DrawLayer(nil, 0)
+	nilScene.SetLayerVisible(0, false)
+}
+
+func TestSceneUsesMapParallaxAndRepeat(t *testing.T) {
+	cfg := threeLayerMap()
+	slow := float32(0.25)
+	cfg.Layers[0].ScrollX = &slow
+	cfg.Layers[0].RepeatX = true
+	scene := loadTestScene(t, cfg)
+
+	info, ok := scene.Map().LayerInfo(0)
+	if !ok || info.Parallax != (ParallaxLayer{SpeedX: 0.25, SpeedY: 1}) || !info.RepeatX {
+		t.Fatalf("LayerInfo(0) = %+v, want scroll (0.25,1) repeating on x", info)
+	}
+	if got := scene.LayerParallax(0); got != (ParallaxLayer{SpeedX: 0.25, SpeedY: 1}) {
+		t.Fatalf("LayerParallax(0) = %+v", got)
+	}
+	if got := scene.LayerParallax(1); got != (ParallaxLayer{SpeedX: 1, SpeedY: 1}) {
+		t.Fatalf("LayerParallax(1) = %+v, want default", got)
+	}
+
+	// The layer scrolls to x=100, far past the 2-tile map; 48px starting
+	// half way into a tile covers 7 repeated cells.
+	scene.DrawLayer(&Camera{X: 400, Width: 48, Height: 8}, 0)
+	if got := scene.Stats().VisibleTiles; got != 7 {
+		t.Fatalf("VisibleTiles = %d, want 7 for a repeating layer", got)
+	}
+}
+
+func TestSceneStartsMapHiddenLayersHidden(t *testing.T) {
+	cfg := threeLayerMap()
+	cfg.Layers[1].Name = "collision"
+	cfg.Layers[1].Hidden = true
+	scene := loadTestScene(t, cfg)
+
+	layer, ok := scene.Map().LayerByName("collision")
+	if !ok || layer != 1 {
+		t.Fatalf("LayerByName(collision) = %d, %v, want 1, true", layer, ok)
+	}
+	if _, ok := scene.Map().LayerByName("missing"); ok {
+		t.Fatal("LayerByName(missing) found a layer")
+	}
+	if scene.LayerVisible(1) || !scene.LayerVisible(0) {
+		t.Fatalf("LayerVisible = %v, %v, want layer 1 hidden only", scene.LayerVisible(0), scene.LayerVisible(1))
+	}
+	scene.SetLayerVisible(1, true)
+	if !scene.LayerVisible(1) {
+		t.Fatal("SetLayerVisible(1, true) left the layer hidden")
+	}
+}
+
+func TestMapLinksComeFromTheMapAsset(t *testing.T) {
+	cfg := threeLayerMap()
+	cfg.Links = []format.MapLinkConfig{{Dir: "e", Map: "cave", OffsetX: 16, OffsetY: -8}}
+	scene := loadTestScene(t, cfg)
+
+	links := scene.Map().Links()
+	if len(links) != 1 || links[0] != (MapLink{Dir: "e", Map: "cave", OffsetX: 16, OffsetY: -8}) {
+		t.Fatalf("Links() = %+v, want one link east to cave", links)
+	}
+	if (*Map)(nil).Links() != nil {
+		t.Fatal("nil map has links")
+	}
+}
+
+func TestSceneSetParallaxOverridesMap(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+	scene.SetParallax(NewParallaxConfig(
+		ParallaxLayer{SpeedX: 0, SpeedY: 0},
+		ParallaxLayer{SpeedX: 0.5, SpeedY: 0.5},
+	))
+	scene.SetLayerRepeat(2, false, true)
+
+	if got := scene.LayerParallax(0); got != (ParallaxLayer{}) {
+		t.Fatalf("LayerParallax(0) = %+v, want fixed", got)
+	}
+	if got := scene.LayerParallax(2); got != (ParallaxLayer{SpeedX: 1, SpeedY: 1}) {
+		t.Fatalf("LayerParallax(2) = %+v, want untouched default", got)
+	}
+	if x, y := scene.LayerRepeat(2); x || !y {
+		t.Fatalf("LayerRepeat(2) = (%v,%v), want (false,true)", x, y)
+	}
+
+	// A fixed layer stays on screen no matter where the camera goes.
+	scene.DrawLayer(&Camera{X: 1000, Y: 1000, Width: 16, Height: 8}, 0)
+	if got := scene.Stats().VisibleTiles; got != 2 {
+		t.Fatalf("VisibleTiles = %d, want 2 for a fixed layer", got)
+	}
+}
+
+func TestSceneZoomedOutCameraDrawsMoreTiles(t *testing.T) {
+	scene := loadTestScene(t, threeLayerMap())
+
+	scene.DrawLayer(&Camera{Width: 8, Height: 8}, 0)
+	if got := scene.Stats().VisibleTiles; got != 1 {
+		t.Fatalf("VisibleTiles = %d, want 1 at zoom 1", got)
+	}
+	scene.DrawLayer(&Camera{Width: 8, Height: 8, Zoom: 0.5}, 0)
+	if got := scene.Stats().VisibleTiles; got != 2 {
+		t.Fatalf("VisibleTiles = %d, want 2 at zoom 0.5", got)
+	}
+}
+
+func TestSceneCellFlipsReachTileAtAndRuns(t *testing.T) {
+	scene := loadTestScene(t, format.MapConfig{
+		Width: 4, Height: 1, LayerCount: 1, CellBits: 16, ChunkWidth: 4, ChunkHeight: 1,
+		Layers: []format.MapLayerConfig{{
+			Cells: []uint16{2, 2, 2, 2},
+			Flips: []uint8{0, 0, format.CellFlipH, format.CellFlipD | format.CellFlipH},
+		}},
+	})
+
+	tile, flip, ok := scene.Map().TileAt(0, 3, 0)
+	if !ok || tile != 2 || flip != TileFlipD|TileFlipH {
+		t.Fatalf("TileAt(0, 3, 0) = (%d, %v, %v), want tile 2 turned clockwise", tile, flip, ok)
+	}
+	if _, flip, _ := scene.Map().TileAt(0, 0, 0); flip != 0 {
+		t.Fatalf("TileAt(0, 0, 0) flip = %v, want none", flip)
+	}
+
+	// Cells with the same tile but different flips are separate runs.
+	runs := scene.renderScene.Layers[0].Runs
+	if len(runs) != 3 || runs[0].Count != 2 || runs[1].Tile.Flip != format.CellFlipH || runs[2].Tile.Flip != format.CellFlipD|format.CellFlipH {
+		t.Fatalf("runs = %+v, want [2 plain, 1 H, 1 D|H]", runs)
+	}
+}
+
+func TestFlippedImageMirrorsAndTransposes(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.NRGBA{R: 1, A: 255})
+	src.Set(1, 0, color.NRGBA{R: 2, A: 255})
+
+	mirrored := flippedImage(src, TileFlipH)
+	if r, _, _, _ := mirrored.At(0, 0).RGBA(); r>>8 != 2 {
+		t.Fatalf("FlipH pixel (0,0) red = %d, want 2", r>>8)
+	}
+	turned := flippedImage(src, TileFlipD)
+	if b := turned.Bounds(); b.Dx() != 1 || b.Dy() != 2 {
+		t.Fatalf("FlipD bounds = %v, want 1x2", b)
+	}
+	if r, _, _, _ := turned.At(0, 1).RGBA(); r>>8 != 2 {
+		t.Fatalf("FlipD pixel (0,1) red = %d, want 2", r>>8)
+	}
+	if flippedImage(src, 0) != image.Image(src) {
+		t.Fatal("flippedImage with no flip should return the source")
+	}
+}
@@ -0,0 +1,231 @@
+package gosprite64
+
+import (
+	"iter"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// Script is a sequence of events written as ordinary Go code. The blocking
+// helpers on ScriptContext (Wait, Tween, Say, ...) suspend the script until
+// a later ScriptRunner.Update.
+type Script func(sc *ScriptContext)
+
+// ScriptRunner steps a Script exactly once per Update. Scripts run as
+// coroutines, not goroutines: nothing happens between Updates, so a
+// cutscene plays out identically on every run and under input replay.
+type ScriptRunner struct {
+	// Advance reports whether the player dismissed the current Say line.
+	// Nil means ButtonA was just pressed.
+	Advance func() bool
+
+	main *scriptCoroutine
+	text string
+}
+
+// ScriptContext is passed to a running Script and provides the blocking
+// helpers. It is only valid inside the script it was passed to.
+type ScriptContext struct {
+	runner *ScriptRunner
+	yield  func(struct{}) bool
+}
+
+type scriptCoroutine struct {
+	next func() (struct{}, bool)
+	stop func()
+}
+
+type scriptStopped struct{}
+
+// NewScriptRunner creates an idle script runner.
+func NewScriptRunner() *ScriptRunner {
+	return &ScriptRunner{}
+}
+
+// Start replaces any running script with script. The script's first step
+// runs on the next Update.
+func (r *ScriptRunner) Start(script Script) {
+	if r == nil {
+		return
+	}
+	r.Stop()
+	if script == nil {
+		return
+	}
+	r.main = r.spawn(script)
+}
+
+// Stop abandons the running script. Code after the current blocking call
+// never runs, but deferred functions inside the script do.
+func (r *ScriptRunner) Stop() {
+	if r == nil || r.main == nil {
+		return
+	}
+	r.main.stop()
+	r.main = nil
+	r.text = ""
+}
+
+// Update runs the script until its next blocking call.
+func (r *ScriptRunner) Update() {
+	if r == nil || r.main == nil {
+		return
+	}
+	if !r.main.step() {
+		r.main = nil
+		r.text = ""
+	}
+}
+
+// Running reports whether a script is in progress.
+func (r *ScriptRunner) Running() bool {
+	return r != nil && r.main != nil
+}
+
+// Text returns the line passed to the active Say call, or "" if none.
+func (r *ScriptRunner) Text() string {
+	if r == nil {
+		return ""
+	}
+	return r.text
+}
+
+func (r *ScriptRunner) spawn(script Script) *scriptCoroutine {
+	seq := func(yield func(struct{}) bool) {
+		defer func() {
+			if v := recover(); v != nil {
+				if _, ok := v.(scriptStopped); !ok {
+					panic(v)
+				}
+			}
+		}()
+		script(&ScriptContext{runner: r, yield: yield})
+	}
+	next, stop := iter.Pull(iter.Seq[struct{}](seq))
+	return &scriptCoroutine{next: next, stop: stop}
+}
+
+func (r *ScriptRunner) advanced() bool {
+	if r.Advance != nil {
+		return r.Advance()
+	}
+	return IsButtonJustPressed(ButtonA)
+}
+
+// step resumes the coroutine and reports whether it is still running.
+func (c *scriptCoroutine) step() bool {
+	_, ok := c.next()
+	return ok
+}
+
+// frame suspends the script until the next Update.
+func (sc *ScriptContext) frame() {
+	if !sc.yield(struct{}{}) {
+		panic(scriptStopped{})
+	}
+}
+
+// Wait suspends the script for the given number of frames.
+func (sc *ScriptContext) Wait(frames int) {
+	t := NewTimer(frames)
+	for !t.Done() {
+		t.Tick()
+		sc.frame()
+	}
+}
+
+// WaitUntil suspends the script until cond returns true. cond is checked
+// once per frame, starting immediately.
+func (sc *ScriptContext) WaitUntil(cond func() bool) {
+	if cond == nil {
+		return
+	}
+	for !cond() {
+		sc.frame()
+	}
+}
+
+// Tween moves *value to `to` over the given number of frames, shaped by
+// ease (linear when nil). *value is exactly `to` when Tween returns.
+func (sc *ScriptContext) Tween(value *float32, to float32, frames int, ease func(t float32) float32) {
+	if value == nil {
+		return
+	}
+	from := *value
+	t := NewTimer(frames)
+	for !t.Done() {
+		t.Tick()
+		p := t.Progress()
+		if ease != nil {
+			p = ease(p)
+		}
+		*value = math2d.Lerp(from, to, p)
+		sc.frame()
+	}
+	*value = to
+}
+
+// Say shows text through ScriptRunner.Text and suspends the script until
+// the player advances it. Input is checked from the frame after the line
+// appears, so the press that started the line does not also skip it.
+func (sc *ScriptContext) Say(text string) {
+	sc.runner.text = text
+	defer func() { sc.runner.text = "" }()
+	for {
+		sc.frame()
+		if sc.runner.advanced() {
+			return
+		}
+	}
+}
+
+// Every calls fn times times, once every intervalFrames frames, and returns
+// after the last call.
+func (sc *ScriptContext) Every(intervalFrames, times int, fn func(i int)) {
+	if times <= 0 {
+		return
+	}
+	rt := NewRepeatingTimer(intervalFrames)
+	for rt.Count() < times {
+		if rt.Tick() && fn != nil {
+			fn(rt.Count() - 1)
+		}
+		sc.frame()
+	}
+}
+
+// Parallel runs scripts side by side, stepping each once per frame in the
+// order given, and returns when all of them have finished.
+func (sc *ScriptContext) Parallel(scripts ...Script) {
+	children := make([]*scriptCoroutine, 0, len(scripts))
+	for _, script := range scripts {
+		if script != nil {
+			children = append(children, sc.runner.spawn(script))
+		}
+	}
+	defer func() {
+		for _, child := range children {
+			if child != nil {
+				child.stop()
+			}
+		}
+	}()
+
+	for {
+		running := false
+		for i, child := range children {
+			if child == nil {
+				continue
+			}
+			if child.step() {
+				running = true
+			} else {
+				children[i] = nil
+			}
+		}
+		if !running {
+			return
+		}
+		sc.frame()
+	}
+}
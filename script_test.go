@@ -0,0 +1,224 @@
+package gosprite64
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+func TestScriptRunnerWait(t *testing.T) {
+	log := make([]string, 0)
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		log = append(log, "start")
+		sc.Wait(2)
+		log = append(log, "end")
+	})
+	if len(log) != 0 {
+		t.Fatal("Start should not run the script before Update")
+	}
+
+	r.Update()
+	r.Update()
+	if len(log) != 1 || !r.Running() {
+		t.Fatalf("Wait(2) should consume two updates, got %v", log)
+	}
+	r.Update()
+	if len(log) != 2 || log[1] != "end" {
+		t.Fatalf("script should resume on the third update, got %v", log)
+	}
+	if r.Running() {
+		t.Fatal("finished script should not be running")
+	}
+	r.Update()
+}
+
+func TestScriptRunnerWaitUntil(t *testing.T) {
+	ready := false
+	done := false
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		sc.WaitUntil(func() bool { return ready })
+		done = true
+	})
+	r.Update()
+	r.Update()
+	if done {
+		t.Fatal("WaitUntil should block while the condition is false")
+	}
+	ready = true
+	r.Update()
+	if !done {
+		t.Fatal("WaitUntil should return on the first update the condition holds")
+	}
+}
+
+func TestScriptRunnerTween(t *testing.T) {
+	x := float32(0)
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		sc.Tween(&x, 8, 4, nil)
+		sc.Tween(&x, 0, 2, math2d.EaseInQuad)
+	})
+	expected := []float32{2, 4, 6, 8, 6, 0}
+	for i, want := range expected {
+		r.Update()
+		if x != want {
+			t.Fatalf("update %d: x = %v, want %v", i+1, x, want)
+		}
+	}
+	r.Update()
+	if r.Running() {
+		t.Fatal("script should finish after both tweens")
+	}
+}
+
+func TestScriptRunnerSay(t *testing.T) {
+	pressed := false
+	r := NewScriptRunner()
+	r.Advance = func() bool { return pressed }
+	r.Start(func(sc *ScriptContext) {
+		sc.Say("hello")
+		sc.Say("bye")
+	})
+
+	pressed = true
+	r.Update()
+	if r.Text() != "hello" {
+		t.Fatalf("expected hello, got %q", r.Text())
+	}
+	r.Update()
+	if r.Text() != "bye" {
+		t.Fatalf("press should advance to the next line, got %q", r.Text())
+	}
+	pressed = false
+	r.Update()
+	r.Update()
+	if r.Text() != "bye" {
+		t.Fatalf("line should stay up until the next press, got %q", r.Text())
+	}
+	pressed = true
+	r.Update()
+	if r.Text() != "" || r.Running() {
+		t.Fatalf("script should end with no text, got %q", r.Text())
+	}
+}
+
+func TestScriptRunnerParallel(t *testing.T) {
+	log := make([]string, 0)
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		sc.Parallel(
+			func(sc *ScriptContext) {
+				sc.Wait(1)
+				log = append(log, "a")
+			},
+			func(sc *ScriptContext) {
+				sc.Wait(3)
+				log = append(log, "b")
+			},
+		)
+		log = append(log, "after")
+	})
+
+	for i := 0; i < 3; i++ {
+		r.Update()
+	}
+	if len(log) != 1 || log[0] != "a" {
+		t.Fatalf("only the short branch should have finished, got %v", log)
+	}
+	r.Update()
+	if len(log) != 3 || log[1] != "b" || log[2] != "after" {
+		t.Fatalf("Parallel should return once every branch finished, got %v", log)
+	}
+}
+
+func TestScriptRunnerEvery(t *testing.T) {
+	calls := make([]string, 0)
+	frame := 0
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		sc.Every(2, 3, func(i int) {
+			calls = append(calls, fmt.Sprintf("%d@%d", i, frame))
+		})
+	})
+	for frame = 1; frame <= 6; frame++ {
+		r.Update()
+	}
+	expected := []string{"0@2", "1@4", "2@6"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, calls)
+	}
+	r.Update()
+	if r.Running() {
+		t.Fatal("Every should return after the last call")
+	}
+}
+
+func TestScriptRunnerStopRunsDefers(t *testing.T) {
+	cleaned := 0
+	reached := false
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		defer func() { cleaned++ }()
+		sc.Parallel(func(sc *ScriptContext) {
+			defer func() { cleaned++ }()
+			sc.Say("stuck")
+		})
+		reached = true
+	})
+	r.Advance = func() bool { return false }
+	r.Update()
+	if r.Text() != "stuck" {
+		t.Fatalf("expected Say from the parallel branch, got %q", r.Text())
+	}
+
+	r.Stop()
+	if r.Running() || r.Text() != "" {
+		t.Fatal("Stop should clear the runner")
+	}
+	if cleaned != 2 || reached {
+		t.Fatalf("Stop should unwind both scripts, cleaned=%d reached=%v", cleaned, reached)
+	}
+}
+
+func TestScriptRunnerStartReplacesRunningScript(t *testing.T) {
+	log := make([]string, 0)
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		sc.Wait(10)
+		log = append(log, "first")
+	})
+	r.Update()
+	r.Start(func(sc *ScriptContext) {
+		log = append(log, "second")
+	})
+	r.Update()
+	if len(log) != 1 || log[0] != "second" {
+		t.Fatalf("Start should abandon the previous script, got %v", log)
+	}
+}
+
+func TestScriptRunnerPanicsPropagate(t *testing.T) {
+	r := NewScriptRunner()
+	r.Start(func(sc *ScriptContext) {
+		panic("boom")
+	})
+	defer func() {
+		if recover() != "boom" {
+			t.Fatal("script panics should reach the caller of Update")
+		}
+	}()
+	r.Update()
+}
+
+func TestScriptRunnerNilIsNoop(t *testing.T) {
+	var r *ScriptRunner
+	r.Start(func(sc *ScriptContext) {})
+	r.Update()
+	r.Stop()
+	if r.Running() || r.Text() != "" {
+		t.Fatal("nil runner should be idle")
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"image/color"
 
 	n64draw "github.com/clktmr/n64/drivers/draw"
+	"github.com/clktmr/n64/rcp/texture"
 	"github.com/drpaneas/gosprite64/internal/rendergeom"
+	"github.com/drpaneas/gosprite64/internal/sprite"
 )
 
 // DrawRect draws the outline of a rectangle using DrawLine.
@@ -124,9 +126,32 @@ func DrawWorldImage(src image.Image, worldX, worldY int, cam *Camera) {
 		DrawImage(src, worldX, worldY)
 		return
 	}
+	if cam.transformed() {
+		drawWorldImageTransformed(src, worldX, worldY, cam)
+		return
+	}
 	drawLogicalImage(src, worldX-cam.X, worldY-cam.Y)
 }
 
+// drawWorldImageTransformed draws src through the sprite scale and rotation
+// path. Images that are not textures cannot be scaled and are drawn at
+// their transformed position instead.
+func drawWorldImageTransformed(src image.Image, worldX, worldY int, cam *Camera) {
+	video := currentVideo()
+	if video == nil || video.Framebuffer == nil || src == nil {
+		return
+	}
+	x, y := cam.WorldToScreen(float32(worldX), float32(worldY))
+	if _, ok := src.(*texture.Texture); !ok {
+		drawLogicalImage(src, int(x), int(y))
+		return
+	}
+	z := cam.EffectiveZoom()
+	sprite.RenderSprite(video.Framebuffer, src, int(x), int(y),
+		false, false, z, z, uint8(BlendNone), 1,
+		-cam.Rotation, 0, 0)
+}
+
 func drawFramebufferRect(x1, y1, x2, y2 int, c color.Color) {
 	video := currentVideo()
 	if video == nil || video.Framebuffer == nil {
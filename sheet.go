@@ -3,13 +3,17 @@ package gosprite64
 import (
 	"image"
 
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/tile2d/autotile"
 	"github.com/drpaneas/gosprite64/internal/tile2d/format"
 	tilerender "github.com/drpaneas/gosprite64/internal/tile2d/render"
 )
 
 type Sheet struct {
-	parsed  format.ParsedSheet
-	tileset *tilerender.Tileset
+	parsed   format.ParsedSheet
+	tileset  *tilerender.Tileset
+	autotile *autotile.Set
+	palette  *Palette
 }
 
 type SheetInfo struct {
@@ -44,11 +48,43 @@ func (s *Sheet) atlasImage() image.Image {
 	if len(s.parsed.Pixels) == 0 {
 		return nil
 	}
+	rect := image.Rect(0, 0, int(s.parsed.AtlasWidth), int(s.parsed.AtlasHeight))
+	pix := s.parsed.Pixels
+	switch s.parsed.Encoding {
+	case format.SheetCI8, format.SheetCI4:
+		return s.indexedAtlas()
+	case format.SheetRGBA16:
+		return s.copiedAtlas(texture.NewRGBA16(rect))
+	case format.SheetI8:
+		return s.copiedAtlas(texture.NewI8(rect))
+	case format.SheetI4:
+		return s.copiedAtlas(texture.NewI4(rect))
+	case format.SheetIA4:
+		// The texture package has no IA images. RGBA16 holds a 3-bit
+		// intensity and a 1-bit alpha without loss.
+		atlas := texture.NewRGBA16(rect)
+		for y := 0; y < rect.Dy(); y++ {
+			for x := 0; x < rect.Dx(); x++ {
+				atlas.Set(x, y, s.parsed.PixelAt(x, y))
+			}
+		}
+		atlas.Writeback()
+		return atlas.Image
+	case format.SheetIA8:
+		// A 4-bit alpha needs more than RGBA16's one bit.
+		pix = make([]byte, 0, rect.Dx()*rect.Dy()*4)
+		for y := 0; y < rect.Dy(); y++ {
+			for x := 0; x < rect.Dx(); x++ {
+				c := s.parsed.PixelAt(x, y)
+				pix = append(pix, c.R, c.G, c.B, c.A)
+			}
+		}
+	}
 
 	nrgba := &image.NRGBA{
-		Pix:    append([]byte(nil), s.parsed.Pixels...),
-		Stride: int(s.parsed.AtlasWidth) * 4,
-		Rect:   image.Rect(0, 0, int(s.parsed.AtlasWidth), int(s.parsed.AtlasHeight)),
+		Pix:    append([]byte(nil), pix...),
+		Stride: rect.Dx() * 4,
+		Rect:   rect,
 	}
 	if allOpaque(nrgba.Pix) {
 		rgba := image.NewRGBA(nrgba.Rect)
@@ -62,6 +98,49 @@ func (s *Sheet) atlasImage() image.Image {
 	return nrgba
 }
 
+// copiedAtlas fills atlas, a texture in the sheet's encoding, with the
+// sheet's pixels, whose rows are laid out as the texture's are.
+func (s *Sheet) copiedAtlas(atlas *texture.Texture) image.Image {
+	copy(atlas.Pix(), s.parsed.Pixels)
+	atlas.Writeback()
+	return atlas.Image
+}
+
+// indexedAtlas returns a palettized sheet's atlas as a CI8 image drawing
+// through the sheet's palette. CI4 sheets are expanded, since textures hold
+// at least a byte per index.
+func (s *Sheet) indexedAtlas() image.Image {
+	w, h := int(s.parsed.AtlasWidth), int(s.parsed.AtlasHeight)
+	atlas := texture.NewCI8(image.Rect(0, 0, w, h), s.Palette().colors)
+	pix := atlas.Pix()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pix[y*w+x] = s.parsed.PaletteIndex(x, y)
+		}
+	}
+	atlas.Writeback()
+	return atlas.Image
+}
+
+// Palette returns the palette of a sheet built with -format ci4 or ci8,
+// shared by every tile drawn from the sheet, or nil for other sheets.
+func (s *Sheet) Palette() *Palette {
+	if s == nil || !s.parsed.Encoding.Palettized() {
+		return nil
+	}
+	if s.palette == nil {
+		colors, err := texture.NewColorPalette(len(s.parsed.Palette))
+		if err != nil {
+			return nil
+		}
+		for i, c := range s.parsed.Palette {
+			colors.Set(i, 0, c)
+		}
+		s.palette = newPalette(colors)
+	}
+	return s.palette
+}
+
 func (s *Sheet) tileImage(tileID uint16) image.Image {
 	if s == nil || tileID == 0 {
 		return nil
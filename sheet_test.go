@@ -0,0 +1,52 @@
+package gosprite64
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func TestSheetTileDecodesDirectEncodings(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	gray := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+	img.Set(9, 1, gray)
+
+	for _, tt := range []struct {
+		enc   format.SheetEncoding
+		model color.Model
+	}{
+		{format.SheetRGBA16, nil},
+		{format.SheetIA8, color.NRGBAModel},
+		{format.SheetIA4, nil},
+		{format.SheetI8, color.GrayModel},
+		{format.SheetI4, nil},
+	} {
+		raw, err := format.BuildSheetWithEncoding(img, 8, 8, format.TilePropsConfig{}, tt.enc)
+		if err != nil {
+			t.Fatalf("BuildSheetWithEncoding(%v): %v", tt.enc, err)
+		}
+		parsed, err := format.ParseSheet(raw)
+		if err != nil {
+			t.Fatalf("ParseSheet(%v): %v", tt.enc, err)
+		}
+		sheet := &Sheet{parsed: parsed}
+		tile := sheet.Tile(2)
+		if tile == nil {
+			t.Fatalf("%v Tile(2) = nil", tt.enc)
+		}
+		if tt.model != nil && tile.ColorModel() != tt.model {
+			t.Fatalf("%v tile color model = %v, want %v", tt.enc, tile.ColorModel(), tt.model)
+		}
+		origin := tile.Bounds().Min
+		if got := asRGBA16(tile.At(origin.X+1, origin.Y+1)); got != asRGBA16(gray) {
+			t.Fatalf("%v tile pixel = %v, want white", tt.enc, got)
+		}
+		// Intensity encodings are opaque, the others keep transparency.
+		_, _, _, a := tile.At(origin.X, origin.Y).RGBA()
+		if opaque := tt.enc == format.SheetI8 || tt.enc == format.SheetI4; (a != 0) != opaque {
+			t.Fatalf("%v empty pixel alpha = %d", tt.enc, a)
+		}
+	}
+}
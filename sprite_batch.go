@@ -0,0 +1,69 @@
+package gosprite64
+
+import "github.com/drpaneas/gosprite64/internal/sprite"
+
+// SpriteBatch defers sprite draws until Flush. Flush draws them sorted by
+// layer, lowest first, and within a layer groups the draws of the same
+// frame and blend state, so a run of identical frames loads its texture
+// once instead of once per sprite. Groups keep the order in which they
+// were first drawn and draws keep their order within a group, but draws
+// of different groups on one layer no longer overlap in call order: put
+// sprites that must overlap in order on different layers.
+//
+// The zero value is ready to use. Queue draws during Draw and call Flush
+// before it returns.
+type SpriteBatch struct {
+	draws   []sprite.Draw
+	uploads int
+}
+
+// Draw queues a sprite draw on layer, taking the same arguments as
+// DrawSpriteWithOptions.
+func (b *SpriteBatch) Draw(layer int, sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions) {
+	if b == nil {
+		return
+	}
+	d, ok := spriteDraw(sheet, frame, x, y, opts)
+	if !ok {
+		return
+	}
+	d.Layer = layer
+	b.draws = append(b.draws, d)
+}
+
+// DrawWorld queues a sprite draw on layer, taking the same arguments as
+// DrawWorldSpriteWithOptions.
+func (b *SpriteBatch) DrawWorld(layer int, sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions) {
+	x, y, opts := worldSpriteOptions(worldX, worldY, cam, opts)
+	b.Draw(layer, sheet, frame, x, y, opts)
+}
+
+// Len returns the number of queued draws.
+func (b *SpriteBatch) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.draws)
+}
+
+// Flush sorts and draws the queued sprites and empties the batch.
+func (b *SpriteBatch) Flush() {
+	if b == nil {
+		return
+	}
+	sprite.Sort(b.draws)
+	b.uploads = 0
+	if video := currentVideo(); video != nil && video.Framebuffer != nil {
+		b.uploads = sprite.Render(video.Framebuffer, b.draws)
+	}
+	clear(b.draws)
+	b.draws = b.draws[:0]
+}
+
+// Stats reports the texture loads of the last Flush in UploadCount.
+func (b *SpriteBatch) Stats() RuntimeStats {
+	if b == nil {
+		return RuntimeStats{}
+	}
+	return RuntimeStats{UploadCount: b.uploads}
+}
@@ -0,0 +1,66 @@
+package gosprite64
+
+import (
+	"image"
+	"testing"
+
+	"github.com/clktmr/n64/rcp/texture"
+	"github.com/drpaneas/gosprite64/internal/rendergeom"
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func loadTestSpriteSheet(t *testing.T, frames int) *SpriteSheet {
+	t.Helper()
+	raw, err := format.BuildSheet(image.NewNRGBA(image.Rect(0, 0, 8*frames, 8)), 8, 8)
+	if err != nil {
+		t.Fatalf("BuildSheet: %v", err)
+	}
+	parsed, err := format.ParseSheet(raw)
+	if err != nil {
+		t.Fatalf("ParseSheet: %v", err)
+	}
+	return &SpriteSheet{sheet: &Sheet{parsed: parsed}}
+}
+
+func TestSpriteBatchCountsUploadsPerRun(t *testing.T) {
+	saved := activeRuntime
+	defer activateRuntime(saved)
+	activateRuntime(&runtimeState{video: &videoState{
+		Framebuffer: texture.NewRGBA16(rendergeom.FramebufferBounds()),
+	}})
+
+	sheet := loadTestSpriteSheet(t, 2)
+	var batch SpriteBatch
+	// 200 bullets alternate between two frames; sorting groups them into
+	// one run per frame.
+	for i := 0; i < 200; i++ {
+		batch.Draw(1, sheet, i%2, float32(i), 10, DrawSpriteOptions{})
+	}
+	batch.DrawWorld(0, sheet, 0, 50, 50, &Camera{X: 10, Y: 10}, DrawSpriteOptions{Blend: BlendMasked})
+	batch.Draw(0, sheet, 5, 0, 0, DrawSpriteOptions{})
+	batch.Draw(0, nil, 0, 0, 0, DrawSpriteOptions{})
+	if batch.Len() != 201 {
+		t.Fatalf("Len() = %d, want 201", batch.Len())
+	}
+
+	// Layer 0 draws frame 0 masked, then layer 1 draws frame 0 and frame 1:
+	// the blend change keeps the loaded frame.
+	batch.Flush()
+	if got := batch.Stats().UploadCount; got != 2 {
+		t.Fatalf("UploadCount = %d, want 2", got)
+	}
+	if batch.Len() != 0 {
+		t.Fatalf("Len() = %d after Flush, want 0", batch.Len())
+	}
+	batch.Flush()
+	if got := batch.Stats().UploadCount; got != 0 {
+		t.Fatalf("UploadCount = %d after an empty Flush, want 0", got)
+	}
+
+	var nilBatch *SpriteBatch
+	nilBatch.Draw(0, sheet, 0, 0, 0, DrawSpriteOptions{})
+	nilBatch.Flush()
+	if nilBatch.Len() != 0 || nilBatch.Stats() != (RuntimeStats{}) {
+		t.Fatal("nil batch is not a no-op")
+	}
+}
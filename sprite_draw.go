@@ -1,6 +1,8 @@
 package gosprite64
 
 import (
+	"image/color"
+
 	"github.com/drpaneas/gosprite64/internal/sprite"
 )
 
@@ -10,6 +12,14 @@ const (
 	BlendNone   BlendMode = iota
 	BlendMasked
 	BlendAlpha
+	// BlendAdd adds the sprite's color, scaled by its alpha and Alpha, to
+	// the pixels under it.
+	BlendAdd
+	// BlendMultiply multiplies the pixels under the sprite by its alpha
+	// times Alpha. The RDP blender multiplies by one factor per pixel, not
+	// by a color, so the sprite's RGB is ignored: author the factor in the
+	// frame's alpha.
+	BlendMultiply
 )
 
 type DrawSpriteOptions struct {
@@ -22,6 +32,16 @@ type DrawSpriteOptions struct {
 	OriginY  float32
 	Blend    BlendMode
 	Alpha    float32
+	// Tint multiplies the frame's color by its RGB, keeping the frame's
+	// alpha. The zero value draws the frame untinted; use A: 255 for black.
+	Tint color.RGBA
+	// Flash mixes the frame's color toward its RGB by its alpha, keeping
+	// the frame's alpha: A: 255 draws a solid silhouette for hit feedback.
+	// The zero value is off. A flash replaces Tint.
+	Flash color.RGBA
+	// Palette draws a palettized sheet's frame with these colors instead
+	// of the sheet's own. It is ignored for other sheets.
+	Palette *Palette
 }
 
 func (o DrawSpriteOptions) effectiveScaleX() float32 {
@@ -50,7 +70,9 @@ func (o DrawSpriteOptions) isDefault() bool {
 		o.effectiveScaleX() == 1 && o.effectiveScaleY() == 1 &&
 		o.Rotation == 0 &&
 		o.OriginX == 0 && o.OriginY == 0 &&
-		o.Blend == BlendNone
+		o.Blend == BlendNone &&
+		o.Tint == (color.RGBA{}) && o.Flash == (color.RGBA{}) &&
+		o.Palette == nil
 }
 
 func DrawSprite(sheet *SpriteSheet, frame int, x, y float32) {
@@ -72,9 +94,26 @@ func DrawSpriteWithOptions(sheet *SpriteSheet, frame int, x, y float32, opts Dra
 		DrawSprite(sheet, frame, x, y)
 		return
 	}
+	d, ok := spriteDraw(sheet, frame, x, y, opts)
+	if !ok {
+		return
+	}
+
+	video := currentVideo()
+	if video == nil || video.Framebuffer == nil {
+		return
+	}
+	sprite.Render(video.Framebuffer, []sprite.Draw{d})
+}
+
+// spriteDraw resolves a frame and its options into a sprite draw.
+func spriteDraw(sheet *SpriteSheet, frame int, x, y float32, opts DrawSpriteOptions) (sprite.Draw, bool) {
+	if sheet == nil || frame < 0 || frame >= sheet.FrameCount() {
+		return sprite.Draw{}, false
+	}
 	img := sheet.sheet.tileImage(uint16(frame + 1))
 	if img == nil {
-		return
+		return sprite.Draw{}, false
 	}
 	sx := opts.effectiveScaleX()
 	sy := opts.effectiveScaleY()
@@ -87,14 +126,15 @@ func DrawSpriteWithOptions(sheet *SpriteSheet, frame int, x, y float32, opts Dra
 		ox = x - opts.OriginX*sx
 		oy = y - opts.OriginY*sy
 	}
-
-	video := currentVideo()
-	if video == nil || video.Framebuffer == nil {
-		return
-	}
-	sprite.RenderSprite(video.Framebuffer, img, int(ox), int(oy),
-		opts.FlipH, opts.FlipV, sx, sy, uint8(opts.Blend), opts.effectiveAlpha(),
-		opts.Rotation, opts.OriginX, opts.OriginY)
+	return sprite.Draw{
+		Src: img, X: int(ox), Y: int(oy),
+		FlipH: opts.FlipH, FlipV: opts.FlipV,
+		ScaleX: sx, ScaleY: sy,
+		Blend: uint8(opts.Blend), Alpha: opts.effectiveAlpha(),
+		Rotation: opts.Rotation, OriginX: opts.OriginX, OriginY: opts.OriginY,
+		Tint: opts.Tint, Flash: opts.Flash,
+		Palette: opts.Palette.tlut(),
+	}, true
 }
 
 func DrawWorldSprite(sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera) {
@@ -102,13 +142,37 @@ func DrawWorldSprite(sheet *SpriteSheet, frame int, worldX, worldY float32, cam
 		DrawSprite(sheet, frame, worldX, worldY)
 		return
 	}
+	if cam.transformed() {
+		DrawWorldSpriteWithOptions(sheet, frame, worldX, worldY, cam, DrawSpriteOptions{})
+		return
+	}
 	DrawSprite(sheet, frame, worldX-float32(cam.X), worldY-float32(cam.Y))
 }
 
 func DrawWorldSpriteWithOptions(sheet *SpriteSheet, frame int, worldX, worldY float32, cam *Camera, opts DrawSpriteOptions) {
+	x, y, opts := worldSpriteOptions(worldX, worldY, cam, opts)
+	DrawSpriteWithOptions(sheet, frame, x, y, opts)
+}
+
+// worldSpriteOptions returns the screen position and options that draw a
+// sprite at a world position as cam sees it.
+func worldSpriteOptions(worldX, worldY float32, cam *Camera, opts DrawSpriteOptions) (float32, float32, DrawSpriteOptions) {
 	if cam == nil {
-		DrawSpriteWithOptions(sheet, frame, worldX, worldY, opts)
-		return
+		return worldX, worldY, opts
 	}
-	DrawSpriteWithOptions(sheet, frame, worldX-float32(cam.X), worldY-float32(cam.Y), opts)
+	if cam.transformed() {
+		x, y := cam.WorldToScreen(worldX, worldY)
+		return x, y, cameraSpriteOptions(cam, opts)
+	}
+	return worldX - float32(cam.X), worldY - float32(cam.Y), opts
+}
+
+// cameraSpriteOptions folds the camera's zoom and rotation into opts, so a
+// sprite lands where WorldToScreen puts its anchor point.
+func cameraSpriteOptions(cam *Camera, opts DrawSpriteOptions) DrawSpriteOptions {
+	z := cam.EffectiveZoom()
+	opts.ScaleX = opts.effectiveScaleX() * z
+	opts.ScaleY = opts.effectiveScaleY() * z
+	opts.Rotation -= cam.Rotation
+	return opts
 }
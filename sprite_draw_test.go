@@ -1,6 +1,9 @@
 package gosprite64
 
-import "testing"
+import (
+	"image/color"
+	"testing"
+)
 
 func TestDrawSpriteOptionsDefaults(t *testing.T) {
 	var opts DrawSpriteOptions
@@ -61,6 +64,18 @@ func TestOptionsNonDefaultWithBlend(t *testing.T) {
 	}
 }
 
+func TestOptionsNonDefaultWithTintAndFlash(t *testing.T) {
+	if (DrawSpriteOptions{Tint: color.RGBA{R: 255, A: 255}}).isDefault() {
+		t.Fatal("Tint should make options non-default")
+	}
+	if (DrawSpriteOptions{Flash: color.RGBA{R: 255, G: 255, B: 255, A: 255}}).isDefault() {
+		t.Fatal("Flash should make options non-default")
+	}
+	if (DrawSpriteOptions{Blend: BlendAdd}).isDefault() || (DrawSpriteOptions{Blend: BlendMultiply}).isDefault() {
+		t.Fatal("BlendAdd and BlendMultiply should make options non-default")
+	}
+}
+
 func TestOptionsNonDefaultWithRotation(t *testing.T) {
 	opts := DrawSpriteOptions{Rotation: 0.5}
 	if opts.isDefault() {
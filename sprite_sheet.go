@@ -41,3 +41,12 @@ func (s *SpriteSheet) FrameHeight() int {
 	}
 	return int(s.sheet.parsed.TileHeight)
 }
+
+// Palette returns the palette of a sheet built with -format ci4 or ci8,
+// shared by every frame drawn from the sheet, or nil for other sheets.
+func (s *SpriteSheet) Palette() *Palette {
+	if s == nil {
+		return nil
+	}
+	return s.sheet.Palette()
+}
@@ -1,5 +1,7 @@
 package gosprite64
 
+import "image/color"
+
 // GameState represents a single game screen or mode (title, menu, gameplay, pause, etc.).
 // Implement this interface for each distinct screen in your game.
 type GameState interface {
@@ -9,11 +11,40 @@ type GameState interface {
 	Exit()
 }
 
+// StatePolicy controls how a state shares the frame with the states below it.
+// The zero value keeps the classic behavior: only the top state runs.
+type StatePolicy struct {
+	// DrawBelow draws the states underneath before this one.
+	DrawBelow bool
+	// UpdateBelow keeps updating the states underneath (HUDs, cutscenes).
+	UpdateBelow bool
+	// Dim is blended over the states below before this one draws.
+	// Only used with DrawBelow; a zero alpha disables dimming.
+	Dim color.RGBA
+}
+
+// OverlayState is a GameState that declares a StatePolicy.
+// States that do not implement it use the zero policy.
+type OverlayState interface {
+	GameState
+	Policy() StatePolicy
+}
+
 // StateMachine manages a stack of GameStates. The top state receives
 // Update and Draw calls. Push overlays a new state (e.g. pause menu),
 // Pop removes it, Switch replaces the top state entirely.
+// States implementing OverlayState can let the states below keep
+// drawing and updating.
 type StateMachine struct {
 	stack []GameState
+	walk  []GameState
+}
+
+func statePolicy(state GameState) StatePolicy {
+	if ps, ok := state.(OverlayState); ok {
+		return ps.Policy()
+	}
+	return StatePolicy{}
 }
 
 // NewStateMachine creates a state machine with the given initial state.
@@ -34,20 +65,52 @@ func (sm *StateMachine) Init() {
 	}
 }
 
-// Update delegates to the top state's Update.
+// Update delegates to the top state's Update. When the top state's policy
+// has UpdateBelow, the states underneath are updated too, bottom to top.
+// States removed by an earlier Update in the same frame are skipped.
 func (sm *StateMachine) Update() {
 	if len(sm.stack) == 0 {
 		return
 	}
-	sm.stack[len(sm.stack)-1].Update()
+	base := len(sm.stack) - 1
+	for base > 0 && statePolicy(sm.stack[base]).UpdateBelow {
+		base--
+	}
+	if base == len(sm.stack)-1 {
+		sm.stack[base].Update()
+		return
+	}
+
+	sm.walk = append(sm.walk[:0], sm.stack[base:]...)
+	for _, state := range sm.walk {
+		if !sm.Contains(state) {
+			continue
+		}
+		state.Update()
+	}
+	clear(sm.walk)
 }
 
-// Draw delegates to the top state's Draw.
+// Draw delegates to the top state's Draw. When the top state's policy has
+// DrawBelow, the states underneath are drawn first, bottom to top, with
+// each overlay's Dim color blended over everything drawn before it.
 func (sm *StateMachine) Draw() {
 	if len(sm.stack) == 0 {
 		return
 	}
-	sm.stack[len(sm.stack)-1].Draw()
+	base := len(sm.stack) - 1
+	for base > 0 && statePolicy(sm.stack[base]).DrawBelow {
+		base--
+	}
+	for i := base; i < len(sm.stack); i++ {
+		state := sm.stack[i]
+		if i > base {
+			if dim := statePolicy(state).Dim; dim.A > 0 {
+				drawTransitionOverlay(dim)
+			}
+		}
+		state.Draw()
+	}
 }
 
 // Switch replaces the top state. Calls Exit on the old top and Enter on the new one.
@@ -86,6 +149,72 @@ func (sm *StateMachine) Pop() {
 	top.Exit()
 }
 
+// PopTo removes states from the top until state is the current one, calling
+// Exit on each removed state in top-down order. It returns false and leaves
+// the stack untouched if state is not on the stack.
+func (sm *StateMachine) PopTo(state GameState) bool {
+	idx := sm.indexOf(state)
+	if idx < 0 {
+		return false
+	}
+	for len(sm.stack) > idx+1 {
+		top := sm.stack[len(sm.stack)-1]
+		sm.stack[len(sm.stack)-1] = nil
+		sm.stack = sm.stack[:len(sm.stack)-1]
+		top.Exit()
+	}
+	return true
+}
+
+// ReplaceAll exits every state on the stack (top first) and leaves state as
+// the only one. Use it to return to the title screen from a menu hierarchy.
+func (sm *StateMachine) ReplaceAll(state GameState) {
+	if state == nil {
+		return
+	}
+	for len(sm.stack) > 0 {
+		top := sm.stack[len(sm.stack)-1]
+		sm.stack[len(sm.stack)-1] = nil
+		sm.stack = sm.stack[:len(sm.stack)-1]
+		top.Exit()
+	}
+	sm.stack = append(sm.stack, state)
+	state.Enter()
+}
+
+// FindState returns the topmost state on the stack with type T.
+//
+//	pause, ok := gosprite64.FindState[*PauseState](sm)
+func FindState[T GameState](sm *StateMachine) (T, bool) {
+	var zero T
+	if sm == nil {
+		return zero, false
+	}
+	for i := len(sm.stack) - 1; i >= 0; i-- {
+		if state, ok := sm.stack[i].(T); ok {
+			return state, true
+		}
+	}
+	return zero, false
+}
+
+// Contains reports whether state is anywhere on the stack.
+func (sm *StateMachine) Contains(state GameState) bool {
+	return sm.indexOf(state) >= 0
+}
+
+func (sm *StateMachine) indexOf(state GameState) int {
+	if state == nil {
+		return -1
+	}
+	for i := len(sm.stack) - 1; i >= 0; i-- {
+		if sm.stack[i] == state {
+			return i
+		}
+	}
+	return -1
+}
+
 // Current returns the top state, or nil if the stack is empty.
 func (sm *StateMachine) Current() GameState {
 	if len(sm.stack) == 0 {
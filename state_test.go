@@ -235,3 +235,202 @@ func TestStateMachineDoubleInit(t *testing.T) {
 		t.Fatalf("double Init calls Enter twice: %v", log)
 	}
 }
+
+type overlayTestState struct {
+	testState
+	policy StatePolicy
+}
+
+func (s *overlayTestState) Policy() StatePolicy {
+	return s.policy
+}
+
+func TestStateMachineDrawBelowWalksBottomUp(t *testing.T) {
+	log := make([]string, 0)
+	game := &testState{name: "game", log: &log}
+	hud := &overlayTestState{testState: testState{name: "hud", log: &log}, policy: StatePolicy{DrawBelow: true}}
+	pause := &overlayTestState{testState: testState{name: "pause", log: &log}, policy: StatePolicy{DrawBelow: true}}
+
+	sm := NewStateMachine(game)
+	sm.Init()
+	sm.Push(hud)
+	sm.Push(pause)
+	log = log[:0]
+
+	sm.Draw()
+	expected := []string{"game:draw", "hud:draw", "pause:draw"}
+	if len(log) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, log)
+	}
+	for i, e := range expected {
+		if log[i] != e {
+			t.Fatalf("log[%d] = %q, want %q", i, log[i], e)
+		}
+	}
+
+	log = log[:0]
+	sm.Update()
+	if len(log) != 1 || log[0] != "pause:update" {
+		t.Fatalf("DrawBelow alone should only update the top, got %v", log)
+	}
+}
+
+func TestStateMachineDrawBelowStopsAtOpaqueState(t *testing.T) {
+	log := make([]string, 0)
+	title := &testState{name: "title", log: &log}
+	game := &testState{name: "game", log: &log}
+	pause := &overlayTestState{testState: testState{name: "pause", log: &log}, policy: StatePolicy{DrawBelow: true}}
+
+	sm := NewStateMachine(title)
+	sm.Init()
+	sm.Push(game)
+	sm.Push(pause)
+	log = log[:0]
+
+	sm.Draw()
+	if len(log) != 2 || log[0] != "game:draw" || log[1] != "pause:draw" {
+		t.Fatalf("draw should stop at the first state without DrawBelow, got %v", log)
+	}
+}
+
+func TestStateMachineUpdateBelow(t *testing.T) {
+	log := make([]string, 0)
+	game := &testState{name: "game", log: &log}
+	dialog := &overlayTestState{testState: testState{name: "dialog", log: &log}, policy: StatePolicy{UpdateBelow: true}}
+
+	sm := NewStateMachine(game)
+	sm.Init()
+	sm.Push(dialog)
+	log = log[:0]
+
+	sm.Update()
+	if len(log) != 2 || log[0] != "game:update" || log[1] != "dialog:update" {
+		t.Fatalf("UpdateBelow should update bottom to top, got %v", log)
+	}
+
+	log = log[:0]
+	sm.Draw()
+	if len(log) != 1 || log[0] != "dialog:draw" {
+		t.Fatalf("UpdateBelow alone should only draw the top, got %v", log)
+	}
+}
+
+type poppingState struct {
+	testState
+	sm *StateMachine
+}
+
+func (s *poppingState) Update() {
+	s.testState.Update()
+	s.sm.Pop()
+}
+
+func TestStateMachineUpdateBelowSkipsStatesRemovedMidFrame(t *testing.T) {
+	log := make([]string, 0)
+	sm := NewStateMachine(nil)
+	game := &poppingState{testState: testState{name: "game", log: &log}, sm: sm}
+	dialog := &overlayTestState{testState: testState{name: "dialog", log: &log}, policy: StatePolicy{UpdateBelow: true}}
+
+	sm.Switch(game)
+	sm.Push(dialog)
+	log = log[:0]
+
+	sm.Update()
+	if len(log) != 2 || log[0] != "game:update" || log[1] != "dialog:exit" {
+		t.Fatalf("popped state should not be updated, got %v", log)
+	}
+}
+
+func TestStateMachinePopTo(t *testing.T) {
+	log := make([]string, 0)
+	game := &testState{name: "game", log: &log}
+	menu := &testState{name: "menu", log: &log}
+	options := &testState{name: "options", log: &log}
+	audio := &testState{name: "audio", log: &log}
+
+	sm := NewStateMachine(game)
+	sm.Init()
+	sm.Push(menu)
+	sm.Push(options)
+	sm.Push(audio)
+	log = log[:0]
+
+	if !sm.PopTo(menu) {
+		t.Fatal("PopTo should find menu on the stack")
+	}
+	if len(log) != 2 || log[0] != "audio:exit" || log[1] != "options:exit" {
+		t.Fatalf("PopTo should exit top-down, got %v", log)
+	}
+	if sm.Current() != menu || sm.Depth() != 2 {
+		t.Fatalf("expected menu on top at depth 2, got depth %d", sm.Depth())
+	}
+
+	log = log[:0]
+	if sm.PopTo(audio) {
+		t.Fatal("PopTo should report a state that is not on the stack")
+	}
+	if len(log) != 0 || sm.Depth() != 2 {
+		t.Fatalf("PopTo on a missing state should be a no-op, got %v", log)
+	}
+}
+
+func TestStateMachineReplaceAll(t *testing.T) {
+	log := make([]string, 0)
+	game := &testState{name: "game", log: &log}
+	pause := &testState{name: "pause", log: &log}
+	title := &testState{name: "title", log: &log}
+
+	sm := NewStateMachine(game)
+	sm.Init()
+	sm.Push(pause)
+	log = log[:0]
+
+	sm.ReplaceAll(title)
+	expected := []string{"pause:exit", "game:exit", "title:enter"}
+	if len(log) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, log)
+	}
+	for i, e := range expected {
+		if log[i] != e {
+			t.Fatalf("log[%d] = %q, want %q", i, log[i], e)
+		}
+	}
+	if sm.Depth() != 1 || sm.Current() != title {
+		t.Fatalf("expected only title on the stack, depth %d", sm.Depth())
+	}
+
+	log = log[:0]
+	sm.ReplaceAll(nil)
+	if len(log) != 0 || sm.Depth() != 1 {
+		t.Fatalf("ReplaceAll(nil) should be a no-op, got %v", log)
+	}
+}
+
+func TestFindState(t *testing.T) {
+	log := make([]string, 0)
+	game := &testState{name: "game", log: &log}
+	pause := &overlayTestState{testState: testState{name: "pause", log: &log}}
+
+	sm := NewStateMachine(game)
+	sm.Init()
+
+	if _, ok := FindState[*overlayTestState](sm); ok {
+		t.Fatal("FindState should not find a state type that is not on the stack")
+	}
+
+	sm.Push(pause)
+	found, ok := FindState[*overlayTestState](sm)
+	if !ok || found != pause {
+		t.Fatal("FindState should return the pushed overlay")
+	}
+	base, ok := FindState[*testState](sm)
+	if !ok || base != game {
+		t.Fatal("FindState should search below the top")
+	}
+	if !sm.Contains(game) || sm.Contains(&testState{}) {
+		t.Fatal("Contains should match states by identity")
+	}
+	if _, ok := FindState[*testState](nil); ok {
+		t.Fatal("FindState on a nil machine should report false")
+	}
+}
@@ -0,0 +1,80 @@
+package gosprite64
+
+import (
+	"slices"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// TileAnim is an in-place tile animation from a sheet's tile properties
+// file: every map cell holding the animated tile shows Frames in turn at
+// FPS frames per second.
+type TileAnim struct {
+	FPS    int
+	Frames []uint16
+}
+
+// FrameAt returns the tile shown tick updates after the animation started.
+// Ticks run at 60 per second, like AnimationPlayer.Advance.
+func (a TileAnim) FrameAt(tick int) uint16 {
+	if len(a.Frames) == 0 {
+		return 0
+	}
+	fps := a.FPS
+	if fps <= 0 {
+		fps = defaultTickRate
+	}
+	n := tick * fps
+	i := n / defaultTickRate
+	if n < 0 && n%defaultTickRate != 0 {
+		i--
+	}
+	i %= len(a.Frames)
+	if i < 0 {
+		i += len(a.Frames)
+	}
+	return a.Frames[i]
+}
+
+// TileAnim returns the animation defined for tileID, if any.
+func (s *Sheet) TileAnim(tileID uint16) (TileAnim, bool) {
+	if s == nil {
+		return TileAnim{}, false
+	}
+	anims := s.parsed.TileAnims
+	i, ok := slices.BinarySearchFunc(anims, tileID, func(a format.ParsedTileAnim, id uint16) int {
+		return int(a.Tile) - int(id)
+	})
+	if !ok {
+		return TileAnim{}, false
+	}
+	return TileAnim{FPS: int(anims[i].FPS), Frames: anims[i].Frames}, true
+}
+
+// Update advances the scene's animated tiles by one tick. Call it once per
+// frame from the game's Update; every cell holding the same animated tile
+// stays in step.
+func (s *Scene) Update() {
+	if s == nil {
+		return
+	}
+	s.SetTileTick(s.tileTick + 1)
+}
+
+// TileTick returns the tick animated tiles are currently showing.
+func (s *Scene) TileTick() int {
+	if s == nil {
+		return 0
+	}
+	return s.tileTick
+}
+
+// SetTileTick jumps animated tiles to tick, for example to restart them
+// or to keep two scenes in step.
+func (s *Scene) SetTileTick(tick int) {
+	if s == nil {
+		return
+	}
+	s.tileTick = tick
+	s.preparer.animate(tick)
+}
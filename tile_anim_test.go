@@ -0,0 +1,66 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func TestTileAnimFrameAt(t *testing.T) {
+	anim := TileAnim{FPS: 8, Frames: []uint16{12, 13, 14, 15}}
+	for _, tc := range []struct {
+		tick int
+		want uint16
+	}{{0, 12}, {7, 12}, {8, 13}, {23, 15}, {30, 12}, {-1, 15}} {
+		if got := anim.FrameAt(tc.tick); got != tc.want {
+			t.Fatalf("FrameAt(%d) = %d, want %d", tc.tick, got, tc.want)
+		}
+	}
+	if got := (TileAnim{}).FrameAt(5); got != 0 {
+		t.Fatalf("empty FrameAt = %d, want 0", got)
+	}
+}
+
+func TestSceneAnimatesTilesWithoutSplittingRuns(t *testing.T) {
+	scene := loadTestSceneWithProps(t, format.MapConfig{
+		Width: 4, Height: 1, LayerCount: 1, CellBits: 8, ChunkWidth: 4, ChunkHeight: 1,
+		Layers: []format.MapLayerConfig{{Cells: []uint16{1, 1, 1, 2}}},
+	}, format.TilePropsConfig{
+		Anims: []format.TileAnimConfig{{Tile: 1, FPS: 30, Frames: []uint16{1, 3}}},
+	})
+	sheet := scene.Sheet(0)
+	if anim, ok := sheet.TileAnim(1); !ok || anim.FPS != 30 || len(anim.Frames) != 2 {
+		t.Fatalf("TileAnim(1) = (%+v, %v)", anim, ok)
+	}
+	if _, ok := sheet.TileAnim(2); ok {
+		t.Fatal("tile 2 does not animate")
+	}
+
+	layer := &scene.renderScene.Layers[0]
+	if len(layer.Runs) != 2 || layer.Runs[0].Count != 3 {
+		t.Fatalf("runs = %+v, want the three animated cells as one run", layer.Runs)
+	}
+	if layer.Runs[0].Tile.Source != sheet.tileImage(1) {
+		t.Fatal("tick 0 should show the first frame")
+	}
+
+	scene.Update()
+	scene.Update()
+	if scene.TileTick() != 2 {
+		t.Fatalf("TileTick() = %d, want 2", scene.TileTick())
+	}
+	if layer.Runs[0].Tile.Source != sheet.tileImage(3) || layer.Draws[0].Tile.Source != sheet.tileImage(3) {
+		t.Fatal("tick 2 at 30 fps should show the second frame")
+	}
+	if layer.Runs[0].Count != 3 || layer.Runs[0].Tile.TileID != 1 {
+		t.Fatalf("run = %+v, want one run still keyed by tile 1", layer.Runs[0])
+	}
+	if layer.Runs[1].Tile.Source != sheet.tileImage(2) {
+		t.Fatal("static tile should not change")
+	}
+
+	scene.SetTileTick(0)
+	if layer.Runs[0].Tile.Source != sheet.tileImage(1) {
+		t.Fatal("SetTileTick(0) should restart the animation")
+	}
+}
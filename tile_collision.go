@@ -0,0 +1,453 @@
+package gosprite64
+
+import (
+	"math"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// TileShape is the collision shape of a tile ID, used by MoveAndSlide and
+// RaycastTiles. Tile 0 is always TileEmpty. Other tiles take their shape
+// from Map.SetTileShape, then from the "shape" in the sheet's tile
+// properties, and are TileSolid otherwise.
+type TileShape uint8
+
+const (
+	TileEmpty TileShape = iota
+	TileSolid
+
+	// TileOneWay only stops bodies landing on it from above.
+	TileOneWay
+
+	// Slopes are floors named by the direction they rise, walking left to
+	// right. A 45° slope rises one tile over one tile. A 22.5° slope rises
+	// half a tile per tile and is built from two tiles, placed in walking
+	// order: Up22Low then Up22High, or Down22High then Down22Low.
+	TileSlopeUp45
+	TileSlopeDown45
+	TileSlopeUp22Low
+	TileSlopeUp22High
+	TileSlopeDown22High
+	TileSlopeDown22Low
+
+	// TileLadder and TileHazard never block movement; MoveAndSlide reports
+	// when the body overlaps them.
+	TileLadder
+	TileHazard
+)
+
+// TileContacts describes what a body touched during MoveAndSlide.
+type TileContacts struct {
+	Grounded  bool
+	Ceiling   bool
+	WallLeft  bool
+	WallRight bool
+
+	// OnSlope and OnOneWay describe the floor when Grounded is set.
+	OnSlope  bool
+	OnOneWay bool
+
+	// Ladder and Hazard report overlap at the resolved position.
+	Ladder bool
+	Hazard bool
+}
+
+// Wall reports whether the body was stopped by a wall on either side.
+func (c TileContacts) Wall() bool {
+	return c.WallLeft || c.WallRight
+}
+
+// TileHit describes where a ray from RaycastTiles met a tile.
+type TileHit struct {
+	Point    math2d.Vec2
+	Normal   math2d.Vec2
+	Distance float32
+	CellX    int
+	CellY    int
+	Shape    TileShape
+}
+
+// collisionEpsilon keeps edges that touch a tile boundary from counting as
+// overlap with the tile on the other side.
+const collisionEpsilon = 0.001
+
+// tileShapeUnset marks tiles SetTileShape has not been called for.
+const tileShapeUnset TileShape = 0xFF
+
+// SetTileShape sets the collision shape of every cell holding tile,
+// overriding the sheet's tile properties.
+func (m *Map) SetTileShape(tile uint16, shape TileShape) {
+	if m == nil || tile == 0 {
+		return
+	}
+	for len(m.shapes) <= int(tile) {
+		m.shapes = append(m.shapes, tileShapeUnset)
+	}
+	m.shapes[tile] = shape
+}
+
+// ShapeOf returns the collision shape set for tile with SetTileShape, or
+// TileSolid for any other non-zero tile.
+func (m *Map) ShapeOf(tile uint16) TileShape {
+	if shape := m.shapeOverride(tile); shape != tileShapeUnset {
+		return shape
+	}
+	return TileSolid
+}
+
+// ShapeAt returns the collision shape of the cell at (x, y) on layer.
+// Cells outside the map are TileEmpty, and a cell flipped horizontally
+// mirrors its slope.
+func (m *Map) ShapeAt(layer, x, y int) TileShape {
+	tile, flip, ok := m.TileAt(layer, x, y)
+	if !ok {
+		return TileEmpty
+	}
+	shape := m.shapeOverride(tile)
+	if shape == tileShapeUnset {
+		shape = TileSolid
+		if layer < len(m.layerSheets) {
+			if props := m.layerSheets[layer].TileProps(tile); props.HasShape {
+				shape = props.Shape
+			}
+		}
+	}
+	if flip&TileFlipH != 0 {
+		shape = mirroredShape(shape)
+	}
+	return shape
+}
+
+// mirroredShape returns the slope that shape becomes when its tile is
+// flipped horizontally.
+func mirroredShape(shape TileShape) TileShape {
+	switch shape {
+	case TileSlopeUp45:
+		return TileSlopeDown45
+	case TileSlopeDown45:
+		return TileSlopeUp45
+	case TileSlopeUp22Low:
+		return TileSlopeDown22Low
+	case TileSlopeDown22Low:
+		return TileSlopeUp22Low
+	case TileSlopeUp22High:
+		return TileSlopeDown22High
+	case TileSlopeDown22High:
+		return TileSlopeUp22High
+	}
+	return shape
+}
+
+func (m *Map) shapeOverride(tile uint16) TileShape {
+	switch {
+	case m == nil || tile == 0:
+		return TileEmpty
+	case int(tile) < len(m.shapes):
+		return m.shapes[tile]
+	}
+	return tileShapeUnset
+}
+
+// MoveAndSlide moves rect by velocity against the tiles of layer and returns
+// the resolved top-left position. Movement is resolved horizontally, then
+// vertically: walls and ceilings stop the body, floors and slopes catch it,
+// and a grounded body follows slopes down instead of bouncing off them.
+//
+// One-way platforms only catch a body whose bottom starts at or above the
+// platform, so moving the body one pixel down before calling MoveAndSlide
+// drops it through.
+func (m *Map) MoveAndSlide(rect math2d.Rect, velocity math2d.Vec2, layer int) (math2d.Vec2, TileContacts) {
+	var contacts TileContacts
+	if m == nil || layer < 0 || layer >= m.LayerCount() {
+		return math2d.Vec2{X: rect.X + velocity.X, Y: rect.Y + velocity.Y}, contacts
+	}
+
+	// A grounded body may step up or down by as much as a slope rises over
+	// the horizontal move.
+	step := float32(math.Abs(float64(velocity.X))) + 0.5
+	bottom := rect.Bottom()
+	_, floor, grounded := m.findFloor(rect, layer, bottom, bottom-0.5, bottom+0.5)
+	grounded = grounded && velocity.Y >= 0
+
+	rect.X = m.slideX(rect, velocity.X, layer, grounded && isSlope(floor), step, &contacts)
+
+	if velocity.Y < 0 {
+		rect.Y = m.slideUp(rect, velocity.Y, layer, &contacts)
+	} else {
+		reach := bottom + velocity.Y
+		if grounded {
+			reach += step
+		}
+		if y, shape, ok := m.findFloor(rect, layer, bottom, bottom-step, reach); ok {
+			rect.Y = y - rect.H
+			contacts.Grounded = true
+			contacts.OnSlope = isSlope(shape)
+			contacts.OnOneWay = shape == TileOneWay
+		} else {
+			rect.Y += velocity.Y
+		}
+	}
+
+	m.overlapFlags(rect, layer, &contacts)
+	return math2d.Vec2{X: rect.X, Y: rect.Y}, contacts
+}
+
+// slideX moves rect horizontally until a solid tile blocks it.
+// A body standing on a slope may step onto a solid tile up to step pixels
+// above its feet, which is how it leaves the top of the slope.
+func (m *Map) slideX(rect math2d.Rect, vx float32, layer int, onSlope bool, step float32, contacts *TileContacts) float32 {
+	if vx == 0 {
+		return rect.X
+	}
+	tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+	r0 := cellOf(rect.Y, th)
+	r1 := cellOf(rect.Bottom()-collisionEpsilon, th)
+	blocks := func(col int) bool {
+		for row := r0; row <= r1; row++ {
+			if m.ShapeAt(layer, col, row) != TileSolid {
+				continue
+			}
+			if onSlope && float32(row)*th >= rect.Bottom()-step {
+				continue
+			}
+			return true
+		}
+		return false
+	}
+
+	if vx > 0 {
+		edge := rect.Right()
+		last := cellOf(edge+vx-collisionEpsilon, tw)
+		for col := int(math.Ceil(float64(edge / tw))); col <= last; col++ {
+			if blocks(col) {
+				contacts.WallRight = true
+				return float32(col)*tw - rect.W
+			}
+		}
+	} else {
+		last := cellOf(rect.X+vx, tw)
+		for col := cellOf(rect.X, tw) - 1; col >= last; col-- {
+			if blocks(col) {
+				contacts.WallLeft = true
+				return float32(col+1) * tw
+			}
+		}
+	}
+	return rect.X + vx
+}
+
+// slideUp moves rect up until the underside of a solid or slope tile
+// blocks it.
+func (m *Map) slideUp(rect math2d.Rect, vy float32, layer int, contacts *TileContacts) float32 {
+	tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+	c0 := cellOf(rect.X, tw)
+	c1 := cellOf(rect.Right()-collisionEpsilon, tw)
+	last := cellOf(rect.Y+vy, th)
+	for row := cellOf(rect.Y, th) - 1; row >= last; row-- {
+		for col := c0; col <= c1; col++ {
+			shape := m.ShapeAt(layer, col, row)
+			if shape == TileSolid || isSlope(shape) {
+				contacts.Ceiling = true
+				return float32(row+1) * th
+			}
+		}
+	}
+	return rect.Y + vy
+}
+
+// findFloor returns the highest floor surface under rect between the y
+// values from and to. oldBottom decides whether one-way platforms count.
+func (m *Map) findFloor(rect math2d.Rect, layer int, oldBottom, from, to float32) (float32, TileShape, bool) {
+	tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+	c0 := cellOf(rect.X, tw)
+	c1 := cellOf(rect.Right()-collisionEpsilon, tw)
+	r0 := cellOf(from, th)
+	r1 := cellOf(to, th)
+
+	var best float32
+	var bestShape TileShape
+	found := false
+	for col := c0; col <= c1; col++ {
+		for row := r0; row <= r1; row++ {
+			shape := m.ShapeAt(layer, col, row)
+			top := float32(row) * th
+			var surface float32
+			switch {
+			case shape == TileSolid:
+				surface = top
+			case shape == TileOneWay:
+				if oldBottom > top+collisionEpsilon {
+					continue
+				}
+				surface = top
+			case isSlope(shape):
+				surface = slopeSurface(shape, rect, float32(col)*tw, top, tw, th)
+			default:
+				continue
+			}
+			if surface < from || surface > to {
+				continue
+			}
+			if !found || surface < best {
+				best, bestShape, found = surface, shape, true
+			}
+		}
+	}
+	return best, bestShape, found
+}
+
+// overlapFlags records ladder and hazard tiles under rect.
+func (m *Map) overlapFlags(rect math2d.Rect, layer int, contacts *TileContacts) {
+	tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+	for row := cellOf(rect.Y, th); row <= cellOf(rect.Bottom()-collisionEpsilon, th); row++ {
+		for col := cellOf(rect.X, tw); col <= cellOf(rect.Right()-collisionEpsilon, tw); col++ {
+			switch m.ShapeAt(layer, col, row) {
+			case TileLadder:
+				contacts.Ladder = true
+			case TileHazard:
+				contacts.Hazard = true
+			}
+		}
+	}
+}
+
+// RaycastTiles casts a ray from origin along dir against the solid, slope
+// and one-way tiles of layer and returns the first hit within maxDist
+// pixels. One-way platforms are only hit from above.
+func (m *Map) RaycastTiles(layer int, origin, dir math2d.Vec2, maxDist float32) (TileHit, bool) {
+	if m == nil || layer < 0 || layer >= m.LayerCount() || maxDist <= 0 {
+		return TileHit{}, false
+	}
+	d := dir.Normalize()
+	if d.X == 0 && d.Y == 0 {
+		return TileHit{}, false
+	}
+	tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+
+	// Nothing lies beyond the farthest map corner, so stop there even if
+	// maxDist is huge.
+	pw, ph := float32(m.PixelWidth()), float32(m.PixelHeight())
+	limit := max(origin.Distance(math2d.Vec2{}), origin.Distance(math2d.Vec2{X: pw}),
+		origin.Distance(math2d.Vec2{Y: ph}), origin.Distance(math2d.Vec2{X: pw, Y: ph}))
+	maxDist = min(maxDist, limit)
+
+	col, row := cellOf(origin.X, tw), cellOf(origin.Y, th)
+	stepX, tMaxX, tDeltaX := rayAxis(origin.X, d.X, tw, col)
+	stepY, tMaxY, tDeltaY := rayAxis(origin.Y, d.Y, th, row)
+
+	var t float32
+	var normal math2d.Vec2
+	for t <= maxDist {
+		exit := min(tMaxX, tMaxY)
+		if hit, ok := m.rayCell(layer, col, row, origin, d, t, exit, normal); ok {
+			if hit.Distance > maxDist {
+				return TileHit{}, false
+			}
+			return hit, true
+		}
+		if tMaxX < tMaxY {
+			t = tMaxX
+			tMaxX += tDeltaX
+			col += stepX
+			normal = math2d.Vec2{X: float32(-stepX)}
+		} else {
+			t = tMaxY
+			tMaxY += tDeltaY
+			row += stepY
+			normal = math2d.Vec2{Y: float32(-stepY)}
+		}
+	}
+	return TileHit{}, false
+}
+
+// rayAxis returns the DDA step direction, the distance to the first cell
+// boundary and the distance between boundaries along one axis.
+func rayAxis(origin, d, size float32, cell int) (int, float32, float32) {
+	inf := float32(math.Inf(1))
+	switch {
+	case d > 0:
+		return 1, (float32(cell+1)*size - origin) / d, size / d
+	case d < 0:
+		return -1, (float32(cell)*size - origin) / d, -size / d
+	}
+	return 0, inf, inf
+}
+
+// rayCell tests the part of the ray between enter and exit against one
+// cell. normal is the face the ray entered through, zero for the start cell.
+func (m *Map) rayCell(layer, col, row int, origin, d math2d.Vec2, enter, exit float32, normal math2d.Vec2) (TileHit, bool) {
+	shape := m.ShapeAt(layer, col, row)
+	hit := TileHit{CellX: col, CellY: row, Shape: shape, Distance: enter, Normal: normal}
+	switch {
+	case shape == TileSolid:
+	case shape == TileOneWay:
+		if normal.Y >= 0 {
+			return TileHit{}, false
+		}
+	case isSlope(shape):
+		tw, th := float32(m.TileWidth()), float32(m.TileHeight())
+		x0, bottom := float32(col)*tw, float32(row+1)*th
+		left, right := slopeHeights(shape, th)
+		rise := (right - left) / tw
+
+		// f(t) is how far below the surface the ray is at distance t.
+		a := origin.Y - bottom + left + rise*(origin.X-x0)
+		b := d.Y + rise*d.X
+		if a+b*enter >= 0 {
+			break
+		}
+		if b <= 0 {
+			return TileHit{}, false
+		}
+		t := -a / b
+		if t > exit {
+			return TileHit{}, false
+		}
+		hit.Distance = t
+		hit.Normal = math2d.Vec2{X: left - right, Y: -tw}.Normalize()
+	default:
+		return TileHit{}, false
+	}
+	hit.Point = origin.Add(d.Scale(hit.Distance))
+	return hit, true
+}
+
+// slopeHeights returns a slope's floor height above the tile bottom at the
+// tile's left and right edges.
+func slopeHeights(shape TileShape, th float32) (left, right float32) {
+	switch shape {
+	case TileSlopeUp45:
+		return 0, th
+	case TileSlopeDown45:
+		return th, 0
+	case TileSlopeUp22Low:
+		return 0, th / 2
+	case TileSlopeUp22High:
+		return th / 2, th
+	case TileSlopeDown22High:
+		return th, th / 2
+	case TileSlopeDown22Low:
+		return th / 2, 0
+	}
+	return 0, 0
+}
+
+// slopeSurface returns the highest point of a slope tile under the bottom
+// edge of rect. The body rests on its corner nearest the uphill side.
+func slopeSurface(shape TileShape, rect math2d.Rect, tileX, tileY, tw, th float32) float32 {
+	left, right := slopeHeights(shape, th)
+	x := math2d.Clamp(rect.Right(), tileX, tileX+tw)
+	if left > right {
+		x = math2d.Clamp(rect.X, tileX, tileX+tw)
+	}
+	return tileY + th - math2d.Lerp(left, right, (x-tileX)/tw)
+}
+
+func isSlope(shape TileShape) bool {
+	return shape >= TileSlopeUp45 && shape <= TileSlopeDown22Low
+}
+
+// cellOf returns the index of the cell of the given size containing v.
+func cellOf(v, size float32) int {
+	return int(math.Floor(float64(v / size)))
+}
@@ -0,0 +1,187 @@
+package gosprite64
+
+import (
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// collisionMap builds an 8x8-tile map from rows of shape characters.
+func collisionMap(rows ...string) *Map {
+	shapes := map[byte]TileShape{
+		'#': TileSolid, '-': TileOneWay, '/': TileSlopeUp45, '\\': TileSlopeDown45,
+		'a': TileSlopeUp22Low, 'b': TileSlopeUp22High, 'H': TileLadder, '^': TileHazard,
+	}
+	ids := map[byte]uint16{}
+	w, h := len(rows[0]), len(rows)
+	cells := make([]uint16, w*h)
+	m := newMap(format.ParsedMap{Width: uint16(w), Height: uint16(h)})
+	for y, row := range rows {
+		for x := 0; x < w; x++ {
+			shape, ok := shapes[row[x]]
+			if !ok {
+				continue
+			}
+			id, ok := ids[row[x]]
+			if !ok {
+				id = uint16(len(ids) + 1)
+				ids[row[x]] = id
+				m.SetTileShape(id, shape)
+			}
+			cells[y*w+x] = id
+		}
+	}
+	m.parsed.Layers = []format.ParsedMapLayer{{Cells: cells}}
+	return m
+}
+
+func TestMoveAndSlideLandsOnFloor(t *testing.T) {
+	m := collisionMap(
+		"....",
+		"....",
+		"####",
+	)
+	pos, c := m.MoveAndSlide(math2d.Rect{X: 8, Y: 0, W: 8, H: 8}, math2d.Vec2{Y: 12}, 0)
+	if pos.Y != 8 || !c.Grounded {
+		t.Fatalf("expected to land at Y=8 grounded, got Y=%f contacts=%+v", pos.Y, c)
+	}
+}
+
+func TestMoveAndSlideWallsAndCeiling(t *testing.T) {
+	m := collisionMap(
+		"###",
+		"..#",
+		"..#",
+	)
+	pos, c := m.MoveAndSlide(math2d.Rect{X: 4, Y: 12, W: 8, H: 8}, math2d.Vec2{X: 5, Y: -10}, 0)
+	if pos.X != 8 || !c.WallRight || !c.Wall() {
+		t.Fatalf("expected wall stop at X=8, got X=%f contacts=%+v", pos.X, c)
+	}
+	if pos.Y != 8 || !c.Ceiling {
+		t.Fatalf("expected ceiling stop at Y=8, got Y=%f contacts=%+v", pos.Y, c)
+	}
+}
+
+func TestMoveAndSlideOneWay(t *testing.T) {
+	m := collisionMap(
+		"..",
+		"--",
+		"..",
+	)
+	body := math2d.Rect{X: 0, Y: 0, W: 8, H: 8}
+	if pos, c := m.MoveAndSlide(body, math2d.Vec2{Y: 4}, 0); pos.Y != 0 || !c.OnOneWay {
+		t.Fatalf("expected to stand on the platform, got Y=%f contacts=%+v", pos.Y, c)
+	}
+
+	body.Y = 16
+	if pos, c := m.MoveAndSlide(body, math2d.Vec2{Y: -12}, 0); pos.Y != 4 || c.Ceiling {
+		t.Fatalf("expected to jump through from below to Y=4, got Y=%f contacts=%+v", pos.Y, c)
+	}
+
+	body.Y = 1
+	if pos, _ := m.MoveAndSlide(body, math2d.Vec2{Y: 4}, 0); pos.Y != 5 {
+		t.Fatalf("expected to drop through to Y=5, got Y=%f", pos.Y)
+	}
+}
+
+func TestMoveAndSlideWalksUpSlope(t *testing.T) {
+	m := collisionMap(
+		"....",
+		"./##",
+		"####",
+	)
+	body := math2d.Rect{X: 2, Y: 8, W: 4, H: 8}
+	var c TileContacts
+	for i := 0; i < 8; i++ {
+		var pos math2d.Vec2
+		pos, c = m.MoveAndSlide(body, math2d.Vec2{X: 2, Y: 1}, 0)
+		body.X, body.Y = pos.X, pos.Y
+		if !c.Grounded || c.Wall() {
+			t.Fatalf("step %d: expected grounded without walls, got %+v at %v", i, c, pos)
+		}
+	}
+	if body.X != 18 || body.Bottom() != 8 {
+		t.Fatalf("expected to reach the plateau at (18, bottom 8), got (%f, bottom %f)", body.X, body.Bottom())
+	}
+}
+
+func TestMoveAndSlideFollowsSlopeDown(t *testing.T) {
+	m := collisionMap(
+		"....",
+		"##\\.",
+		"####",
+	)
+	body := math2d.Rect{X: 10, Y: 0, W: 4, H: 8}
+	for i := 0; i < 8; i++ {
+		pos, c := m.MoveAndSlide(body, math2d.Vec2{X: 2}, 0)
+		body.X, body.Y = pos.X, pos.Y
+		if !c.Grounded {
+			t.Fatalf("step %d: body left the slope at %v", i, pos)
+		}
+	}
+	if body.Bottom() != 16 {
+		t.Fatalf("expected to end on the lower floor, bottom=%f", body.Bottom())
+	}
+}
+
+func TestMoveAndSlideGentleSlope(t *testing.T) {
+	m := collisionMap(
+		"..",
+		"ab",
+		"##",
+	)
+	body := math2d.Rect{X: 10, Y: 0, W: 4, H: 8}
+	pos, c := m.MoveAndSlide(body, math2d.Vec2{Y: 8}, 0)
+	if !c.OnSlope || pos.Y+8 != 9 {
+		t.Fatalf("expected to rest on the upper slope at bottom 9, got bottom=%f contacts=%+v", pos.Y+8, c)
+	}
+}
+
+func TestMoveAndSlideLadderAndHazard(t *testing.T) {
+	m := collisionMap(
+		"H^",
+		"##",
+	)
+	_, c := m.MoveAndSlide(math2d.Rect{X: 4, Y: 0, W: 8, H: 8}, math2d.Vec2{}, 0)
+	if !c.Ladder || !c.Hazard || !c.Grounded {
+		t.Fatalf("expected ladder, hazard and ground contacts, got %+v", c)
+	}
+}
+
+func TestRaycastTiles(t *testing.T) {
+	m := collisionMap(
+		"....",
+		"-./.",
+		"####",
+	)
+	hit, ok := m.RaycastTiles(0, math2d.Vec2{X: 12, Y: 0}, math2d.Vec2{Y: 1}, 100)
+	if !ok || hit.Point.Y != 16 || hit.Normal.Y != -1 || hit.CellY != 2 {
+		t.Fatalf("expected floor hit at Y=16, got %+v ok=%v", hit, ok)
+	}
+
+	hit, ok = m.RaycastTiles(0, math2d.Vec2{X: 20, Y: 0}, math2d.Vec2{Y: 1}, 100)
+	if !ok || !almostEq(hit.Point.Y, 12, 0.01) || hit.Shape != TileSlopeUp45 || hit.Normal.X >= 0 {
+		t.Fatalf("expected slope hit at Y=12 facing up-left, got %+v ok=%v", hit, ok)
+	}
+
+	hit, ok = m.RaycastTiles(0, math2d.Vec2{X: 4, Y: 15}, math2d.Vec2{Y: -1}, 100)
+	if ok {
+		t.Fatalf("one-way platform should not stop an upward ray, got %+v", hit)
+	}
+
+	if _, ok = m.RaycastTiles(0, math2d.Vec2{X: 12, Y: 0}, math2d.Vec2{Y: 1}, 10); ok {
+		t.Fatal("hit beyond maxDist should be ignored")
+	}
+}
+
+func TestShapeAtMirrorsFlippedSlopes(t *testing.T) {
+	m := collisionMap("/a#")
+	m.parsed.Layers[0].Flips = []uint8{format.CellFlipH, format.CellFlipH, format.CellFlipH}
+	want := []TileShape{TileSlopeDown45, TileSlopeDown22Low, TileSolid}
+	for x, shape := range want {
+		if got := m.ShapeAt(0, x, 0); got != shape {
+			t.Fatalf("ShapeAt(0, %d, 0) = %d, want %d", x, got, shape)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package gosprite64
+
+import (
+	"image"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// TileFlip holds a map cell's flip bits. TileFlipD transposes the tile
+// before TileFlipH and TileFlipV mirror it, as in Tiled, so
+// TileFlipD|TileFlipH turns a tile 90° clockwise.
+type TileFlip uint8
+
+const (
+	TileFlipH TileFlip = TileFlip(format.CellFlipH)
+	TileFlipV TileFlip = TileFlip(format.CellFlipV)
+	TileFlipD TileFlip = TileFlip(format.CellFlipD)
+)
+
+// flippedImage returns a copy of src drawn with flip. The textured path
+// flips through texture coordinates; this serves the image fallback.
+func flippedImage(src image.Image, flip TileFlip) image.Image {
+	if src == nil || flip == 0 {
+		return src
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if flip&TileFlipD != 0 {
+		w, h = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			u, v := x, y
+			if flip&TileFlipH != 0 {
+				u = w - 1 - u
+			}
+			if flip&TileFlipV != 0 {
+				v = h - 1 - v
+			}
+			if flip&TileFlipD != 0 {
+				u, v = v, u
+			}
+			dst.Set(x, y, src.At(b.Min.X+u, b.Min.Y+v))
+		}
+	}
+	return dst
+}
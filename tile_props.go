@@ -0,0 +1,129 @@
+package gosprite64
+
+import (
+	"slices"
+	"strconv"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+// TileFlags is a bitfield of per-tile flags. Bits are named in the sheet's
+// tile properties file; Sheet.TileFlag looks a bit up by name.
+type TileFlags uint32
+
+// TileProps is the metadata a sheet stores for one tile: an optional
+// collision shape, flags and key/value properties. The zero TileProps means
+// the tile has no metadata.
+type TileProps struct {
+	Shape    TileShape
+	HasShape bool
+	Flags    TileFlags
+
+	props []format.ParsedTileKV
+}
+
+// Has reports whether every bit in flags is set.
+func (p TileProps) Has(flags TileFlags) bool {
+	return p.Flags&flags == flags
+}
+
+func (p TileProps) value(key string) (string, bool) {
+	for _, kv := range p.props {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return "", false
+}
+
+// Str returns the property stored under key, or def when it is missing.
+func (p TileProps) Str(key, def string) string {
+	if s, ok := p.value(key); ok {
+		return s
+	}
+	return def
+}
+
+// Int returns the property stored under key as an integer, or def when it
+// is missing or not a number.
+func (p TileProps) Int(key string, def int) int {
+	s, ok := p.value(key)
+	if !ok {
+		return def
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// Float returns the property stored under key as a float, or def when it
+// is missing or not a number.
+func (p TileProps) Float(key string, def float32) float32 {
+	s, ok := p.value(key)
+	if !ok {
+		return def
+	}
+	v, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return def
+	}
+	return float32(v)
+}
+
+// Bool returns the property stored under key as a bool, or def when it is
+// missing or not a bool.
+func (p TileProps) Bool(key string, def bool) bool {
+	s, ok := p.value(key)
+	if !ok {
+		return def
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// TileProps returns the metadata for tileID, or the zero TileProps if the
+// sheet has none for it.
+func (s *Sheet) TileProps(tileID uint16) TileProps {
+	if s == nil {
+		return TileProps{}
+	}
+	tile, ok := s.parsed.TileProps.Find(tileID)
+	if !ok {
+		return TileProps{}
+	}
+	p := TileProps{Flags: TileFlags(tile.Flags), props: tile.Props}
+	if tile.Shape != 0 {
+		p.Shape = TileShape(tile.Shape - 1)
+		p.HasShape = true
+	}
+	return p
+}
+
+// TileFlag returns the bit for the flag called name, or 0 if the sheet does
+// not declare it.
+func (s *Sheet) TileFlag(name string) TileFlags {
+	if s == nil {
+		return 0
+	}
+	i := slices.Index(s.parsed.TileProps.FlagNames, name)
+	if i < 0 {
+		return 0
+	}
+	return 1 << i
+}
+
+// TilePropsAt returns the metadata of the tile in cell (x, y) of layer,
+// read from the sheet the layer draws with. Maps not loaded through a
+// Scene have no sheets and return the zero TileProps.
+func (m *Map) TilePropsAt(layer, x, y int) TileProps {
+	tile, _, ok := m.TileAt(layer, x, y)
+	if !ok || tile == 0 || layer >= len(m.layerSheets) {
+		return TileProps{}
+	}
+	return m.layerSheets[layer].TileProps(tile)
+}
@@ -0,0 +1,85 @@
+package gosprite64
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+)
+
+func TestTileShapeNamesMatchTileShape(t *testing.T) {
+	if len(format.TileShapeNames) != int(TileHazard)+1 {
+		t.Fatalf("format lists %d shapes, runtime has %d", len(format.TileShapeNames), TileHazard+1)
+	}
+	for name, shape := range map[string]TileShape{
+		"empty": TileEmpty, "one_way": TileOneWay, "slope_up_22_high": TileSlopeUp22High, "ladder": TileLadder,
+	} {
+		if got := slices.Index(format.TileShapeNames, name); got != int(shape) {
+			t.Fatalf("%s is at index %d, want %d", name, got, shape)
+		}
+	}
+}
+
+func propsScene(t *testing.T) *Scene {
+	t.Helper()
+	return loadTestSceneWithProps(t, format.MapConfig{
+		Width: 4, Height: 1, LayerCount: 1, CellBits: 16, ChunkWidth: 4, ChunkHeight: 1,
+		Layers: []format.MapLayerConfig{{SheetID: 1, Cells: []uint16{1, 2, 3, 4}}},
+	}, format.TilePropsConfig{
+		Flags: []string{"water", "ice"},
+		Tiles: []format.TilePropConfig{
+			{ID: 2, Shape: "one_way", Flags: []string{"ice"}, Props: map[string]any{"friction": 0.25}},
+			{ID: 3, Shape: "hazard", Props: map[string]any{"damage": 3, "tag": "spikes", "lethal": true}},
+		},
+	})
+}
+
+func TestSheetTileProps(t *testing.T) {
+	scene := propsScene(t)
+	sheet := scene.Sheet(0)
+
+	ice := sheet.TileFlag("ice")
+	if ice != 2 || sheet.TileFlag("lava") != 0 {
+		t.Fatalf("TileFlag(ice) = %d, TileFlag(lava) = %d", ice, sheet.TileFlag("lava"))
+	}
+
+	p := sheet.TileProps(2)
+	if !p.HasShape || p.Shape != TileOneWay || !p.Has(ice) || p.Has(sheet.TileFlag("water")) {
+		t.Fatalf("tile 2 props = %+v", p)
+	}
+	if got := p.Float("friction", 1); got != 0.25 {
+		t.Fatalf("friction = %f, want 0.25", got)
+	}
+
+	spikes := scene.Map().TilePropsAt(0, 2, 0)
+	if spikes.Int("damage", 0) != 3 || !spikes.Bool("lethal", false) {
+		t.Fatalf("spike props = %+v", spikes)
+	}
+	if tag := spikes.Str("tag", ""); tag != "spikes" {
+		t.Fatalf("tag = %q, want spikes", tag)
+	}
+	if spikes.Str("missing", "none") != "none" || !spikes.Bool("tag", true) {
+		t.Fatal("missing or mistyped props should return the default")
+	}
+	if p := sheet.TileProps(1); p.HasShape || p.Flags != 0 || p.Int("damage", -1) != -1 {
+		t.Fatalf("tile without metadata = %+v", p)
+	}
+}
+
+func TestShapeAtReadsSheetProps(t *testing.T) {
+	m := propsScene(t).Map()
+	want := []TileShape{TileSolid, TileOneWay, TileHazard, TileSolid}
+	for x, shape := range want {
+		if got := m.ShapeAt(0, x, 0); got != shape {
+			t.Fatalf("ShapeAt(%d) = %d, want %d", x, got, shape)
+		}
+	}
+
+	m.SetTileShape(2, TileLadder)
+	if got := m.ShapeAt(0, 1, 0); got != TileLadder {
+		t.Fatalf("SetTileShape should override sheet props, got %d", got)
+	}
+	if got := m.ShapeOf(3); got != TileSolid {
+		t.Fatalf("ShapeOf ignores sheets and should return solid, got %d", got)
+	}
+}
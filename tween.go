@@ -0,0 +1,269 @@
+package gosprite64
+
+import (
+	"image/color"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// Tween animates one value over a fixed number of frames. Create tweens
+// through a TweenManager; the builder methods return the tween so options
+// can be chained:
+//
+//	tweens.Float(&alpha, 1, 30, math2d.EaseOutQuad).Delay(10).OnComplete(showMenu)
+type Tween struct {
+	manager    *TweenManager
+	frames     int
+	elapsed    int
+	delay      int
+	repeat     int
+	yoyo       bool
+	reverse    bool
+	ease       math2d.EaseFunc
+	onComplete func()
+	next       *Tween
+
+	capture func()
+	apply   func(p float32)
+
+	started bool
+	pending bool
+	done    bool
+}
+
+// TweenManager advances all of its tweens once per Update. Tweens are
+// stepped in creation order, so results are identical on host and console.
+type TweenManager struct {
+	tweens []*Tween
+}
+
+// NewTweenManager creates an empty tween manager.
+func NewTweenManager() *TweenManager {
+	return &TweenManager{}
+}
+
+// Float animates *value to `to` over frames, shaped by ease (linear when nil).
+func (m *TweenManager) Float(value *float32, to float32, frames int, ease math2d.EaseFunc) *Tween {
+	if m == nil || value == nil {
+		return nil
+	}
+	var from float32
+	return m.add(frames, ease,
+		func() { from = *value },
+		func(p float32) { *value = math2d.Lerp(from, to, p) })
+}
+
+// Vec2 animates *value to `to` over frames.
+func (m *TweenManager) Vec2(value *math2d.Vec2, to math2d.Vec2, frames int, ease math2d.EaseFunc) *Tween {
+	if m == nil || value == nil {
+		return nil
+	}
+	var from math2d.Vec2
+	return m.add(frames, ease,
+		func() { from = *value },
+		func(p float32) { *value = from.Lerp(to, p) })
+}
+
+// Color animates *value to `to` over frames, channel by channel. Channels
+// are clamped to 0..255 when a curve overshoots.
+func (m *TweenManager) Color(value *color.RGBA, to color.RGBA, frames int, ease math2d.EaseFunc) *Tween {
+	if m == nil || value == nil {
+		return nil
+	}
+	var from color.RGBA
+	return m.add(frames, ease,
+		func() { from = *value },
+		func(p float32) {
+			*value = color.RGBA{
+				R: lerpChannel(from.R, to.R, p),
+				G: lerpChannel(from.G, to.G, p),
+				B: lerpChannel(from.B, to.B, p),
+				A: lerpChannel(from.A, to.A, p),
+			}
+		})
+}
+
+func lerpChannel(a, b uint8, p float32) uint8 {
+	v := math2d.Clamp(math2d.Lerp(float32(a), float32(b), p), 0, 255)
+	return uint8(v + 0.5)
+}
+
+func (m *TweenManager) add(frames int, ease math2d.EaseFunc, capture func(), apply func(p float32)) *Tween {
+	if frames < 0 {
+		frames = 0
+	}
+	if ease == nil {
+		ease = math2d.Linear
+	}
+	tw := &Tween{manager: m, frames: frames, ease: ease, capture: capture, apply: apply}
+	m.tweens = append(m.tweens, tw)
+	return tw
+}
+
+// Update advances every running tween by one frame. Tweens started by Then
+// during this Update begin on the next one.
+func (m *TweenManager) Update() {
+	if m == nil {
+		return
+	}
+	n := len(m.tweens)
+	for i := 0; i < n; i++ {
+		tw := m.tweens[i]
+		if tw.done || tw.pending {
+			continue
+		}
+		tw.step()
+	}
+
+	live := m.tweens[:0]
+	for _, tw := range m.tweens {
+		if !tw.done && !tw.pending {
+			live = append(live, tw)
+		}
+	}
+	clear(m.tweens[len(live):])
+	m.tweens = live
+}
+
+// Len returns the number of running tweens, not counting ones waiting on Then.
+func (m *TweenManager) Len() int {
+	if m == nil {
+		return 0
+	}
+	count := 0
+	for _, tw := range m.tweens {
+		if !tw.done && !tw.pending {
+			count++
+		}
+	}
+	return count
+}
+
+// Clear stops every tween without calling completion callbacks.
+func (m *TweenManager) Clear() {
+	if m == nil {
+		return
+	}
+	for _, tw := range m.tweens {
+		tw.done = true
+	}
+	clear(m.tweens)
+	m.tweens = m.tweens[:0]
+}
+
+func (tw *Tween) step() {
+	if tw.delay > 0 {
+		tw.delay--
+		return
+	}
+	if !tw.started {
+		tw.started = true
+		tw.capture()
+	}
+	if tw.elapsed < tw.frames {
+		tw.elapsed++
+	}
+	tw.apply(tw.ease(tw.progress()))
+	if tw.elapsed < tw.frames {
+		return
+	}
+
+	if tw.repeat != 0 {
+		if tw.repeat > 0 {
+			tw.repeat--
+		}
+		tw.elapsed = 0
+		if tw.yoyo {
+			tw.reverse = !tw.reverse
+		}
+		return
+	}
+
+	tw.done = true
+	if tw.onComplete != nil {
+		tw.onComplete()
+	}
+	if next := tw.next; next != nil && !next.done {
+		next.pending = false
+		tw.manager.tweens = append(tw.manager.tweens, next)
+	}
+}
+
+func (tw *Tween) progress() float32 {
+	p := float32(1)
+	if tw.frames > 0 {
+		p = float32(tw.elapsed) / float32(tw.frames)
+	}
+	if tw.reverse {
+		p = 1 - p
+	}
+	return p
+}
+
+// Delay waits the given number of frames before the tween starts. The start
+// value is read when the delay ends, not when the tween is created.
+func (tw *Tween) Delay(frames int) *Tween {
+	if tw == nil {
+		return nil
+	}
+	if frames < 0 {
+		frames = 0
+	}
+	tw.delay = frames
+	return tw
+}
+
+// Repeat plays the tween times more after the first run. A negative count
+// repeats forever.
+func (tw *Tween) Repeat(times int) *Tween {
+	if tw == nil {
+		return nil
+	}
+	tw.repeat = times
+	return tw
+}
+
+// Yoyo makes each repeat run in the opposite direction of the one before.
+func (tw *Tween) Yoyo(enabled bool) *Tween {
+	if tw == nil {
+		return nil
+	}
+	tw.yoyo = enabled
+	return tw
+}
+
+// OnComplete calls fn once, on the frame the tween finishes its last repeat.
+func (tw *Tween) OnComplete(fn func()) *Tween {
+	if tw == nil {
+		return nil
+	}
+	tw.onComplete = fn
+	return tw
+}
+
+// Then holds next until this tween completes, then starts it on the
+// following frame. It returns next, so chains read in playback order:
+//
+//	slideIn.Then(hold).Then(slideOut)
+func (tw *Tween) Then(next *Tween) *Tween {
+	if tw == nil || next == nil || next == tw {
+		return next
+	}
+	next.pending = true
+	tw.next = next
+	return next
+}
+
+// Stop ends the tween where it is, without calling its completion callback
+// or starting chained tweens.
+func (tw *Tween) Stop() {
+	if tw == nil {
+		return
+	}
+	tw.done = true
+}
+
+// Done reports whether the tween has finished or been stopped.
+func (tw *Tween) Done() bool {
+	return tw == nil || tw.done
+}
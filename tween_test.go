@@ -0,0 +1,159 @@
+package gosprite64
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+func TestTweenFloatLinear(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	tw := m.Float(&x, 10, 4, nil)
+	expected := []float32{2.5, 5, 7.5, 10}
+	for i, want := range expected {
+		m.Update()
+		if !almostEq(x, want, 0.001) {
+			t.Fatalf("frame %d: x = %v, want %v", i+1, x, want)
+		}
+	}
+	if !tw.Done() || m.Len() != 0 {
+		t.Fatal("tween should be done and removed after its last frame")
+	}
+}
+
+func TestTweenEaseAndZeroFrames(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	m.Float(&x, 8, 2, math2d.EaseInQuad)
+	m.Update()
+	if !almostEq(x, 2, 0.001) {
+		t.Fatalf("EaseInQuad at half should give 2, got %v", x)
+	}
+
+	y := float32(3)
+	m.Float(&y, 9, 0, nil)
+	m.Update()
+	if y != 9 {
+		t.Fatalf("zero-frame tween should jump to the target, got %v", y)
+	}
+}
+
+func TestTweenDelayCapturesStartLate(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	m.Float(&x, 10, 2, nil).Delay(2)
+	m.Update()
+	x = 4
+	m.Update()
+	if x != 4 {
+		t.Fatalf("tween should not move during its delay, got %v", x)
+	}
+	m.Update()
+	if !almostEq(x, 7, 0.001) {
+		t.Fatalf("tween should start from the value at the end of the delay, got %v", x)
+	}
+}
+
+func TestTweenRepeatYoyo(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	completed := 0
+	m.Float(&x, 4, 2, nil).Repeat(1).Yoyo(true).OnComplete(func() { completed++ })
+	expected := []float32{2, 4, 2, 0}
+	for i, want := range expected {
+		m.Update()
+		if !almostEq(x, want, 0.001) {
+			t.Fatalf("frame %d: x = %v, want %v", i+1, x, want)
+		}
+	}
+	if completed != 1 {
+		t.Fatalf("OnComplete should fire once after the last repeat, got %d", completed)
+	}
+	if m.Len() != 0 {
+		t.Fatal("finished tween should be removed")
+	}
+}
+
+func TestTweenRepeatForever(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	tw := m.Float(&x, 1, 1, nil).Repeat(-1)
+	for i := 0; i < 10; i++ {
+		m.Update()
+	}
+	if tw.Done() {
+		t.Fatal("negative repeat should never finish")
+	}
+	tw.Stop()
+	m.Update()
+	if m.Len() != 0 {
+		t.Fatal("stopped tween should be removed")
+	}
+}
+
+func TestTweenThenChains(t *testing.T) {
+	m := NewTweenManager()
+	pos := math2d.Vec2{}
+	log := make([]string, 0)
+	first := m.Vec2(&pos, math2d.Vec2{X: 4}, 2, nil).OnComplete(func() { log = append(log, "first") })
+	last := first.Then(m.Vec2(&pos, math2d.Vec2{X: 4, Y: 6}, 2, nil)).OnComplete(func() { log = append(log, "second") })
+
+	if m.Len() != 1 {
+		t.Fatalf("chained tween should wait, got %d running", m.Len())
+	}
+	m.Update()
+	m.Update()
+	if pos.X != 4 || pos.Y != 0 || len(log) != 1 {
+		t.Fatalf("first tween should have finished alone, got %+v %v", pos, log)
+	}
+	m.Update()
+	if !almostEq(pos.Y, 3, 0.001) || pos.X != 4 {
+		t.Fatalf("second tween should start from the end of the first, got %+v", pos)
+	}
+	m.Update()
+	if pos.Y != 6 || !last.Done() || len(log) != 2 {
+		t.Fatalf("chain should finish, got %+v %v", pos, log)
+	}
+}
+
+func TestTweenColorClampsOvershoot(t *testing.T) {
+	m := NewTweenManager()
+	c := color.RGBA{R: 250, A: 0}
+	m.Color(&c, color.RGBA{R: 255, G: 100, A: 255}, 2, math2d.EaseOutBack)
+	m.Update()
+	if c.R != 255 {
+		t.Fatalf("overshooting channel should clamp to 255, got %d", c.R)
+	}
+	m.Update()
+	if c != (color.RGBA{R: 255, G: 100, A: 255}) {
+		t.Fatalf("color should land on the target, got %+v", c)
+	}
+}
+
+func TestTweenManagerClear(t *testing.T) {
+	m := NewTweenManager()
+	x := float32(0)
+	called := false
+	tw := m.Float(&x, 1, 10, nil).OnComplete(func() { called = true })
+	m.Clear()
+	m.Update()
+	if !tw.Done() || called || m.Len() != 0 {
+		t.Fatal("Clear should stop tweens without completion callbacks")
+	}
+}
+
+func TestTweenNilIsNoop(t *testing.T) {
+	var m *TweenManager
+	if m.Float(nil, 1, 1, nil) != nil {
+		t.Fatal("nil manager should not create tweens")
+	}
+	m.Update()
+	m.Clear()
+	var tw *Tween
+	tw.Delay(1).Repeat(1).Yoyo(true).OnComplete(nil).Stop()
+	if !tw.Done() {
+		t.Fatal("nil tween should report done")
+	}
+}
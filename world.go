@@ -0,0 +1,434 @@
+package gosprite64
+
+import (
+	"fmt"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// RoomTransitionStyle selects how a World moves between rooms.
+type RoomTransitionStyle int
+
+const (
+	// RoomCut switches rooms at once.
+	RoomCut RoomTransitionStyle = iota
+	// RoomFade fades to black, switches rooms, then fades back in.
+	RoomFade
+	// RoomScroll pans the camera across the room edge, drawing both rooms.
+	// Links whose Dir is not "n", "s", "e" or "w" fade instead.
+	RoomScroll
+)
+
+// RoomTransition is a transition style and its length. Frames zero means
+// 30; RoomCut ignores it.
+type RoomTransition struct {
+	Style  RoomTransitionStyle
+	Frames int
+}
+
+// RoomChangeFunc is called when a World enters room to from room from.
+// ShiftX and ShiftY move positions from the old room's coordinates into the
+// new one's; the world's camera has already been moved.
+type RoomChangeFunc func(from, to string, shiftX, shiftY int)
+
+// World is a set of rooms, one map each, loaded from a single bundle. The
+// bundle's sheets and animations are shared by every room, and map links
+// join the rooms into a graph. A World keeps the current room and its
+// linked neighbours loaded, and keeps a RoomState per room for as long as
+// the World lives.
+type World struct {
+	// Camera is the view Draw uses and room transitions move. It starts as
+	// a full-screen camera at the origin.
+	Camera *Camera
+
+	bundle     *Bundle
+	sheets     []*Sheet
+	animations []*AnimationSet
+	rooms      []string
+	scenes     map[string]*Scene
+	states     map[string]*RoomState
+	current    string
+	onChange   []RoomChangeFunc
+
+	// The transition in progress: prev is the room being left while
+	// scrolling, next the room a fade enters when it turns black.
+	style      RoomTransitionStyle
+	link       MapLink
+	fade       *Transition
+	fadeIn     int
+	prev       string
+	next       string
+	frame      int
+	frames     int
+	from, pan  math2d.Vec2
+	scrolling  bool
+	transiting bool
+}
+
+// LoadWorld loads the sheets and animations of bundle and enters the map
+// named start. Every map in the bundle is a room, named after its bundle
+// entry.
+func LoadWorld(bundle *Bundle, start string) (*World, error) {
+	if bundle == nil {
+		return nil, fmt.Errorf("load world: nil bundle")
+	}
+
+	w := &World{
+		Camera: newDefaultCamera(),
+		bundle: bundle,
+		scenes: make(map[string]*Scene),
+		states: make(map[string]*RoomState),
+	}
+	for _, entry := range bundle.manifest.Entries {
+		switch entry.Kind {
+		case format.BundleKindSheet:
+			sheet, err := bundle.loadSheetEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			w.sheets = append(w.sheets, sheet)
+		case format.BundleKindMap:
+			w.rooms = append(w.rooms, entry.Name)
+		case format.BundleKindAnim:
+			anim, err := bundle.loadAnimEntry(entry)
+			if err != nil {
+				return nil, err
+			}
+			w.animations = append(w.animations, anim)
+		}
+	}
+	if _, err := w.load(start); err != nil {
+		return nil, err
+	}
+	w.current = start
+	w.preload()
+	return w, nil
+}
+
+// Room returns the name of the current room.
+func (w *World) Room() string {
+	if w == nil {
+		return ""
+	}
+	return w.current
+}
+
+// Rooms returns the names of every room, in bundle order.
+func (w *World) Rooms() []string {
+	if w == nil {
+		return nil
+	}
+	return append([]string(nil), w.rooms...)
+}
+
+// Scene returns the scene of the current room.
+func (w *World) Scene() *Scene {
+	if w == nil {
+		return nil
+	}
+	return w.scenes[w.current]
+}
+
+// Map returns the map of the current room.
+func (w *World) Map() *Map {
+	return w.Scene().Map()
+}
+
+// State returns the persistent state of room, creating it on first use. It
+// survives leaving the room and the room being unloaded.
+func (w *World) State(room string) *RoomState {
+	if w == nil {
+		return nil
+	}
+	s, ok := w.states[room]
+	if !ok {
+		s = &RoomState{}
+		w.states[room] = s
+	}
+	return s
+}
+
+// OnRoomChange registers fn to be called each time the world enters a
+// room.
+func (w *World) OnRoomChange(fn RoomChangeFunc) {
+	if w == nil || fn == nil {
+		return
+	}
+	w.onChange = append(w.onChange, fn)
+}
+
+// Link returns the current room's link with direction dir, such as "e" or
+// the name of a door.
+func (w *World) Link(dir string) (MapLink, bool) {
+	for _, l := range w.Map().Links() {
+		if l.Dir == dir {
+			return l, true
+		}
+	}
+	return MapLink{}, false
+}
+
+// LinkAt returns the link to the neighbouring room covering p, a point in
+// the current room's pixel coordinates. Use it with a body's centre once
+// the body leaves the room. Only rooms in the world's bundle are found.
+func (w *World) LinkAt(p math2d.Vec2) (MapLink, bool) {
+	if w == nil {
+		return MapLink{}, false
+	}
+	for _, l := range w.Map().Links() {
+		scene, ok := w.scenes[l.Map]
+		if !ok {
+			continue
+		}
+		m := scene.Map()
+		r := math2d.Rect{X: float32(l.OffsetX), Y: float32(l.OffsetY), W: float32(m.PixelWidth()), H: float32(m.PixelHeight())}
+		if r.ContainsPoint(p) {
+			return l, true
+		}
+	}
+	return MapLink{}, false
+}
+
+// Go moves to the room link points at with transition tr. It fails if the
+// room is not in the world's bundle or another transition is running.
+func (w *World) Go(link MapLink, tr RoomTransition) error {
+	if w == nil {
+		return fmt.Errorf("world: nil world")
+	}
+	if w.transiting {
+		return fmt.Errorf("world: transition to %q in progress", w.next)
+	}
+	if _, err := w.load(link.Map); err != nil {
+		return err
+	}
+
+	frames := tr.Frames
+	if frames <= 0 {
+		frames = 30
+	}
+	pan, ok := w.scrollPan(link.Dir)
+	style := tr.Style
+	if style == RoomScroll && !ok {
+		style = RoomFade
+	}
+
+	switch style {
+	case RoomFade:
+		w.transiting = true
+		w.next = link.Map
+		w.link = link
+		w.fade = StartTransition(FadeToBlack, frames/2)
+		w.fadeIn = frames - frames/2
+	case RoomScroll:
+		w.transiting = true
+		w.scrolling = true
+		w.prev = w.current
+		w.next = link.Map
+		w.enter(link)
+		w.frame, w.frames = 0, frames
+		w.from = math2d.Vec2{X: float32(w.Camera.X), Y: float32(w.Camera.Y)}
+		w.pan = pan
+	default:
+		w.enter(link)
+	}
+	w.style = style
+	return nil
+}
+
+// Transitioning reports whether a room transition is running. Games
+// usually pause play and camera follow until it ends.
+func (w *World) Transitioning() bool {
+	return w != nil && w.transiting
+}
+
+// Update advances the running transition and the current room's tile
+// animations. Call it once per frame.
+func (w *World) Update() {
+	if w == nil {
+		return
+	}
+	w.Scene().Update()
+	if !w.transiting {
+		return
+	}
+
+	switch w.style {
+	case RoomFade:
+		w.fade.Advance()
+		if !w.fade.Done() {
+			return
+		}
+		if w.fade.Style == FadeToBlack {
+			w.enter(w.link)
+			w.fade = StartTransition(FadeFromBlack, w.fadeIn)
+			return
+		}
+		w.fade = nil
+		w.transiting = false
+	case RoomScroll:
+		w.frame++
+		t := float32(w.frame) / float32(w.frames)
+		w.Camera.X = int(w.from.X + w.pan.X*t)
+		w.Camera.Y = int(w.from.Y + w.pan.Y*t)
+		if w.frame >= w.frames {
+			w.scrolling = false
+			w.transiting = false
+			w.prev = ""
+			w.preload()
+		}
+	}
+}
+
+// Draw draws the current room with the world's camera. While scrolling it
+// also draws the room being left; while fading it draws the fade on top.
+func (w *World) Draw() {
+	if w == nil {
+		return
+	}
+	if w.scrolling {
+		if prev, ok := w.scenes[w.prev]; ok {
+			cam := *w.Camera
+			cam.X += w.link.OffsetX
+			cam.Y += w.link.OffsetY
+			prev.Draw(&cam)
+		}
+	}
+	w.Scene().Draw(w.Camera)
+	w.fade.Draw()
+}
+
+// scrollPan returns how far the camera pans to scroll one screen along dir.
+func (w *World) scrollPan(dir string) (math2d.Vec2, bool) {
+	zoom := w.Camera.EffectiveZoom()
+	width := float32(w.Camera.Width) / zoom
+	height := float32(w.Camera.Height) / zoom
+	switch dir {
+	case "e":
+		return math2d.Vec2{X: width}, true
+	case "w":
+		return math2d.Vec2{X: -width}, true
+	case "s":
+		return math2d.Vec2{Y: height}, true
+	case "n":
+		return math2d.Vec2{Y: -height}, true
+	}
+	return math2d.Vec2{}, false
+}
+
+// enter makes link's room current, moving the camera into its coordinates.
+func (w *World) enter(link MapLink) {
+	from := w.current
+	w.current = link.Map
+	w.link = link
+	w.Camera.X -= link.OffsetX
+	w.Camera.Y -= link.OffsetY
+	w.preload()
+	for _, fn := range w.onChange {
+		fn(from, link.Map, -link.OffsetX, -link.OffsetY)
+	}
+}
+
+// load returns the scene of room, loading its map if needed.
+func (w *World) load(room string) (*Scene, error) {
+	if scene, ok := w.scenes[room]; ok {
+		return scene, nil
+	}
+	m, err := w.bundle.LoadMap(room)
+	if err != nil {
+		return nil, fmt.Errorf("load world: room %q: %w", room, err)
+	}
+	scene, err := newScene(w.bundle, w.sheets, w.animations, m)
+	if err != nil {
+		return nil, fmt.Errorf("load world: room %q: %w", room, err)
+	}
+	w.scenes[room] = scene
+	return scene, nil
+}
+
+// preload loads the current room's neighbours in the bundle and unloads
+// every other room except one being scrolled away from.
+func (w *World) preload() {
+	keep := map[string]bool{w.current: true, w.prev: true}
+	for _, l := range w.Map().Links() {
+		if !w.hasRoom(l.Map) {
+			continue
+		}
+		if _, err := w.load(l.Map); err == nil {
+			keep[l.Map] = true
+		}
+	}
+	for room := range w.scenes {
+		if !keep[room] {
+			delete(w.scenes, room)
+		}
+	}
+}
+
+func (w *World) hasRoom(room string) bool {
+	for _, r := range w.rooms {
+		if r == room {
+			return true
+		}
+	}
+	return false
+}
+
+// RoomState holds what a game remembers about a room between visits, such
+// as opened chests and defeated enemies, keyed by name. The zero value is
+// empty and ready to use.
+type RoomState struct {
+	flags map[string]bool
+	ints  map[string]int
+}
+
+// Set turns the flag key on or off.
+func (s *RoomState) Set(key string, on bool) {
+	if s == nil {
+		return
+	}
+	if !on {
+		delete(s.flags, key)
+		return
+	}
+	if s.flags == nil {
+		s.flags = make(map[string]bool)
+	}
+	s.flags[key] = true
+}
+
+// Has reports whether the flag key is on.
+func (s *RoomState) Has(key string) bool {
+	return s != nil && s.flags[key]
+}
+
+// SetInt stores a number under key.
+func (s *RoomState) SetInt(key string, v int) {
+	if s == nil {
+		return
+	}
+	if s.ints == nil {
+		s.ints = make(map[string]int)
+	}
+	s.ints[key] = v
+}
+
+// Int returns the number stored under key, or def if there is none.
+func (s *RoomState) Int(key string, def int) int {
+	if s == nil {
+		return def
+	}
+	if v, ok := s.ints[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Reset forgets everything stored for the room.
+func (s *RoomState) Reset() {
+	if s == nil {
+		return
+	}
+	s.flags = nil
+	s.ints = nil
+}
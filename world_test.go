@@ -0,0 +1,197 @@
+package gosprite64
+
+import (
+	"image"
+	"testing"
+
+	"github.com/drpaneas/gosprite64/internal/tile2d/format"
+	tileloader "github.com/drpaneas/gosprite64/internal/tile2d/loader"
+	"github.com/drpaneas/gosprite64/math2d"
+)
+
+// loadTestWorld builds three 4x2-tile rooms: a, b east of a, and c south
+// of b, and enters a.
+func loadTestWorld(t *testing.T) *World {
+	t.Helper()
+
+	sheet, err := format.BuildSheet(image.NewNRGBA(image.Rect(0, 0, 32, 8)), 8, 8)
+	if err != nil {
+		t.Fatalf("BuildSheet: %v", err)
+	}
+	room := func(links ...format.MapLinkConfig) []byte {
+		raw, err := format.BuildMap(format.MapConfig{
+			Width: 4, Height: 2, LayerCount: 1, CellBits: 8, ChunkWidth: 4, ChunkHeight: 2,
+			Layers: []format.MapLayerConfig{{Cells: []uint16{1, 1, 1, 1, 2, 2, 2, 2}}},
+			Links:  links,
+		})
+		if err != nil {
+			t.Fatalf("BuildMap: %v", err)
+		}
+		return raw
+	}
+	bundleRaw, err := format.BuildBundle([]format.BundleEntry{
+		{Kind: format.BundleKindSheet, Name: "tiles", Path: "tiles.sht2"},
+		{Kind: format.BundleKindMap, Name: "a", Path: "a.map2"},
+		{Kind: format.BundleKindMap, Name: "b", Path: "b.map2"},
+		{Kind: format.BundleKindMap, Name: "c", Path: "c.map2"},
+	})
+	if err != nil {
+		t.Fatalf("BuildBundle: %v", err)
+	}
+
+	loader := tileloader.NewMemoryLoader(map[string][]byte{
+		"world.bnd2": bundleRaw,
+		"tiles.sht2": sheet,
+		"a.map2":     room(format.MapLinkConfig{Dir: "e", Map: "b", OffsetX: 32}, format.MapLinkConfig{Dir: "door", Map: "elsewhere"}),
+		"b.map2":     room(format.MapLinkConfig{Dir: "w", Map: "a", OffsetX: -32}, format.MapLinkConfig{Dir: "s", Map: "c", OffsetY: 16}),
+		"c.map2":     room(format.MapLinkConfig{Dir: "n", Map: "b", OffsetY: -16}),
+	})
+	bundle, err := OpenBundleWithLoader("world.bnd2", loader)
+	if err != nil {
+		t.Fatalf("OpenBundleWithLoader: %v", err)
+	}
+	w, err := LoadWorld(bundle, "a")
+	if err != nil {
+		t.Fatalf("LoadWorld: %v", err)
+	}
+	w.Camera.Width, w.Camera.Height = 32, 16
+	return w
+}
+
+func requireLoadedRooms(t *testing.T, w *World, want ...string) {
+	t.Helper()
+	if len(w.scenes) != len(want) {
+		t.Fatalf("loaded rooms = %d, want %v", len(w.scenes), want)
+	}
+	for _, room := range want {
+		if _, ok := w.scenes[room]; !ok {
+			t.Fatalf("room %q not loaded, want %v", room, want)
+		}
+	}
+}
+
+func TestLoadWorldPreloadsNeighbours(t *testing.T) {
+	w := loadTestWorld(t)
+	if w.Room() != "a" || w.Map() == nil {
+		t.Fatalf("Room() = %q, want a", w.Room())
+	}
+	if rooms := w.Rooms(); len(rooms) != 3 || rooms[2] != "c" {
+		t.Fatalf("Rooms() = %v", rooms)
+	}
+	requireLoadedRooms(t, w, "a", "b")
+
+	if l, ok := w.LinkAt(math2d.Vec2{X: 40, Y: 8}); !ok || l.Map != "b" {
+		t.Fatalf("LinkAt(40, 8) = %+v, %v; want the link to b", l, ok)
+	}
+	if _, ok := w.LinkAt(math2d.Vec2{X: 10, Y: 8}); ok {
+		t.Fatal("LinkAt() found a link inside the current room")
+	}
+
+	if _, err := LoadWorld(w.bundle, "missing"); err == nil {
+		t.Fatal("LoadWorld() accepted a start room not in the bundle")
+	}
+	door, _ := w.Link("door")
+	if err := w.Go(door, RoomTransition{}); err == nil || w.Room() != "a" {
+		t.Fatalf("Go() to a room outside the bundle = %v, room %q", err, w.Room())
+	}
+}
+
+func TestWorldCutKeepsRoomState(t *testing.T) {
+	w := loadTestWorld(t)
+	var changes []string
+	var shift [2]int
+	w.OnRoomChange(func(from, to string, shiftX, shiftY int) {
+		changes = append(changes, from+">"+to)
+		shift = [2]int{shiftX, shiftY}
+	})
+	w.State("a").Set("chest", true)
+	w.State("a").SetInt("enemies", 2)
+
+	east, _ := w.Link("e")
+	if err := w.Go(east, RoomTransition{Style: RoomCut}); err != nil {
+		t.Fatalf("Go(e) error = %v", err)
+	}
+	if w.Room() != "b" || w.Transitioning() || w.Camera.X != -32 || shift != [2]int{-32, 0} {
+		t.Fatalf("after cut: room %q, camera x %d, shift %v", w.Room(), w.Camera.X, shift)
+	}
+	requireLoadedRooms(t, w, "a", "b", "c")
+
+	south, _ := w.Link("s")
+	if err := w.Go(south, RoomTransition{}); err != nil {
+		t.Fatalf("Go(s) error = %v", err)
+	}
+	requireLoadedRooms(t, w, "b", "c")
+
+	north, _ := w.Link("n")
+	w.Go(north, RoomTransition{})
+	west, _ := w.Link("w")
+	w.Go(west, RoomTransition{})
+	if len(changes) != 4 || changes[3] != "b>a" {
+		t.Fatalf("room changes = %v", changes)
+	}
+	if s := w.State("a"); !s.Has("chest") || s.Int("enemies", 0) != 2 || s.Has("door") {
+		t.Fatalf("room a state lost after unloading: %+v", s)
+	}
+	w.State("a").Reset()
+	if w.State("a").Has("chest") {
+		t.Fatal("Reset() kept a flag")
+	}
+}
+
+func TestWorldFadeSwitchesRoomsWhenBlack(t *testing.T) {
+	w := loadTestWorld(t)
+	east, _ := w.Link("e")
+	if err := w.Go(east, RoomTransition{Style: RoomFade, Frames: 4}); err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	if w.Room() != "a" || !w.Transitioning() {
+		t.Fatalf("room %q at fade start, want a while fading out", w.Room())
+	}
+	if err := w.Go(east, RoomTransition{}); err == nil {
+		t.Fatal("Go() during a transition succeeded")
+	}
+
+	w.Update()
+	w.Update()
+	if w.Room() != "b" || w.fade.alpha() != 255 {
+		t.Fatalf("room %q halfway, want b behind a black screen", w.Room())
+	}
+	w.Update()
+	w.Draw()
+	w.Update()
+	if w.Transitioning() {
+		t.Fatal("fade still running after its frames")
+	}
+}
+
+func TestWorldScrollPansAcrossTheEdge(t *testing.T) {
+	w := loadTestWorld(t)
+	east, _ := w.Link("e")
+	if err := w.Go(east, RoomTransition{Style: RoomScroll, Frames: 4}); err != nil {
+		t.Fatalf("Go() error = %v", err)
+	}
+	// The camera moves into b's coordinates at once and pans one screen.
+	if w.Room() != "b" || w.Camera.X != -32 {
+		t.Fatalf("room %q, camera x %d at scroll start", w.Room(), w.Camera.X)
+	}
+	w.Update()
+	w.Update()
+	if w.Camera.X != -16 || w.Camera.Y != 0 {
+		t.Fatalf("camera = (%d, %d) halfway, want (-16, 0)", w.Camera.X, w.Camera.Y)
+	}
+	w.Draw()
+	if w.scenes["a"].Stats().VisibleTiles == 0 || w.Scene().Stats().VisibleTiles == 0 {
+		t.Fatal("scroll did not draw both rooms")
+	}
+	w.Update()
+	w.Update()
+	if w.Transitioning() || w.Camera.X != 0 {
+		t.Fatalf("scroll ended at camera x %d, transitioning %v", w.Camera.X, w.Transitioning())
+	}
+
+	// Links without a compass direction fade instead of scrolling.
+	w.Go(MapLink{Dir: "door", Map: "c"}, RoomTransition{Style: RoomScroll, Frames: 2})
+	if w.Room() != "b" || w.style != RoomFade {
+		t.Fatalf("door scroll: room %q, style %v; want a fade", w.Room(), w.style)
+	}
+}